@@ -0,0 +1,636 @@
+package grss
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"golang.org/x/net/html"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Activity Streams 2.0 https://www.w3.org/TR/activitystreams-core/
+// Activity Vocabulary https://www.w3.org/TR/activitystreams-vocabulary/
+// ActivityPub outbox https://www.w3.org/TR/activitypub/#outbox
+
+const (
+	// ActivityStreamsMime https://www.w3.org/TR/activitystreams-core/#media-type
+	ActivityStreamsMime         = "application/activity+json"
+	ActivityStreamsMimeFallback = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+
+	// ActivityStreamsContext the JSON-LD context of Activity Streams 2.0 documents.
+	ActivityStreamsContext = "https://www.w3.org/ns/activitystreams"
+
+	// ActivityStreamsPublic the special collection addressing an activity to everyone.
+	ActivityStreamsPublic = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+var (
+	ErrNotCollection = errors.New("not an ActivityStreams collection")
+)
+
+// ASObject an Activity Streams 2.0 object, link, activity or collection, with the properties a feed maps to.
+// The type tells them apart: Create, Update and Announce are activities, OrderedCollection and OrderedCollectionPage hold an outbox, Link and Hashtag are links.
+type ASObject struct {
+	// Context @context is the JSON-LD context, https://www.w3.org/ns/activitystreams for the top-level object.
+	Context interface{} `json:"@context,omitempty"`
+
+	// ID id provides the globally unique identifier of an object.
+	ID string `json:"id,omitempty"`
+
+	// Type type identifies the type of the object, such as Note, Article, Create or OrderedCollection.
+	Type string `json:"type,omitempty"`
+
+	// Name name is a simple, human-readable, plain-text name for the object. HTML markup must not be included.
+	Name string `json:"name,omitempty"`
+
+	// Summary summary is a natural language summarization of the object encoded as HTML.
+	Summary string `json:"summary,omitempty"`
+
+	// Content content is the content or textual representation of the object encoded as a JSON string. By default, the value of content is HTML, mediaType can be used to indicate a different content type.
+	Content string `json:"content,omitempty"`
+
+	// MediaType mediaType identifies the MIME media type of the value of content, or of the resource a Link refers to.
+	MediaType string `json:"mediaType,omitempty"`
+
+	// Href href is the target resource pointed to by a Link.
+	Href string `json:"href,omitempty"`
+
+	// Rel rel is a link relation associated with a Link, such as the ones of HTML link elements.
+	Rel string `json:"rel,omitempty"`
+
+	// URL url identifies one or more links to representations of the object.
+	URL ASObjects `json:"url,omitempty"`
+
+	// Published published is the date and time at which the object was published.
+	Published string `json:"published,omitempty"`
+
+	// Updated updated is the date and time at which the object was updated.
+	Updated string `json:"updated,omitempty"`
+
+	// AttributedTo attributedTo identifies one or more entities to which this object is attributed.
+	AttributedTo ASObjects `json:"attributedTo,omitempty"`
+
+	// Actor actor describes one or more entities that either performed or are expected to perform the activity.
+	Actor ASObjects `json:"actor,omitempty"`
+
+	// Object object describes the direct object of the activity, such as the Note a Create creates.
+	Object ASObjects `json:"object,omitempty"`
+
+	// To to identifies an entity considered to be part of the public primary audience of an object.
+	To ASObjects `json:"to,omitempty"`
+
+	// Cc cc identifies an object that is part of the public secondary audience.
+	Cc ASObjects `json:"cc,omitempty"`
+
+	// InReplyTo inReplyTo indicates one or more entities for which this object is considered a response.
+	InReplyTo ASObjects `json:"inReplyTo,omitempty"`
+
+	// Attachment attachment identifies a resource attached or related to an object, such as the Image, Audio or Video of a post.
+	Attachment ASArray `json:"attachment,omitempty"`
+
+	// Tag tag has one or more "tags" that have been associated with an object, such as Hashtag and Mention links.
+	Tag ASArray `json:"tag,omitempty"`
+
+	// Icon icon indicates an entity that describes an icon for this object, square, as the avatar of an actor.
+	Icon ASObjects `json:"icon,omitempty"`
+
+	// Image image indicates an entity that describes an image for this object, without the square restriction of icon.
+	Image ASObjects `json:"image,omitempty"`
+
+	// TotalItems totalItems is a non-negative integer specifying the total number of objects contained by the logical view of the collection.
+	TotalItems *int `json:"totalItems,omitempty"`
+
+	// First first in a paged collection, indicates the furthest preceding page of items in the collection.
+	First ASObjects `json:"first,omitempty"`
+
+	// Next next in a paged collection, indicates the next page of items.
+	Next ASObjects `json:"next,omitempty"`
+
+	// Items items identifies the items contained in a collection, whose order may not be significant.
+	Items ASArray `json:"items,omitempty"`
+
+	// OrderedItems orderedItems the items of an OrderedCollection, an outbox having the most recent first.
+	OrderedItems ASArray `json:"orderedItems,omitempty"`
+}
+
+// ASObjects the values of a property, which in JSON may be a single value or an array, each one an object or the URL of one.
+// A URL is read as an object of which only ID is set, and such an object is written as its URL.
+type ASObjects []*ASObject
+
+func (s *ASObjects) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case len(b) == 0 || bytes.Equal(b, []byte("null")):
+		*s = nil
+	case b[0] == '[':
+		var values []json.RawMessage
+		if err := json.Unmarshal(b, &values); err != nil {
+			return err
+		}
+		*s = nil
+		for _, v := range values {
+			var one ASObjects
+			if err := one.UnmarshalJSON(v); err != nil {
+				return err
+			}
+			*s = append(*s, one...)
+		}
+	case b[0] == '"':
+		var id string
+		if err := json.Unmarshal(b, &id); err != nil {
+			return err
+		}
+		*s = ASObjects{{ID: id}}
+	default:
+		o := &ASObject{}
+		if err := json.Unmarshal(b, o); err != nil {
+			return err
+		}
+		*s = ASObjects{o}
+	}
+	return nil
+}
+
+func (s ASObjects) MarshalJSON() ([]byte, error) {
+	values := asValues(s)
+	if len(values) == 1 {
+		return json.Marshal(values[0])
+	}
+	return json.Marshal(values)
+}
+
+// ASArray the values of a property that is written as an array even when there is one, as the items of a collection, which consumers iterate over.
+type ASArray []*ASObject
+
+func (s *ASArray) UnmarshalJSON(b []byte) error {
+	return (*ASObjects)(s).UnmarshalJSON(b)
+}
+
+func (s ASArray) MarshalJSON() ([]byte, error) {
+	return json.Marshal(asValues(s))
+}
+
+// asValues the JSON values of objects, the URL of those that are only one.
+func asValues(objects []*ASObject) []interface{} {
+	values := make([]interface{}, len(objects))
+	for i, o := range objects {
+		if o.isRef() {
+			values[i] = o.ID
+		} else {
+			values[i] = o
+		}
+	}
+	return values
+}
+
+// asRef the property value referring to the URL s, none when s is empty.
+func asRef(s string) ASObjects {
+	if s == "" {
+		return nil
+	}
+	return ASObjects{{ID: s}}
+}
+
+// isRef reports whether o is only the URL of an object.
+func (o *ASObject) isRef() bool {
+	return reflect.DeepEqual(*o, ASObject{ID: o.ID})
+}
+
+// link the URL a link or an object refers to, the href of a Link or the id of an object.
+func (o *ASObject) link() string {
+	if o.Href != "" {
+		return o.Href
+	}
+	return o.ID
+}
+
+// firstLink the URL of the first value of s.
+func (s ASObjects) firstLink() string {
+	for _, o := range s {
+		if l := o.link(); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// embedded the first value of s that is an object rather than a URL.
+func (s ASObjects) embedded() *ASObject {
+	for _, o := range s {
+		if !o.isRef() {
+			return o
+		}
+	}
+	return nil
+}
+
+func (o *ASObject) Mime(fallback bool) string {
+	if fallback {
+		return ActivityStreamsMimeFallback
+	} else {
+		return ActivityStreamsMime
+	}
+}
+
+func (o *ASObject) WriteOut(w io.Writer) error {
+	return o.WriteOutWith(w, WriteOptions{Indent: defaultIndent})
+}
+
+func (o *ASObject) WriteOutWith(w io.Writer, opts WriteOptions) error {
+	return writeJSON(w, o, opts)
+}
+
+// asActor the Person of a JSON Feed author, or its URL alone.
+func asActor(a *JSONAuthor) *ASObject {
+	if a.Name == "" && a.Avatar == "" {
+		return &ASObject{ID: a.URL}
+	}
+	actor := &ASObject{
+		ID:   a.URL,
+		Type: "Person",
+		Name: a.Name,
+		URL:  asRef(a.URL),
+	}
+	if a.Avatar != "" {
+		actor.Icon = ASObjects{{Type: "Image", URL: asRef(a.Avatar)}}
+	}
+	return actor
+}
+
+// asMediaType the type of the object of an attachment of the MIME type mime.
+func asMediaType(mime string) string {
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return "Image"
+	case strings.HasPrefix(mime, "audio/"):
+		return "Audio"
+	case strings.HasPrefix(mime, "video/"):
+		return "Video"
+	default:
+		return "Document"
+	}
+}
+
+// asHashtag the name of the Hashtag of a tag, which has no white space.
+func asHashtag(tag string) string {
+	return "#" + strings.Join(strings.Fields(strings.TrimPrefix(tag, "#")), "")
+}
+
+// asActivity the Create activity of a JSON Feed item, authored by authors.
+func asActivity(jitem *JSONItem, authors []*JSONAuthor) *ASObject {
+	// An item with a title is an Article, as a blog post, one without is a Note, as a microblog post.
+	object := &ASObject{
+		ID:        jitem.ID,
+		Type:      "Note",
+		Summary:   html.EscapeString(jitem.Summary),
+		Published: jitem.DatePublished,
+		Updated:   jitem.DateModified,
+		To:        asRef(ActivityStreamsPublic),
+	}
+	if jitem.Title != "" {
+		object.Type = "Article"
+		object.Name = jitem.Title
+	}
+
+	// content is HTML, as the fediverse servers expect, content_text is escaped.
+	if jitem.ContentHTML != "" {
+		object.Content = jitem.ContentHTML
+	} else if jitem.ContentText != "" {
+		object.Content = "<p>" + strings.ReplaceAll(html.EscapeString(jitem.ContentText), "\n", "<br>") + "</p>"
+	}
+
+	// url is the permalink, external_url and the _related extension related links.
+	object.URL = asRef(jitem.URL)
+	for _, u := range jitem.relatedURLs() {
+		object.URL = append(object.URL, &ASObject{Type: "Link", Href: u, Rel: "related"})
+	}
+
+	// The authors are attributedTo, and the actor of the activity.
+	if jitem.Author != nil {
+		authors = append([]*JSONAuthor{jitem.Author}, jitem.Authors...)
+	} else if len(jitem.Authors) > 0 {
+		authors = jitem.Authors
+	}
+	for _, a := range authors {
+		object.AttributedTo = append(object.AttributedTo, asActor(a))
+	}
+
+	// Attachments, the enclosures, are attachment, typed by their MIME type.
+	for _, a := range jitem.Attachments {
+		object.Attachment = append(object.Attachment, &ASObject{
+			Type:      asMediaType(a.MimeType),
+			Name:      a.Title,
+			MediaType: a.MimeType,
+			URL:       asRef(a.URL),
+		})
+	}
+
+	if jitem.Image != "" {
+		object.Image = ASObjects{{Type: "Image", URL: asRef(jitem.Image)}}
+	}
+
+	// Tags, the categories, are Hashtag.
+	for _, tag := range jitem.Tags {
+		if strings.TrimSpace(tag) != "" {
+			object.Tag = append(object.Tag, &ASObject{Type: "Hashtag", Name: asHashtag(tag)})
+		}
+	}
+
+	activity := &ASObject{
+		Type:      "Create",
+		Actor:     object.AttributedTo,
+		Published: object.Published,
+		To:        object.To,
+		Object:    ASObjects{object},
+	}
+	if jitem.ID != "" {
+		activity.ID = jitem.ID + "#create"
+	}
+	return activity
+}
+
+// ToActivityStreams renders the feed as an Activity Streams 2.0 OrderedCollection of Create activities, as an ActivityPub outbox.
+// An item with a title is an Article and one without a Note, its authors are attributedTo, its attachments attachment and its tags Hashtag.
+func (f *JSONFeed) ToActivityStreams() *ASObject {
+	ff := f.ToJSON()
+
+	c := &ASObject{
+		Context: ActivityStreamsContext,
+		ID:      ff.FeedURL,
+		Type:    "OrderedCollection",
+		Name:    ff.Title,
+		Summary: html.EscapeString(ff.Description),
+		URL:     asRef(ff.HomePageURL),
+	}
+	if c.ID == "" {
+		c.ID = ff.HomePageURL
+	}
+	if ff.Icon != "" {
+		c.Icon = ASObjects{{Type: "Image", URL: asRef(ff.Icon)}}
+	}
+	for _, a := range ff.Authors {
+		c.AttributedTo = append(c.AttributedTo, asActor(a))
+	}
+
+	for _, jitem := range ff.Items {
+		c.OrderedItems = append(c.OrderedItems, asActivity(jitem, ff.Authors))
+	}
+
+	// A feed with a next page is a page of the collection.
+	if ff.NextURL != "" {
+		c.Type = "OrderedCollectionPage"
+		c.Next = asRef(ff.NextURL)
+	} else {
+		total := len(c.OrderedItems)
+		c.TotalItems = &total
+	}
+	return c
+}
+
+func (f *RssFeed) ToActivityStreams() *ASObject {
+	return f.ToJSON().ToActivityStreams()
+}
+
+func (f *AtomFeed) ToActivityStreams() *ASObject {
+	ff := f.ToJSON()
+	// The categories of the entries are the tags of the objects.
+	for i, entry := range f.Entries {
+		for _, category := range entry.Categories {
+			tag := category.Label
+			if tag == "" {
+				tag = category.Term
+			}
+			ff.Items[i].Tags = append(ff.Items[i].Tags, tag)
+		}
+	}
+	return ff.ToActivityStreams()
+}
+
+// isActivityStreams reports whether the JSON object b is an Activity Streams document, having a @context and not the version of a JSON Feed.
+func isActivityStreams(b []byte) bool {
+	var probe struct {
+		Context json.RawMessage `json:"@context"`
+		Version *string         `json:"version"`
+	}
+	if json.Unmarshal(b, &probe) != nil {
+		return false
+	}
+	return len(probe.Context) > 0 && probe.Version == nil
+}
+
+// ParseActivityStreams parses an Activity Streams 2.0 collection, as an ActivityPub outbox, as a JSON Feed.
+func ParseActivityStreams(r io.Reader) (*JSONFeed, error) {
+	c := &ASObject{}
+	if err := json.NewDecoder(r).Decode(c); err != nil {
+		return nil, err
+	}
+	return c.ToJSON()
+}
+
+// ToJSON the JSON Feed of the collection c, an item for each object it creates, updates or announces.
+// Of a paged collection, it has the items of the first page when it is embedded, and the next page as next_url.
+func (c *ASObject) ToJSON() (*JSONFeed, error) {
+	switch c.Type {
+	case "OrderedCollection", "Collection", "OrderedCollectionPage", "CollectionPage":
+	default:
+		return nil, ErrNotCollection
+	}
+
+	ff := &JSONFeed{
+		Title:       c.Name,
+		Description: asText(c.Summary),
+		FeedURL:     c.ID,
+		HomePageURL: c.URL.firstLink(),
+		Icon:        c.Icon.asImage(),
+		NextURL:     c.Next.firstLink(),
+	}
+	for _, a := range c.AttributedTo {
+		ff.Authors = append(ff.Authors, a.asAuthor())
+	}
+
+	page := c
+	if len(c.OrderedItems) == 0 && len(c.Items) == 0 {
+		if first := c.First.embedded(); first != nil {
+			page = first
+			ff.NextURL = first.Next.firstLink()
+		} else {
+			ff.NextURL = c.First.firstLink()
+		}
+	}
+
+	for _, o := range append(page.OrderedItems, page.Items...) {
+		if jitem := o.asItem(); jitem != nil {
+			ff.Items = append(ff.Items, jitem)
+		}
+	}
+
+	ff.Uniform()
+	return ff, nil
+}
+
+// asText the plain text of the HTML s, as summary, which is HTML in Activity Streams and text in JSON Feed.
+func asText(s string) string {
+	nodes, err := parseHTMLFragment(s)
+	if err != nil {
+		return s
+	}
+	var b strings.Builder
+	for _, n := range nodes {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		} else {
+			b.WriteString(htmlText(n))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// asImage the URL of an Image, or of an image.
+func (s ASObjects) asImage() string {
+	for _, o := range s {
+		if l := o.URL.firstLink(); l != "" {
+			return l
+		}
+		if l := o.link(); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// asAuthor the JSON Feed author of an actor.
+func (o *ASObject) asAuthor() *JSONAuthor {
+	a := &JSONAuthor{
+		Name:   o.Name,
+		URL:    o.URL.firstLink(),
+		Avatar: o.Icon.asImage(),
+	}
+	if a.URL == "" {
+		a.URL = o.ID
+	}
+	return a
+}
+
+// asItem the JSON Feed item of an activity or an object, nil for the activities that publish nothing, as Delete or Like.
+func (o *ASObject) asItem() *JSONItem {
+	if len(o.Object) == 0 {
+		if o.Type == "Tombstone" || o.isRef() {
+			return nil
+		}
+		return o.objectItem()
+	}
+
+	switch o.Type {
+	case "Create", "Update", "Announce":
+	default:
+		return nil
+	}
+
+	object := o.Object.embedded()
+	if object == nil {
+		// an Announce of a URL, as a boost of a post of another server
+		ref := o.Object.firstLink()
+		return &JSONItem{
+			ID:            o.ID,
+			ExternalURL:   ref,
+			ContentText:   ref,
+			DatePublished: o.Published,
+			Authors:       o.actors(),
+		}
+	}
+
+	jitem := object.objectItem()
+	if jitem.DatePublished == "" {
+		jitem.DatePublished = o.Published
+	}
+	if len(jitem.Authors) == 0 {
+		jitem.Authors = o.actors()
+	}
+	return jitem
+}
+
+func (o *ASObject) actors() []*JSONAuthor {
+	var authors []*JSONAuthor
+	for _, a := range o.Actor {
+		authors = append(authors, a.asAuthor())
+	}
+	return authors
+}
+
+// objectItem the JSON Feed item of an object, as a Note or an Article.
+func (o *ASObject) objectItem() *JSONItem {
+	jitem := &JSONItem{
+		ID:            o.ID,
+		Title:         o.Name,
+		Summary:       asText(o.Summary),
+		DatePublished: o.Published,
+		DateModified:  o.Updated,
+		Image:         o.Image.asImage(),
+	}
+
+	// url is the permalink, the related Links the external_url and the _related extension, and the id the permalink when there is no url.
+	var related []string
+	for _, u := range o.URL {
+		switch u.Rel {
+		case "", "alternate":
+			if jitem.URL == "" && (u.MediaType == "" || u.MediaType == "text/html") {
+				jitem.URL = u.link()
+			}
+		case "related":
+			related = append(related, u.link())
+		}
+	}
+	jitem.setRelatedURLs(related)
+	if jitem.URL == "" {
+		jitem.URL = o.ID
+	}
+
+	// content is HTML unless mediaType says otherwise.
+	switch o.MediaType {
+	case "", "text/html":
+		jitem.ContentHTML = o.Content
+	default:
+		jitem.ContentText = o.Content
+	}
+	if jitem.ContentHTML == "" && jitem.ContentText == "" {
+		// content_html or content_text is required
+		jitem.ContentText = jitem.Summary
+		if jitem.ContentText == "" {
+			jitem.ContentText = jitem.Title
+		}
+	}
+
+	for _, a := range o.AttributedTo {
+		jitem.Authors = append(jitem.Authors, a.asAuthor())
+	}
+
+	for _, a := range o.Attachment {
+		attachment := &JSONAttachments{
+			URL:      a.URL.firstLink(),
+			MimeType: a.MediaType,
+			Title:    a.Name,
+		}
+		if attachment.URL == "" {
+			attachment.URL = a.link()
+		}
+		if attachment.MimeType == "" {
+			switch a.Type {
+			case "Image", "Audio", "Video":
+				attachment.MimeType = mimeOfExtension(attachment.URL, strings.ToLower(a.Type)+"/")
+			}
+		}
+		if attachment.URL != "" {
+			jitem.Attachments = append(jitem.Attachments, attachment)
+		}
+	}
+
+	for _, tag := range o.Tag {
+		if tag.Type == "Hashtag" && tag.Name != "" {
+			jitem.Tags = append(jitem.Tags, strings.TrimPrefix(tag.Name, "#"))
+		}
+	}
+
+	return jitem
+}
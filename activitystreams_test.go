@@ -0,0 +1,198 @@
+package grss
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_ToActivityStreams(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(`<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Example</title>
+    <link>https://example.com/</link>
+    <description>Posts &amp; notes</description>
+    <atom:link rel="self" href="https://example.com/feed.xml"/>
+    <item>
+      <title>Episode 1</title>
+      <link>https://example.com/1</link>
+      <guid>https://example.com/1</guid>
+      <author>alice@example.com (Alice)</author>
+      <category>podcast</category>
+      <category>Go lang</category>
+      <pubDate>Mon, 02 Jan 2023 10:00:00 GMT</pubDate>
+      <description>Our first &lt;episode&gt;</description>
+      <enclosure url="https://example.com/1.mp3" length="1024" type="audio/mpeg"/>
+    </item>
+  </channel>
+</rss>`))
+	assert.Nil(t, err)
+
+	c := f.ToActivityStreams()
+	assert.Equal(t, "OrderedCollection", c.Type)
+	assert.Equal(t, "https://example.com/feed.xml", c.ID)
+	assert.Equal(t, 1, *c.TotalItems)
+
+	var b bytes.Buffer
+	assert.Nil(t, c.WriteOut(&b))
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal(b.Bytes(), &m))
+	assert.Equal(t, ActivityStreamsContext, m["@context"])
+	assert.Equal(t, "https://example.com/", m["url"])
+	assert.Equal(t, "Posts &amp; notes", m["summary"])
+
+	create := m["orderedItems"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "Create", create["type"])
+	assert.Equal(t, "https://example.com/1#create", create["id"])
+	assert.Equal(t, ActivityStreamsPublic, create["to"])
+	assert.Equal(t, "alice@example.com (Alice)", create["actor"].(map[string]interface{})["name"])
+
+	object := create["object"].(map[string]interface{})
+	assert.Equal(t, "Article", object["type"])
+	assert.Equal(t, "Episode 1", object["name"])
+	assert.Equal(t, "https://example.com/1", object["id"])
+	assert.Equal(t, "https://example.com/1", object["url"])
+	assert.Equal(t, "2023-01-02T10:00:00Z", object["published"])
+	assert.Equal(t, "<p>Our first &lt;episode&gt;</p>", object["content"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "Audio", "mediaType": "audio/mpeg", "url": "https://example.com/1.mp3"},
+	}, object["attachment"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "Hashtag", "name": "#podcast"},
+		map[string]interface{}{"type": "Hashtag", "name": "#Golang"},
+	}, object["tag"])
+
+	// a note and the categories of Atom
+	_, f, err = Parse(strings.NewReader(`<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Notes</title>
+  <id>https://example.com/</id>
+  <link rel="next" href="https://example.com/page/2"/>
+  <entry>
+    <id>https://example.com/n/1</id>
+    <author><name>Bob</name><uri>https://bob.example/</uri></author>
+    <category term="misc" label="Miscellaneous"/>
+    <updated>2023-01-03T10:00:00Z</updated>
+    <content type="html">&lt;p&gt;A note&lt;/p&gt;</content>
+  </entry>
+</feed>`))
+	assert.Nil(t, err)
+	c = f.ToActivityStreams()
+	note := c.OrderedItems[0].Object[0]
+	assert.Equal(t, "Note", note.Type)
+	assert.Equal(t, "", note.Name)
+	assert.Equal(t, "<p>A note</p>", note.Content)
+	assert.Equal(t, "2023-01-03T10:00:00Z", note.Updated)
+	assert.Equal(t, "#Miscellaneous", note.Tag[0].Name)
+	assert.Equal(t, "Bob", note.AttributedTo[0].Name)
+	assert.Equal(t, "https://bob.example/", note.AttributedTo[0].ID)
+}
+
+const testOutbox = `{
+  "@context": ["https://www.w3.org/ns/activitystreams", {"Hashtag": "as:Hashtag"}],
+  "id": "https://social.example/users/alice/outbox",
+  "type": "OrderedCollection",
+  "totalItems": 4,
+  "first": {
+    "id": "https://social.example/users/alice/outbox?page=true",
+    "type": "OrderedCollectionPage",
+    "next": "https://social.example/users/alice/outbox?page=2",
+    "orderedItems": [
+      {
+        "id": "https://social.example/users/alice/statuses/2/activity",
+        "type": "Create",
+        "actor": "https://social.example/users/alice",
+        "published": "2023-01-03T10:00:00Z",
+        "object": {
+          "id": "https://social.example/users/alice/statuses/2",
+          "type": "Note",
+          "summary": "CW: &lt;food&gt;",
+          "url": [{"type": "Link", "href": "https://social.example/@alice/2", "mediaType": "text/html"}, {"type": "Link", "href": "https://elsewhere.example/", "rel": "related"}],
+          "attributedTo": {"type": "Person", "id": "https://social.example/users/alice", "name": "Alice", "url": "https://social.example/@alice", "icon": {"type": "Image", "url": "https://social.example/alice.png"}},
+          "content": "<p>Lunch <a href=\"https://social.example/tags/food\">#food</a></p>",
+          "attachment": [{"type": "Document", "mediaType": "image/jpeg", "url": "https://social.example/media/1.jpg", "name": "a sandwich"}, {"type": "Image", "url": "https://social.example/media/2.png"}],
+          "tag": [{"type": "Hashtag", "name": "#food", "href": "https://social.example/tags/food"}, {"type": "Mention", "name": "@bob@other.example"}]
+        }
+      },
+      {
+        "id": "https://social.example/users/alice/statuses/3/activity",
+        "type": "Announce",
+        "actor": "https://social.example/users/alice",
+        "published": "2023-01-02T10:00:00Z",
+        "object": "https://other.example/notes/1"
+      },
+      {"id": "https://social.example/likes/1", "type": "Like", "actor": "https://social.example/users/alice", "object": "https://other.example/notes/2"},
+      {"id": "https://social.example/users/alice/statuses/1", "type": "Article", "name": "Hello", "content": "Plain", "mediaType": "text/plain", "published": "2023-01-01T10:00:00Z"}
+    ]
+  }
+}`
+
+func Test_ParseActivityStreams(t *testing.T) {
+	typ, feed, err := Parse(strings.NewReader(testOutbox))
+	assert.Nil(t, err)
+	assert.Equal(t, TypeJSON|TypeJSONActivityStreams, typ)
+
+	f := feed.(*JSONFeed)
+	assert.Equal(t, "https://social.example/users/alice/outbox", f.FeedURL)
+	assert.Equal(t, f.FeedURL, f.Title)
+	assert.Equal(t, "https://social.example/users/alice/outbox?page=2", f.NextURL)
+	assert.Equal(t, 3, len(f.Items))
+
+	note := f.Items[0]
+	assert.Equal(t, "https://social.example/users/alice/statuses/2", note.ID)
+	assert.Equal(t, "https://social.example/@alice/2", note.URL)
+	assert.Equal(t, "https://elsewhere.example/", note.ExternalURL)
+	assert.Equal(t, "", note.Title)
+	assert.Equal(t, "CW: <food>", note.Summary)
+	assert.Equal(t, "2023-01-03T10:00:00Z", note.DatePublished)
+	assert.Equal(t, `<p>Lunch <a href="https://social.example/tags/food">#food</a></p>`, note.ContentHTML)
+	assert.Equal(t, []*JSONAuthor{{Name: "Alice", URL: "https://social.example/@alice", Avatar: "https://social.example/alice.png"}}, note.Authors)
+	assert.Equal(t, []*JSONAttachments{
+		{URL: "https://social.example/media/1.jpg", MimeType: "image/jpeg", Title: "a sandwich"},
+		{URL: "https://social.example/media/2.png", MimeType: "image/png"},
+	}, note.Attachments)
+	assert.Equal(t, []string{"food"}, note.Tags)
+
+	boost := f.Items[1]
+	assert.Equal(t, "https://social.example/users/alice/statuses/3/activity", boost.ID)
+	assert.Equal(t, "https://other.example/notes/1", boost.ExternalURL)
+	assert.Equal(t, []*JSONAuthor{{URL: "https://social.example/users/alice"}}, boost.Authors)
+
+	article := f.Items[2]
+	assert.Equal(t, "Hello", article.Title)
+	assert.Equal(t, "Plain", article.ContentText)
+	assert.Equal(t, "https://social.example/users/alice/statuses/1", article.URL)
+
+	// the first page is not embedded
+	f, err = ParseActivityStreams(strings.NewReader(`{"@context": "https://www.w3.org/ns/activitystreams", "type": "OrderedCollection", "id": "https://social.example/outbox", "first": "https://social.example/outbox?page=1"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(f.Items))
+	assert.Equal(t, "https://social.example/outbox?page=1", f.NextURL)
+
+	_, _, err = Parse(strings.NewReader(`{"@context": "https://www.w3.org/ns/activitystreams", "type": "Person", "id": "https://social.example/users/alice"}`))
+	assert.Equal(t, ErrNotCollection, err)
+
+	// a JSON Feed with a @context stays a JSON Feed
+	typ, _, err = Parse(strings.NewReader(`{"version": "https://jsonfeed.org/version/1.1", "@context": "x", "title": "t", "items": []}`))
+	assert.Nil(t, err)
+	assert.Equal(t, TypeJSON, typ)
+
+	// round trip
+	_, feed, err = Parse(strings.NewReader(testOutbox))
+	assert.Nil(t, err)
+	var b bytes.Buffer
+	assert.Nil(t, feed.ToActivityStreams().WriteOut(&b))
+	again, err := ParseActivityStreams(&b)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(again.Items))
+	assert.Equal(t, note.ID, again.Items[0].ID)
+	assert.Equal(t, note.URL, again.Items[0].URL)
+	assert.Equal(t, note.ContentHTML, again.Items[0].ContentHTML)
+	assert.Equal(t, note.Summary, again.Items[0].Summary)
+	assert.Equal(t, note.Authors, again.Items[0].Authors)
+	assert.Equal(t, note.Attachments, again.Items[0].Attachments)
+	assert.Equal(t, note.Tags, again.Items[0].Tags)
+	assert.Equal(t, "Hello", again.Items[2].Title)
+}
@@ -428,6 +428,11 @@ type AtomLink struct {
 	Title    string          `xml:"title,attr,omitempty"`
 	Length   string          `xml:"length,attr,omitempty"`
 
+	// ThrCount RFC 4685 thr:count, the number of responses in the rel="replies" resource.
+	ThrCount string `xml:"http://purl.org/syndication/thread/1.0 thr:count,attr,omitempty"`
+	// ThrUpdated RFC 4685 thr:updated, when the rel="replies" resource was last updated.
+	ThrUpdated string `xml:"http://purl.org/syndication/thread/1.0 thr:updated,attr,omitempty"`
+
 	UndefinedContent []UndefinedContent `xml:",any"`
 }
 
@@ -519,6 +524,13 @@ type AtomEntry struct {
 	Title        *AtomTextConstruct     `xml:"title,omitempty"`
 	Updated      *AtomDateConstruct     `xml:"updated,omitempty"`
 
+	// InReplyTo RFC 4685 thr:in-reply-to, the resources this entry is a response to.
+	InReplyTo []*AtomInReplyTo `xml:"http://purl.org/syndication/thread/1.0 thr:in-reply-to,omitempty"`
+	// Total RFC 4685 thr:total, the total number of unique responses to this entry.
+	Total string `xml:"http://purl.org/syndication/thread/1.0 thr:total,omitempty"`
+
+	EventItem
+
 	ExtensionElement []XmlGeneric `xml:",any"`
 }
 
@@ -0,0 +1,176 @@
+package grss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// TrackingParams the query parameters that only tell where the reader came from, which CanonicalURL removes whatever their case, a trailing * matches any suffix.
+var TrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid", "igshid", "_hsenc", "_hsmi", "mkt_tok", "ref_src",
+}
+
+// URLRules how CanonicalURLWith rewrites the URLs, the zero value being the rules of CanonicalURL.
+type URLRules struct {
+	// StripParams are the query parameters removed, a trailing * matches any suffix, TrackingParams when nil.
+	StripParams []string
+	// KeepParams are, when not empty, the only query parameters kept, a trailing * matches any suffix.
+	KeepParams []string
+	// KeepScheme keeps http and https apart, http is made https otherwise.
+	KeepScheme bool
+	// KeepWWW keeps the www. of the hosts.
+	KeepWWW bool
+	// KeepTrailingSlash keeps the trailing slash of the paths.
+	KeepTrailingSlash bool
+	// KeepFragment keeps the fragments, which are removed otherwise as they point into the same page.
+	KeepFragment bool
+}
+
+// MatchParam reports whether the query parameter name is one of params, ignoring case, a trailing * matches any suffix.
+func MatchParam(name string, params []string) bool {
+	name = strings.ToLower(name)
+	for _, p := range params {
+		p = strings.ToLower(p)
+		if strings.HasSuffix(p, "*") && strings.HasPrefix(name, p[:len(p)-1]) || name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalURL the URL s the same page has whatever the variations of the publisher or of the source:
+// https, the host lowercased and without www. nor the default port, without the fragment, the tracking parameters nor the trailing slash,
+// and with the query parameters sorted.
+// s is returned as it is when it is not an absolute http or https URL, such as a tag: URI.
+func CanonicalURL(s string) string {
+	return CanonicalURLWith(s, URLRules{})
+}
+
+// CanonicalURLWith the URL s canonicalized as CanonicalURL but by the rules.
+func CanonicalURLWith(s string, rules URLRules) string {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || u.Host == "" || (!strings.EqualFold(u.Scheme, "http") && !strings.EqualFold(u.Scheme, "https")) {
+		return s
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !rules.KeepScheme {
+		u.Scheme = "https"
+	}
+	u.User = nil
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if !rules.KeepWWW {
+		host = strings.TrimPrefix(host, "www.")
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if !rules.KeepFragment {
+		u.Fragment, u.RawFragment = "", ""
+	}
+
+	strip := rules.StripParams
+	if strip == nil {
+		strip = TrackingParams
+	}
+	if u.RawQuery != "" {
+		var params []string
+		for _, p := range strings.Split(u.RawQuery, "&") {
+			name := p
+			if i := strings.IndexByte(p, '='); i >= 0 {
+				name = p[:i]
+			}
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			if p == "" || MatchParam(name, strip) || len(rules.KeepParams) > 0 && !MatchParam(name, rules.KeepParams) {
+				continue
+			}
+			params = append(params, p)
+		}
+		sort.Strings(params)
+		u.RawQuery = strings.Join(params, "&")
+	}
+	u.ForceQuery = false
+
+	if u.Path == "" {
+		u.Path, u.RawPath = "/", ""
+	} else if len(u.Path) > 1 && !rules.KeepTrailingSlash {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	}
+	return u.String()
+}
+
+// StableID the identity of an item that does not change between the polls of its feed, whatever the variations of its links,
+// for an *RssItem, *RdfItem, *AtomEntry or *JSONItem, empty for any other value:
+//
+//   - an RSS guid that is a permalink, as they are unless isPermaLink is "false", is canonicalized as CanonicalURL, keeping its fragment,
+//     the other guids are opaque strings, kept as they are
+//   - an Atom id is kept as it is, as RFC 4287 has ids compared character by character
+//   - a JSON Feed id is canonicalized when it is an http or https URL, as the ids are ideally the URLs of the items, and is kept as it is otherwise
+//   - an item without one is identified by its canonical link, or url, external_url or rdf:about
+//   - an item without any is identified by the SHA-256 hash of its normalized title and text, "sha256:" followed by the hash in hex
+func StableID(item interface{}) string {
+	return StableIDWith(item, URLRules{})
+}
+
+// StableIDWith the identity of an item as StableID, the links being canonicalized by the rules.
+func StableIDWith(item interface{}, rules URLRules) string {
+	idRules := rules
+	idRules.KeepFragment = true
+
+	var jitem *JSONItem
+	var links []string
+	switch it := item.(type) {
+	case *RssItem:
+		if permalink := it.Guid.PermaLink(); permalink != "" {
+			return CanonicalURLWith(permalink, idRules)
+		}
+		if it.Guid != nil && strings.TrimSpace(it.Guid.Guid) != "" {
+			return strings.TrimSpace(it.Guid.Guid)
+		}
+		links = []string{it.Link}
+		jitem = (&RssFeed{Channel: &RssChannel{Items: []*RssItem{it}}}).ToJSON().Items[0]
+	case *RdfItem:
+		links = []string{it.Link, it.About}
+		jitem = &JSONItem{Title: it.Title}
+		if it.ContentEncoded != nil {
+			jitem.ContentHTML = it.ContentEncoded.String()
+		} else if it.Description != nil {
+			jitem.ContentText = it.Description.String()
+		}
+	case *AtomEntry:
+		if it.ID != nil && strings.TrimSpace(string(it.ID.AtomUri)) != "" {
+			return strings.TrimSpace(string(it.ID.AtomUri))
+		}
+		jitem = (&AtomFeed{Entries: []*AtomEntry{it}}).ToJSON().Items[0]
+		links = []string{jitem.URL, jitem.ExternalURL}
+	case *JSONItem:
+		if id := strings.TrimSpace(it.ID); id != "" {
+			return CanonicalURLWith(id, idRules)
+		}
+		links = []string{it.URL, it.ExternalURL}
+		jitem = it
+	default:
+		return ""
+	}
+
+	for _, link := range links {
+		if link = strings.TrimSpace(link); link != "" {
+			return CanonicalURLWith(link, rules)
+		}
+	}
+
+	sum := sha256.Sum256([]byte(NormalizeTitle(jitem.Title) + "\n" + strings.Join(fingerprintWords(itemText(jitem)), " ")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
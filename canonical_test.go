@@ -0,0 +1,104 @@
+package grss
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_CanonicalURL(t *testing.T) {
+	for _, s := range []string{
+		"https://example.com/news/story",
+		"http://www.example.com/news/story/",
+		"HTTPS://Example.COM:443/news/story?utm_source=rss&utm_medium=feed#comments",
+		"https://user@example.com/news/story?fbclid=abc",
+		"https://example.com/news/story?UTM_Source=rss&FBCLID=abc",
+		" https://example.com/news/story? ",
+	} {
+		assert.Equal(t, "https://example.com/news/story", CanonicalURL(s), s)
+	}
+	assert.Equal(t, "https://example.com/", CanonicalURL("http://www.example.com"))
+	assert.Equal(t, "https://example.com:8080/?a=1&b=2", CanonicalURL("http://example.com:8080/?b=2&utm_campaign=x&a=1"))
+	assert.Equal(t, "https://[::1]:8080/a", CanonicalURL("http://[::1]:8080/a/"))
+	assert.Equal(t, "https://example.com/a%2Fb", CanonicalURL("https://example.com/a%2Fb/"))
+	for _, s := range []string{"tag:example.com,2023:1", "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a", "post-1", "/relative/path/"} {
+		assert.Equal(t, s, CanonicalURL(s))
+	}
+}
+
+func Test_CanonicalURLWith(t *testing.T) {
+	s := "http://www.example.com/news/story/?id=7&utm_source=rss&ref=home&session=abc#top"
+	assert.Equal(t, "https://example.com/news/story?id=7&ref=home&session=abc", CanonicalURL(s))
+	assert.Equal(t, "http://www.example.com/news/story/?id=7&ref=home&session=abc#top", CanonicalURLWith(s, URLRules{
+		KeepScheme:        true,
+		KeepWWW:           true,
+		KeepTrailingSlash: true,
+		KeepFragment:      true,
+	}))
+	assert.Equal(t, "https://example.com/news/story?id=7&utm_source=rss", CanonicalURLWith(s, URLRules{StripParams: []string{"ref", "session*"}}))
+	assert.Equal(t, "https://example.com/news/story?id=7&ref=home&session=abc&utm_source=rss", CanonicalURLWith(s, URLRules{StripParams: []string{}}))
+	assert.Equal(t, "https://example.com/news/story?id=7", CanonicalURLWith(s, URLRules{KeepParams: []string{"id"}}))
+	assert.Equal(t, "https://example.com/news/story?ID=7", CanonicalURLWith("https://example.com/news/story?ID=7&Ref=home", URLRules{StripParams: []string{"REF"}}))
+}
+
+func Test_MatchParam(t *testing.T) {
+	assert.True(t, MatchParam("utm_source", []string{"UTM_*"}))
+	assert.True(t, MatchParam("Ref", []string{"ref"}))
+	assert.True(t, MatchParam("ref", []string{"REF"}))
+	assert.False(t, MatchParam("referrer", []string{"ref"}))
+	assert.False(t, MatchParam("utm", []string{"utm_*"}))
+}
+
+func Test_StableID(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <link>https://blog.example/</link>
+    <description>A blog</description>
+    <item>
+      <title>Permalink</title>
+      <guid>http://www.blog.example/1/?utm_source=rss#p1</guid>
+      <link>https://blog.example/elsewhere</link>
+    </item>
+    <item>
+      <title>Opaque</title>
+      <guid isPermaLink="false"> http://www.blog.example/2/ </guid>
+    </item>
+    <item>
+      <title>Link</title>
+      <link>http://blog.example/3/?utm_medium=feed</link>
+    </item>
+    <item>
+      <title>Nothing</title>
+      <description>  Only   a text. </description>
+    </item>
+  </channel>
+</rss>`))
+	assert.Nil(t, err)
+	items := f.(*RssFeed).Channel.Items
+	assert.Equal(t, "https://blog.example/1#p1", StableID(items[0]))
+	assert.Equal(t, "http://www.blog.example/2/", StableID(items[1]))
+	assert.Equal(t, "https://blog.example/3", StableID(items[2]))
+	hash := StableID(items[3])
+	assert.True(t, strings.HasPrefix(hash, "sha256:"))
+	assert.Equal(t, 7+64, len(hash))
+
+	// the same text, differently spaced, has the same hash, in any format
+	assert.Equal(t, hash, StableID(&JSONItem{Title: "Nothing", ContentHTML: "<p>Only a <b>text</b>.</p>"}))
+	assert.Equal(t, hash, StableID(&RdfItem{Title: "Nothing", Description: &XmlText{Text: "Only a text."}}))
+	assert.NotEqual(t, hash, StableID(&JSONItem{Title: "Nothing", ContentText: "Another text."}))
+
+	assert.Equal(t, "tag:blog.example,2023:1", StableID(&AtomEntry{ID: &AtomId{AtomUri: "tag:blog.example,2023:1"}}))
+	assert.Equal(t, "http://www.blog.example/1/", StableID(&AtomEntry{ID: &AtomId{AtomUri: "http://www.blog.example/1/"}}))
+	assert.Equal(t, "https://blog.example/4", StableID(&AtomEntry{Links: []*AtomLink{{Href: "http://blog.example/4/", Rel: "alternate"}}}))
+
+	assert.Equal(t, "https://blog.example/5", StableID(&JSONItem{ID: "http://blog.example/5/?fbclid=x", URL: "https://blog.example/other"}))
+	assert.Equal(t, "https://blog.example/5", StableID(&JSONItem{URL: "https://blog.example/5?UTM_Source=rss"}))
+	assert.Equal(t, "note-5", StableID(&JSONItem{ID: "note-5", URL: "https://blog.example/5"}))
+	assert.Equal(t, "https://blog.example/6", StableID(&JSONItem{ExternalURL: "https://blog.example/6/"}))
+	assert.Equal(t, "https://blog.example/7", StableID(&RdfItem{About: "http://blog.example/7/"}))
+
+	assert.Equal(t, "http://blog.example/3/?utm_medium=feed", StableIDWith(items[2], URLRules{KeepScheme: true, KeepTrailingSlash: true, StripParams: []string{}}))
+	assert.Equal(t, "", StableID("https://blog.example/8"))
+}
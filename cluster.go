@@ -0,0 +1,321 @@
+package grss
+
+import (
+	"sort"
+	"time"
+)
+
+// JSONRelated the _related item extension, the links to other versions of the item, such as the same story in other feeds.
+// It maps to the Atom links and the ActivityStreams Links of rel="related" after the one of external_url.
+type JSONRelated struct {
+	URLs []string `json:"urls,omitempty"`
+}
+
+// Related returns the _related extension of the item, nil if it has none.
+func (item *JSONItem) Related() *JSONRelated {
+	switch v := item.Extensions["_related"].(type) {
+	case nil:
+		return nil
+	case *JSONRelated:
+		return v
+	default:
+		r := &JSONRelated{}
+		if !decodeJSONExtension(v, r) {
+			return nil
+		}
+		return r
+	}
+}
+
+// relatedURLs the related links of the item, its external_url then the URLs of its _related extension, without repetitions.
+func (item *JSONItem) relatedURLs() []string {
+	var urls []string
+	seen := map[string]bool{"": true}
+	add := func(u string) {
+		if !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	add(item.ExternalURL)
+	if r := item.Related(); r != nil {
+		for _, u := range r.URLs {
+			add(u)
+		}
+	}
+	return urls
+}
+
+// setRelatedURLs sets the related links of the item, the first as its external_url and the others in its _related extension.
+func (item *JSONItem) setRelatedURLs(urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	item.ExternalURL = urls[0]
+	if len(urls) > 1 {
+		item.setExtension("_related", &JSONRelated{URLs: urls[1:]})
+	}
+}
+
+// ClusterItem an item to cluster, with the feed it comes from.
+type ClusterItem struct {
+	Item *JSONItem
+	// Source is the feed the item comes from, for PreferSources, such as the feed_url of its feed.
+	Source string
+	// Fingerprint is the fingerprint of the item, computed by Cluster when nil.
+	Fingerprint *Fingerprint
+}
+
+// ItemCluster near-duplicate items, the same story from several feeds.
+type ItemCluster struct {
+	// Representative is the item picked by the rules to stand for the cluster.
+	Representative *ClusterItem
+	// Duplicates are the other items of the cluster, in the order they were given.
+	Duplicates []*ClusterItem
+}
+
+// Item a copy of the representative item, with the links to the duplicates in its _related extension.
+// The links are the url of the duplicates, or their external_url, those the representative has being left out.
+func (c *ItemCluster) Item() *JSONItem {
+	item := *c.Representative.Item
+	if len(c.Duplicates) == 0 {
+		return &item
+	}
+
+	item.Extensions = make(map[string]interface{}, len(c.Representative.Item.Extensions)+1)
+	for k, v := range c.Representative.Item.Extensions {
+		item.Extensions[k] = v
+	}
+
+	related := &JSONRelated{}
+	seen := map[string]bool{"": true, item.URL: true}
+	for _, u := range item.relatedURLs() {
+		seen[u] = true
+	}
+	if r := item.Related(); r != nil {
+		related.URLs = append(related.URLs, r.URLs...)
+	}
+	for _, d := range c.Duplicates {
+		u := d.Item.URL
+		if u == "" {
+			u = d.Item.ExternalURL
+		}
+		if !seen[u] {
+			seen[u] = true
+			related.URLs = append(related.URLs, u)
+		}
+	}
+	if len(related.URLs) > 0 {
+		item.setExtension("_related", related)
+	}
+	return &item
+}
+
+// Rule compares two items of a cluster to pick its representative:
+// negative when a is the better one, positive when b is, zero when the rule cannot tell them apart.
+type Rule func(a, b *ClusterItem) int
+
+// Earliest prefers the item published first, by its date_published or else date_modified, the items without a date last.
+func Earliest(a, b *ClusterItem) int {
+	ta, oka := clusterItemDate(a.Item)
+	tb, okb := clusterItemDate(b.Item)
+	switch {
+	case oka && okb && ta.Before(tb), oka && !okb:
+		return -1
+	case oka && okb && tb.Before(ta), okb && !oka:
+		return 1
+	}
+	return 0
+}
+
+// LongestContent prefers the item with the longest text.
+func LongestContent(a, b *ClusterItem) int {
+	return len(itemText(b.Item)) - len(itemText(a.Item))
+}
+
+// PreferSources prefers the items of the sources in the order given, then the items of the other sources.
+func PreferSources(sources ...string) Rule {
+	rank := func(item *ClusterItem) int {
+		for i, s := range sources {
+			if item.Source == s {
+				return i
+			}
+		}
+		return len(sources)
+	}
+	return func(a, b *ClusterItem) int {
+		return rank(a) - rank(b)
+	}
+}
+
+func clusterItemDate(item *JSONItem) (time.Time, bool) {
+	for _, d := range []string{item.DatePublished, item.DateModified} {
+		if d == "" {
+			continue
+		}
+		if t, err := ParseDate(d); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ClusterOptions how Cluster tells near-duplicates and picks the representatives.
+type ClusterOptions struct {
+	// Similarity is the estimated Jaccard similarity of the shingles of the texts from which two items are duplicates, 0.5 when zero.
+	Similarity float64
+	// Distance is the number of bits the SimHashes of the texts of two duplicates may differ by at most, 3 when zero, none when negative.
+	Distance int
+	// TitleWords is the number of words two items with the same normalized title must have at least to be duplicates,
+	// 4 when zero, the titles are not compared when it is negative.
+	TitleWords int
+	// Rules are the rules picking the representative of a cluster, applied in order until one tells two items apart,
+	// Earliest then LongestContent when empty, the first item given winning the ties.
+	Rules []Rule
+}
+
+func (opts *ClusterOptions) duplicates(a, b *Fingerprint) bool {
+	if a.URL != "" && a.URL == b.URL {
+		return true
+	}
+
+	titleWords := opts.TitleWords
+	if titleWords == 0 {
+		titleWords = 4
+	}
+	if titleWords > 0 && a.Title != "" && a.Title == b.Title && len(fingerprintWords(a.Title)) >= titleWords {
+		return true
+	}
+
+	if len(a.MinHash) == 0 || len(b.MinHash) == 0 {
+		return false
+	}
+	distance := opts.Distance
+	if distance == 0 {
+		distance = 3
+	}
+	if distance > 0 && a.Distance(b) <= distance {
+		return true
+	}
+	similarity := opts.Similarity
+	if similarity == 0 {
+		similarity = 0.5
+	}
+	return a.Similarity(b) >= similarity
+}
+
+// Cluster groups the near-duplicate items: those with the same canonical URL, or the same normalized title, or close texts, see ClusterOptions.
+// Being a duplicate is transitive, a cluster holds the items linked by a chain of duplicates.
+// Every item is in one cluster, the clusters are in the order of their first item, opts may be nil.
+func Cluster(items []*ClusterItem, opts *ClusterOptions) []*ItemCluster {
+	if opts == nil {
+		opts = &ClusterOptions{}
+	}
+	for _, item := range items {
+		if item.Fingerprint == nil {
+			item.Fingerprint = ItemFingerprint(item.Item)
+		}
+	}
+
+	// union-find of the items by index, the root of a set being its first item
+	parents := make([]int, len(items))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			if ri, rj := find(i), find(j); ri != rj && opts.duplicates(items[i].Fingerprint, items[j].Fingerprint) {
+				if ri < rj {
+					parents[rj] = ri
+				} else {
+					parents[ri] = rj
+				}
+			}
+		}
+	}
+
+	rules := opts.Rules
+	if len(rules) == 0 {
+		rules = []Rule{Earliest, LongestContent}
+	}
+	better := func(a, b *ClusterItem) bool {
+		for _, rule := range rules {
+			if c := rule(a, b); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}
+
+	var clusters []*ItemCluster
+	byRoot := map[int][]*ClusterItem{}
+	for i, item := range items {
+		root := find(i)
+		byRoot[root] = append(byRoot[root], item)
+	}
+	for i := range items {
+		members, ok := byRoot[i]
+		if !ok {
+			continue
+		}
+		best := 0
+		for j := 1; j < len(members); j++ {
+			if better(members[j], members[best]) {
+				best = j
+			}
+		}
+		c := &ItemCluster{Representative: members[best]}
+		c.Duplicates = append(append(c.Duplicates, members[:best]...), members[best+1:]...)
+		clusters = append(clusters, c)
+	}
+	return clusters
+}
+
+// ClusterFeeds clusters the items of the feeds as Cluster, the source of an item being the feed_url of its feed, or else its home_page_url.
+// The items of the clusters are copies, made uniform by ToJSON, the feeds are left as they are.
+func ClusterFeeds(opts *ClusterOptions, feeds ...Feed) []*ItemCluster {
+	var items []*ClusterItem
+	for _, f := range feeds {
+		ff := f.ToJSON()
+		source := ff.FeedURL
+		if source == "" {
+			source = ff.HomePageURL
+		}
+		for _, item := range ff.Items {
+			items = append(items, &ClusterItem{Item: item, Source: source})
+		}
+	}
+	return Cluster(items, opts)
+}
+
+// Deduplicate a JSON feed of the items of the clusters, each the Item of its cluster, the most recent first,
+// whose titles and links are those of the first feed.
+func Deduplicate(opts *ClusterOptions, feeds ...Feed) *JSONFeed {
+	ff := &JSONFeed{}
+	if len(feeds) > 0 {
+		first := feeds[0].ToJSON()
+		ff.Title = first.Title
+		ff.HomePageURL = first.HomePageURL
+		ff.FeedURL = first.FeedURL
+		ff.Description = first.Description
+	}
+
+	clusters := ClusterFeeds(opts, feeds...)
+	for _, c := range clusters {
+		ff.Items = append(ff.Items, c.Item())
+	}
+	sort.SliceStable(ff.Items, func(i, j int) bool {
+		ti, oki := clusterItemDate(ff.Items[i])
+		tj, okj := clusterItemDate(ff.Items[j])
+		return oki && (!okj || ti.After(tj))
+	})
+	ff.Uniform()
+	return ff
+}
@@ -0,0 +1,172 @@
+package grss
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const clusterTestWire = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Wire</title>
+    <link>https://wire.example/</link>
+    <description>The wire</description>
+    <item>
+      <title>Storm hits the coast - Wire</title>
+      <link>https://wire.example/storm?utm_source=rss</link>
+      <guid isPermaLink="false">wire-1001</guid>
+      <pubDate>Mon, 02 Jan 2023 08:00:00 GMT</pubDate>
+      <description>Heavy rain and strong winds hit the northern coast on Monday, cutting power to thousands of homes, officials said.</description>
+    </item>
+    <item>
+      <title>Markets close higher</title>
+      <link>https://wire.example/markets</link>
+      <pubDate>Mon, 02 Jan 2023 17:00:00 GMT</pubDate>
+      <description>Stocks rose on Monday as investors welcomed the inflation figures.</description>
+    </item>
+  </channel>
+</rss>`
+
+const clusterTestPaper = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>https://paper.example/</id>
+  <title>Paper</title>
+  <link rel="self" href="https://paper.example/feed.atom"/>
+  <updated>2023-01-02T12:00:00Z</updated>
+  <entry>
+    <id>tag:paper.example,2023:storm</id>
+    <title>Storm hits the coast</title>
+    <link href="https://paper.example/2023/storm"/>
+    <updated>2023-01-02T10:00:00Z</updated>
+    <content type="html">&lt;p&gt;Heavy rain and strong winds hit the northern coast on Monday, cutting power to thousands of homes, officials said. Schools were closed and trains cancelled as the storm moved inland.&lt;/p&gt;</content>
+  </entry>
+  <entry>
+    <id>tag:paper.example,2023:markets</id>
+    <title>Local team wins the cup</title>
+    <link href="https://www.wire.example/markets/"/>
+    <updated>2023-01-02T18:00:00Z</updated>
+    <content>A copy of the wire story under another title.</content>
+  </entry>
+  <entry>
+    <id>tag:paper.example,2023:letters</id>
+    <title>Letters</title>
+    <link href="https://paper.example/2023/letters"/>
+    <updated>2023-01-02T11:00:00Z</updated>
+    <content>The letters of the readers.</content>
+  </entry>
+</feed>`
+
+func clusterTestFeeds(t *testing.T) []Feed {
+	var feeds []Feed
+	for _, s := range []string{clusterTestWire, clusterTestPaper} {
+		_, f, err := Parse(strings.NewReader(s))
+		assert.Nil(t, err)
+		feeds = append(feeds, f)
+	}
+	return feeds
+}
+
+func clusterURLs(clusters []*ItemCluster) [][]string {
+	var urls [][]string
+	for _, c := range clusters {
+		u := []string{c.Representative.Item.URL}
+		for _, d := range c.Duplicates {
+			u = append(u, d.Item.URL)
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+func Test_Cluster(t *testing.T) {
+	feeds := clusterTestFeeds(t)
+
+	// the same story, the same canonical URL, and an item alone
+	clusters := ClusterFeeds(nil, feeds...)
+	assert.Equal(t, [][]string{
+		{"https://wire.example/storm?utm_source=rss", "https://paper.example/2023/storm"},
+		{"https://wire.example/markets", "https://www.wire.example/markets/"},
+		{"https://paper.example/2023/letters"},
+	}, clusterURLs(clusters))
+	assert.Equal(t, "https://paper.example/feed.atom", clusters[0].Duplicates[0].Source)
+
+	// the representative with the longest content, or from the preferred source
+	clusters = ClusterFeeds(&ClusterOptions{Rules: []Rule{LongestContent}}, feeds...)
+	assert.Equal(t, "https://paper.example/2023/storm", clusters[0].Representative.Item.URL)
+	clusters = ClusterFeeds(&ClusterOptions{Rules: []Rule{PreferSources("https://paper.example/feed.atom"), Earliest}}, feeds...)
+	assert.Equal(t, []string{"https://paper.example/2023/storm", "https://www.wire.example/markets/"}, []string{clusters[0].Representative.Item.URL, clusters[1].Representative.Item.URL})
+
+	// without the comparison of the texts, the stories only have their titles in common
+	clusters = ClusterFeeds(&ClusterOptions{Distance: -1, Similarity: 1.1, TitleWords: -1}, feeds...)
+	assert.Equal(t, 4, len(clusters))
+	clusters = ClusterFeeds(&ClusterOptions{Distance: -1, Similarity: 1.1}, feeds...)
+	assert.Equal(t, 3, len(clusters))
+
+	// a cluster is transitive
+	items := []*ClusterItem{
+		{Item: &JSONItem{URL: "https://a.example/1", Title: "One two three four"}},
+		{Item: &JSONItem{URL: "https://b.example/1", Title: "One two three four - B"}},
+		{Item: &JSONItem{URL: "http://b.example/1/", Title: "Other"}},
+		{Item: &JSONItem{URL: "https://c.example/1", Title: "Five six seven"}},
+	}
+	assert.Equal(t, [][]string{{"https://a.example/1", "https://b.example/1", "http://b.example/1/"}, {"https://c.example/1"}}, clusterURLs(Cluster(items, nil)))
+}
+
+func Test_Cluster_Related(t *testing.T) {
+	clusters := ClusterFeeds(nil, clusterTestFeeds(t)...)
+
+	item := clusters[0].Item()
+	assert.Equal(t, "https://wire.example/storm?utm_source=rss", item.URL)
+	assert.Equal(t, &JSONRelated{URLs: []string{"https://paper.example/2023/storm"}}, item.Related())
+	assert.Nil(t, clusters[0].Representative.Item.Related())
+	assert.Nil(t, clusters[2].Item().Related())
+
+	// the related links are Atom and ActivityStreams links
+	ff := &JSONFeed{Title: "Merged", Items: []*JSONItem{item}}
+	ff.Items[0].ExternalURL = "https://linked.example/"
+	entry := ff.ToAtom().Entries[0]
+	var related []string
+	for _, link := range entry.Links {
+		if link.Rel == "related" {
+			related = append(related, string(link.Href))
+		}
+	}
+	assert.Equal(t, []string{"https://linked.example/", "https://paper.example/2023/storm"}, related)
+
+	back := ff.ToAtom().ToJSON().Items[0]
+	assert.Equal(t, "https://linked.example/", back.ExternalURL)
+	assert.Equal(t, []string{"https://paper.example/2023/storm"}, back.Related().URLs)
+
+	var buf bytes.Buffer
+	assert.Nil(t, ff.ToActivityStreams().WriteOut(&buf))
+	_, parsed, err := Parse(&buf)
+	assert.Nil(t, err)
+	back = parsed.ToJSON().Items[0]
+	assert.Equal(t, "https://linked.example/", back.ExternalURL)
+	assert.Equal(t, []string{"https://paper.example/2023/storm"}, back.Related().URLs)
+
+	// the JSON extension survives a round trip
+	buf.Reset()
+	assert.Nil(t, ff.WriteOut(&buf))
+	_, parsed, err = Parse(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://paper.example/2023/storm"}, parsed.ToJSON().Items[0].Related().URLs)
+
+	// the items are copies, a JSON feed is not made uniform in place
+	_, jsonFeed, err := Parse(strings.NewReader(`{"version":"https://jsonfeed.org/version/1.1","title":"JSON","items":[{"url":"https://wire.example/storm","title":"Storm hits the coast","date_published":"Mon, 02 Jan 2023 09:00:00 GMT"}]}`))
+	assert.Nil(t, err)
+	jsonItem := *jsonFeed.(*JSONFeed).Items[0]
+	clusters = ClusterFeeds(nil, jsonFeed)
+	assert.False(t, clusters[0].Representative.Item == jsonFeed.(*JSONFeed).Items[0])
+	assert.Equal(t, "2023-01-02T09:00:00Z", clusters[0].Representative.Item.DatePublished)
+	Deduplicate(nil, jsonFeed)
+	assert.Equal(t, jsonItem, *jsonFeed.(*JSONFeed).Items[0])
+
+	merged := Deduplicate(nil, clusterTestFeeds(t)...)
+	assert.Equal(t, "Wire", merged.Title)
+	assert.Equal(t, 3, len(merged.Items))
+	assert.Equal(t, "https://wire.example/markets", merged.Items[0].URL)
+	assert.Equal(t, []string{"https://www.wire.example/markets/"}, merged.Items[0].Related().URLs)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hellodword/grss"
+	"io"
+	"strings"
+)
+
+var rssVersions = []grss.RssVersion{
+	grss.RssVersion090,
+	grss.RssVersion091Netscape,
+	grss.RssVersion091,
+	grss.RssVersion092,
+	grss.RssVersion093,
+	grss.RssVersion094,
+	grss.RssVersion20,
+}
+
+// parseRssVersion accepts the names of RssVersion.String, with "0.91-netscape" for the Netscape dialect.
+func parseRssVersion(s string) (grss.RssVersion, bool) {
+	if strings.EqualFold(s, "0.91-netscape") || strings.EqualFold(s, "netscape") {
+		return grss.RssVersion091Netscape, true
+	}
+	for _, v := range rssVersions {
+		if v.String() == s {
+			return v, true
+		}
+	}
+	return grss.RssVersionUnknown, false
+}
+
+// writer the feed in the format to write, RSS 1.0 is no grss.Feed.
+type writer interface {
+	WriteOutWith(w io.Writer, opts grss.WriteOptions) error
+}
+
+func runConvert(e *env, args []string) int {
+	fs := newFlagSet(e, "convert", "--to rss|atom|json|rss10|as2|ics [-o out] [file]")
+	to := fs.String("to", "", "output format: rss, atom, json, rss10, as2, an Activity Streams 2.0 outbox, or ics, an iCalendar of the events (required)")
+	out := fs.String("o", "", "output file, the standard output when empty or -")
+	compact := fs.Bool("compact", false, "write without indentation")
+	charset := fs.String("charset", "", "IANA charset of XML output, such as ISO-8859-1, UTF-8 when empty")
+	cdata := fs.Bool("cdata", false, "wrap HTML content in CDATA sections in XML output")
+	rssVersion := fs.String("rss-version", "", "RSS version of rss output: 0.90, 0.91, 0.91-netscape, 0.92, 0.93, 0.94 or 2.0")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return fail(e, err)
+	}
+	if len(files) > 1 {
+		return fail(e, fmt.Errorf("convert reads one feed, got %d files", len(files)))
+	}
+	if *rssVersion != "" && *to != "rss" {
+		return fail(e, fmt.Errorf("--rss-version needs --to rss"))
+	}
+
+	// the format is checked before the feed is read, which can be the standard input
+	var convert func(feed grss.Feed) writer
+	switch *to {
+	case "rss":
+		v := grss.RssVersionUnknown
+		if *rssVersion != "" {
+			var ok bool
+			if v, ok = parseRssVersion(*rssVersion); !ok {
+				return fail(e, fmt.Errorf("unknown RSS version %q", *rssVersion))
+			}
+		}
+		convert = func(feed grss.Feed) writer {
+			r := feed.ToRss()
+			if v != grss.RssVersionUnknown {
+				r = r.ToRssVersion(v)
+			}
+			return r
+		}
+	case "atom":
+		convert = func(feed grss.Feed) writer { return feed.ToAtom() }
+	case "json":
+		convert = func(feed grss.Feed) writer { return feed.ToJSON() }
+	case "rss10":
+		convert = func(feed grss.Feed) writer { return feed.ToRss10() }
+	case "as2":
+		convert = func(feed grss.Feed) writer { return feed.ToActivityStreams() }
+	case "ics":
+		convert = func(feed grss.Feed) writer { return grss.ToICalendar(feed) }
+	case "":
+		fs.Usage()
+		return fail(e, fmt.Errorf("--to is required"))
+	default:
+		return fail(e, fmt.Errorf("unknown format %q, want rss, atom, json, rss10, as2 or ics", *to))
+	}
+
+	var file string
+	if len(files) == 1 {
+		file = files[0]
+	}
+
+	in, err := load(e, file)
+	if err != nil {
+		return fail(e, err)
+	}
+	f := convert(in.feed)
+
+	opts := grss.WriteOptions{Indent: "    ", Charset: *charset}
+	if *compact {
+		opts.Indent = ""
+	}
+	if *cdata {
+		opts.HTMLContent = grss.HTMLContentCDATA
+	}
+
+	err = output(e, *out, func(w io.Writer) error {
+		err := f.WriteOutWith(w, opts)
+		switch {
+		case err != nil:
+			return err
+		case *to == "json" || *to == "as2" || *to == "ics":
+			// JSON ends with the newline of its encoder, and an iCalendar stream with its own CRLF
+			return nil
+		}
+		_, err = io.WriteString(w, "\n")
+		return err
+	})
+	if err != nil {
+		return fail(e, err)
+	}
+	return exitOK
+}
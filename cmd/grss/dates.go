@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hellodword/grss"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// dateField a date of a feed as written in the document.
+type dateField struct {
+	path string
+	raw  string
+	// layouts the format expects, RFC 3339 or RFC 822
+	format  string
+	layouts []string
+}
+
+func dateFields(f grss.Feed) []dateField {
+	var fields []dateField
+	add := func(path, raw, format string, layouts []string) {
+		if strings.TrimSpace(raw) != "" {
+			fields = append(fields, dateField{path, strings.TrimSpace(raw), format, layouts})
+		}
+	}
+
+	switch ff := f.(type) {
+	case *grss.AtomFeed:
+		if ff.Updated != nil {
+			add("feed.updated", ff.Updated.DateTime, "RFC 3339", rfc3339Layouts)
+		}
+		for i, entry := range ff.Entries {
+			if entry.Published != nil {
+				add(fmt.Sprintf("entry[%d].published", i), entry.Published.DateTime, "RFC 3339", rfc3339Layouts)
+			}
+			if entry.Updated != nil {
+				add(fmt.Sprintf("entry[%d].updated", i), entry.Updated.DateTime, "RFC 3339", rfc3339Layouts)
+			}
+		}
+	case *grss.RssFeed:
+		if ff.Channel == nil {
+			break
+		}
+		add("channel.pubDate", ff.Channel.PubDate, "RFC 822", rfc822Layouts)
+		add("channel.lastBuildDate", ff.Channel.LastBuildDate, "RFC 822", rfc822Layouts)
+		for _, ext := range ff.Channel.ExtensionElement {
+			if ext.XMLName.Space == grss.NamespaceDublinCore && ext.XMLName.Local == "date" {
+				add("channel.dc:date", ext.String(), "RFC 3339", rfc3339Layouts)
+			}
+		}
+		for i, item := range append(append([]*grss.RssItem(nil), ff.Channel.Items...), ff.Items...) {
+			add(fmt.Sprintf("item[%d].pubDate", i), item.PubDate, "RFC 822", rfc822Layouts)
+		}
+	case *grss.JSONFeed:
+		for i, item := range ff.Items {
+			add(fmt.Sprintf("items[%d].date_published", i), item.DatePublished, "RFC 3339", rfc3339Layouts)
+			add(fmt.Sprintf("items[%d].date_modified", i), item.DateModified, "RFC 3339", rfc3339Layouts)
+		}
+	}
+	return fields
+}
+
+func runDates(e *env, args []string) int {
+	fs := newFlagSet(e, "dates", "[--utc] [file]")
+	utc := fs.Bool("utc", false, "print the parsed dates in UTC")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return fail(e, err)
+	}
+	if len(files) > 1 {
+		return fail(e, fmt.Errorf("dates reads one feed, got %d files", len(files)))
+	}
+	var file string
+	if len(files) == 1 {
+		file = files[0]
+	}
+
+	in, err := load(e, file)
+	if err != nil {
+		return fail(e, err)
+	}
+
+	code := exitOK
+	tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	for _, field := range dateFields(in.feed) {
+		t, err := grss.ParseDate(field.raw)
+		if err != nil {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", field.path, field.raw, "unparsed: "+err.Error())
+			code = exitFound
+			continue
+		}
+		if *utc {
+			t = t.UTC()
+		}
+
+		// lenient dates are understood by grss, other readers may reject them
+		note := field.format
+		strict := false
+		for _, layout := range field.layouts {
+			if _, err := time.Parse(layout, field.raw); err == nil {
+				strict = true
+				break
+			}
+		}
+		if !strict {
+			note = "lenient, not " + field.format
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", field.path, field.raw, t.Format(time.RFC3339), note)
+	}
+	_ = tw.Flush()
+	return code
+}
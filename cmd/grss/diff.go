@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hellodword/grss"
+	"io"
+	"strconv"
+)
+
+// diff compares feeds through their JSON Feed mapping, so feeds in different formats compare too.
+
+type diffField struct {
+	name string
+	get  func(item *grss.JSONItem) string
+	date bool
+}
+
+var diffItemFields = []diffField{
+	{"title", func(item *grss.JSONItem) string { return item.Title }, false},
+	{"url", func(item *grss.JSONItem) string { return item.URL }, false},
+	{"external_url", func(item *grss.JSONItem) string { return item.ExternalURL }, false},
+	{"summary", func(item *grss.JSONItem) string { return item.Summary }, false},
+	{"content_html", func(item *grss.JSONItem) string { return item.ContentHTML }, false},
+	{"content_text", func(item *grss.JSONItem) string { return item.ContentText }, false},
+	{"date_published", func(item *grss.JSONItem) string { return item.DatePublished }, true},
+	{"date_modified", func(item *grss.JSONItem) string { return item.DateModified }, true},
+	{"attachments", func(item *grss.JSONItem) string {
+		var s string
+		for _, a := range item.Attachments {
+			s += a.URL + " "
+		}
+		return s
+	}, false},
+}
+
+// sameValue compares two values of a field, dates are equal when they denote the same instant.
+func sameValue(a, b string, date bool) bool {
+	if a == b {
+		return true
+	}
+	if !date || a == "" || b == "" {
+		return false
+	}
+	ta, erra := grss.ParseDate(a)
+	tb, errb := grss.ParseDate(b)
+	return erra == nil && errb == nil && ta.Equal(tb)
+}
+
+func itemKey(item *grss.JSONItem) string {
+	if item.ID != "" {
+		return item.ID
+	}
+	return item.URL
+}
+
+// diffFeeds writes the differences of b from a and reports whether there are any.
+func diffFeeds(w io.Writer, a, b grss.Feed) bool {
+	ja, jb := a.ToJSON(), b.ToJSON()
+	differ := false
+
+	for _, field := range []struct {
+		name string
+		a, b string
+	}{
+		{"title", ja.Title, jb.Title},
+		{"home_page_url", ja.HomePageURL, jb.HomePageURL},
+		{"feed_url", ja.FeedURL, jb.FeedURL},
+		{"description", ja.Description, jb.Description},
+		{"language", ja.Language, jb.Language},
+	} {
+		if field.a != field.b {
+			fmt.Fprintf(w, "~ feed %s: %s -> %s\n", field.name, strconv.Quote(field.a), strconv.Quote(field.b))
+			differ = true
+		}
+	}
+
+	// items sharing a key are matched in order
+	old := map[string][]*grss.JSONItem{}
+	for _, item := range ja.Items {
+		key := itemKey(item)
+		old[key] = append(old[key], item)
+	}
+
+	for _, item := range jb.Items {
+		key := itemKey(item)
+		if key == "" || len(old[key]) == 0 {
+			fmt.Fprintf(w, "+ %s %s\n", key, strconv.Quote(item.Title))
+			differ = true
+			continue
+		}
+		prev := old[key][0]
+		old[key] = old[key][1:]
+		for _, field := range diffItemFields {
+			va, vb := field.get(prev), field.get(item)
+			if sameValue(va, vb, field.date) {
+				continue
+			}
+			differ = true
+			if len(va) > 80 || len(vb) > 80 {
+				fmt.Fprintf(w, "~ %s %s changed\n", key, field.name)
+			} else {
+				fmt.Fprintf(w, "~ %s %s: %s -> %s\n", key, field.name, strconv.Quote(va), strconv.Quote(vb))
+			}
+		}
+	}
+
+	for _, item := range ja.Items {
+		key := itemKey(item)
+		if rest := old[key]; len(rest) > 0 && rest[0] == item {
+			old[key] = rest[1:]
+			fmt.Fprintf(w, "- %s %s\n", key, strconv.Quote(item.Title))
+			differ = true
+		}
+	}
+	return differ
+}
+
+func runDiff(e *env, args []string) int {
+	fs := newFlagSet(e, "diff", "old new")
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return fail(e, err)
+	}
+	if len(files) != 2 {
+		fs.Usage()
+		return fail(e, fmt.Errorf("diff compares two feeds, got %d files", len(files)))
+	}
+	if files[0] == "-" && files[1] == "-" {
+		return fail(e, fmt.Errorf("only one feed can be read from the standard input"))
+	}
+
+	a, err := load(e, files[0])
+	if err != nil {
+		return fail(e, err)
+	}
+	b, err := load(e, files[1])
+	if err != nil {
+		return fail(e, err)
+	}
+
+	if diffFeeds(e.stdout, a.feed, b.feed) {
+		return exitFound
+	}
+	return exitOK
+}
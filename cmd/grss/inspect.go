@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/hellodword/grss"
+	"github.com/nbio/xml"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// coreNamespaces the namespaces of the formats themselves, the others are extensions.
+var coreNamespaces = map[string]bool{
+	"":                                     true,
+	"http://www.w3.org/2005/Atom":          true,
+	"http://purl.org/atom/ns#":             true,
+	"http://www.w3.org/1999/xhtml":         true,
+	grss.NamespaceRdf:                      true,
+	"http://purl.org/rss/1.0/":             true,
+	grss.NamespaceRss090Netscape:           true,
+	grss.NamespaceRss090Channel:            true,
+	"http://www.w3.org/XML/1998/namespace": true,
+}
+
+// Extension a namespace or JSON Feed extension and the number of elements or objects using it.
+type Extension struct {
+	Name   string `json:"name"`
+	Prefix string `json:"prefix,omitempty"`
+	Count  int    `json:"count"`
+}
+
+// Report what inspect prints.
+type Report struct {
+	Type       string       `json:"type"`
+	Version    string       `json:"version"`
+	Title      string       `json:"title"`
+	Items      int          `json:"items"`
+	Oldest     *time.Time   `json:"oldest,omitempty"`
+	Newest     *time.Time   `json:"newest,omitempty"`
+	Undated    int          `json:"undated"`
+	Extensions []*Extension `json:"extensions"`
+}
+
+// xmlExtensions counts the elements of each extension namespace of an XML document.
+func xmlExtensions(data []byte) []*Extension {
+	counts := map[string]int{}
+	prefixes := map[string]string{}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// names are ASCII, the text does not matter here
+		return input, nil
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Space == "xmlns" || attr.Name.Space == "http://www.w3.org/2000/xmlns/" {
+				if _, ok := prefixes[attr.Value]; !ok {
+					prefixes[attr.Value] = attr.Name.Local
+				}
+			}
+		}
+		if !coreNamespaces[start.Name.Space] {
+			counts[start.Name.Space]++
+		}
+	}
+
+	var extensions []*Extension
+	for _, ns := range sortedKeys(counts) {
+		extensions = append(extensions, &Extension{Name: ns, Prefix: prefixes[ns], Count: counts[ns]})
+	}
+	return extensions
+}
+
+// jsonExtensions counts the objects of each JSON Feed extension, at the top level and in items.
+func jsonExtensions(f *grss.JSONFeed) []*Extension {
+	counts := map[string]int{}
+	for k := range f.Extensions {
+		counts[k]++
+	}
+	for _, item := range f.Items {
+		for k := range item.Extensions {
+			counts[k]++
+		}
+	}
+
+	var extensions []*Extension
+	for _, k := range sortedKeys(counts) {
+		extensions = append(extensions, &Extension{Name: k, Count: counts[k]})
+	}
+	return extensions
+}
+
+func inspect(in *input) *Report {
+	r := &Report{
+		Type:       typeName(in.typ),
+		Version:    version(in.feed),
+		Extensions: []*Extension{},
+	}
+
+	j := in.feed.ToJSON()
+	r.Title = j.Title
+	r.Items = len(j.Items)
+	for _, item := range j.Items {
+		dated := false
+		for _, s := range []string{item.DatePublished, item.DateModified} {
+			if s == "" {
+				continue
+			}
+			t, err := grss.ParseDate(s)
+			if err != nil {
+				continue
+			}
+			dated = true
+			t = t.UTC()
+			if r.Oldest == nil || t.Before(*r.Oldest) {
+				oldest := t
+				r.Oldest = &oldest
+			}
+			if r.Newest == nil || t.After(*r.Newest) {
+				newest := t
+				r.Newest = &newest
+			}
+		}
+		if !dated {
+			r.Undated++
+		}
+	}
+
+	if jf, ok := in.feed.(*grss.JSONFeed); ok {
+		r.Extensions = append(r.Extensions, jsonExtensions(jf)...)
+	} else {
+		r.Extensions = append(r.Extensions, xmlExtensions(in.data)...)
+	}
+	return r
+}
+
+func runInspect(e *env, args []string) int {
+	fs := newFlagSet(e, "inspect", "[--json] [file]")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return fail(e, err)
+	}
+	if len(files) > 1 {
+		return fail(e, fmt.Errorf("inspect reads one feed, got %d files", len(files)))
+	}
+	var file string
+	if len(files) == 1 {
+		file = files[0]
+	}
+
+	in, err := load(e, file)
+	if err != nil {
+		return fail(e, err)
+	}
+	r := inspect(in)
+
+	if *asJSON {
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+		if err != nil {
+			return fail(e, err)
+		}
+		return exitOK
+	}
+
+	tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "type:\t%s\n", r.Type)
+	fmt.Fprintf(tw, "version:\t%s\n", r.Version)
+	fmt.Fprintf(tw, "title:\t%s\n", r.Title)
+	fmt.Fprintf(tw, "items:\t%d\n", r.Items)
+	if r.Oldest != nil {
+		fmt.Fprintf(tw, "oldest:\t%s\n", r.Oldest.Format(time.RFC3339))
+		fmt.Fprintf(tw, "newest:\t%s\n", r.Newest.Format(time.RFC3339))
+	}
+	if r.Undated > 0 {
+		fmt.Fprintf(tw, "undated:\t%d\n", r.Undated)
+	}
+	for i, ext := range r.Extensions {
+		label := ""
+		if i == 0 {
+			label = "extensions:"
+		}
+		name := ext.Name
+		if ext.Prefix != "" {
+			name = ext.Prefix + " " + name
+		}
+		fmt.Fprintf(tw, "%s\t%s (%d)\n", label, name, ext.Count)
+	}
+	_ = tw.Flush()
+	return exitOK
+}
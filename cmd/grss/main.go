@@ -0,0 +1,219 @@
+// Command grss converts, inspects, validates and compares RSS, Atom and JSON feeds.
+//
+// Usage:
+//
+//	grss convert --to rss|atom|json|rss10|as2|ics [-o out] [file]
+//	grss inspect [--json] [file]
+//	grss validate [--strict] [file...]
+//	grss dates [file]
+//	grss diff old new
+//	grss serve [--listen addr] config.yaml
+//
+// A missing file or - reads the standard input, the output goes to the standard output unless -o is given.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/hellodword/grss"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// exit codes, as diff(1): 0 fine, 1 problems found (invalid, different), 2 trouble (bad usage, unreadable input)
+const (
+	exitOK      = 0
+	exitFound   = 1
+	exitTrouble = 2
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(env *env, args []string) int
+}
+
+var commands = []command{
+	{"convert", "convert a feed to another format", runConvert},
+	{"inspect", "print the type, version, items, date range and extensions of a feed", runInspect},
+	{"validate", "print the problems of feeds, exit 1 when one is invalid", runValidate},
+	{"dates", "print how each date of a feed parses", runDates},
+	{"diff", "compare two feeds, exit 1 when they differ", runDiff},
+	{"serve", "serve feeds transformed by the routes of a configuration", runServe},
+}
+
+// env the standard streams, replaced in tests.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+func run(args []string, e *env) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(e.stderr)
+		if len(args) == 0 {
+			return exitTrouble
+		}
+		return exitOK
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(e, args[1:])
+		}
+	}
+	fmt.Fprintf(e.stderr, "grss: unknown command %q\n", args[0])
+	usage(e.stderr)
+	return exitTrouble
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: grss <command> [flags] [file...]")
+	fmt.Fprintln(w)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "A missing file or - reads the standard input. Run grss <command> -h for the flags of a command.")
+}
+
+func newFlagSet(e *env, name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: grss %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses flags placed before, between or after the file arguments, and returns the files.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var files []string
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return files, nil
+		}
+		files = append(files, args[0])
+		args = args[1:]
+	}
+}
+
+// input a feed read from a file or the standard input.
+type input struct {
+	name string
+	data []byte
+	typ  grss.Type
+	feed grss.Feed
+}
+
+func readInput(e *env, name string) ([]byte, error) {
+	if name == "" || name == "-" {
+		return io.ReadAll(e.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+func load(e *env, name string) (*input, error) {
+	in := &input{name: name}
+	if name == "" || name == "-" {
+		in.name = "<stdin>"
+	}
+
+	var err error
+	in.data, err = readInput(e, name)
+	if err != nil {
+		return nil, err
+	}
+
+	in.typ, in.feed, err = grss.Parse(bytes.NewReader(in.data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", in.name, err)
+	}
+	return in, nil
+}
+
+// output the standard output, or the file named by -o, which is only replaced once the whole output was written.
+func output(e *env, name string, write func(w io.Writer) error) error {
+	if name == "" || name == "-" {
+		return write(e.stdout)
+	}
+
+	var buf bytes.Buffer
+	err := write(&buf)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, buf.Bytes(), 0644)
+}
+
+func typeName(t grss.Type) string {
+	switch {
+	case t&grss.TypeXMLAtom != 0:
+		return "atom"
+	case t&grss.TypeXMLRss != 0:
+		return "rss"
+	case t&grss.TypeXMLSitemap != 0:
+		return "sitemap"
+	case t&grss.TypeJSONActivityStreams != 0:
+		return "activitystreams"
+	case t&grss.TypeJSON != 0:
+		return "json"
+	case t&grss.TypeHTML != 0:
+		return "html"
+	case t&grss.TypeXML != 0:
+		return "xml"
+	default:
+		return "unknown"
+	}
+}
+
+// version the version of the format of f.
+func version(f grss.Feed) string {
+	switch ff := f.(type) {
+	case *grss.AtomFeed:
+		if ff.XMLName.Space == "http://purl.org/atom/ns#" {
+			return "0.3"
+		}
+		return "1.0"
+	case *grss.RssFeed:
+		if ff.ParsedVersion != grss.RssVersionUnknown {
+			return ff.ParsedVersion.String()
+		}
+		return ff.Version
+	case *grss.JSONFeed:
+		return strings.TrimPrefix(ff.Version, "https://jsonfeed.org/version/")
+	default:
+		return ""
+	}
+}
+
+func fail(e *env, err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	fmt.Fprintf(e.stderr, "grss: %v\n", err)
+	return exitTrouble
+}
+
+func sortedKeys(m map[string]int) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"github.com/hellodword/grss"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRss = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/">
+  <channel>
+    <title>Example</title>
+    <link>http://example.com/</link>
+    <description>An example</description>
+    <pubDate>2023-01-03</pubDate>
+    <item>
+      <title>one</title>
+      <guid>1</guid>
+      <pubDate>Mon, 02 Jan 2023 10:00:00 GMT</pubDate>
+      <media:content url="http://example.com/1.mp3"/>
+    </item>
+    <item>
+      <title>two</title>
+      <guid>2</guid>
+      <pubDate>Tue, 03 Jan 2023 10:00:00 +0000</pubDate>
+      <enclosure url="http://example.com/2.mp3"/>
+    </item>
+  </channel>
+</rss>`
+
+// runTest runs the command with stdin and returns the exit code, stdout and stderr.
+func runTest(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func writeTemp(t *testing.T, name, content string) string {
+	p := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(p, []byte(content), 0644)
+	assert.Nil(t, err)
+	return p
+}
+
+func Test_Convert(t *testing.T) {
+	code, stdout, stderr := runTest(testRss, "convert", "--to", "json")
+	assert.Equal(t, exitOK, code, stderr)
+	// the documents end with a single newline
+	assert.True(t, strings.HasSuffix(stdout, "}\n"), stdout)
+	_, f, err := grss.Parse(strings.NewReader(stdout))
+	assert.Nil(t, err)
+	j := f.(*grss.JSONFeed)
+	assert.Equal(t, "Example", j.Title)
+	assert.Equal(t, 2, len(j.Items))
+
+	// a pipeline, then a file, flags after the file
+	in := writeTemp(t, "in.json", stdout)
+	out := filepath.Join(t.TempDir(), "out.xml")
+	code, stdout, stderr = runTest("", "convert", in, "--to", "atom", "-o", out, "--compact")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "", stdout)
+	b, err := os.ReadFile(out)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(b), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<feed xmlns="http://www.w3.org/2005/Atom"`), string(b))
+
+	code, stdout, _ = runTest(testRss, "convert", "--to", "rss", "--rss-version", "0.91")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, `<rss version="0.91">`)
+	assert.True(t, strings.HasSuffix(stdout, "</rss>\n"), stdout)
+
+	code, stdout, stderr = runTest(testRss, "convert", "--to", "as2")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.True(t, strings.HasSuffix(stdout, "}\n"), stdout)
+	typ, f, err := grss.Parse(strings.NewReader(stdout))
+	assert.Nil(t, err)
+	assert.Equal(t, "activitystreams", typeName(typ))
+	assert.Equal(t, 2, len(f.ToJSON().Items))
+
+	code, stdout, stderr = runTest(testRss, "convert", "--to", "ics")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.True(t, strings.HasPrefix(stdout, "BEGIN:VCALENDAR\r\n"), stdout)
+	assert.True(t, strings.HasSuffix(stdout, "END:VEVENT\r\nEND:VCALENDAR\r\n"), stdout)
+
+	code, _, stderr = runTest(testRss, "convert")
+	assert.Equal(t, exitTrouble, code)
+	assert.Contains(t, stderr, "--to is required")
+
+	// the format is checked before the feed is read
+	code, _, stderr = runTest("not a feed", "convert", "--to", "html")
+	assert.Equal(t, exitTrouble, code)
+	assert.Contains(t, stderr, `unknown format "html"`)
+	code, _, stderr = runTest("not a feed", "convert", "--to", "rss", "--rss-version", "3.0")
+	assert.Equal(t, exitTrouble, code)
+	assert.Contains(t, stderr, `unknown RSS version "3.0"`)
+	code, _, _ = runTest("not a feed", "convert", "--to", "rss")
+	assert.Equal(t, exitTrouble, code)
+	code, _, _ = runTest("", "convert", "--to", "rss", filepath.Join(t.TempDir(), "missing.xml"))
+	assert.Equal(t, exitTrouble, code)
+}
+
+func Test_Inspect(t *testing.T) {
+	code, stdout, stderr := runTest(testRss, "inspect")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "type:        rss\n")
+	assert.Contains(t, stdout, "version:     2.0\n")
+	assert.Contains(t, stdout, "items:       2\n")
+	assert.Contains(t, stdout, "oldest:      2023-01-02T10:00:00Z\n")
+	assert.Contains(t, stdout, "newest:      2023-01-03T10:00:00Z\n")
+	assert.Contains(t, stdout, "extensions:  media http://search.yahoo.com/mrss/ (1)\n")
+
+	code, stdout, _ = runTest(`{"version": "https://jsonfeed.org/version/1.1", "title": "j", "_x": {}, "items": [{"id": "1", "content_text": "1", "_x": {}, "_y": 1}]}`, "inspect", "--json")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, `"type": "json"`)
+	assert.Contains(t, stdout, `"undated": 1`)
+	assert.Contains(t, stdout, `"name": "_x",
+      "count": 2`)
+}
+
+func Test_Validate(t *testing.T) {
+	code, stdout, _ := runTest(testRss, "validate")
+	assert.Equal(t, exitFound, code)
+	assert.Equal(t, `<stdin>: warning: channel.pubDate: "2023-01-03" is not an RFC 822 date
+<stdin>: error: item[1].enclosure[0].length: required
+<stdin>: error: item[1].enclosure[0].type: required
+`, stdout)
+
+	valid := strings.Replace(testRss, `<enclosure url="http://example.com/2.mp3"/>`, "", 1)
+	code, stdout, _ = runTest(valid, "validate")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "warning")
+	code, _, _ = runTest(valid, "validate", "--strict")
+	assert.Equal(t, exitFound, code)
+	code, stdout, _ = runTest(testRss, "validate", "--quiet")
+	assert.Equal(t, exitFound, code)
+	assert.Equal(t, "", stdout)
+
+	atom := writeTemp(t, "feed.atom", `<feed xmlns="http://www.w3.org/2005/Atom"><title>a</title><id>urn:a</id><updated>2003-12-13T18:30:02Z</updated>
+<entry><id>urn:1</id><title>1</title><updated>yesterday</updated></entry></feed>`)
+	code, stdout, _ = runTest("", "validate", atom, filepath.Join(t.TempDir(), "missing.xml"))
+	assert.Equal(t, exitTrouble, code)
+	assert.Contains(t, stdout, `error: entry[0].author: required as the feed has no author`)
+	assert.Contains(t, stdout, `error: entry[0].updated: "yesterday" is not a date`)
+	assert.Contains(t, stdout, `error: entry[0]: needs a content or an alternate link`)
+
+	code, stdout, _ = runTest(`{"version": "1", "items": [{"id": "1", "date_published": "Mon, 02 Jan 2023 10:00:00 GMT"}, {"id": "1", "content_text": "x"}]}`, "validate")
+	assert.Equal(t, exitFound, code)
+	assert.Equal(t, `<stdin>: error: version: "1" is not a JSON Feed version URL
+<stdin>: error: title: required
+<stdin>: error: items[0]: needs content_html or content_text
+<stdin>: warning: items[0].date_published: "Mon, 02 Jan 2023 10:00:00 GMT" is not an RFC 3339 date
+<stdin>: warning: items[1]: duplicate id "1", first used by items[0]
+`, stdout)
+}
+
+func Test_Dates(t *testing.T) {
+	code, stdout, _ := runTest(testRss, "dates")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, `channel.pubDate  2023-01-03                       2023-01-03T00:00:00Z  lenient, not RFC 822
+item[0].pubDate  Mon, 02 Jan 2023 10:00:00 GMT    2023-01-02T10:00:00Z  RFC 822
+item[1].pubDate  Tue, 03 Jan 2023 10:00:00 +0000  2023-01-03T10:00:00Z  RFC 822
+`, stdout)
+
+	code, stdout, _ = runTest(strings.Replace(testRss, "2023-01-03<", "someday<", 1), "dates")
+	assert.Equal(t, exitFound, code)
+	assert.Contains(t, stdout, "channel.pubDate  someday")
+	assert.Contains(t, stdout, "unparsed: ")
+}
+
+func Test_Diff(t *testing.T) {
+	a := writeTemp(t, "a.xml", testRss)
+
+	code, stdout, _ := runTest(testRss, "diff", a, "-")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "", stdout)
+
+	// the same feed in another format
+	_, j, _ := runTest(testRss, "convert", "--to", "json")
+	code, stdout, _ = runTest(j, "diff", a, "-")
+	assert.Equal(t, exitOK, code, stdout)
+
+	changed := strings.NewReplacer(
+		"<title>two</title>", "<title>deux</title>",
+		"<guid>1</guid>", "<guid>3</guid>",
+		"<title>Example</title>", "<title>Sample</title>",
+	).Replace(testRss)
+	code, stdout, _ = runTest(changed, "diff", a, "-")
+	assert.Equal(t, exitFound, code)
+	assert.Equal(t, `~ feed title: "Example" -> "Sample"
++ 3 "one"
+~ 2 title: "two" -> "deux"
+- 1 "one"
+`, stdout)
+
+	code, _, _ = runTest("", "diff", a)
+	assert.Equal(t, exitTrouble, code)
+}
+
+func Test_Usage(t *testing.T) {
+	code, _, stderr := runTest("")
+	assert.Equal(t, exitTrouble, code)
+	assert.Contains(t, stderr, "usage: grss")
+
+	code, _, _ = runTest("", "help")
+	assert.Equal(t, exitOK, code)
+
+	code, _, stderr = runTest("", "frobnicate")
+	assert.Equal(t, exitTrouble, code)
+	assert.Contains(t, stderr, `unknown command "frobnicate"`)
+
+	code, _, stderr = runTest("", "convert", "-h")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stderr, "-rss-version")
+}
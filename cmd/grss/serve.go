@@ -0,0 +1,646 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hellodword/grss"
+	"github.com/hellodword/grss/pipeline"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// serve re-serves upstream feeds through a pipeline of operations, as configured by a YAML or JSON file:
+//
+//	listen: ":8080"
+//	ttl: 5m
+//	max_body: 10485760
+//	routes:
+//	  - path: /example.atom
+//	    upstream: https://example.com/feed.xml
+//	    pipeline:
+//	      - filter: '!(title =~ /sponsored|advert/i)'
+//	      - stages: |
+//	          clean-urls
+//	          dedupe url
+//	      - sanitize: true
+//	      - resolve: true
+//	      - merge: [https://example.com/comments.xml]
+//	      - limit: 20
+//	      - convert: atom
+//
+// A filter is an expression of pipeline.Compile and stages a pipeline written as text, see pipeline.Parse.
+// Upstreams are URLs or files, files are relative to the configuration file.
+// An upstream URL whose body is larger than max_body bytes, 10 MiB by default, fails.
+// An upstream is fetched again once ttl passed, with a conditional request, and a route is only processed again when one of its upstreams changed.
+
+const (
+	defaultTTL     = 5 * time.Minute
+	defaultMaxBody = 10 << 20
+)
+
+type serveConfig struct {
+	Listen string `yaml:"listen"`
+	// TTL how long an upstream is used before it is revalidated, 0 revalidates on every request
+	TTL *duration `yaml:"ttl"`
+	// MaxBody the size in bytes of the largest body read from an upstream URL
+	MaxBody *int64         `yaml:"max_body"`
+	Routes  []*routeConfig `yaml:"routes"`
+}
+
+// duration a time.Duration written as 5m or as a number of seconds.
+type duration time.Duration
+
+func (d *duration) UnmarshalYAML(value *yaml.Node) error {
+	var seconds int64
+	if err := value.Decode(&seconds); err == nil {
+		*d = duration(time.Duration(seconds) * time.Second)
+		return nil
+	}
+	var s string
+	err := value.Decode(&s)
+	if err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*d = duration(v)
+	return nil
+}
+
+type routeConfig struct {
+	Path     string       `yaml:"path"`
+	Upstream string       `yaml:"upstream"`
+	Pipeline []*operation `yaml:"pipeline"`
+}
+
+// operation one step of a pipeline, exactly one of the fields is set.
+type operation struct {
+	// Filter keeps the items for which the expression holds, see pipeline.Compile
+	Filter string `yaml:"filter"`
+	// Stages runs a pipeline written as text, see pipeline.Parse
+	Stages string `yaml:"stages"`
+	// Sanitize removes scripts, styles and frames from the HTML content
+	Sanitize *bool `yaml:"sanitize"`
+	// Resolve makes the relative URLs absolute
+	Resolve *resolveOperation `yaml:"resolve"`
+	// Merge adds the items of other upstreams, newest first
+	Merge []string `yaml:"merge"`
+	// Limit keeps the first items
+	Limit *int `yaml:"limit"`
+	// Convert the format served to clients without preference, atom, rss or json
+	Convert string `yaml:"convert"`
+
+	// stages the compiled Filter or Stages
+	stages pipeline.Pipeline
+}
+
+// resolveOperation resolves against Base, or against the item URL and the home page of the feed when it is true.
+type resolveOperation struct {
+	Enabled bool
+	Base    string
+}
+
+func (r *resolveOperation) UnmarshalYAML(value *yaml.Node) error {
+	var enabled bool
+	if err := value.Decode(&enabled); err == nil {
+		r.Enabled = enabled
+		return nil
+	}
+	err := value.Decode(&r.Base)
+	if err != nil {
+		return fmt.Errorf("resolve is true, false or a base URL")
+	}
+	r.Enabled = r.Base != ""
+	return nil
+}
+
+// parseServeConfig parses a configuration, YAML or JSON, whose relative file upstreams are relative to dir.
+func parseServeConfig(data []byte, dir string) (*serveConfig, error) {
+	var config serveConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(&config)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if config.TTL == nil {
+		ttl := duration(defaultTTL)
+		config.TTL = &ttl
+	}
+	if *config.TTL < 0 {
+		return nil, fmt.Errorf("ttl is negative")
+	}
+	if config.MaxBody == nil {
+		maxBody := int64(defaultMaxBody)
+		config.MaxBody = &maxBody
+	}
+	if *config.MaxBody <= 0 {
+		return nil, fmt.Errorf("max_body must be positive")
+	}
+	if len(config.Routes) == 0 {
+		return nil, fmt.Errorf("no routes")
+	}
+
+	local := func(upstream string) string {
+		if isURL(upstream) || filepath.IsAbs(upstream) || dir == "" {
+			return upstream
+		}
+		return filepath.Join(dir, upstream)
+	}
+
+	paths := map[string]bool{}
+	for i, route := range config.Routes {
+		if route == nil || !strings.HasPrefix(route.Path, "/") {
+			return nil, fmt.Errorf("routes[%d]: the path must start with /", i)
+		}
+		if paths[route.Path] {
+			return nil, fmt.Errorf("routes[%d]: duplicate path %s", i, route.Path)
+		}
+		paths[route.Path] = true
+		if route.Upstream == "" {
+			return nil, fmt.Errorf("%s: no upstream", route.Path)
+		}
+		route.Upstream = local(route.Upstream)
+
+		for j, op := range route.Pipeline {
+			if op == nil {
+				return nil, fmt.Errorf("%s: pipeline[%d]: empty operation", route.Path, j)
+			}
+			set := 0
+			for _, ok := range []bool{op.Filter != "", op.Stages != "", op.Sanitize != nil, op.Resolve != nil, op.Merge != nil, op.Limit != nil, op.Convert != ""} {
+				if ok {
+					set++
+				}
+			}
+			if set != 1 {
+				return nil, fmt.Errorf("%s: pipeline[%d]: an operation has one of filter, stages, sanitize, resolve, merge, limit or convert", route.Path, j)
+			}
+
+			switch {
+			case op.Filter != "":
+				var e *pipeline.Expr
+				if e, err = pipeline.Compile(op.Filter); err != nil {
+					err = fmt.Errorf("filter: %w", err)
+				}
+				op.stages = pipeline.Pipeline{pipeline.Filter(e)}
+			case op.Stages != "":
+				if op.stages, err = pipeline.Parse(op.Stages); err != nil {
+					err = fmt.Errorf("stages: %w", err)
+				}
+			case op.Merge != nil:
+				if len(op.Merge) == 0 {
+					err = fmt.Errorf("merge has no upstreams")
+				}
+				for k := range op.Merge {
+					op.Merge[k] = local(op.Merge[k])
+				}
+			case op.Limit != nil:
+				if *op.Limit <= 0 {
+					err = fmt.Errorf("limit must be positive")
+				}
+			case op.Convert != "":
+				switch grss.Format(op.Convert) {
+				case grss.FormatAtom, grss.FormatRss, grss.FormatJSON:
+				default:
+					err = fmt.Errorf("convert to %q, expected atom, rss or json", op.Convert)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: pipeline[%d]: %w", route.Path, j, err)
+			}
+		}
+	}
+	return &config, nil
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// fetched an upstream document and its validators.
+type fetched struct {
+	body         []byte
+	etag         string
+	lastModified string
+	// notModified the upstream did not change since the validators passed to the fetcher
+	notModified bool
+}
+
+// fetcher fetches an upstream, conditionally when etag or lastModified is set.
+type fetcher func(ctx context.Context, upstream, etag, lastModified string) (*fetched, error)
+
+// fetchUpstream fetches URLs with client and reads the other upstreams as files, their modification time being the validator.
+// A body of a URL larger than maxBody bytes is an error.
+func fetchUpstream(client *http.Client, maxBody int64) fetcher {
+	return func(ctx context.Context, upstream, etag, lastModified string) (*fetched, error) {
+		if !isURL(upstream) {
+			info, err := os.Stat(upstream)
+			if err != nil {
+				return nil, err
+			}
+			modified := info.ModTime().UTC().Format(time.RFC3339Nano)
+			if modified == lastModified {
+				return &fetched{notModified: true, lastModified: modified}, nil
+			}
+			body, err := os.ReadFile(upstream)
+			if err != nil {
+				return nil, err
+			}
+			return &fetched{body: body, lastModified: modified}, nil
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/atom+xml, application/rss+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		f := &fetched{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+		switch {
+		case resp.StatusCode == http.StatusNotModified:
+			f.notModified = true
+			if f.etag == "" {
+				f.etag = etag
+			}
+			if f.lastModified == "" {
+				f.lastModified = lastModified
+			}
+			return f, nil
+		case resp.StatusCode < 200 || resp.StatusCode > 299:
+			return nil, fmt.Errorf("%s: %s", upstream, resp.Status)
+		}
+		f.body, err = io.ReadAll(io.LimitReader(resp.Body, maxBody+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(f.body)) > maxBody {
+			return nil, fmt.Errorf("%s: the body is larger than %d bytes", upstream, maxBody)
+		}
+		return f, nil
+	}
+}
+
+// upstreamEntry the cached feed of an upstream.
+type upstreamEntry struct {
+	mu sync.Mutex
+	fetched
+	feed    grss.Feed
+	checked time.Time
+	// version changes whenever the upstream does
+	version string
+}
+
+// routeEntry the processed feed of a route and the versions of the upstreams it was processed from.
+type routeEntry struct {
+	mu       sync.Mutex
+	versions string
+	feed     grss.Feed
+}
+
+type server struct {
+	config *serveConfig
+	fetch  fetcher
+	now    func() time.Time
+	// logf reports upstream errors, the last good feed of the upstream is served meanwhile
+	logf func(format string, args ...interface{})
+
+	mu        sync.Mutex
+	upstreams map[string]*upstreamEntry
+	routes    map[string]*routeEntry
+}
+
+func newServer(config *serveConfig, fetch fetcher) *server {
+	return &server{
+		config:    config,
+		fetch:     fetch,
+		now:       time.Now,
+		logf:      func(string, ...interface{}) {},
+		upstreams: map[string]*upstreamEntry{},
+		routes:    map[string]*routeEntry{},
+	}
+}
+
+// upstream the feed of an upstream, fetched again once the ttl passed.
+func (s *server) upstream(ctx context.Context, upstream string) (*upstreamEntry, error) {
+	s.mu.Lock()
+	e := s.upstreams[upstream]
+	if e == nil {
+		e = &upstreamEntry{}
+		s.upstreams[upstream] = e
+	}
+	s.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := s.now()
+	if e.feed != nil && now.Sub(e.checked) < time.Duration(*s.config.TTL) {
+		return e, nil
+	}
+
+	f, err := s.fetch(ctx, upstream, e.etag, e.lastModified)
+	if err == nil && f.notModified && e.feed == nil {
+		err = fmt.Errorf("%s: not modified, but not cached", upstream)
+	}
+	if err == nil && !f.notModified {
+		var feed grss.Feed
+		_, feed, err = grss.Parse(bytes.NewReader(f.body))
+		if err == nil {
+			sum := sha256.Sum256(f.body)
+			e.fetched, e.feed, e.version = *f, feed, hex.EncodeToString(sum[:])
+			e.body = nil
+		}
+	}
+	if err != nil {
+		if e.feed == nil {
+			return nil, err
+		}
+		s.logf("%s: %v, serving the cached feed", upstream, err)
+	}
+	e.checked = now
+	return e, nil
+}
+
+// feed the processed feed of route, processed again when one of its upstreams changed.
+func (s *server) feed(ctx context.Context, route *routeConfig) (grss.Feed, error) {
+	upstreams := map[string]grss.Feed{}
+	var versions []string
+	for _, u := range append([]string{route.Upstream}, mergedUpstreams(route)...) {
+		if _, ok := upstreams[u]; ok {
+			continue
+		}
+		e, err := s.upstream(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		e.mu.Lock()
+		upstreams[u] = e.feed
+		versions = append(versions, e.version)
+		e.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	r := s.routes[route.Path]
+	if r == nil {
+		r = &routeEntry{}
+		s.routes[route.Path] = r
+	}
+	s.mu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := strings.Join(versions, " ")
+	if r.feed == nil || r.versions != key {
+		r.feed, r.versions = process(route, upstreams), key
+	}
+	return r.feed, nil
+}
+
+func mergedUpstreams(route *routeConfig) []string {
+	var merged []string
+	for _, op := range route.Pipeline {
+		merged = append(merged, op.Merge...)
+	}
+	return merged
+}
+
+// process runs the pipeline of route on the feed of its upstream, as a JSON Feed.
+// ToJSON copies the items, the pipeline changes them without changing the cached upstreams that concurrent requests read.
+func process(route *routeConfig, upstreams map[string]grss.Feed) grss.Feed {
+	j := upstreams[route.Upstream].ToJSON()
+	format := grss.Format("")
+
+	for _, op := range route.Pipeline {
+		switch {
+		case op.stages != nil:
+			j = op.stages.Run(j).(*grss.JSONFeed)
+
+		case op.Sanitize != nil:
+			if *op.Sanitize {
+				for _, item := range j.Items {
+					for _, content := range htmlContents(item) {
+						*content = grss.SanitizeHTML(*content)
+					}
+				}
+			}
+
+		case op.Resolve != nil:
+			if op.Resolve.Enabled {
+				resolveFeed(j, op.Resolve.Base, route.Upstream)
+			}
+
+		case op.Merge != nil:
+			feeds := []grss.Feed{j}
+			for _, u := range op.Merge {
+				feeds = append(feeds, upstreams[u].ToJSON())
+			}
+			j = grss.Stitch(feeds...).(*grss.JSONFeed)
+			sortNewestFirst(j.Items)
+
+		case op.Limit != nil:
+			if len(j.Items) > *op.Limit {
+				j.Items = j.Items[:*op.Limit]
+			}
+
+		case op.Convert != "":
+			format = grss.Format(op.Convert)
+		}
+	}
+
+	switch format {
+	case grss.FormatAtom:
+		return j.ToAtom()
+	case grss.FormatRss:
+		return j.ToRss()
+	case grss.FormatJSON:
+		return j
+	default:
+		// the format of the upstream
+		switch upstreams[route.Upstream].(type) {
+		case *grss.AtomFeed:
+			return j.ToAtom()
+		case *grss.RssFeed:
+			return j.ToRss()
+		default:
+			return j
+		}
+	}
+}
+
+// htmlTag the start of an element.
+var htmlTag = regexp.MustCompile(`<[a-zA-Z][^>]*>`)
+
+// htmlContents the HTML contents of item, content_html and the content_text holding markup,
+// as RSS descriptions are HTML and map to content_text.
+func htmlContents(item *grss.JSONItem) []*string {
+	contents := []*string{&item.ContentHTML}
+	if htmlTag.MatchString(item.ContentText) {
+		contents = append(contents, &item.ContentText)
+	}
+	return contents
+}
+
+// resolveFeed resolves the URLs of j against base, or the home page of the feed, or the upstream URL.
+// The HTML of an item is resolved against its own URL unless base is given.
+func resolveFeed(j *grss.JSONFeed, base, upstream string) {
+	feedBase := base
+	for _, u := range []string{j.HomePageURL, j.FeedURL, upstream} {
+		if feedBase == "" && isURL(u) {
+			feedBase = u
+		}
+	}
+	b, err := url.Parse(feedBase)
+	if feedBase == "" || err != nil {
+		return
+	}
+	abs := func(s string) string {
+		if s == "" {
+			return s
+		}
+		r, err := url.Parse(strings.TrimSpace(s))
+		if err != nil || r.IsAbs() {
+			return s
+		}
+		return b.ResolveReference(r).String()
+	}
+
+	j.HomePageURL, j.FeedURL, j.Icon, j.Favicon = abs(j.HomePageURL), abs(j.FeedURL), abs(j.Icon), abs(j.Favicon)
+	for _, item := range j.Items {
+		item.URL, item.ExternalURL = abs(item.URL), abs(item.ExternalURL)
+		item.Image, item.BannerImage = abs(item.Image), abs(item.BannerImage)
+		if len(item.Attachments) > 0 {
+			attachments := make([]*grss.JSONAttachments, len(item.Attachments))
+			for i, a := range item.Attachments {
+				copied := *a
+				copied.URL = abs(copied.URL)
+				attachments[i] = &copied
+			}
+			item.Attachments = attachments
+		}
+
+		contentBase := base
+		if contentBase == "" {
+			contentBase = item.URL
+		}
+		if contentBase == "" {
+			contentBase = feedBase
+		}
+		for _, content := range htmlContents(item) {
+			*content = grss.ResolveHTML(*content, contentBase)
+		}
+	}
+}
+
+// sortNewestFirst sorts items by date, the undated ones last in their order.
+func sortNewestFirst(items []*grss.JSONItem) {
+	dates := map[*grss.JSONItem]time.Time{}
+	for _, item := range items {
+		for _, s := range []string{item.DatePublished, item.DateModified} {
+			if t, err := grss.ParseDate(s); s != "" && err == nil {
+				dates[item] = t
+				break
+			}
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return dates[items[i]].After(dates[items[j]])
+	})
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, route := range s.config.Routes {
+		route := route
+		mux.Handle(route.Path, grss.Handler(func(r *http.Request) (grss.Feed, error) {
+			f, err := s.feed(r.Context(), route)
+			if err != nil {
+				s.logf("%s: %v", route.Path, err)
+			}
+			return f, err
+		}))
+	}
+	return mux
+}
+
+func runServe(e *env, args []string) int {
+	fs := newFlagSet(e, "serve", "[--listen addr] config.yaml")
+	listen := fs.String("listen", "", "the address to listen on, instead of the listen of the configuration, :8080 by default")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return fail(e, err)
+	}
+	if len(files) != 1 {
+		fs.Usage()
+		return fail(e, fmt.Errorf("serve needs one configuration file"))
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return fail(e, err)
+	}
+	config, err := parseServeConfig(data, filepath.Dir(files[0]))
+	if err != nil {
+		return fail(e, fmt.Errorf("%s: %w", files[0], err))
+	}
+
+	addr := config.Listen
+	if *listen != "" {
+		addr = *listen
+	}
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	s := newServer(config, fetchUpstream(&http.Client{Timeout: time.Minute}, *config.MaxBody))
+	s.logf = func(format string, args ...interface{}) {
+		fmt.Fprintf(e.stderr, "grss: "+format+"\n", args...)
+	}
+	srv := &http.Server{Addr: addr, Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(e.stderr, "grss: serving %d routes on %s\n", len(config.Routes), addr)
+	err = srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fail(e, err)
+	}
+	return exitOK
+}
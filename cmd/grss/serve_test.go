@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hellodword/grss"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testUpstream = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Upstream</title>
+    <link>https://example.com/blog/</link>
+    <description>An upstream</description>
+    <item>
+      <title>Sponsored: buy things</title>
+      <guid>https://example.com/blog/ad</guid>
+      <pubDate>Wed, 04 Jan 2023 10:00:00 GMT</pubDate>
+    </item>
+    <item>
+      <title>Hello</title>
+      <link>https://example.com/blog/hello</link>
+      <guid>https://example.com/blog/hello</guid>
+      <pubDate>Tue, 03 Jan 2023 10:00:00 GMT</pubDate>
+      <description><![CDATA[<p onclick="x()">Hi <img src="hi.png"><script>alert(1)</script></p>]]></description>
+    </item>
+    <item>
+      <title>Older</title>
+      <guid>https://example.com/blog/older</guid>
+      <pubDate>Mon, 02 Jan 2023 10:00:00 GMT</pubDate>
+    </item>
+  </channel>
+</rss>`
+
+// stubUpstream serves a feed with an ETag and counts the full and the conditional responses.
+type stubUpstream struct {
+	mu          sync.Mutex
+	body        string
+	full        int
+	notModified int
+}
+
+func (u *stubUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	etag := fmt.Sprintf(`"%d"`, len(u.body))
+	if r.Header.Get("If-None-Match") == etag {
+		u.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	u.full++
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/rss+xml")
+	_, _ = w.Write([]byte(u.body))
+}
+
+func get(h http.Handler, target, accept string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func Test_Serve(t *testing.T) {
+	upstream := &stubUpstream{body: testUpstream}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+
+	local := writeTemp(t, "local.json", `{"version": "https://jsonfeed.org/version/1.1", "title": "Local", "items": [
+  {"id": "l1", "content_text": "local", "date_published": "2023-01-05T00:00:00Z"}
+]}`)
+
+	config, err := parseServeConfig([]byte(fmt.Sprintf(`
+ttl: 0
+routes:
+  - path: /clean.atom
+    upstream: %s
+    pipeline:
+      - filter: '!(title =~ /^sponsored/i)'
+      - sanitize: true
+      - resolve: true
+      - convert: atom
+  - path: /merged.json
+    upstream: local.json
+    pipeline:
+      - merge: [%s]
+      - filter: 'title =~ /hello/i || content =~ /local/i'
+      - stages: 'rewrite title /^$/ "Untitled"'
+      - limit: 1
+`, srv.URL, srv.URL)), strings.TrimSuffix(local, "local.json"))
+	assert.Nil(t, err)
+
+	h := newServer(config, fetchUpstream(srv.Client(), defaultMaxBody)).handler()
+
+	w := get(h, "/clean.atom", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, grss.AtomMime+"; charset=UTF-8", w.Header().Get("Content-Type"))
+	_, f, err := grss.Parse(w.Body)
+	assert.Nil(t, err)
+	a := f.(*grss.AtomFeed)
+	assert.Equal(t, 2, len(a.Entries))
+	assert.Equal(t, "Hello", a.Entries[0].Title.String())
+	content := a.ToJSON().Items[0].ContentText
+	assert.Equal(t, `<p>Hi <img src="https://example.com/blog/hi.png"/></p>`, content)
+	assert.Equal(t, 1, upstream.full)
+
+	// negotiated, and revalidated with a conditional request as the ttl is 0
+	w = get(h, "/clean.atom", grss.JSONMime)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, grss.JSONMime, w.Header().Get("Content-Type"))
+	assert.Equal(t, 1, upstream.full)
+	assert.Equal(t, 1, upstream.notModified)
+
+	w = get(h, "/merged.json", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, f, err = grss.Parse(w.Body)
+	assert.Nil(t, err)
+	j := f.(*grss.JSONFeed)
+	assert.Equal(t, "Local", j.Title)
+	assert.Equal(t, 1, len(j.Items))
+	assert.Equal(t, "l1", j.Items[0].ID)
+	assert.Equal(t, "Untitled", j.Items[0].Title)
+
+	// the upstream changed
+	upstream.mu.Lock()
+	upstream.body = strings.Replace(testUpstream, "<title>Hello</title>", "<title>Hello again</title>", 1)
+	upstream.mu.Unlock()
+	w = get(h, "/clean.atom", "")
+	assert.Contains(t, w.Body.String(), "Hello again")
+	assert.Equal(t, 2, upstream.full)
+
+	assert.Equal(t, http.StatusNotFound, get(h, "/other", "").Code)
+}
+
+func Test_Serve_Cache(t *testing.T) {
+	var fetches int
+	var fail error
+	docs := map[string]string{"a": testRss, "b": testUpstream}
+	fetch := func(ctx context.Context, upstream, etag, lastModified string) (*fetched, error) {
+		fetches++
+		if fail != nil {
+			return nil, fail
+		}
+		if etag == upstream {
+			return &fetched{notModified: true, etag: etag}, nil
+		}
+		return &fetched{body: []byte(docs[upstream]), etag: upstream}, nil
+	}
+
+	config, err := parseServeConfig([]byte(`{"ttl": "1m", "routes": [{"path": "/a", "upstream": "a", "pipeline": [{"merge": ["b"]}, {"convert": "json"}]}]}`), "")
+	assert.Nil(t, err)
+	s := newServer(config, fetch)
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	var logged []string
+	s.logf = func(format string, args ...interface{}) { logged = append(logged, fmt.Sprintf(format, args...)) }
+
+	f1, err := s.feed(context.Background(), config.Routes[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(f1.(*grss.JSONFeed).Items))
+	// newest first
+	assert.Equal(t, "https://example.com/blog/ad", f1.(*grss.JSONFeed).Items[0].ID)
+	assert.Equal(t, 2, fetches)
+
+	// within the ttl
+	f2, _ := s.feed(context.Background(), config.Routes[0])
+	assert.Equal(t, 2, fetches)
+	assert.True(t, f1 == f2)
+
+	// revalidated, not modified, not processed again
+	now = now.Add(time.Minute)
+	f2, _ = s.feed(context.Background(), config.Routes[0])
+	assert.Equal(t, 4, fetches)
+	assert.True(t, f1 == f2)
+
+	// an upstream failing serves the cached feed
+	now = now.Add(time.Minute)
+	fail = errors.New("down")
+	f2, err = s.feed(context.Background(), config.Routes[0])
+	assert.Nil(t, err)
+	assert.True(t, f1 == f2)
+	assert.Equal(t, []string{"a: down, serving the cached feed", "b: down, serving the cached feed"}, logged)
+
+	// nothing cached
+	s = newServer(config, fetch)
+	assert.Equal(t, http.StatusInternalServerError, get(s.handler(), "/a", "").Code)
+}
+
+func Test_ServeConfig(t *testing.T) {
+	config, err := parseServeConfig([]byte(`
+routes:
+  - path: /x
+    upstream: feed.xml
+    pipeline:
+      - resolve: https://example.com/
+      - sanitize: false
+`), "/srv")
+	assert.Nil(t, err)
+	assert.Equal(t, defaultTTL, time.Duration(*config.TTL))
+	assert.Equal(t, int64(defaultMaxBody), *config.MaxBody)
+	assert.Equal(t, "/srv/feed.xml", config.Routes[0].Upstream)
+	assert.Equal(t, "https://example.com/", config.Routes[0].Pipeline[0].Resolve.Base)
+	assert.True(t, config.Routes[0].Pipeline[0].Resolve.Enabled)
+
+	for config, msg := range map[string]string{
+		``:                                 "no routes",
+		`routes: [{path: x, upstream: u}]`: "routes[0]: the path must start with /",
+		`routes: [{path: /x, upstream: u}, {path: /x, upstream: u}]`: "routes[1]: duplicate path /x",
+		`routes: [{path: /x}]`: "/x: no upstream",
+		`routes: [{path: /x, upstream: u, pipeline: [{limit: 1, sanitize: true}]}]`: "/x: pipeline[0]: an operation has one of filter, stages, sanitize, resolve, merge, limit or convert",
+		`routes: [{path: /x, upstream: u, pipeline: [{limit: 0}]}]`:                 "/x: pipeline[0]: limit must be positive",
+		`routes: [{path: /x, upstream: u, pipeline: [{convert: rss10}]}]`:           `/x: pipeline[0]: convert to "rss10", expected atom, rss or json`,
+		`routes: [{path: /x, upstream: u, pipeline: [{filter: "title =="}]}]`:       "/x: pipeline[0]: filter: offset 8: unexpected end of expression",
+		`routes: [{path: /x, upstream: u, pipeline: [{stages: "limit ten"}]}]`:      `/x: pipeline[0]: stages: line 1: limit needs a number, got "ten"`,
+		`ttl: -1s`:    "ttl is negative",
+		`max_body: 0`: "max_body must be positive",
+	} {
+		_, err := parseServeConfig([]byte(config), "")
+		if assert.NotNil(t, err, config) {
+			assert.Equal(t, msg, err.Error(), config)
+		}
+	}
+
+	_, err = parseServeConfig([]byte(`routes: [{path: /x, upstream: u, pipline: []}]`), "")
+	assert.NotNil(t, err)
+}
+
+func Test_FetchUpstream_MaxBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, strings.Repeat("a", 100))
+	}))
+	defer srv.Close()
+
+	f, err := fetchUpstream(srv.Client(), 100)(context.Background(), srv.URL, "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 100, len(f.body))
+
+	_, err = fetchUpstream(srv.Client(), 99)(context.Background(), srv.URL, "", "")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "the body is larger than 99 bytes")
+	}
+}
+
+func Test_Serve_Concurrent(t *testing.T) {
+	// a JSON Feed upstream whose items are not uniform, concurrent requests must not write to the cached feeds
+	docs := map[string]string{
+		"a": `{"version": "https://jsonfeed.org/version/1.1", "title": "A", "items": [
+  {"url": "https://example.com/a/1", "content_html": "<p>one</p>", "date_published": "Thu, 05 Jan 2023 10:00:00 GMT"}
+]}`,
+		"b": testUpstream,
+	}
+	fetch := func(ctx context.Context, upstream, etag, lastModified string) (*fetched, error) {
+		return &fetched{body: []byte(docs[upstream])}, nil
+	}
+
+	config, err := parseServeConfig([]byte(`{"ttl": "1m", "routes": [
+  {"path": "/a", "upstream": "a", "pipeline": [{"sanitize": true}, {"resolve": true}]},
+  {"path": "/merged", "upstream": "a", "pipeline": [{"merge": ["b"]}, {"convert": "json"}]},
+  {"path": "/b", "upstream": "b", "pipeline": [{"merge": ["a"]}]}
+]}`), "")
+	assert.Nil(t, err)
+	h := newServer(config, fetch).handler()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for _, path := range []string{"/a", "/merged", "/b"} {
+			for _, format := range []grss.Format{grss.FormatAtom, grss.FormatRss, grss.FormatJSON, grss.FormatRss10} {
+				wg.Add(1)
+				go func(target string) {
+					defer wg.Done()
+					assert.Equal(t, http.StatusOK, get(h, target, "").Code)
+				}(path + "?format=" + string(format))
+			}
+		}
+	}
+	wg.Wait()
+
+	w := get(h, "/merged", "")
+	_, f, err := grss.Parse(w.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(f.(*grss.JSONFeed).Items))
+	assert.Equal(t, "https://example.com/a/1", f.(*grss.JSONFeed).Items[0].ID)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hellodword/grss"
+	"strings"
+	"time"
+)
+
+// Atom https://datatracker.ietf.org/doc/html/rfc4287
+// RSS 2.0 https://www.rssboard.org/rss-specification
+// RSS 1.0 https://web.resource.org/rss/1.0/spec
+// JSON Feed https://www.jsonfeed.org/version/1.1/
+
+type severity string
+
+const (
+	severityError   severity = "error"
+	severityWarning severity = "warning"
+)
+
+// diagnostic a problem found in a feed, path locates it such as entry[2].updated.
+type diagnostic struct {
+	severity severity
+	path     string
+	msg      string
+}
+
+type validator struct {
+	diagnostics []diagnostic
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.diagnostics = append(v.diagnostics, diagnostic{severityError, path, fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(path, format string, args ...interface{}) {
+	v.diagnostics = append(v.diagnostics, diagnostic{severityWarning, path, fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(path, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.errorf(path, "required")
+	}
+}
+
+// date checks a date against the layouts of the format, a date ParseDate still understands is a warning.
+func (v *validator) date(path, s, format string, layouts ...string) {
+	if s == "" {
+		return
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return
+		}
+	}
+	if _, err := grss.ParseDate(s); err == nil {
+		v.warnf(path, "%q is not an %s date", s, format)
+		return
+	}
+	v.errorf(path, "%q is not a date", s)
+}
+
+// unique warns about ids seen before.
+func (v *validator) unique(seen map[string]string, path, id string) {
+	if id == "" {
+		return
+	}
+	if first, ok := seen[id]; ok {
+		v.warnf(path, "duplicate id %q, first used by %s", id, first)
+		return
+	}
+	seen[id] = path
+}
+
+var rfc3339Layouts = []string{time.RFC3339, time.RFC3339Nano}
+
+// rfc822Layouts RFC 822 with the four digit years RSS prefers, with and without the optional day of the week and seconds.
+var rfc822Layouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+func validate(f grss.Feed) []diagnostic {
+	v := &validator{}
+	switch ff := f.(type) {
+	case *grss.AtomFeed:
+		v.atom(ff)
+	case *grss.RssFeed:
+		v.rss(ff)
+	case *grss.JSONFeed:
+		v.json(ff)
+	}
+	return v.diagnostics
+}
+
+func textOf(t *grss.AtomTextConstruct) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+func (v *validator) atom(f *grss.AtomFeed) {
+	v.required("feed.id", string(f.ID.AtomUri))
+	v.required("feed.title", textOf(f.Title))
+	if f.Updated == nil {
+		v.errorf("feed.updated", "required")
+	} else {
+		v.date("feed.updated", f.Updated.DateTime, "RFC 3339", rfc3339Layouts...)
+	}
+
+	// atom:feed elements MUST contain one or more atom:author elements, unless all of the atom:feed element's child atom:entry elements contain at least one atom:author element.
+	if len(f.Authors) == 0 {
+		for i, entry := range f.Entries {
+			if len(entry.Authors) == 0 && (entry.Source == nil || len(entry.Source.Authors) == 0) {
+				v.errorf(fmt.Sprintf("entry[%d].author", i), "required as the feed has no author")
+			}
+		}
+	}
+
+	for i, link := range f.Links {
+		v.required(fmt.Sprintf("feed.link[%d].href", i), string(link.Href))
+	}
+
+	seen := map[string]string{}
+	for i, entry := range f.Entries {
+		path := fmt.Sprintf("entry[%d]", i)
+		id := ""
+		if entry.ID != nil {
+			id = string(entry.ID.AtomUri)
+		}
+		v.required(path+".id", id)
+		v.unique(seen, path, id)
+		v.required(path+".title", textOf(entry.Title))
+		if entry.Updated == nil {
+			v.errorf(path+".updated", "required")
+		} else {
+			v.date(path+".updated", entry.Updated.DateTime, "RFC 3339", rfc3339Layouts...)
+		}
+		if entry.Published != nil {
+			v.date(path+".published", entry.Published.DateTime, "RFC 3339", rfc3339Layouts...)
+		}
+
+		// atom:entry elements that contain no child atom:content element MUST contain at least one atom:link element with a rel attribute value of "alternate".
+		if entry.Content == nil {
+			alternate := false
+			for _, link := range entry.Links {
+				alternate = alternate || link.Rel == "" || link.Rel == "alternate"
+			}
+			if !alternate {
+				v.errorf(path, "needs a content or an alternate link")
+			}
+		}
+		for j, link := range entry.Links {
+			v.required(fmt.Sprintf("%s.link[%d].href", path, j), string(link.Href))
+		}
+	}
+}
+
+func (v *validator) rss(f *grss.RssFeed) {
+	if f.Channel == nil {
+		v.errorf("channel", "required")
+		return
+	}
+	rdf := f.ParsedVersion == grss.RssVersion10 || f.ParsedVersion == grss.RssVersion090
+	c := f.Channel
+
+	v.required("channel.title", c.Title.String())
+	v.required("channel.link", c.Link)
+	if f.ParsedVersion != grss.RssVersion090 {
+		v.required("channel.description", c.Description.String())
+	}
+	v.date("channel.pubDate", c.PubDate, "RFC 822", rfc822Layouts...)
+	v.date("channel.lastBuildDate", c.LastBuildDate, "RFC 822", rfc822Layouts...)
+
+	items := append(append([]*grss.RssItem(nil), c.Items...), f.Items...)
+	if f.ParsedVersion == grss.RssVersion091 || f.ParsedVersion == grss.RssVersion091Netscape {
+		if len(items) > 15 {
+			v.warnf("channel", "%d items, RSS %s allows 15", len(items), f.ParsedVersion)
+		}
+	}
+
+	seen := map[string]string{}
+	for i, item := range items {
+		path := fmt.Sprintf("item[%d]", i)
+		if rdf {
+			v.required(path+".title", item.Title)
+			v.required(path+".link", item.Link)
+		} else if item.Title == "" && (item.Description == nil || item.Description.String() == "") {
+			v.errorf(path, "needs a title or a description")
+		}
+		v.date(path+".pubDate", item.PubDate, "RFC 822", rfc822Layouts...)
+		if item.Guid != nil {
+			v.required(path+".guid", item.Guid.Guid)
+			v.unique(seen, path, item.Guid.Guid)
+		}
+		for j, enclosure := range item.Enclosures {
+			epath := fmt.Sprintf("%s.enclosure[%d]", path, j)
+			v.required(epath+".url", enclosure.Url)
+			v.required(epath+".length", enclosure.Length)
+			v.required(epath+".type", enclosure.Type)
+		}
+	}
+}
+
+func (v *validator) json(f *grss.JSONFeed) {
+	if !strings.HasPrefix(f.Version, "https://jsonfeed.org/version/") {
+		v.errorf("version", "%q is not a JSON Feed version URL", f.Version)
+	}
+	v.required("title", f.Title)
+
+	seen := map[string]string{}
+	for i, item := range f.Items {
+		path := fmt.Sprintf("items[%d]", i)
+		v.required(path+".id", item.ID)
+		v.unique(seen, path, item.ID)
+		if item.ContentHTML == "" && item.ContentText == "" {
+			v.errorf(path, "needs content_html or content_text")
+		}
+		v.date(path+".date_published", item.DatePublished, "RFC 3339", rfc3339Layouts...)
+		v.date(path+".date_modified", item.DateModified, "RFC 3339", rfc3339Layouts...)
+	}
+}
+
+func runValidate(e *env, args []string) int {
+	fs := newFlagSet(e, "validate", "[--strict] [--quiet] [file...]")
+	strict := fs.Bool("strict", false, "fail on warnings too")
+	quiet := fs.Bool("quiet", false, "print nothing, only set the exit code")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return fail(e, err)
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	code := exitOK
+	for _, file := range files {
+		in, err := load(e, file)
+		if err != nil {
+			if !*quiet {
+				fmt.Fprintf(e.stderr, "grss: %v\n", err)
+			}
+			code = exitTrouble
+			continue
+		}
+
+		diagnostics := validate(in.feed)
+		for _, d := range diagnostics {
+			if !*quiet {
+				fmt.Fprintf(e.stdout, "%s: %s: %s: %s\n", in.name, d.severity, d.path, d.msg)
+			}
+			if (d.severity == severityError || *strict) && code == exitOK {
+				code = exitFound
+			}
+		}
+	}
+	return code
+}
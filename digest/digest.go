@@ -0,0 +1,347 @@
+// Package digest renders feeds as a self-contained HTML page or a Markdown document, for email digests and archive pages.
+//
+// The items of the feeds, in any format, are grouped by day or by feed, and shown with their sanitized content or their summary:
+//
+//	d := digest.New(&digest.Options{Title: "Daily digest", GroupBy: digest.GroupByDate, Summary: true}, feeds...)
+//	d.WriteHTML(w)
+//
+// The templates are executed with the *Digest, DefaultHTMLTemplate and DefaultMarkdownTemplate are the ones used unless Options overrides them.
+package digest
+
+import (
+	"github.com/hellodword/grss"
+	"github.com/hellodword/grss/internal/textutil"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// GroupBy how the items of a digest are grouped.
+type GroupBy int
+
+const (
+	// GroupByDate groups the items by the day they were published, the most recent first, the undated items last.
+	GroupByDate GroupBy = iota
+	// GroupBySource groups the items by the feed they come from, in the order the feeds are given.
+	GroupBySource
+	// GroupByNone puts all the items in one group, the most recent first.
+	GroupByNone
+)
+
+// summaryLength the length in runes of the summaries made out of the content of the items without one.
+const summaryLength = 280
+
+// Options controls how New builds a digest.
+type Options struct {
+	// Title is the title of the digest, the title of the feed when there is one feed and Title is empty.
+	Title string
+	// GroupBy selects how the items are grouped.
+	GroupBy GroupBy
+	// Summary shows the summary of the items instead of their content, the beginning of the text of their content when they have none.
+	Summary bool
+	// ImageLinks replaces the images of the content by links to them, as many email clients block remote images.
+	ImageLinks bool
+	// Location is the time zone of the dates and of the days the items are grouped by, UTC when nil.
+	Location *time.Location
+
+	// HTMLTemplate overrides DefaultHTMLTemplate.
+	HTMLTemplate *htmltemplate.Template
+	// MarkdownTemplate overrides DefaultMarkdownTemplate.
+	MarkdownTemplate *texttemplate.Template
+}
+
+// Digest the data the templates are executed with.
+type Digest struct {
+	Title     string
+	Generated time.Time
+	Sources   []*Source
+	Groups    []*Group
+	// Count is the number of items of all the groups.
+	Count int
+
+	options *Options
+}
+
+// Source a feed of the digest.
+type Source struct {
+	Title string
+	URL   string
+	Icon  string
+}
+
+// Group the items of a day, of a source, or all of them.
+type Group struct {
+	// Name is the day as "Monday, January 2, 2006", the title of the source, or empty.
+	Name string
+	// Date is the day of the items grouped by date, zero for the undated ones.
+	Date time.Time
+	// Source is the source of the items grouped by source.
+	Source *Source
+	Items  []*Item
+}
+
+// Item an item of a feed, with its content rendered in HTML and in Markdown.
+type Item struct {
+	ID    string
+	Title string
+	URL   string
+	// Date is the publication date, or else the modification date, zero when the item has none.
+	Date    time.Time
+	Authors []string
+	Tags    []string
+	Image   string
+	Source  *Source
+	// HTML is the sanitized content of the item, or its summary, for the HTML template.
+	HTML htmltemplate.HTML
+	// Markdown is the content of the item, or its summary, in Markdown for the Markdown template.
+	Markdown string
+}
+
+// New builds the digest of the items of feeds.
+func New(opts *Options, feeds ...grss.Feed) *Digest {
+	if opts == nil {
+		opts = &Options{}
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	d := &Digest{
+		Title:     opts.Title,
+		Generated: time.Now().In(loc),
+		options:   opts,
+	}
+
+	var items []*Item
+	for _, f := range feeds {
+		j := f.ToJSON()
+		source := &Source{Title: j.Title, URL: j.HomePageURL, Icon: j.Icon}
+		if source.URL == "" {
+			source.URL = j.FeedURL
+		}
+		d.Sources = append(d.Sources, source)
+
+		group := &Group{Name: source.Title, Source: source}
+		for _, jitem := range j.Items {
+			item := newItem(jitem, source, opts, loc)
+			items = append(items, item)
+			group.Items = append(group.Items, item)
+		}
+		if opts.GroupBy == GroupBySource && len(group.Items) > 0 {
+			sortNewestFirst(group.Items)
+			d.Groups = append(d.Groups, group)
+		}
+	}
+	if d.Title == "" && len(d.Sources) == 1 {
+		d.Title = d.Sources[0].Title
+	}
+	d.Count = len(items)
+
+	switch opts.GroupBy {
+	case GroupByDate:
+		sortNewestFirst(items)
+		var group *Group
+		for _, item := range items {
+			day := item.Date
+			if !day.IsZero() {
+				day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+			}
+			if group == nil || !group.Date.Equal(day) {
+				group = &Group{Date: day, Name: "Undated"}
+				if !day.IsZero() {
+					group.Name = day.Format("Monday, January 2, 2006")
+				}
+				d.Groups = append(d.Groups, group)
+			}
+			group.Items = append(group.Items, item)
+		}
+	case GroupByNone:
+		sortNewestFirst(items)
+		if len(items) > 0 {
+			d.Groups = []*Group{{Items: items}}
+		}
+	}
+
+	return d
+}
+
+// sortNewestFirst sorts items by date, the most recent first and the undated ones last, keeping the order of the feeds otherwise.
+func sortNewestFirst(items []*Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].Date, items[j].Date
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.After(b)
+	})
+}
+
+func newItem(jitem *grss.JSONItem, source *Source, opts *Options, loc *time.Location) *Item {
+	item := &Item{
+		ID:     jitem.ID,
+		Title:  jitem.Title,
+		URL:    jitem.URL,
+		Tags:   jitem.Tags,
+		Image:  jitem.Image,
+		Source: source,
+	}
+	if item.URL == "" {
+		item.URL = jitem.ExternalURL
+	}
+
+	for _, d := range []string{jitem.DatePublished, jitem.DateModified} {
+		if t, err := grss.ParseDate(d); d != "" && err == nil {
+			item.Date = t.In(loc)
+			break
+		}
+	}
+
+	authors := jitem.Authors
+	if jitem.Author != nil {
+		authors = append([]*grss.JSONAuthor{jitem.Author}, authors...)
+	}
+	for _, a := range authors {
+		if a.Name != "" {
+			item.Authors = append(item.Authors, a.Name)
+		}
+	}
+
+	// the content as sanitized HTML, from content_html, or content_text as paragraphs
+	content := jitem.ContentHTML
+	if content == "" && jitem.ContentText != "" {
+		content = textutil.HTML(jitem.ContentText)
+	}
+	content = grss.SanitizeHTML(content)
+
+	text := htmlText(content)
+	if item.Title == "" {
+		// a note has no title, the beginning of its text is one
+		item.Title = textutil.Truncate(text, 80)
+	}
+
+	if opts.Summary {
+		summary := jitem.Summary
+		if summary == "" {
+			summary = textutil.Truncate(text, summaryLength)
+		}
+		content = textutil.HTML(summary)
+	}
+
+	item.Markdown = ToMarkdown(content, opts.ImageLinks)
+	if opts.ImageLinks {
+		content = imageLinks(content)
+	}
+	item.HTML = htmltemplate.HTML(content)
+	return item
+}
+
+func parseFragment(s string) []*html.Node {
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		return nil
+	}
+	return nodes
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// htmlText the text of the HTML s, with the white space collapsed.
+func htmlText(s string) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode && n.DataAtom != atom.A && n.DataAtom != atom.B && n.DataAtom != atom.I &&
+			n.DataAtom != atom.Em && n.DataAtom != atom.Strong && n.DataAtom != atom.Code && n.DataAtom != atom.Span {
+			b.WriteString(" ")
+		}
+	}
+	for _, n := range parseFragment(s) {
+		walk(n)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// imageLinks replaces the images of the HTML s by links to them, named after their alternative text.
+func imageLinks(s string) string {
+	nodes := parseFragment(s)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type != html.ElementNode || n.DataAtom != atom.Img {
+			return
+		}
+		src := attr(n, "src")
+		if src == "" {
+			n.Parent.RemoveChild(n)
+			return
+		}
+		name := attr(n, "alt")
+		if name == "" {
+			name = "image"
+		}
+		link := &html.Node{Type: html.ElementNode, Data: "a", DataAtom: atom.A, Attr: []html.Attribute{{Key: "href", Val: src}}}
+		link.AppendChild(&html.Node{Type: html.TextNode, Data: name})
+		n.Parent.InsertBefore(link, n)
+		n.Parent.RemoveChild(n)
+	}
+
+	var b strings.Builder
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	walk(root)
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		_ = html.Render(&b, c)
+	}
+	return b.String()
+}
+
+// WriteHTML writes the digest as a self-contained HTML page, with Options.HTMLTemplate or DefaultHTMLTemplate.
+func (d *Digest) WriteHTML(w io.Writer) error {
+	t := d.options.HTMLTemplate
+	if t == nil {
+		t = htmltemplate.Must(htmltemplate.New("digest").Parse(DefaultHTMLTemplate))
+	}
+	return t.Execute(w, d)
+}
+
+// WriteMarkdown writes the digest as a Markdown document, with Options.MarkdownTemplate or DefaultMarkdownTemplate.
+func (d *Digest) WriteMarkdown(w io.Writer) error {
+	t := d.options.MarkdownTemplate
+	if t == nil {
+		t = texttemplate.Must(texttemplate.New("digest").Funcs(MarkdownFuncs).Parse(DefaultMarkdownTemplate))
+	}
+	return t.Execute(w, d)
+}
+
+// WriteHTML writes the digest of feeds as a self-contained HTML page.
+func WriteHTML(w io.Writer, opts *Options, feeds ...grss.Feed) error {
+	return New(opts, feeds...).WriteHTML(w)
+}
+
+// WriteMarkdown writes the digest of feeds as a Markdown document.
+func WriteMarkdown(w io.Writer, opts *Options, feeds ...grss.Feed) error {
+	return New(opts, feeds...).WriteMarkdown(w)
+}
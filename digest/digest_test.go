@@ -0,0 +1,198 @@
+package digest
+
+import (
+	"bytes"
+	"github.com/hellodword/grss"
+	"github.com/stretchr/testify/assert"
+	htmltemplate "html/template"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+	"time"
+)
+
+const testRss = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Blog</title>
+    <link>https://blog.example/</link>
+    <description>A blog</description>
+    <item>
+      <title>Morning post</title>
+      <link>https://blog.example/morning</link>
+      <author>alice@blog.example (Alice)</author>
+      <pubDate>Mon, 02 Jan 2023 08:00:00 GMT</pubDate>
+      <content:encoded><![CDATA[<p onclick="x()">Hello <b>world</b> <img src="https://blog.example/sun.png" alt="the sun"></p><script>alert(1)</script>]]></content:encoded>
+    </item>
+    <item>
+      <title>Older post</title>
+      <link>https://blog.example/older</link>
+      <pubDate>Sun, 01 Jan 2023 20:00:00 GMT</pubDate>
+      <description>Plain description</description>
+    </item>
+  </channel>
+</rss>`
+
+const testAtom = `<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>News</title>
+  <id>https://news.example/</id>
+  <link href="https://news.example/"/>
+  <entry>
+    <title>Evening news</title>
+    <id>https://news.example/evening</id>
+    <link href="https://news.example/evening"/>
+    <published>2023-01-02T19:00:00Z</published>
+    <summary>What happened today</summary>
+    <content type="html">&lt;p&gt;A long story about today.&lt;/p&gt;</content>
+  </entry>
+</feed>`
+
+const testJSON = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Notes",
+  "home_page_url": "https://notes.example/",
+  "items": [
+    {"id": "1", "url": "https://notes.example/1", "content_text": "A note without a title, which is rather long, so long that its title is cut somewhere before the end of it", "date_published": "2023-01-02T12:00:00Z"},
+    {"id": "2", "content_text": "An undated note"}
+  ]
+}`
+
+func feeds(t *testing.T) []grss.Feed {
+	var fs []grss.Feed
+	for _, s := range []string{testRss, testAtom, testJSON} {
+		_, f, err := grss.Parse(strings.NewReader(s))
+		assert.Nil(t, err)
+		fs = append(fs, f)
+	}
+	return fs
+}
+
+func titles(g *Group) []string {
+	var s []string
+	for _, item := range g.Items {
+		s = append(s, item.Title)
+	}
+	return s
+}
+
+func Test_New(t *testing.T) {
+	d := New(&Options{Title: "Digest"}, feeds(t)...)
+	assert.Equal(t, "Digest", d.Title)
+	assert.Equal(t, 5, d.Count)
+	assert.Equal(t, 3, len(d.Sources))
+
+	// by date, the most recent first, the undated last
+	assert.Equal(t, 3, len(d.Groups))
+	assert.Equal(t, "Monday, January 2, 2023", d.Groups[0].Name)
+	assert.Equal(t, []string{"Evening news", "A note without a title, which is rather long, so long that its title is cut…", "Morning post"}, titles(d.Groups[0]))
+	assert.Equal(t, "Sunday, January 1, 2023", d.Groups[1].Name)
+	assert.Equal(t, []string{"Older post"}, titles(d.Groups[1]))
+	assert.Equal(t, "Undated", d.Groups[2].Name)
+	assert.True(t, d.Groups[2].Date.IsZero())
+
+	morning := d.Groups[0].Items[2]
+	assert.Equal(t, []string{"alice@blog.example (Alice)"}, morning.Authors)
+	assert.Equal(t, "Blog", morning.Source.Title)
+	assert.Equal(t, htmltemplate.HTML(`<p>Hello <b>world</b> <img src="https://blog.example/sun.png" alt="the sun"/></p>`), morning.HTML)
+	assert.Equal(t, `Hello **world** ![the sun](https://blog.example/sun.png)`, morning.Markdown)
+
+	// the days are those of Location
+	tokyo := time.FixedZone("JST", 9*60*60)
+	d = New(&Options{Location: tokyo}, feeds(t)...)
+	assert.Equal(t, "Tuesday, January 3, 2023", d.Groups[0].Name)
+	assert.Equal(t, []string{"Evening news"}, titles(d.Groups[0]))
+	assert.Equal(t, "", d.Title)
+
+	// by source, in the order of the feeds
+	d = New(&Options{GroupBy: GroupBySource}, feeds(t)...)
+	assert.Equal(t, 3, len(d.Groups))
+	assert.Equal(t, "Blog", d.Groups[0].Name)
+	assert.Equal(t, "https://blog.example/", d.Groups[0].Source.URL)
+	assert.Equal(t, []string{"Morning post", "Older post"}, titles(d.Groups[0]))
+	assert.Equal(t, "Notes", d.Groups[2].Name)
+
+	d = New(&Options{GroupBy: GroupByNone}, feeds(t)[0])
+	assert.Equal(t, "Blog", d.Title)
+	assert.Equal(t, 1, len(d.Groups))
+	assert.Equal(t, "", d.Groups[0].Name)
+
+	// summaries
+	d = New(&Options{GroupBy: GroupByNone, Summary: true}, feeds(t)...)
+	assert.Equal(t, htmltemplate.HTML(`<p>What happened today</p>`), d.Groups[0].Items[0].HTML)
+	assert.Equal(t, "Hello world", d.Groups[0].Items[2].Markdown)
+
+	// images as links
+	d = New(&Options{GroupBy: GroupByNone, ImageLinks: true}, feeds(t)[0])
+	assert.Equal(t, htmltemplate.HTML(`<p>Hello <b>world</b> <a href="https://blog.example/sun.png">the sun</a></p>`), d.Groups[0].Items[0].HTML)
+	assert.Equal(t, `Hello **world** [the sun](https://blog.example/sun.png)`, d.Groups[0].Items[0].Markdown)
+}
+
+func Test_WriteHTML(t *testing.T) {
+	var b bytes.Buffer
+	assert.Nil(t, WriteHTML(&b, &Options{Title: "Daily <digest>"}, feeds(t)...))
+	s := b.String()
+	assert.True(t, strings.HasPrefix(s, "<!DOCTYPE html>"))
+	assert.Contains(t, s, "<title>Daily &lt;digest&gt;</title>")
+	assert.Contains(t, s, "<h2>Monday, January 2, 2023</h2>")
+	assert.Contains(t, s, `<h3><a href="https://blog.example/morning">Morning post</a></h3>`)
+	assert.Contains(t, s, `<time datetime="2023-01-02T08:00:00Z">2023-01-02 08:00</time> · alice@blog.example (Alice) · Blog`)
+	assert.Contains(t, s, `<div class="content"><p>Hello <b>world</b>`)
+	assert.NotContains(t, s, "<script")
+	assert.NotContains(t, s, "onclick")
+
+	// an overriding template
+	b.Reset()
+	tmpl := htmltemplate.Must(htmltemplate.New("").Parse(`{{range .Groups}}{{range .Items}}<li>{{.Title}}</li>{{end}}{{end}}`))
+	assert.Nil(t, WriteHTML(&b, &Options{GroupBy: GroupBySource, HTMLTemplate: tmpl}, feeds(t)[0]))
+	assert.Equal(t, "<li>Morning post</li><li>Older post</li>", b.String())
+}
+
+func Test_WriteMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	assert.Nil(t, WriteMarkdown(&b, &Options{Title: "Daily digest", GroupBy: GroupBySource, Summary: true}, feeds(t)[:2]...))
+	assert.Equal(t, `# Daily digest
+
+## Blog
+
+### [Morning post](https://blog.example/morning)
+
+*2023-01-02 08:00* · alice@blog.example (Alice) · Blog
+
+Hello world
+
+### [Older post](https://blog.example/older)
+
+*2023-01-01 20:00* · Blog
+
+Plain description
+
+## News
+
+### [Evening news](https://news.example/evening)
+
+*2023-01-02 19:00* · News
+
+What happened today
+`, b.String())
+
+	b.Reset()
+	tmpl := texttemplate.Must(texttemplate.New("").Funcs(MarkdownFuncs).Parse(`{{range .Groups}}{{range .Items}}- {{escape .Title}}{{"\n"}}{{end}}{{end}}`))
+	assert.Nil(t, WriteMarkdown(&b, &Options{MarkdownTemplate: tmpl}, feeds(t)[2]))
+	assert.Equal(t, "- A note without a title, which is rather long, so long that its title is cut…\n- An undated note\n", b.String())
+}
+
+func Test_ToMarkdown(t *testing.T) {
+	for s, expected := range map[string]string{
+		`<h2>Title *x*</h2><p>Hello <b>bold</b> <a href="https://e.com/a (b)">link</a><br>next</p>`: "## Title \\*x\\*\n\nHello **bold** [link](https://e.com/a%20%28b%29)\\\nnext",
+		`<ul><li>one</li><li>two <p>para</p><ul><li>nested</li></ul></li></ul>`:                     "- one\n- two\n\n  para\n\n  - nested",
+		`<ol><li>first</li><li>second</li></ol>`:                                                    "1. first\n2. second",
+		`<blockquote><p>quote</p><p>more</p></blockquote>`:                                          "> quote\n>\n> more",
+		"<pre><code>code\n  indented</code></pre>":                                                  "```\ncode\n  indented\n```",
+		"<p>inline <code>x`y</code></p><hr><p>end</p>":                                              "inline ``x`y``\n\n---\n\nend",
+		`<a href="https://e.com/"></a> <img src="i.png">`:                                           "<https://e.com/> ![](i.png)",
+		`plain text_with_underscores`:                                                               `plain text\_with\_underscores`,
+	} {
+		assert.Equal(t, expected, ToMarkdown(s, false), s)
+	}
+	assert.Equal(t, "[image](i.png)", ToMarkdown(`<img src="i.png">`, true))
+}
@@ -0,0 +1,230 @@
+package digest
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"regexp"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+// CommonMark https://spec.commonmark.org/0.30/
+
+// MarkdownFuncs the functions of DefaultMarkdownTemplate, to add to the templates overriding it.
+var MarkdownFuncs = texttemplate.FuncMap{
+	"escape": EscapeMarkdown,
+	"url":    markdownURL,
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// EscapeMarkdown escapes the characters of the text s that Markdown would read as markup.
+func EscapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// markdownURL the URL s as the destination of a link, without the white space and parentheses that would end it.
+func markdownURL(s string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace(s)
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// ToMarkdown converts the HTML s to Markdown: paragraphs, headings, emphasis, code, links, images, lists, quotes and rules.
+// With imageLinks, the images are links to them instead.
+func ToMarkdown(s string, imageLinks bool) string {
+	c := &markdownConverter{imageLinks: imageLinks}
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, n := range parseFragment(s) {
+		root.AppendChild(n)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(c.children(root), "\n\n"))
+}
+
+type markdownConverter struct {
+	imageLinks bool
+}
+
+// block the Markdown block s, separated from the others by blank lines.
+func block(s string) string {
+	s = strings.Trim(s, " \n")
+	if s == "" {
+		return ""
+	}
+	return "\n\n" + s + "\n\n"
+}
+
+// indent the lines of s after the first one by n spaces, as the continuation lines of a list item.
+func indent(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = strings.Repeat(" ", n) + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// children the Markdown of the children of n, without the spaces around the line breaks.
+func (c *markdownConverter) children(n *html.Node) string {
+	s := ""
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		part := c.node(child)
+		if part == "" {
+			continue
+		}
+		if s == "" || strings.HasSuffix(s, "\n") {
+			part = strings.TrimLeft(part, " ")
+		}
+		if strings.HasPrefix(part, "\n") {
+			s = strings.TrimRight(s, " ")
+		}
+		s += part
+	}
+	return s
+}
+
+// text the text of n as it is, for code.
+func text(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Br {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(text(child))
+	}
+	return b.String()
+}
+
+// inline s on one line, wrapped in the emphasis delimiter, which must be next to the text.
+func inline(s, delimiter string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return ""
+	}
+	return delimiter + s + delimiter
+}
+
+func (c *markdownConverter) node(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		s := strings.Join(strings.Fields(n.Data), " ")
+		if s == "" {
+			if n.Data != "" {
+				return " "
+			}
+			return ""
+		}
+		// the white space at the ends separates the text from its siblings
+		if strings.TrimLeft(n.Data, " \t\r\n") != n.Data {
+			s = " " + s
+		}
+		if strings.TrimRight(n.Data, " \t\r\n") != n.Data {
+			s += " "
+		}
+		return EscapeMarkdown(s)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Template:
+		return ""
+	case atom.Br:
+		return "\\\n"
+	case atom.Hr:
+		return "\n\n---\n\n"
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Figure, atom.Table, atom.Tr, atom.Dl:
+		return block(c.children(n))
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		heading := strings.Join(strings.Fields(c.children(n)), " ")
+		if heading == "" {
+			return ""
+		}
+		return block(strings.Repeat("#", level) + " " + heading)
+	case atom.Strong, atom.B:
+		return inline(c.children(n), "**")
+	case atom.Em, atom.I:
+		return inline(c.children(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return inline(c.children(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp:
+		code := text(n)
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+			code = " " + code + " "
+		}
+		return fence + code + fence
+	case atom.Pre:
+		code := strings.TrimRight(text(n), "\n")
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return "\n\n" + fence + "\n" + code + "\n" + fence + "\n\n"
+	case atom.A:
+		content := strings.TrimSpace(c.children(n))
+		href := attr(n, "href")
+		switch {
+		case href == "":
+			return content
+		case content == "":
+			return "<" + markdownURL(href) + ">"
+		}
+		return "[" + content + "](" + markdownURL(href) + ")"
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		alt := EscapeMarkdown(strings.Join(strings.Fields(attr(n, "alt")), " "))
+		if c.imageLinks {
+			if alt == "" {
+				alt = "image"
+			}
+			return "[" + alt + "](" + markdownURL(src) + ")"
+		}
+		return "![" + alt + "](" + markdownURL(src) + ")"
+	case atom.Ul, atom.Ol:
+		var items []string
+		number := 1
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode || child.DataAtom != atom.Li {
+				continue
+			}
+			marker := "- "
+			if n.DataAtom == atom.Ol {
+				marker = strconv.Itoa(number) + ". "
+				number++
+			}
+			content := strings.Trim(blankLines.ReplaceAllString(c.children(child), "\n\n"), " \n")
+			items = append(items, marker+indent(content, len(marker)))
+		}
+		return block(strings.Join(items, "\n"))
+	case atom.Blockquote:
+		content := strings.Trim(blankLines.ReplaceAllString(c.children(n), "\n\n"), " \n")
+		if content == "" {
+			return ""
+		}
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return block(strings.Join(lines, "\n"))
+	case atom.Td, atom.Th, atom.Dt, atom.Dd:
+		return c.children(n) + " "
+	}
+	return c.children(n)
+}
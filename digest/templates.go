@@ -0,0 +1,62 @@
+package digest
+
+// DefaultHTMLTemplate the html/template of WriteHTML, a page with its styles inlined, which email clients and archives show as is.
+const DefaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 42em; margin: 0 auto; padding: 1em; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; color: #222; }
+h1 { font-size: 1.6em; }
+h2 { font-size: 1.2em; margin-top: 2em; padding-bottom: .2em; border-bottom: 1px solid #ddd; }
+h3 { font-size: 1.05em; margin-bottom: .2em; }
+article { margin-bottom: 1.5em; }
+.meta { color: #666; font-size: .85em; }
+.content img { max-width: 100%; height: auto; }
+.content pre { overflow-x: auto; }
+a { color: #1a5fb4; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Groups}}
+{{- if .Name}}
+<section>
+<h2>{{if .Source}}{{if .Source.URL}}<a href="{{.Source.URL}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}{{else}}{{.Name}}{{end}}</h2>
+{{- end}}
+{{range .Items}}
+<article>
+<h3>{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</h3>
+<div class="meta">
+{{- $sep := ""}}
+{{- if not .Date.IsZero}}<time datetime="{{.Date.Format "2006-01-02T15:04:05Z07:00"}}">{{.Date.Format "2006-01-02 15:04"}}</time>{{$sep = " · "}}{{end}}
+{{- range .Authors}}{{$sep}}{{.}}{{$sep = " · "}}{{end}}
+{{- if .Source.Title}}{{$sep}}{{.Source.Title}}{{end -}}
+</div>
+<div class="content">{{.HTML}}</div>
+</article>
+{{end}}
+{{- if .Name}}
+</section>
+{{- end}}
+{{end}}
+</body>
+</html>
+`
+
+// DefaultMarkdownTemplate the text/template of WriteMarkdown, with the functions of MarkdownFuncs.
+const DefaultMarkdownTemplate = `# {{escape .Title}}
+{{range .Groups}}{{if .Name}}
+## {{escape .Name}}
+{{end}}{{range .Items}}
+### {{if .URL}}[{{escape .Title}}]({{url .URL}}){{else}}{{escape .Title}}{{end}}
+
+{{$sep := ""}}
+{{- if not .Date.IsZero}}*{{.Date.Format "2006-01-02 15:04"}}*{{$sep = " · "}}{{end}}
+{{- range .Authors}}{{$sep}}{{escape .}}{{$sep = " · "}}{{end}}
+{{- if .Source.Title}}{{$sep}}{{escape .Source.Title}}{{end}}
+{{if .Markdown}}
+{{.Markdown}}
+{{end}}{{end}}{{end}}`
@@ -0,0 +1,384 @@
+// Package email exports the items of feeds as RFC 5322 email messages, written to an mbox file or to a Maildir, for reading feeds in a mail client.
+//
+// The items of the feeds, in any format, become multipart/alternative messages of their HTML content and of its plain text rendering:
+//
+//	err := email.WriteMbox(w, &email.Options{To: "me@example.com"}, feeds...)
+//
+// The Message-ID of a message is derived from the guid or id of its item, so that the mail clients dedupe the messages of repeated exports,
+// and WriteMaildir skips the messages already delivered.
+package email
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/hellodword/grss"
+	"github.com/hellodword/grss/digest"
+	"github.com/hellodword/grss/internal/textutil"
+	"golang.org/x/net/html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// RFC 5322 Internet Message Format https://www.rfc-editor.org/rfc/rfc5322
+// RFC 2045 MIME https://www.rfc-editor.org/rfc/rfc2045
+// RFC 2046 multipart https://www.rfc-editor.org/rfc/rfc2046#section-5.1
+// RFC 2047 encoded words https://www.rfc-editor.org/rfc/rfc2047
+// RFC 2183 Content-Disposition https://www.rfc-editor.org/rfc/rfc2183
+
+const (
+	// defaultFrom the address of the messages when Options.From is empty.
+	defaultFrom = "feeds@localhost"
+	// defaultMaxAttachmentSize the size limit of the attachments when Options.MaxAttachmentSize is 0.
+	defaultMaxAttachmentSize = 10 << 20
+)
+
+// Options controls how the messages are made.
+type Options struct {
+	// From is the address of the messages of the items without author, and of the authors known by name only, feeds@localhost when empty.
+	From string
+	// To is the recipient of the messages, left out when empty.
+	To string
+	// Attach attaches the enclosures of the items to their messages, instead of linking them.
+	// The enclosures larger than MaxAttachmentSize, or which cannot be fetched, are still linked.
+	Attach bool
+	// MaxAttachmentSize is the size limit of the attached enclosures, 10 MiB when 0.
+	MaxAttachmentSize int64
+	// Client fetches the enclosures to attach, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// Message an email message of an item.
+type Message struct {
+	// ID is the Message-ID, without the angle brackets.
+	ID string
+	// From is the address of the sender.
+	From string
+	// Date is the publication date of the item, or else its modification date, or else the time of the export.
+	Date time.Time
+	// Data is the message, with CRLF line endings.
+	Data []byte
+}
+
+// Messages the messages of the items of feeds, in the order of the feeds.
+func Messages(opts *Options, feeds ...grss.Feed) []*Message {
+	var messages []*Message
+	for _, f := range feeds {
+		j := f.ToJSON()
+		for _, item := range j.Items {
+			messages = append(messages, NewMessage(j, item, opts))
+		}
+	}
+	return messages
+}
+
+// NewMessage the message of the item of the feed f.
+func NewMessage(f *grss.JSONFeed, item *grss.JSONItem, opts *Options) *Message {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	feedURL := f.FeedURL
+	if feedURL == "" {
+		feedURL = f.HomePageURL
+	}
+	itemURL := item.URL
+	if itemURL == "" {
+		itemURL = item.ExternalURL
+	}
+
+	m := &Message{
+		ID:   messageID(feedURL, itemURL, item),
+		Date: time.Now(),
+	}
+	for _, d := range []string{item.DatePublished, item.DateModified} {
+		if t, err := grss.ParseDate(d); d != "" && err == nil {
+			m.Date = t
+			break
+		}
+	}
+
+	from := sender(f, item, opts.From)
+	m.From = from.Address
+
+	// the content as sanitized HTML, from content_html, or content_text as paragraphs
+	content := item.ContentHTML
+	if content == "" && item.ContentText != "" {
+		content = textutil.HTML(item.ContentText)
+	} else if content == "" {
+		content = textutil.HTML(item.Summary)
+	}
+	content = grss.SanitizeHTML(content)
+	text := digest.ToMarkdown(content, true)
+
+	subject := strings.Join(strings.Fields(item.Title), " ")
+	if subject == "" {
+		// a note has no title, the beginning of its text is one
+		subject = textutil.Truncate(strings.Join(strings.Fields(text), " "), 80)
+	}
+
+	// the enclosures that are not attached are linked, after the content
+	var attachments []*attachment
+	var links []*grss.JSONAttachments
+	for _, a := range item.Attachments {
+		if a.URL == "" {
+			continue
+		}
+		if opts.Attach {
+			if at := fetch(opts, a); at != nil {
+				attachments = append(attachments, at)
+				continue
+			}
+		}
+		links = append(links, a)
+	}
+
+	var htmlBody strings.Builder
+	var textParts []string
+	htmlBody.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + html.EscapeString(subject) + "</title>\n</head>\n<body>\n")
+	htmlBody.WriteString(content + "\n")
+	if text != "" {
+		textParts = append(textParts, text)
+	}
+	if len(links) > 0 {
+		var lines []string
+		htmlBody.WriteString("<ul>\n")
+		for _, a := range links {
+			name := attachmentName(a)
+			htmlBody.WriteString("<li><a href=\"" + html.EscapeString(a.URL) + "\">" + html.EscapeString(name) + "</a></li>\n")
+			lines = append(lines, "- "+name+": "+a.URL)
+		}
+		htmlBody.WriteString("</ul>\n")
+		textParts = append(textParts, strings.Join(lines, "\n"))
+	}
+	if itemURL != "" {
+		htmlBody.WriteString("<p><a href=\"" + html.EscapeString(itemURL) + "\">" + html.EscapeString(itemURL) + "</a></p>\n")
+		textParts = append(textParts, itemURL)
+	}
+	htmlBody.WriteString("</body>\n</html>\n")
+	textBody := strings.Join(textParts, "\n\n") + "\n"
+
+	var b bytes.Buffer
+	writeHeader(&b, "From", from.String())
+	if opts.To != "" {
+		writeHeader(&b, "To", opts.To)
+	}
+	writeHeader(&b, "Date", m.Date.Format(time.RFC1123Z))
+	writeHeader(&b, "Subject", mime.QEncoding.Encode("utf-8", subject))
+	writeHeader(&b, "Message-ID", "<"+m.ID+">")
+	writeHeader(&b, "MIME-Version", "1.0")
+	// the X-RSS- headers of rss2email, for the filters of the mail clients
+	writeHeader(&b, "X-RSS-Feed", feedURL)
+	writeHeader(&b, "X-RSS-ID", item.ID)
+	writeHeader(&b, "X-RSS-URL", itemURL)
+	writeHeader(&b, "X-RSS-Tags", mime.QEncoding.Encode("utf-8", strings.Join(item.Tags, ", ")))
+
+	// the boundaries are derived from the Message-ID, so that repeated exports write the same messages
+	boundary := "grss-" + hash(m.ID)
+	if len(attachments) == 0 {
+		writeHeader(&b, "Content-Type", "multipart/alternative; boundary=\""+boundary+"\"")
+		b.WriteString("\r\n")
+		writeAlternative(&b, boundary, textBody, htmlBody.String())
+	} else {
+		mixed := multipart.NewWriter(&b)
+		_ = mixed.SetBoundary(boundary + "-mixed")
+		writeHeader(&b, "Content-Type", "multipart/mixed; boundary=\""+mixed.Boundary()+"\"")
+		b.WriteString("\r\n")
+
+		w, _ := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"multipart/alternative; boundary=\"" + boundary + "\""},
+		})
+		writeAlternative(w, boundary, textBody, htmlBody.String())
+		for _, a := range attachments {
+			w, _ := mixed.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {a.mimeType},
+				"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.name})},
+				"Content-Transfer-Encoding": {"base64"},
+			})
+			writeBase64(w, a.data)
+		}
+		_ = mixed.Close()
+	}
+
+	m.Data = b.Bytes()
+	return m
+}
+
+// messageID the Message-ID of the item, a hash of its id, or else its URL, or else its title and date, at the host of the feed.
+func messageID(feedURL, itemURL string, item *grss.JSONItem) string {
+	key := item.ID
+	if key == "" {
+		key = itemURL
+	}
+	if key == "" {
+		key = item.Title + "\n" + item.DatePublished
+	}
+
+	host := "localhost"
+	if u, err := url.Parse(feedURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	// the ids are unique in their feed only
+	return hash(feedURL+"\n"+key) + "@" + host
+}
+
+func hash(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// sender the address of the first author of the item, of the feed, or the default address named after the author or the feed.
+func sender(f *grss.JSONFeed, item *grss.JSONItem, from string) *mail.Address {
+	def, err := mail.ParseAddress(from)
+	if from == "" || err != nil {
+		def = &mail.Address{Address: from}
+		if from == "" {
+			def.Address = defaultFrom
+		}
+	}
+
+	var authors []*grss.JSONAuthor
+	if item.Author != nil {
+		authors = append(authors, item.Author)
+	}
+	authors = append(authors, item.Authors...)
+	if f.Author != nil {
+		authors = append(authors, f.Author)
+	}
+	authors = append(authors, f.Authors...)
+
+	for _, a := range authors {
+		name := strings.TrimSpace(a.Name)
+		// the RSS author is an address, as "alice@example.com (Alice)"
+		if addr, err := mail.ParseAddress(name); err == nil {
+			return addr
+		}
+		if strings.HasPrefix(a.URL, "mailto:") {
+			return &mail.Address{Name: name, Address: strings.TrimPrefix(a.URL, "mailto:")}
+		}
+		if name != "" {
+			return &mail.Address{Name: name, Address: def.Address}
+		}
+	}
+
+	if def.Name == "" {
+		def.Name = strings.Join(strings.Fields(f.Title), " ")
+	}
+	return def
+}
+
+// writeHeader writes the header field, its value on one line, and none when the value is empty.
+func writeHeader(w io.Writer, key, value string) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return
+	}
+	_, _ = io.WriteString(w, key+": "+value+"\r\n")
+}
+
+// writeAlternative writes the multipart/alternative body of the plain text and of the HTML, the preferred one last.
+func writeAlternative(w io.Writer, boundary, text, htmlText string) {
+	alternative := multipart.NewWriter(w)
+	_ = alternative.SetBoundary(boundary)
+	for _, part := range []struct{ mimeType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", htmlText},
+	} {
+		pw, _ := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.mimeType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qw := quotedprintable.NewWriter(pw)
+		_, _ = io.WriteString(qw, part.body)
+		_ = qw.Close()
+	}
+	_ = alternative.Close()
+}
+
+// writeBase64 writes data in base64, in lines of 76 characters.
+func writeBase64(w io.Writer, data []byte) {
+	s := base64.StdEncoding.EncodeToString(data)
+	for len(s) > 76 {
+		_, _ = io.WriteString(w, s[:76]+"\r\n")
+		s = s[76:]
+	}
+	_, _ = io.WriteString(w, s+"\r\n")
+}
+
+// attachment a fetched enclosure.
+type attachment struct {
+	name     string
+	mimeType string
+	data     []byte
+}
+
+// fetch the enclosure a, nil when it is too large or cannot be fetched.
+func fetch(opts *Options, a *grss.JSONAttachments) *attachment {
+	max := opts.MaxAttachmentSize
+	if max == 0 {
+		max = defaultMaxAttachmentSize
+	}
+	if a.SizeInBytes > uint64(max) {
+		return nil
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(a.URL)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 || resp.ContentLength > max {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil || int64(len(data)) > max {
+		return nil
+	}
+
+	mimeType := a.MimeType
+	if mimeType == "" {
+		mimeType = resp.Header.Get("Content-Type")
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return &attachment{name: fileName(a.URL), mimeType: mimeType, data: data}
+}
+
+// fileName the last segment of the path of the URL s.
+func fileName(s string) string {
+	if u, err := url.Parse(s); err == nil {
+		if name := path.Base(u.Path); name != "." && name != "/" {
+			return name
+		}
+	}
+	return "attachment"
+}
+
+// attachmentName the title of the enclosure, or its file name.
+func attachmentName(a *grss.JSONAttachments) string {
+	if a.Title != "" {
+		return a.Title
+	}
+	return fileName(a.URL)
+}
+
+// WriteMessage writes the message of the item of the feed f.
+func WriteMessage(w io.Writer, f *grss.JSONFeed, item *grss.JSONItem, opts *Options) error {
+	_, err := w.Write(NewMessage(f, item, opts).Data)
+	return err
+}
@@ -0,0 +1,239 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/hellodword/grss"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRss = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Blog</title>
+    <link>https://blog.example/</link>
+    <description>A blog</description>
+    <item>
+      <title>Café post</title>
+      <guid isPermaLink="false">post-1</guid>
+      <link>https://blog.example/cafe</link>
+      <author>alice@blog.example (Alice)</author>
+      <category>coffee</category>
+      <pubDate>Mon, 02 Jan 2023 08:00:00 GMT</pubDate>
+      <content:encoded><![CDATA[<p onclick="x()">Hello <b>world</b></p><script>alert(1)</script>]]></content:encoded>
+      <enclosure url="https://blog.example/episode.mp3" length="1234" type="audio/mpeg"/>
+    </item>
+    <item>
+      <title>Older post</title>
+      <link>https://blog.example/older</link>
+      <pubDate>Sun, 01 Jan 2023 20:00:00 GMT</pubDate>
+      <description>From the archives</description>
+    </item>
+  </channel>
+</rss>`
+
+const testAtom = `<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>News</title>
+  <id>https://news.example/</id>
+  <link href="https://news.example/"/>
+  <entry>
+    <title>Evening news</title>
+    <id>https://news.example/evening</id>
+    <link href="https://news.example/evening"/>
+    <author><name>Bob</name></author>
+    <published>2023-01-02T19:00:00Z</published>
+    <content type="html">&lt;p&gt;A long story about today.&lt;/p&gt;</content>
+  </entry>
+</feed>`
+
+func parse(t *testing.T, s string) grss.Feed {
+	_, f, err := grss.Parse(strings.NewReader(s))
+	assert.Nil(t, err)
+	return f
+}
+
+// parts the decoded parts of the multipart body, by media type.
+func parts(t *testing.T, r io.Reader, boundary string) map[string]string {
+	m := map[string]string{}
+	mr := multipart.NewReader(r, boundary)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		mediaType, params, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		assert.Nil(t, err)
+		if strings.HasPrefix(mediaType, "multipart/") {
+			for k, v := range parts(t, p, params["boundary"]) {
+				m[k] = v
+			}
+			continue
+		}
+		var body io.Reader = p
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			body = base64.NewDecoder(base64.StdEncoding, p)
+		}
+		b, err := io.ReadAll(body)
+		assert.Nil(t, err)
+		m[mediaType] = string(b)
+	}
+	return m
+}
+
+func Test_Messages(t *testing.T) {
+	messages := Messages(&Options{To: "me@example.com"}, parse(t, testRss), parse(t, testAtom))
+	assert.Equal(t, 3, len(messages))
+
+	msg, err := mail.ReadMessage(bytes.NewReader(messages[0].Data))
+	assert.Nil(t, err)
+
+	from, err := msg.Header.AddressList("From")
+	assert.Nil(t, err)
+	assert.Equal(t, "Alice", from[0].Name)
+	assert.Equal(t, "alice@blog.example", from[0].Address)
+	assert.Equal(t, "alice@blog.example", messages[0].From)
+	assert.Equal(t, "me@example.com", msg.Header.Get("To"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Café post", subject)
+
+	date, err := msg.Header.Date()
+	assert.Nil(t, err)
+	assert.Equal(t, "2023-01-02T08:00:00Z", date.UTC().Format("2006-01-02T15:04:05Z07:00"))
+
+	assert.Equal(t, "<"+messages[0].ID+">", msg.Header.Get("Message-ID"))
+	assert.True(t, strings.HasSuffix(messages[0].ID, "@blog.example"))
+	assert.Equal(t, "post-1", msg.Header.Get("X-RSS-ID"))
+	assert.Equal(t, "https://blog.example/cafe", msg.Header.Get("X-RSS-URL"))
+	assert.Equal(t, "coffee", msg.Header.Get("X-RSS-Tags"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	body := parts(t, msg.Body, params["boundary"])
+
+	// the reader decodes the quoted-printable parts
+	assert.Equal(t, "Hello **world**\r\n\r\n- episode.mp3: https://blog.example/episode.mp3\r\n\r\nhttps://blog.example/cafe\r\n", body["text/plain"])
+	assert.Contains(t, body["text/html"], "<p>Hello <b>world</b></p>")
+	assert.Contains(t, body["text/html"], `<a href="https://blog.example/episode.mp3">episode.mp3</a>`)
+	assert.NotContains(t, body["text/html"], "script")
+	assert.NotContains(t, body["text/html"], "onclick")
+
+	// the re-exports are identical, the Message-IDs of the items differ
+	again := Messages(&Options{To: "me@example.com"}, parse(t, testRss), parse(t, testAtom))
+	assert.Equal(t, messages[0].Data, again[0].Data)
+	assert.NotEqual(t, messages[0].ID, messages[1].ID)
+	assert.True(t, strings.HasSuffix(messages[2].ID, "@news.example"))
+
+	// an author known by name only has the default address
+	msg, err = mail.ReadMessage(bytes.NewReader(messages[2].Data))
+	assert.Nil(t, err)
+	from, err = msg.Header.AddressList("From")
+	assert.Nil(t, err)
+	assert.Equal(t, "Bob", from[0].Name)
+	assert.Equal(t, "feeds@localhost", from[0].Address)
+
+	// an item without author is sent by the feed
+	msg, err = mail.ReadMessage(bytes.NewReader(Messages(&Options{From: "reader@example.com"}, parse(t, testRss))[1].Data))
+	assert.Nil(t, err)
+	from, err = msg.Header.AddressList("From")
+	assert.Nil(t, err)
+	assert.Equal(t, "Blog", from[0].Name)
+	assert.Equal(t, "reader@example.com", from[0].Address)
+}
+
+func Test_Messages_Attach(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large.mp3" {
+			_, _ = w.Write(bytes.Repeat([]byte("x"), 100))
+			return
+		}
+		_, _ = w.Write([]byte("ID3 audio"))
+	}))
+	defer server.Close()
+
+	f := parse(t, strings.NewReplacer("https://blog.example/episode.mp3", server.URL+"/episode.mp3", `length="1234"`, `length="9"`).Replace(testRss))
+	j := f.ToJSON()
+	j.Items[0].Attachments = append(j.Items[0].Attachments, &grss.JSONAttachments{URL: server.URL + "/large.mp3", MimeType: "audio/mpeg"})
+
+	m := NewMessage(j, j.Items[0], &Options{Attach: true, MaxAttachmentSize: 50, Client: server.Client()})
+	msg, err := mail.ReadMessage(bytes.NewReader(m.Data))
+	assert.Nil(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+	body := parts(t, msg.Body, params["boundary"])
+	assert.Equal(t, "ID3 audio", body["audio/mpeg"])
+	assert.Contains(t, string(m.Data), `Content-Disposition: attachment; filename=episode.mp3`)
+
+	// the enclosure over the size limit is linked
+	assert.Contains(t, body["text/plain"], "- large.mp3: "+server.URL+"/large.mp3")
+	assert.NotContains(t, body["text/plain"], "episode.mp3")
+}
+
+func Test_WriteMbox(t *testing.T) {
+	var b bytes.Buffer
+	assert.Nil(t, WriteMbox(&b, nil, parse(t, testRss), parse(t, testAtom)))
+	s := b.String()
+
+	assert.True(t, strings.HasPrefix(s, "From alice@blog.example Mon Jan  2 08:00:00 2023\nFrom: \"Alice\" <alice@blog.example>\n"))
+	assert.Equal(t, 3, strings.Count(s, "\nFrom: "))
+	assert.Contains(t, s, "\nFrom feeds@localhost Sun Jan  1 20:00:00 2023\n")
+	assert.NotContains(t, s, "\r\n")
+	// the lines of the bodies starting with "From " are quoted
+	assert.Contains(t, s, "\n>From the archives")
+	assert.NotContains(t, s, "\nFrom the archives")
+}
+
+func Test_WriteMaildir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Feeds")
+
+	n, err := WriteMaildir(dir, nil, parse(t, testRss))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	entries, err := os.ReadDir(filepath.Join(dir, "new"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	tmp, err := os.ReadDir(filepath.Join(dir, "tmp"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(tmp))
+
+	data, err := os.ReadFile(filepath.Join(dir, "new", entries[0].Name()))
+	assert.Nil(t, err)
+	_, err = mail.ReadMessage(bytes.NewReader(data))
+	assert.Nil(t, err)
+
+	// the messages read and moved to cur are not delivered again
+	name := entries[0].Name()
+	assert.Nil(t, os.Rename(filepath.Join(dir, "new", name), filepath.Join(dir, "cur", name+":2,S")))
+	n, err = WriteMaildir(dir, nil, parse(t, testRss), parse(t, testAtom))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	entries, err = os.ReadDir(filepath.Join(dir, "new"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	// the name of a message does not depend on its date, an undated item or a new date is the same message
+	undated := `{"version": "https://jsonfeed.org/version/1.1", "title": "JSON", "items": [{"id": "https://example.com/1", "content_text": "One"}]}`
+	n, err = WriteMaildir(dir, nil, parse(t, undated))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	n, err = WriteMaildir(dir, nil, parse(t, undated), parse(t, strings.Replace(undated, `"content_text"`, `"date_published": "2023-01-02T00:00:00Z", "content_text"`, 1)))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
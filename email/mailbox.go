@@ -0,0 +1,91 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/hellodword/grss"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// mboxrd https://www.loc.gov/preservation/digital/formats/fdd/fdd000385.shtml
+// Maildir https://cr.yp.to/proto/maildir.html
+
+// fromLine the lines the mboxrd format quotes with one more >, as the lines starting with "From " separate the messages.
+var fromLine = regexp.MustCompile(`(?m)^(>*From )`)
+
+// WriteMbox writes the messages of the items of feeds as an mbox file, in the mboxrd format.
+func WriteMbox(w io.Writer, opts *Options, feeds ...grss.Feed) error {
+	for _, m := range Messages(opts, feeds...) {
+		if err := writeMboxMessage(w, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMboxMessage(w io.Writer, m *Message) error {
+	// the mbox files of the local system use LF line endings
+	data := bytes.ReplaceAll(m.Data, []byte("\r\n"), []byte("\n"))
+	data = fromLine.ReplaceAll(data, []byte(">$1"))
+
+	from := m.From
+	if from == "" || strings.ContainsAny(from, " \t") {
+		from = "MAILER-DAEMON"
+	}
+	_, err := fmt.Fprintf(w, "From %s %s\n%s\n", from, m.Date.UTC().Format("Mon Jan _2 15:04:05 2006"), data)
+	return err
+}
+
+// WriteMaildir delivers the messages of the items of feeds to the Maildir dir, created when missing, and returns the number of messages delivered.
+//
+// The file names of the messages are derived from their Message-ID alone, not from their dates, which change with every export of the undated items,
+// so that the messages already in new or cur are skipped.
+func WriteMaildir(dir string, opts *Options, feeds ...grss.Feed) (int, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return 0, err
+		}
+	}
+
+	// the unique part of the names, without the :2,flags the mail clients add when they move the messages to cur
+	delivered := map[string]bool{}
+	for _, sub := range []string{"new", "cur"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if i := strings.IndexByte(name, ':'); i >= 0 {
+				name = name[:i]
+			}
+			delivered[name] = true
+		}
+	}
+
+	n := 0
+	for _, m := range Messages(opts, feeds...) {
+		name := hash(m.ID) + ".grss"
+		if delivered[name] {
+			continue
+		}
+
+		// a message is written to tmp, then moved to new once complete
+		tmp := filepath.Join(dir, "tmp", name)
+		data := bytes.ReplaceAll(m.Data, []byte("\r\n"), []byte("\n"))
+		if err := os.WriteFile(tmp, data, 0600); err != nil {
+			return n, err
+		}
+		if err := os.Rename(tmp, filepath.Join(dir, "new", name)); err != nil {
+			_ = os.Remove(tmp)
+			return n, err
+		}
+		delivered[name] = true
+		n++
+	}
+	return n, nil
+}
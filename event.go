@@ -0,0 +1,155 @@
+package grss
+
+import (
+	"strings"
+	"time"
+)
+
+// RSS 1.0 Event module https://web.resource.org/rss/1.0/modules/event/
+// xCal https://datatracker.ietf.org/doc/html/draft-royer-calsch-xcal-03
+
+const (
+	NamespaceEvent = "http://purl.org/rss/1.0/modules/event/"
+	// NamespaceXCal the xCal draft namespace, whose dtstart, dtend, location and organizer elements some event listings carry instead of the ev: ones.
+	NamespaceXCal = "urn:ietf:params:xml:ns:xcal"
+)
+
+// EventItem the elements of the RSS 1.0 Event module, which describe the event an item announces.
+type EventItem struct {
+	// EventStartDate ev:startdate, the start date and time of the event, W3CDTF, a time without offset being a floating local time.
+	EventStartDate string `xml:"http://purl.org/rss/1.0/modules/event/ ev:startdate,omitempty"`
+	// EventEndDate ev:enddate, the end date and time of the event, W3CDTF.
+	EventEndDate string `xml:"http://purl.org/rss/1.0/modules/event/ ev:enddate,omitempty"`
+	// EventLocation ev:location, where the event takes place.
+	EventLocation string `xml:"http://purl.org/rss/1.0/modules/event/ ev:location,omitempty"`
+	// EventOrganizer ev:organizer, who organizes the event.
+	EventOrganizer string `xml:"http://purl.org/rss/1.0/modules/event/ ev:organizer,omitempty"`
+	// EventType ev:type, the kind of the event, such as conference or meetup.
+	EventType string `xml:"http://purl.org/rss/1.0/modules/event/ ev:type,omitempty"`
+}
+
+func (e *EventItem) empty() bool {
+	return e.EventStartDate == "" && e.EventEndDate == "" && e.EventLocation == "" && e.EventOrganizer == "" && e.EventType == ""
+}
+
+// JSONEvent the _event item extension, carrying the event data JSON Feed has no field for.
+type JSONEvent struct {
+	// StartDate the start of the event, RFC 3339, or a date alone for the events lasting whole days,
+	// or a date and time without offset, 2006-01-02T15:04:05, for a floating time, the same local time wherever the event is seen.
+	StartDate string `json:"start_date,omitempty"`
+	// EndDate the end of the event, RFC 3339, or the date of its last day.
+	EndDate   string `json:"end_date,omitempty"`
+	Location  string `json:"location,omitempty"`
+	Organizer string `json:"organizer,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+// Event returns the _event extension of the item, nil if it has none.
+func (item *JSONItem) Event() *JSONEvent {
+	switch v := item.Extensions["_event"].(type) {
+	case nil:
+		return nil
+	case *JSONEvent:
+		return v
+	default:
+		e := &JSONEvent{}
+		if !decodeJSONExtension(v, e) {
+			return nil
+		}
+		return e
+	}
+}
+
+// event collects the event data of the item, nil if it has none.
+func (e *EventItem) event() *JSONEvent {
+	if e.empty() {
+		return nil
+	}
+	return &JSONEvent{
+		StartDate: eventDate(e.EventStartDate),
+		EndDate:   eventDate(e.EventEndDate),
+		Location:  e.EventLocation,
+		Organizer: e.EventOrganizer,
+		Type:      e.EventType,
+	}
+}
+
+// apply writes the event data as ev: elements.
+func (e *JSONEvent) apply(dst *EventItem) {
+	if e == nil {
+		return
+	}
+
+	*dst = EventItem{
+		EventStartDate: e.StartDate,
+		EventEndDate:   e.EndDate,
+		EventLocation:  e.Location,
+		EventOrganizer: e.Organizer,
+		EventType:      e.Type,
+	}
+}
+
+// floatingLayout the layout of the floating times of the events, a date and time without offset.
+const floatingLayout = "2006-01-02T15:04:05"
+
+// eventDate normalizes a date of an event to RFC 3339, or to 2006-01-02 when it has no time,
+// or to 2006-01-02T15:04:05 when it is a floating time, it is kept as is when it cannot be parsed.
+func eventDate(s string) string {
+	s = strings.TrimSpace(s)
+	t, allDay, floating, ok := parseEventDate(s)
+	switch {
+	case !ok:
+		return s
+	case allDay:
+		return t.Format("2006-01-02")
+	case floating:
+		return t.Format(floatingLayout)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// parseEventDate parses the W3CDTF dates of ev: and the iCalendar basic format of xCal, allDay when there is no time.
+// A time without offset nor Z is floating, the same local time in every time zone https://datatracker.ietf.org/doc/html/rfc5545#section-3.3.5,
+// it is returned in UTC but is not an instant.
+func parseEventDate(s string) (t time.Time, allDay bool, floating bool, ok bool) {
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true, false, true
+		}
+	}
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, false, false, true
+	}
+	for _, layout := range []string{"20060102T150405", floatingLayout, "2006-01-02T15:04"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, false, true, true
+		}
+	}
+	if t, err := ParseDate(s); s != "" && err == nil {
+		return t, false, false, true
+	}
+	return time.Time{}, false, false, false
+}
+
+// xCal the xCal elements of an item, read into the ev: ones they are missing from.
+type xCal struct {
+	DtStart   string `xml:"urn:ietf:params:xml:ns:xcal dtstart"`
+	DtEnd     string `xml:"urn:ietf:params:xml:ns:xcal dtend"`
+	Location  string `xml:"urn:ietf:params:xml:ns:xcal location"`
+	Organizer string `xml:"urn:ietf:params:xml:ns:xcal organizer"`
+}
+
+func (x *xCal) applyEvent(e *EventItem) {
+	if e.EventStartDate == "" && x.DtStart != "" {
+		e.EventStartDate = eventDate(x.DtStart)
+	}
+	if e.EventEndDate == "" && x.DtEnd != "" {
+		e.EventEndDate = eventDate(x.DtEnd)
+	}
+	if e.EventLocation == "" {
+		e.EventLocation = strings.TrimSpace(x.Location)
+	}
+	if e.EventOrganizer == "" {
+		e.EventOrganizer = strings.TrimSpace(x.Organizer)
+	}
+}
@@ -0,0 +1,111 @@
+package grss
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const eventTestRss = `
+<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:ev="http://purl.org/rss/1.0/modules/event/" xmlns:xCal="urn:ietf:params:xml:ns:xcal">
+  <channel>
+    <title>Meetups</title>
+    <link>https://events.example/</link>
+    <description>Upcoming meetups</description>
+    <item>
+      <title>Go meetup</title>
+      <link>https://events.example/go</link>
+      <guid isPermaLink="false">event-1</guid>
+      <pubDate>Mon, 02 Jan 2023 08:00:00 GMT</pubDate>
+      <ev:startdate>2023-03-01T18:00:00+01:00</ev:startdate>
+      <ev:enddate>2023-03-01T21:00:00+01:00</ev:enddate>
+      <ev:location>Berlin, Factory</ev:location>
+      <ev:organizer>Gophers &lt;org@events.example&gt;</ev:organizer>
+      <ev:type>meetup</ev:type>
+    </item>
+    <item>
+      <title>Release party</title>
+      <link>https://events.example/release</link>
+      <xCal:dtstart>20230510T170000Z</xCal:dtstart>
+      <xCal:dtend>20230510</xCal:dtend>
+      <xCal:location>Online</xCal:location>
+    </item>
+    <item>
+      <title>Blog post</title>
+      <link>https://events.example/post</link>
+      <pubDate>Tue, 03 Jan 2023 10:00:00 GMT</pubDate>
+    </item>
+  </channel>
+</rss>
+`
+
+func Test_RssItem_Event(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(eventTestRss))
+	assert.Nil(t, err)
+
+	items := f.(*RssFeed).Channel.Items
+	assert.Equal(t, EventItem{
+		EventStartDate: "2023-03-01T18:00:00+01:00",
+		EventEndDate:   "2023-03-01T21:00:00+01:00",
+		EventLocation:  "Berlin, Factory",
+		EventOrganizer: "Gophers <org@events.example>",
+		EventType:      "meetup",
+	}, items[0].EventItem)
+	// the xCal elements are read as ev: ones
+	assert.Equal(t, EventItem{
+		EventStartDate: "2023-05-10T17:00:00Z",
+		EventEndDate:   "2023-05-10",
+		EventLocation:  "Online",
+	}, items[1].EventItem)
+	assert.True(t, items[2].EventItem.empty())
+
+	j := f.ToJSON()
+	assert.Equal(t, &JSONEvent{
+		StartDate: "2023-03-01T18:00:00+01:00",
+		EndDate:   "2023-03-01T21:00:00+01:00",
+		Location:  "Berlin, Factory",
+		Organizer: "Gophers <org@events.example>",
+		Type:      "meetup",
+	}, j.Items[0].Event())
+	assert.Nil(t, j.Items[2].Event())
+
+	// the _event extension survives the JSON Feed round trip
+	var b bytes.Buffer
+	assert.Nil(t, j.WriteOut(&b))
+	_, parsed, err := Parse(&b)
+	assert.Nil(t, err)
+	assert.Equal(t, j.Items[0].Event(), parsed.ToJSON().Items[0].Event())
+
+	rss := parsed.ToRss()
+	assert.Equal(t, items[0].EventItem, rss.Channel.Items[0].EventItem)
+	b.Reset()
+	assert.Nil(t, rss.WriteOut(&b))
+	assert.Contains(t, b.String(), `xmlns:ev="http://purl.org/rss/1.0/modules/event/"`)
+	assert.Contains(t, b.String(), `<ev:location>Berlin, Factory</ev:location>`)
+
+	atom := f.ToAtom()
+	assert.Equal(t, items[1].EventItem, atom.Entries[1].EventItem)
+	b.Reset()
+	assert.Nil(t, atom.WriteOut(&b))
+	assert.Contains(t, b.String(), `xmlns:ev="http://purl.org/rss/1.0/modules/event/"`)
+	assert.Contains(t, b.String(), `<ev:startdate>2023-05-10T17:00:00Z</ev:startdate>`)
+
+	_, parsed, err = Parse(&b)
+	assert.Nil(t, err)
+	assert.Equal(t, j.Items[1].Event(), parsed.ToJSON().Items[1].Event())
+	assert.Equal(t, items[1].EventItem, parsed.ToRss().Channel.Items[1].EventItem)
+
+	b.Reset()
+	assert.Nil(t, f.ToRss10().WriteOut(&b))
+	assert.Contains(t, b.String(), `xmlns:ev="http://purl.org/rss/1.0/modules/event/"`)
+	assert.Contains(t, b.String(), `<ev:type>meetup</ev:type>`)
+
+	// the feeds without events declare no ev: namespace
+	b.Reset()
+	_, plain, err := Parse(strings.NewReader(threadTestRss))
+	assert.Nil(t, err)
+	assert.Nil(t, plain.ToRss().WriteOut(&b))
+	assert.NotContains(t, b.String(), NamespaceEvent)
+}
@@ -1,11 +1,12 @@
 package grss
 
 import (
-	"encoding/json"
 	"github.com/nbio/xml"
 	"io"
+	"net/url"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,7 +16,10 @@ type Feed interface {
 	ToJSON() *JSONFeed
 	ToRss() *RssFeed
 	ToAtom() *AtomFeed
+	ToRss10() *RdfFeed
+	ToActivityStreams() *ASObject
 	WriteOut(w io.Writer) error
+	WriteOutWith(w io.Writer, opts WriteOptions) error
 }
 
 func (f *JSONFeed) Uniform() {
@@ -46,7 +50,20 @@ func (f *JSONFeed) Mime(fallback bool) string {
 	}
 }
 
+// ToJSON a copy of the feed made uniform, its items are copies too so that f is left as it is.
 func (f *JSONFeed) ToJSON() *JSONFeed {
+	var items []*JSONItem
+	if f.Items != nil {
+		items = make([]*JSONItem, len(f.Items))
+		for i, item := range f.Items {
+			if item != nil {
+				jitem := *item
+				item = &jitem
+			}
+			items[i] = item
+		}
+	}
+
 	ff := &JSONFeed{
 		Version:     "https://jsonfeed.org/version/1.1",
 		Title:       f.Title,
@@ -58,8 +75,8 @@ func (f *JSONFeed) ToJSON() *JSONFeed {
 		Icon:        f.Icon,
 		Favicon:     f.Favicon,
 		Expired:     f.Expired,
-		Items:       f.Items,
-		Hub:         f.Hub,
+		Items:       items,
+		Hubs:        f.Hubs,
 		Extensions:  f.Extensions,
 		Language:    f.Language,
 	}
@@ -114,6 +131,26 @@ func (f *JSONFeed) ToRss() *RssFeed {
 		}
 	}
 
+	// RSS has no element for feed_url and hubs, they map to atom:link with rel="self" and rel="hub".
+	if f.FeedURL != "" {
+		ff.Channel.ExtensionElement = append(ff.Channel.ExtensionElement, atomLinkElement(&AtomLink{
+			Href: AtomUri(f.FeedURL),
+			Rel:  "self",
+			Type: RssMime,
+		}))
+	}
+	for _, link := range f.hubLinks() {
+		ff.Channel.ExtensionElement = append(ff.Channel.ExtensionElement, atomLinkElement(link))
+	}
+	// a hub of type rssCloud maps to cloud, the first one wins as RSS has a single cloud.
+	for _, hub := range f.Hubs {
+		if strings.EqualFold(hub.Type, "rssCloud") {
+			if ff.Channel.Cloud = rssCloudFromURL(hub.URL); ff.Channel.Cloud != nil {
+				break
+			}
+		}
+	}
+
 	for _, jitem := range f.Items {
 		// https://www.jsonfeed.org/mappingrssandatom/#item
 		item := &RssItem{}
@@ -156,11 +193,8 @@ func (f *JSONFeed) ToRss() *RssFeed {
 		}
 
 		// guid maps to id. In RSS, guid can have an isPermaLink attribute; in JSON Feed the url must be the permalink, and id may be the same as url, though it doesn’t have to be.
-		if jitem.ID != "" {
-			item.Guid = &RssGuid{
-				Guid: jitem.ID,
-			}
-		}
+		// isPermaLink is true by default, so the ids that are not the url are isPermaLink="false".
+		item.Guid = newRssGuid(jitem.ID, jitem.URL)
 
 		// The author element is a single value, while in JSON Feed it’s an object with name, url, and avatar values.
 		jauthors := jitem.Authors
@@ -177,13 +211,7 @@ func (f *JSONFeed) ToRss() *RssFeed {
 		}
 
 		// enclosure maps to attachments — but JSON Feed allows for multiple attachments. An RSS enclosure has attributes url, length, and type, and the JSON Feed attachment object has corresponding elements url, size_in_bytes, and mime_type. JSON Feed adds title and duration_in_seconds.
-		if len(jitem.Attachments) > 0 {
-			item.Enclosure = &RssEnclosure{
-				Url:    jitem.Attachments[0].URL,
-				Length: strconv.FormatUint(jitem.Attachments[0].SizeInBytes, 10),
-				Type:   jitem.Attachments[0].MimeType,
-			}
-		}
+		item.Enclosures, item.MediaGroups = rssEnclosures(jitem.Attachments)
 
 		// category maps to tags. In RSS a category can have a domain attribute, and there’s no equivalent in JSON Feed.
 		for i := range jitem.Tags {
@@ -191,6 +219,15 @@ func (f *JSONFeed) ToRss() *RssFeed {
 				Text: jitem.Tags[i],
 			})
 		}
+
+		if podcast := jitem.Podcast(); podcast != nil {
+			item.PodcastTranscripts = podcast.Transcripts
+			item.PodcastChapters = podcast.Chapters
+		}
+
+		jitem.Thread().applyRss(item)
+
+		jitem.Event().apply(&item.EventItem)
 	}
 
 	ff.Uniform()
@@ -226,6 +263,9 @@ func (f *JSONFeed) ToAtom() *AtomFeed {
 		})
 	}
 
+	// WebSub hubs are advertised with rel="hub".
+	ff.Links = append(ff.Links, f.hubLinks()...)
+
 	// Atom has subtitle and id elements. There is no mapping for these in JSON Feed, although description in JSON could be used instead of subtitle.
 	if f.Description != "" {
 		ff.Subtitle = &AtomTextConstruct{
@@ -309,9 +349,10 @@ func (f *JSONFeed) ToAtom() *AtomFeed {
 		}
 
 		// If rel="related" is used for links to an external site, in JSON Feed those map to external_url.
-		if jitem.ExternalURL != "" {
+		// The other related links, those of the _related extension, follow it.
+		for _, u := range jitem.relatedURLs() {
 			entry.Links = append(entry.Links, &AtomLink{
-				Href: AtomUri(jitem.ExternalURL),
+				Href: AtomUri(u),
 				Rel:  "related",
 			})
 		}
@@ -339,15 +380,13 @@ func (f *JSONFeed) ToAtom() *AtomFeed {
 
 		// Atom’s link with rel="enclosure" maps to attachments in JSON Feed. An Atom enclosure has attributes href, length, and type, and the JSON Feed attachment object has corresponding elements url, size_in_bytes, and mime_type. JSON Feed adds title and duration_in_seconds.
 		for i := range jitem.Attachments {
-			entry.Links = append(entry.Links, &AtomLink{
-				Rel:    "enclosure",
-				Href:   AtomUri(jitem.Attachments[i].URL),
-				Length: strconv.FormatUint(jitem.Attachments[i].SizeInBytes, 10),
-				Type:   AtomMediaType(jitem.Attachments[i].MimeType),
-				Title:  jitem.Attachments[i].Title,
-			})
+			entry.Links = append(entry.Links, jitem.Attachments[i].atomLink())
 		}
 
+		jitem.Thread().applyAtom(entry)
+
+		jitem.Event().apply(&entry.EventItem)
+
 		entry.Language = AtomLanguageTag(jitem.Language)
 	}
 
@@ -355,11 +394,16 @@ func (f *JSONFeed) ToAtom() *AtomFeed {
 	return ff
 }
 
+func (f *JSONFeed) ToRss10() *RdfFeed {
+	return f.ToRss().ToRss10()
+}
+
 func (f *JSONFeed) WriteOut(w io.Writer) error {
-	e := json.NewEncoder(w)
-	e.SetEscapeHTML(true)
-	e.SetIndent("", "    ")
-	return e.Encode(f)
+	return f.WriteOutWith(w, WriteOptions{Indent: defaultIndent})
+}
+
+func (f *JSONFeed) WriteOutWith(w io.Writer, opts WriteOptions) error {
+	return writeJSON(w, f, opts)
 }
 
 func (f *RssFeed) Uniform() {
@@ -375,22 +419,51 @@ func (f *RssFeed) Uniform() {
 		{"http://www.w3.org/2000/xmlns/", "content", "http://purl.org/rss/1.0/modules/content/"},
 		{"http://www.w3.org/2000/xmlns/", "atom", "http://www.w3.org/2005/Atom"},
 	}
+	if f.Channel != nil && !f.Channel.PodcastChannel.empty() || f.anyItem(func(item *RssItem) bool { return !item.PodcastItem.empty() }) {
+		pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "podcast", NamespacePodcast})
+	}
+	if f.anyItem(func(item *RssItem) bool { return item.CommentRss != "" }) {
+		pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "wfw", NamespaceWfw})
+	}
+	if f.anyItem(func(item *RssItem) bool { return item.SlashComments != "" }) {
+		pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "slash", NamespaceSlash})
+	}
+	if f.anyItem(func(item *RssItem) bool { return len(item.InReplyTo) > 0 }) {
+		pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "thr", NamespaceThread})
+	}
+	if f.anyItem(func(item *RssItem) bool { return !item.EventItem.empty() }) {
+		pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "ev", NamespaceEvent})
+	}
 
 	f.Attributes = append(f.Attributes, diffAttrs(pre, f.Attributes)...)
 
 	for _, item := range f.Channel.Items {
-		if item.ContentEncoded != nil && item.Description == "" {
+		if item.ContentEncoded != nil && item.Description.String() == "" {
 			if item.Title != "" {
-				item.Description = item.Title
+				item.Description = &XmlText{Text: item.Title}
 			} else if item.Link != "" {
-				item.Description = item.Link
+				item.Description = &XmlText{Text: item.Link}
 			} else {
-				item.Description = "Unknown"
+				item.Description = &XmlText{Text: "Unknown"}
 			}
 		}
 	}
 }
 
+// anyItem reports whether fn holds for any item, it decides which optional namespaces are declared on the root.
+func (f *RssFeed) anyItem(fn func(item *RssItem) bool) bool {
+	items := f.Items
+	if f.Channel != nil {
+		items = append(items[:len(items):len(items)], f.Channel.Items...)
+	}
+	for _, item := range items {
+		if fn(item) {
+			return true
+		}
+	}
+	return false
+}
+
 func (f *RssFeed) Mime(fallback bool) string {
 	if fallback {
 		return RssMimeFallback
@@ -414,6 +487,29 @@ func (f *RssFeed) ToJSON() *JSONFeed {
 	// An RSS link maps to home_page_url.
 	ff.HomePageURL = f.Channel.Link
 
+	// atom:link with rel="self" maps to feed_url and with rel="hub" to hubs.
+	for _, link := range f.Channel.AtomLinks() {
+		switch link.Rel {
+		case "self":
+			if ff.FeedURL == "" {
+				ff.FeedURL = string(link.Href)
+			}
+		case "hub":
+			ff.Hubs = append(ff.Hubs, &JSONHub{
+				Type: "WebSub",
+				URL:  string(link.Href),
+			})
+		}
+	}
+
+	// cloud maps to a hub of type rssCloud.
+	if endpoint := f.Channel.Cloud.Endpoint(); endpoint != "" {
+		ff.Hubs = append(ff.Hubs, &JSONHub{
+			Type: "rssCloud",
+			URL:  endpoint,
+		})
+	}
+
 	// RSS has webmaster and managingEditor items, while JSON Feed has an author item.
 	if f.Channel.WebMaster != "" {
 		ff.Authors = append(ff.Authors, &JSONAuthor{
@@ -447,13 +543,17 @@ func (f *RssFeed) ToJSON() *JSONFeed {
 		// TODO really?
 		if item.ContentEncoded != nil {
 			jitem.ContentHTML = item.ContentEncoded.XmlText.String()
-		} else if item.Description != "" {
-			jitem.ContentText = item.Description
+		} else if item.Description.String() != "" {
+			jitem.ContentText = item.Description.String()
 		}
 
 		// guid maps to id. In RSS, guid can have an isPermaLink attribute; in JSON Feed the url must be the permalink, and id may be the same as url, though it doesn’t have to be.
+		// A permalink guid is the url of an item without a link.
 		if item.Guid != nil {
 			jitem.ID = item.Guid.Guid
+			if jitem.URL == "" {
+				jitem.URL = item.Guid.PermaLink()
+			}
 		}
 
 		// The author element is a single value, while in JSON Feed it’s an object with name, url, and avatar values.
@@ -470,17 +570,35 @@ func (f *RssFeed) ToJSON() *JSONFeed {
 		//jitem.DateModified = item.PubDate
 
 		// enclosure maps to attachments — but JSON Feed allows for multiple attachments. An RSS enclosure has attributes url, length, and type, and the JSON Feed attachment object has corresponding elements url, size_in_bytes, and mime_type. JSON Feed adds title and duration_in_seconds.
-		if item.Enclosure != nil {
-			attachment := &JSONAttachments{
-				URL:      item.Enclosure.Url,
-				MimeType: item.Enclosure.Type,
-			}
-			jitem.Attachments = append(jitem.Attachments, attachment)
-			if length, err := strconv.ParseUint(item.Enclosure.Length, 10, 0); err == nil {
-				attachment.SizeInBytes = length
+		jitem.Attachments = item.attachments()
+
+		// media:thumbnail is the representative image of the item, as image is.
+		for _, thumbnail := range item.MediaThumbnails {
+			if thumbnail.Url != "" {
+				jitem.Image = thumbnail.Url
+				break
 			}
 		}
 
+		// Podcasting 2.0 transcripts and chapters have no JSON Feed field, they are kept in the _podcast extension.
+		podcast := &JSONPodcast{
+			Transcripts: item.PodcastTranscripts,
+			Chapters:    item.PodcastChapters,
+		}
+		if !podcast.empty() {
+			jitem.setExtension("_podcast", podcast)
+		}
+
+		// Threading has no JSON Feed field either, comments, wfw:commentRss, slash:comments and thr:in-reply-to are kept in the _thread extension.
+		if thread := item.thread(); thread != nil {
+			jitem.setExtension("_thread", thread)
+		}
+
+		// The Event module has no JSON Feed field either, it is kept in the _event extension.
+		if event := item.EventItem.event(); event != nil {
+			jitem.setExtension("_event", event)
+		}
+
 		//if item.Content != nil {
 		//	jitem.ContentText = item.Content.XmlText.String()
 		//}
@@ -499,7 +617,18 @@ func (f *RssFeed) ToJSON() *JSONFeed {
 
 func (f *RssFeed) ToRss() *RssFeed {
 	ff := &RssFeed{}
-	ff.Attributes = f.Attributes
+	ff.ParsedVersion = f.ParsedVersion
+
+	// The rdf:RDF root of 0.90 and 1.0 declares the default namespace, which must not leak into the <rss> root.
+	for _, attr := range f.Attributes {
+		if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			continue
+		}
+		if attr.Name.Space == "http://www.w3.org/2000/xmlns/" && attr.Value == NamespaceRdf {
+			continue
+		}
+		ff.Attributes = append(ff.Attributes, attr)
+	}
 
 	if f.Channel == nil {
 		return ff
@@ -527,6 +656,7 @@ func (f *RssFeed) ToRss() *RssFeed {
 		SkipHours:        f.Channel.SkipHours,
 		SkipDays:         f.Channel.SkipDays,
 		Items:            nil,
+		PodcastChannel:   f.Channel.PodcastChannel,
 		ExtensionElement: f.Channel.ExtensionElement,
 	}
 
@@ -544,6 +674,202 @@ func (f *RssFeed) ToRss() *RssFeed {
 	return ff
 }
 
+// rssVersionLimits the maximum lengths in characters of RSS 0.90 and 0.91, zero means unlimited.
+type rssVersionLimits struct {
+	channelTitle, channelDescription, channelLink     int
+	imageTitle, imageUrl, imageLink, imageDescription int
+	imageWidth, imageHeight                           int
+	itemTitle, itemLink, itemDescription              int
+	textInputTitle, textInputDescription              int
+	textInputName, textInputLink                      int
+	items                                             int
+}
+
+var (
+	// https://www.rssboard.org/rss-0-9-0
+	rss090Limits = rssVersionLimits{
+		channelTitle: 40, channelDescription: 500, channelLink: 500,
+		imageTitle: 40, imageUrl: 500, imageLink: 500,
+		itemTitle: 100, itemLink: 500,
+		textInputTitle: 40, textInputDescription: 100, textInputName: 500, textInputLink: 500,
+		items: 15,
+	}
+	// https://www.rssboard.org/rss-0-9-1
+	rss091Limits = rssVersionLimits{
+		channelTitle: 100, channelDescription: 500, channelLink: 500,
+		imageTitle: 100, imageUrl: 500, imageLink: 500, imageDescription: 100,
+		imageWidth: 144, imageHeight: 400,
+		itemTitle: 100, itemLink: 500, itemDescription: 500,
+		textInputTitle: 100, textInputDescription: 500, textInputName: 20, textInputLink: 500,
+		items: 15,
+	}
+)
+
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+func clampNumber(s string, n int) string {
+	if n <= 0 || s == "" {
+		return s
+	}
+	if v, err := strconv.Atoi(s); err == nil && v > n {
+		return strconv.Itoa(n)
+	}
+	return s
+}
+
+// ToRssVersion converts to the given RSS version for legacy consumers, dropping what the version does not support.
+// RSS 0.90 is written as rdf:RDF, 0.91 (Netscape) with its DOCTYPE, and 0.90 and 0.91 are cut down to the documented size limits.
+// RssVersion10 is not an RssFeed, use ToRss10 for it; it and unknown versions give the same result as ToRss.
+func (f *RssFeed) ToRssVersion(v RssVersion) *RssFeed {
+	ff := f.ToRss()
+	if ff.Channel == nil {
+		ff.Channel = &RssChannel{}
+	}
+
+	switch v {
+	case RssVersion090, RssVersion091Netscape, RssVersion091, RssVersion092, RssVersion093, RssVersion094:
+	default:
+		return ff
+	}
+
+	ff.Version = v.Attr()
+
+	// Namespaces arrived with RSS 2.0, modules and extensions are dropped.
+	ff.Attributes = nil
+	ff.Channel.Attributes = nil
+	ff.Channel.ExtensionElement = nil
+	ff.Channel.PodcastChannel = PodcastChannel{}
+
+	// 2.0 channel elements
+	ff.Channel.Generator = ""
+	ff.Channel.Ttl = ""
+
+	if v == RssVersion090 || v == RssVersion091Netscape || v == RssVersion091 {
+		// cloud and category arrived with 0.92
+		ff.Channel.Cloud = nil
+		ff.Channel.Categories = nil
+	}
+
+	var items []*RssItem
+	for _, item := range ff.Channel.Items {
+		c := &RssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+		}
+
+		if item.ContentEncoded != nil && (item.Description == nil || item.Description.String() == item.Title) {
+			content := item.ContentEncoded.XmlText
+			c.Description = &content
+		}
+
+		switch v {
+		case RssVersion092, RssVersion093, RssVersion094:
+			// https://www.rssboard.org/rss-0-9-2
+			c.Categories = item.Categories
+			c.Source = item.Source
+			if len(item.Enclosures) > 0 {
+				c.Enclosures = item.Enclosures[:1]
+			}
+		}
+
+		items = append(items, c)
+	}
+	ff.Channel.Items = items
+
+	var limits rssVersionLimits
+	switch v {
+	case RssVersion090:
+		limits = rss090Limits
+	case RssVersion091Netscape, RssVersion091:
+		limits = rss091Limits
+	}
+
+	ff.Channel.Title = XmlText{Text: truncateRunes(ff.Channel.Title.String(), limits.channelTitle)}
+	ff.Channel.Description = XmlText{Text: truncateRunes(ff.Channel.Description.String(), limits.channelDescription)}
+	ff.Channel.Link = truncateRunes(ff.Channel.Link, limits.channelLink)
+
+	if ff.Channel.Image != nil {
+		image := *ff.Channel.Image
+		image.Title = truncateRunes(image.Title, limits.imageTitle)
+		image.Url = truncateRunes(image.Url, limits.imageUrl)
+		image.Link = truncateRunes(image.Link, limits.imageLink)
+		image.Description = truncateRunes(image.Description, limits.imageDescription)
+		image.Width = clampNumber(image.Width, limits.imageWidth)
+		image.Height = clampNumber(image.Height, limits.imageHeight)
+		ff.Channel.Image = &image
+	}
+
+	if ff.Channel.TextInput != nil {
+		textInput := *ff.Channel.TextInput
+		textInput.Title = truncateRunes(textInput.Title, limits.textInputTitle)
+		textInput.Description = truncateRunes(textInput.Description, limits.textInputDescription)
+		textInput.Name = truncateRunes(textInput.Name, limits.textInputName)
+		textInput.Link = truncateRunes(textInput.Link, limits.textInputLink)
+		ff.Channel.TextInput = &textInput
+	}
+
+	if limits.items > 0 && len(ff.Channel.Items) > limits.items {
+		ff.Channel.Items = ff.Channel.Items[:limits.items]
+	}
+	for _, item := range ff.Channel.Items {
+		item.Title = truncateRunes(item.Title, limits.itemTitle)
+		item.Link = truncateRunes(item.Link, limits.itemLink)
+		if item.Description != nil && limits.itemDescription > 0 {
+			item.Description = &XmlText{Text: truncateRunes(item.Description.String(), limits.itemDescription)}
+		}
+	}
+
+	switch v {
+	case RssVersion090:
+		// https://www.rssboard.org/rss-0-9-0
+		// image, items and textinput are siblings of the channel, items carry title and link only.
+		ff.XMLName = xml.Name{
+			Space: NamespaceRdf,
+			Local: "rdf:RDF",
+		}
+		ff.Attributes = []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: NamespaceRss090Netscape},
+		}
+
+		if ff.Channel.Image != nil {
+			ff.Image = &RssImage{
+				Title: ff.Channel.Image.Title,
+				Url:   ff.Channel.Image.Url,
+				Link:  ff.Channel.Image.Link,
+			}
+		}
+		if ff.Channel.TextInput != nil {
+			ff.TextInput = ff.Channel.TextInput
+		}
+		for _, item := range ff.Channel.Items {
+			ff.Items = append(ff.Items, &RssItem{
+				Title: item.Title,
+				Link:  item.Link,
+			})
+		}
+
+		ff.Channel = &RssChannel{
+			Title:       ff.Channel.Title,
+			Link:        ff.Channel.Link,
+			Description: ff.Channel.Description,
+		}
+	case RssVersion091Netscape:
+		ff.Doctype = Rss091NetscapeDoctype
+	}
+
+	return ff
+}
+
 func (f *RssFeed) ToAtom() *AtomFeed {
 	ff := &AtomFeed{}
 
@@ -583,6 +909,13 @@ func (f *RssFeed) ToAtom() *AtomFeed {
 		}
 	}
 
+	for _, link := range f.Channel.AtomLinks() {
+		switch link.Rel {
+		case "self", "hub":
+			ff.Links = append(ff.Links, link)
+		}
+	}
+
 	if f.Channel.Title.String() != "" {
 		ff.Title = &AtomTextConstruct{
 			XmlText: f.Channel.Title,
@@ -621,6 +954,7 @@ func (f *RssFeed) ToAtom() *AtomFeed {
 
 		if item.ContentEncoded != nil {
 			entry.Content = &AtomContent{
+				Type:    "html",
 				XmlText: item.ContentEncoded.XmlText,
 			}
 		}
@@ -636,14 +970,27 @@ func (f *RssFeed) ToAtom() *AtomFeed {
 			}
 		}
 
-		if item.Link != "" {
+		// A permalink guid is the link of an item without one.
+		if link := item.Link; link != "" || item.Guid.PermaLink() != "" {
+			if link == "" {
+				link = item.Guid.PermaLink()
+			}
 			entry.Links = []*AtomLink{
 				{
-					Href: AtomUri(item.Link),
+					Href: AtomUri(link),
 				},
 			}
 		}
 
+		for _, attachment := range item.attachments() {
+			entry.Links = append(entry.Links, attachment.atomLink())
+		}
+
+		// comments and wfw:commentRss map to rel="replies" links, slash:comments to thr:total.
+		item.thread().applyAtom(entry)
+
+		entry.EventItem = item.EventItem
+
 		if item.PubDate != "" {
 			entry.Published = &AtomDateConstruct{
 				DateTime: FormatDate(item.PubDate, time.RFC3339),
@@ -654,10 +1001,10 @@ func (f *RssFeed) ToAtom() *AtomFeed {
 			}
 		}
 
-		if entry.Content == nil && item.Description != "" {
+		if entry.Content == nil && item.Description.String() != "" {
 			entry.Summary = &AtomTextConstruct{
 				XmlText: XmlText{
-					Cdata: item.Description,
+					Cdata: item.Description.String(),
 				},
 			}
 		}
@@ -676,14 +1023,153 @@ func (f *RssFeed) ToAtom() *AtomFeed {
 	return ff
 }
 
-func (f *RssFeed) WriteOut(w io.Writer) error {
-	_, err := w.Write([]byte(xml.Header))
-	if err != nil {
-		return err
+func (f *RssFeed) ToRss10() *RdfFeed {
+	// https://web.resource.org/rss/1.0/spec
+	ff := &RdfFeed{}
+
+	if f.Channel == nil {
+		ff.Uniform()
+		return ff
+	}
+
+	ff.Channel = &RdfChannel{
+		Title:       f.Channel.Title.String(),
+		Link:        f.Channel.Link,
+		Description: f.Channel.Description,
+	}
+
+	// The channel rdf:about is most commonly the URL of the RSS document itself.
+	for _, link := range f.Channel.AtomLinks() {
+		if link.Rel == "self" {
+			ff.Channel.About = string(link.Href)
+		}
+	}
+
+	// https://web.resource.org/rss/1.0/modules/dc/
+	ff.Channel.Language = f.Channel.Language
+	ff.Channel.Rights = f.Channel.Copyright
+	ff.Channel.Publisher = f.Channel.WebMaster
+	if f.Channel.ManagingEditor != "" {
+		ff.Channel.Creator = []string{f.Channel.ManagingEditor}
+	}
+	if f.Channel.LastBuildDate != "" {
+		ff.Channel.Date = FormatDate(f.Channel.LastBuildDate, time.RFC3339)
+	} else if f.Channel.PubDate != "" {
+		ff.Channel.Date = FormatDate(f.Channel.PubDate, time.RFC3339)
+	}
+	for i := range f.Channel.Categories {
+		ff.Channel.Subject = append(ff.Channel.Subject, f.Channel.Categories[i].Text)
+	}
+
+	// https://web.resource.org/rss/1.0/modules/syndication/
+	if ttl, err := strconv.Atoi(f.Channel.Ttl); err == nil {
+		if period, frequency := syndicationFromTtl(ttl); period != "" {
+			ff.Channel.UpdatePeriod = period
+			ff.Channel.UpdateFrequency = strconv.Itoa(frequency)
+		}
+	}
+
+	image := f.Channel.Image
+	if image == nil {
+		image = f.Image
+	}
+	if image != nil && image.Url != "" {
+		ff.Image = &RdfImage{
+			Title: image.Title,
+			Url:   image.Url,
+			Link:  image.Link,
+		}
+		if ff.Image.Title == "" {
+			ff.Image.Title = ff.Channel.Title
+		}
+		if ff.Image.Link == "" {
+			ff.Image.Link = ff.Channel.Link
+		}
+	}
+
+	textInput := f.Channel.TextInput
+	if textInput == nil {
+		textInput = f.TextInput
+	}
+	if textInput != nil && textInput.Link != "" {
+		ff.TextInput = &RdfTextInput{
+			Title:       textInput.Title,
+			Description: textInput.Description,
+			Name:        textInput.Name,
+			Link:        textInput.Link,
+		}
 	}
-	e := xml.NewEncoder(w)
-	e.Indent("", "    ")
-	return e.Encode(f)
+
+	items := append(f.Items[:len(f.Items):len(f.Items)], f.Channel.Items...)
+	for _, item := range items {
+		ritem := &RdfItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+		}
+		ff.Items = append(ff.Items, ritem)
+
+		// A permalink guid is the link of an item without one.
+		if ritem.Link == "" {
+			ritem.Link = item.Guid.PermaLink()
+		}
+
+		// The {item_uri} should be identical to the value of the link sub-element of the item element, if possible.
+		// Else a guid that is not a permalink identifies the item when it is a URI that cannot be opened, such as a tag: or urn: URI.
+		ritem.About = ritem.Link
+		if ritem.About == "" && item.Guid != nil {
+			if u, err := url.Parse(item.Guid.Guid); err == nil && u.IsAbs() && !absoluteURL(item.Guid.Guid) {
+				ritem.About = item.Guid.Guid
+			}
+		}
+
+		if item.Author != nil {
+			ritem.Creator = []string{item.Author.Email}
+		}
+
+		if item.PubDate != "" {
+			ritem.Date = FormatDate(item.PubDate, time.RFC3339)
+		}
+
+		for i := range item.Categories {
+			ritem.Subject = append(ritem.Subject, item.Categories[i].Text)
+		}
+
+		if item.Source != nil {
+			ritem.Source = item.Source.Url
+		}
+
+		if item.ContentEncoded != nil {
+			content := item.ContentEncoded.XmlText
+			ritem.ContentEncoded = &content
+		}
+
+		ritem.EventItem = item.EventItem
+	}
+
+	// The rdf:about of the items must be unique, an item without a URI, or sharing one, is identified by a urn:uuid named after the feed and the item.
+	seen := map[string]bool{}
+	for i, ritem := range ff.Items {
+		if ritem.About == "" || seen[ritem.About] {
+			name := f.Channel.Link + "\n" + StableID(items[i])
+			ritem.About = urnUUID(name)
+			for n := 2; seen[ritem.About]; n++ {
+				ritem.About = urnUUID(name + "\n" + strconv.Itoa(n))
+			}
+		}
+		seen[ritem.About] = true
+	}
+
+	ff.Uniform()
+	return ff
+}
+
+func (f *RssFeed) WriteOut(w io.Writer) error {
+	return f.WriteOutWith(w, WriteOptions{Indent: defaultIndent})
+}
+
+func (f *RssFeed) WriteOutWith(w io.Writer, opts WriteOptions) error {
+	return writeXml(w, f.withOptions(opts), opts, f.Doctype)
 }
 
 func (f *AtomFeed) Uniform() {
@@ -698,6 +1184,18 @@ func (f *AtomFeed) Uniform() {
 		{"", "xmlns", "http://www.w3.org/2005/Atom"},
 		{"http://www.w3.org/2000/xmlns/", "media", "http://search.yahoo.com/mrss/"},
 	}
+	for _, entry := range f.Entries {
+		if entry.thread() != nil {
+			pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "thr", NamespaceThread})
+			break
+		}
+	}
+	for _, entry := range f.Entries {
+		if !entry.EventItem.empty() {
+			pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "ev", NamespaceEvent})
+			break
+		}
+	}
 
 	f.UndefinedAttribute = append(f.UndefinedAttribute, diffAttrs(pre, f.UndefinedAttribute)...)
 
@@ -825,6 +1323,11 @@ func (f *AtomFeed) ToJSON() *JSONFeed {
 			ff.FeedURL = string(f.Links[i].Href)
 		case "", "alternate":
 			ff.HomePageURL = string(f.Links[i].Href)
+		case "hub":
+			ff.Hubs = append(ff.Hubs, &JSONHub{
+				Type: "WebSub",
+				URL:  string(f.Links[i].Href),
+			})
 		}
 	}
 
@@ -881,26 +1384,21 @@ func (f *AtomFeed) ToJSON() *JSONFeed {
 		// link with rel="alternate" maps to url in JSON.
 		// If rel="related" is used for links to an external site, in JSON Feed those map to external_url.
 		// Atom’s link with rel="enclosure" maps to attachments in JSON Feed. An Atom enclosure has attributes href, length, and type, and the JSON Feed attachment object has corresponding elements url, size_in_bytes, and mime_type. JSON Feed adds title and duration_in_seconds.
+		// The related links after the first are kept in the _related extension.
+		var related []string
 		for i := range entry.Links {
 			switch entry.Links[i].Rel {
-			case "alternate":
-				item.URL = string(entry.Links[i].Href)
+			case "", "alternate":
+				if item.URL == "" {
+					item.URL = string(entry.Links[i].Href)
+				}
 			case "related":
-				item.ExternalURL = string(entry.Links[i].Href)
+				related = append(related, string(entry.Links[i].Href))
 			case "enclosure":
-				attachment := &JSONAttachments{
-					URL:      string(entry.Links[i].Href),
-					MimeType: string(entry.Links[i].Type),
-					Title:    entry.Links[i].Title,
-				}
-				item.Attachments = append(item.Attachments, attachment)
-				if entry.Links[i].Length != "" {
-					if length, err := strconv.ParseUint(entry.Links[i].Length, 10, 0); err == nil {
-						attachment.SizeInBytes = length
-					}
-				}
+				item.Attachments = append(item.Attachments, entry.Links[i].attachment())
 			}
 		}
+		item.setRelatedURLs(related)
 
 		// The author element contains name, uri, and email, while in JSON Feed it’s an object with name, url, and avatar values.
 		for i := range entry.Authors {
@@ -910,6 +1408,14 @@ func (f *AtomFeed) ToJSON() *JSONFeed {
 			})
 		}
 
+		if thread := entry.thread(); thread != nil {
+			item.setExtension("_thread", thread)
+		}
+
+		if event := entry.EventItem.event(); event != nil {
+			item.setExtension("_event", event)
+		}
+
 		// Atom’s published and updated dates map to date_published and date_modified in JSON. Both Atom and JSON Feed use the same date format.
 		if entry.Published != nil {
 			item.DatePublished = entry.Published.DateTime
@@ -980,16 +1486,29 @@ func (f *AtomFeed) ToRss() *RssFeed {
 			}
 		}
 
-		if entry.ID != nil {
-			item.Guid = &RssGuid{
-				Guid: string(entry.ID.AtomUri),
+		var attachments []*JSONAttachments
+		for i := range entry.Links {
+			switch entry.Links[i].Rel {
+			case "", "alternate":
+				if item.Link == "" {
+					item.Link = string(entry.Links[i].Href)
+				}
+			case "enclosure":
+				attachments = append(attachments, entry.Links[i].attachment())
 			}
 		}
+		item.Enclosures, item.MediaGroups = rssEnclosures(attachments)
 
-		if len(entry.Links) > 0 {
-			item.Link = string(entry.Links[0].Href)
+		// The id is a permalink guid only when it is the http URL of the alternate link, or of an entry without one, tag: and urn: ids are isPermaLink="false".
+		if entry.ID != nil {
+			item.Guid = newRssGuid(string(entry.ID.AtomUri), item.Link)
 		}
 
+		// rel="replies" links map to comments and wfw:commentRss, thr:total to slash:comments.
+		entry.thread().applyRss(item)
+
+		item.EventItem = entry.EventItem
+
 		if entry.Published != nil {
 			item.PubDate = FormatDate(entry.Published.DateTime, time.RFC1123Z)
 		} else if entry.Updated != nil {
@@ -997,7 +1516,7 @@ func (f *AtomFeed) ToRss() *RssFeed {
 		}
 
 		if entry.Summary != nil {
-			item.Description = entry.Summary.String()
+			item.Description = &XmlText{Text: entry.Summary.String()}
 		}
 
 		if entry.Title != nil {
@@ -1009,7 +1528,17 @@ func (f *AtomFeed) ToRss() *RssFeed {
 		ff.Channel.Title = f.Title.XmlText
 	}
 
-	if len(f.Links) > 0 {
+	for _, link := range f.Links {
+		switch link.Rel {
+		case "", "alternate":
+			if ff.Channel.Link == "" {
+				ff.Channel.Link = string(link.Href)
+			}
+		case "self", "hub":
+			ff.Channel.ExtensionElement = append(ff.Channel.ExtensionElement, atomLinkElement(link))
+		}
+	}
+	if ff.Channel.Link == "" && len(f.Links) > 0 {
 		ff.Channel.Link = string(f.Links[0].Href)
 	}
 
@@ -1041,12 +1570,197 @@ func (f *AtomFeed) ToAtom() *AtomFeed {
 	return ff
 }
 
+func (f *AtomFeed) ToRss10() *RdfFeed {
+	return f.ToRss().ToRss10()
+}
+
 func (f *AtomFeed) WriteOut(w io.Writer) error {
-	_, err := w.Write([]byte(xml.Header))
-	if err != nil {
-		return err
+	return f.WriteOutWith(w, WriteOptions{Indent: defaultIndent})
+}
+
+func (f *AtomFeed) WriteOutWith(w io.Writer, opts WriteOptions) error {
+	return writeXml(w, f.withHTMLContent(opts.HTMLContent), opts, "")
+}
+
+// rssEnclosures maps attachments to enclosures.
+// Attachments with the exact same title are alternate representations of the same thing (JSON Feed), the first of them becomes the enclosure and all of them a media:group.
+func rssEnclosures(attachments []*JSONAttachments) (enclosures []*RssEnclosure, groups []*MediaGroup) {
+	titles := map[string]int{}
+	for _, attachment := range attachments {
+		if attachment.Title != "" {
+			titles[attachment.Title]++
+		}
+	}
+
+	grouped := map[string]*MediaGroup{}
+	for _, attachment := range attachments {
+		if titles[attachment.Title] < 2 {
+			enclosures = append(enclosures, attachment.rssEnclosure())
+			continue
+		}
+
+		group := grouped[attachment.Title]
+		if group == nil {
+			group = &MediaGroup{Title: attachment.Title}
+			grouped[attachment.Title] = group
+			groups = append(groups, group)
+			enclosures = append(enclosures, attachment.rssEnclosure())
+		}
+
+		content := attachment.mediaContent()
+		if len(group.Contents) == 0 {
+			content.IsDefault = "true"
+		}
+		group.Contents = append(group.Contents, content)
+	}
+
+	return enclosures, groups
+}
+
+// attachments maps enclosures, media:content and media:group to attachments, members of a group share its title so they stay alternates.
+func (item *RssItem) attachments() []*JSONAttachments {
+	var attachments []*JSONAttachments
+	byURL := map[string]*JSONAttachments{}
+
+	for _, enclosure := range item.Enclosures {
+		attachment := &JSONAttachments{
+			URL:      enclosure.Url,
+			MimeType: enclosure.Type,
+		}
+		if length, err := strconv.ParseUint(enclosure.Length, 10, 0); err == nil {
+			attachment.SizeInBytes = length
+		}
+		attachments = append(attachments, attachment)
+		byURL[attachment.URL] = attachment
 	}
-	e := xml.NewEncoder(w)
-	e.Indent("", "    ")
-	return e.Encode(f)
+
+	for _, content := range item.MediaContents {
+		if byURL[content.Url] != nil {
+			continue
+		}
+		attachment := content.attachment()
+		attachments = append(attachments, attachment)
+		byURL[attachment.URL] = attachment
+	}
+
+	for _, group := range item.MediaGroups {
+		title := group.Title
+		if title == "" {
+			title = item.Title
+		}
+		if title == "" {
+			title = "media"
+		}
+
+		for _, content := range group.Contents {
+			if attachment := byURL[content.Url]; attachment != nil {
+				attachment.Title = title
+				continue
+			}
+			attachment := content.attachment()
+			attachment.Title = title
+			attachments = append(attachments, attachment)
+			byURL[attachment.URL] = attachment
+		}
+	}
+
+	return attachments
+}
+
+func (a *JSONAttachments) rssEnclosure() *RssEnclosure {
+	return &RssEnclosure{
+		Url:    a.URL,
+		Length: strconv.FormatUint(a.SizeInBytes, 10),
+		Type:   a.MimeType,
+	}
+}
+
+func (a *JSONAttachments) mediaContent() *MediaContent {
+	content := &MediaContent{
+		Url:  a.URL,
+		Type: a.MimeType,
+	}
+	if a.SizeInBytes > 0 {
+		content.FileSize = strconv.FormatUint(a.SizeInBytes, 10)
+	}
+	if a.DurationInSeconds > 0 {
+		content.Duration = strconv.FormatUint(a.DurationInSeconds, 10)
+	}
+	return content
+}
+
+func (a *JSONAttachments) atomLink() *AtomLink {
+	return &AtomLink{
+		Rel:    "enclosure",
+		Href:   AtomUri(a.URL),
+		Length: strconv.FormatUint(a.SizeInBytes, 10),
+		Type:   AtomMediaType(a.MimeType),
+		Title:  a.Title,
+	}
+}
+
+func (c *MediaContent) attachment() *JSONAttachments {
+	attachment := &JSONAttachments{
+		URL:      c.Url,
+		MimeType: c.Type,
+	}
+	if size, err := strconv.ParseUint(c.FileSize, 10, 0); err == nil {
+		attachment.SizeInBytes = size
+	}
+	if duration, err := strconv.ParseFloat(c.Duration, 64); err == nil && duration > 0 {
+		attachment.DurationInSeconds = uint64(duration)
+	}
+	return attachment
+}
+
+func (a *AtomLink) attachment() *JSONAttachments {
+	attachment := &JSONAttachments{
+		URL:      string(a.Href),
+		MimeType: string(a.Type),
+		Title:    a.Title,
+	}
+	if a.Length != "" {
+		if length, err := strconv.ParseUint(a.Length, 10, 0); err == nil {
+			attachment.SizeInBytes = length
+		}
+	}
+	return attachment
+}
+
+// hubLinks the rel="hub" links of the WebSub hubs, hubs of other protocols such as rssCloud are left out.
+func (f *JSONFeed) hubLinks() []*AtomLink {
+	var links []*AtomLink
+	for _, hub := range f.Hubs {
+		if hub.URL == "" || !(strings.EqualFold(hub.Type, "WebSub") || strings.EqualFold(hub.Type, "PubSubHubbub")) {
+			continue
+		}
+		links = append(links, &AtomLink{
+			Href: AtomUri(hub.URL),
+			Rel:  "hub",
+		})
+	}
+	return links
+}
+
+// atomLinkElement an atom:link carried as an RSS channel extension.
+func atomLinkElement(link *AtomLink) XmlGeneric {
+	e := XmlGeneric{
+		XMLName: xml.Name{
+			Space: "http://www.w3.org/2005/Atom",
+			Local: "link",
+		},
+	}
+	for _, attr := range [][2]string{
+		{"href", string(link.Href)},
+		{"rel", link.Rel},
+		{"type", string(link.Type)},
+		{"hreflang", string(link.Hreflang)},
+		{"title", link.Title},
+		{"length", link.Length},
+	} {
+		if attr[1] != "" {
+			e.Attributes = append(e.Attributes, xml.Attr{Name: xml.Name{Local: attr[0]}, Value: attr[1]})
+		}
+	}
+	return e
 }
@@ -0,0 +1,195 @@
+package grss
+
+import (
+	"github.com/hellodword/grss/internal/textutil"
+	"golang.org/x/text/unicode/norm"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// SimHash https://www.cs.princeton.edu/courses/archive/spring04/cos598B/bib/CharikarEstim.pdf
+// MinHash https://en.wikipedia.org/wiki/MinHash
+
+// MinHashSize the number of hash functions of the MinHash signatures, the similarities they estimate are multiples of 1/MinHashSize.
+const MinHashSize = 64
+
+// Fingerprint what identifies the story of an item across feeds, whatever its guid: its canonical URL, its normalized title, and the hashes of its text.
+type Fingerprint struct {
+	// URL the url of the item, or else its external_url, as CanonicalURL, empty when it has none.
+	URL string
+	// Title the normalized title of the item, see NormalizeTitle.
+	Title string
+	// SimHash the SimHash of the words of the title and the text of the content, close texts have hashes differing by a few bits.
+	SimHash uint64
+	// MinHash the MinHash signature of the shingles of three words of the title and the text of the content,
+	// nil when the item has no text.
+	MinHash []uint32
+}
+
+// ItemFingerprint the fingerprint of the item, the HTML of its content being reduced to its text.
+func ItemFingerprint(item *JSONItem) *Fingerprint {
+	fp := &Fingerprint{Title: NormalizeTitle(item.Title)}
+	for _, u := range []string{item.URL, item.ExternalURL} {
+		if u != "" {
+			fp.URL = CanonicalURL(u)
+			break
+		}
+	}
+
+	words := fingerprintWords(item.Title + " " + itemText(item))
+	if len(words) == 0 {
+		return fp
+	}
+	fp.SimHash = simHash(words)
+	fp.MinHash = minHash(shingles(words, 3))
+	return fp
+}
+
+// Distance the number of bits the SimHashes of fp and other differ by, from 0 for the same words to 64.
+func (fp *Fingerprint) Distance(other *Fingerprint) int {
+	return bits.OnesCount64(fp.SimHash ^ other.SimHash)
+}
+
+// Similarity the estimated Jaccard similarity of the shingles of the texts of fp and other, from 0 to 1, 0 when either has no text.
+func (fp *Fingerprint) Similarity(other *Fingerprint) float64 {
+	if len(fp.MinHash) == 0 || len(fp.MinHash) != len(other.MinHash) {
+		return 0
+	}
+	same := 0
+	for i := range fp.MinHash {
+		if fp.MinHash[i] == other.MinHash[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(fp.MinHash))
+}
+
+// itemText the plain text of the content of the item, content_html without its markup, or else content_text or the summary.
+func itemText(item *JSONItem) string {
+	if item.ContentHTML != "" {
+		if nodes, err := parseHTMLFragment(item.ContentHTML); err == nil {
+			var texts []string
+			for _, n := range nodes {
+				texts = append(texts, htmlText(n))
+			}
+			return strings.Join(texts, " ")
+		}
+	}
+	if item.ContentText != "" {
+		return item.ContentText
+	}
+	return item.Summary
+}
+
+// fingerprintWords the words of s, lowercased and without diacritics, each CJK character being a word as they have no spaces between their words.
+func fingerprintWords(s string) []string {
+	s = textutil.Fold(norm.NFKC.String(s))
+	var words []string
+	word := []rune{}
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+			words = append(words, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		case len(word) > 0:
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// titleSuffixSeparators the separators of the names of the sources many sites append to their titles, "Story - Reuters".
+var titleSuffixSeparators = []string{" | ", " - ", " – ", " — ", " :: "}
+
+// NormalizeTitle the title s as the same story gets it from any source: without the name of the source appended after a separator,
+// when the name has at most three words, lowercased, without diacritics nor punctuation, and with the white space collapsed.
+func NormalizeTitle(s string) string {
+	for _, sep := range titleSuffixSeparators {
+		if i := strings.LastIndex(s, sep); i > 0 {
+			if suffix := fingerprintWords(s[i+len(sep):]); len(suffix) <= 3 && len(fingerprintWords(s[:i])) > len(suffix) {
+				s = s[:i]
+				break
+			}
+		}
+	}
+	return strings.Join(fingerprintWords(s), " ")
+}
+
+// hashString the 64-bit FNV-1a hash of s.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// mix64 the splitmix64 finalizer, which spreads the bits of x.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// simHash the SimHash of the words, each bit set when most of the words whose hash has it set do.
+func simHash(words []string) uint64 {
+	var counts [64]int
+	for _, w := range words {
+		h := hashString(w)
+		for i := range counts {
+			if h&(1<<i) != 0 {
+				counts[i]++
+			} else {
+				counts[i]--
+			}
+		}
+	}
+	var h uint64
+	for i, c := range counts {
+		if c > 0 {
+			h |= 1 << i
+		}
+	}
+	return h
+}
+
+// shingles the runs of n words of words, the words together when there are fewer than n.
+func shingles(words []string, n int) []string {
+	if len(words) <= n {
+		return []string{strings.Join(words, " ")}
+	}
+	s := make([]string, 0, len(words)-n+1)
+	for i := 0; i+n <= len(words); i++ {
+		s = append(s, strings.Join(words[i:i+n], " "))
+	}
+	return s
+}
+
+// minHash the MinHash signature of the shingles, the smallest of their hashes by each of the MinHashSize hash functions.
+func minHash(shingles []string) []uint32 {
+	signature := make([]uint32, MinHashSize)
+	for i := range signature {
+		signature[i] = ^uint32(0)
+	}
+	for _, s := range shingles {
+		h := hashString(s)
+		for i := range signature {
+			if v := uint32(mix64(h^uint64(i)*0x9e3779b97f4a7c15) >> 32); v < signature[i] {
+				signature[i] = v
+			}
+		}
+	}
+	return signature
+}
@@ -0,0 +1,50 @@
+package grss
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_NormalizeTitle(t *testing.T) {
+	assert.Equal(t, "storm hits the coast", NormalizeTitle("Storm hits the coast - Reuters"))
+	assert.Equal(t, "storm hits the coast", NormalizeTitle("Storm Hits the Coast | AP News"))
+	assert.Equal(t, "storm hits the coast", NormalizeTitle("  Storm hits the coast!  "))
+	assert.Equal(t, "cafe creme", NormalizeTitle("Café Crème"))
+	// a long suffix is a part of the title
+	assert.Equal(t, "storm hits the coast thousands of homes without power", NormalizeTitle("Storm hits the coast - thousands of homes without power"))
+	assert.Equal(t, "東 京 の 天 気", NormalizeTitle("東京の天気"))
+}
+
+func Test_ItemFingerprint(t *testing.T) {
+	story := "Heavy rain and strong winds hit the northern coast on Monday, cutting power to thousands of homes, officials said. Schools were closed and trains cancelled as the storm moved inland."
+
+	a := ItemFingerprint(&JSONItem{
+		URL:         "https://www.wire.example/storm?utm_source=rss",
+		Title:       "Storm hits the coast - Wire",
+		ContentHTML: "<p>" + story + "</p><script>track()</script>",
+	})
+	b := ItemFingerprint(&JSONItem{
+		URL:         "https://paper.example/2023/storm",
+		Title:       "Storm hits the coast",
+		ContentText: story + " More to follow.",
+	})
+	c := ItemFingerprint(&JSONItem{
+		URL:         "https://paper.example/2023/election",
+		Title:       "Election results are in",
+		ContentText: "The votes were counted overnight and the results announced this morning by the electoral commission, with a turnout higher than expected.",
+	})
+
+	assert.Equal(t, "https://wire.example/storm", a.URL)
+	assert.Equal(t, "storm hits the coast", a.Title)
+	assert.Equal(t, MinHashSize, len(a.MinHash))
+	assert.Equal(t, 1.0, a.Similarity(a))
+	assert.Equal(t, 0, a.Distance(a))
+
+	assert.Greater(t, a.Similarity(b), 0.7)
+	assert.Less(t, a.Similarity(c), 0.2)
+	assert.Less(t, a.Distance(b), a.Distance(c))
+
+	empty := ItemFingerprint(&JSONItem{})
+	assert.Nil(t, empty.MinHash)
+	assert.Equal(t, 0.0, empty.Similarity(a))
+}
@@ -3,16 +3,18 @@ module github.com/hellodword/grss
 go 1.18
 
 require (
+	github.com/andybalholm/cascadia v1.3.2
 	github.com/dimchansky/utfbom v1.1.1
 	github.com/nbio/xml v0.0.0-20220411153321-a78369b53f35
 	github.com/stretchr/testify v1.8.1
-	golang.org/x/text v0.4.0
+	golang.org/x/net v0.26.0
+	golang.org/x/text v0.16.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace github.com/nbio/xml v0.0.0-20220411153321-a78369b53f35 => github.com/hellodword/xml v0.0.0-20221113140703-bceee1329480
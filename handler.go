@@ -0,0 +1,331 @@
+package grss
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP content negotiation https://www.rfc-editor.org/rfc/rfc9110#section-12.5.1
+// conditional requests https://www.rfc-editor.org/rfc/rfc9110#section-13
+
+// Format an output format a Handler negotiates, the values of the format query parameter.
+type Format string
+
+const (
+	FormatAtom  Format = "atom"
+	FormatRss   Format = "rss"
+	FormatJSON  Format = "json"
+	FormatRss10 Format = "rss10"
+)
+
+// formatOf the format f is in, the one served when the client has no preference.
+func formatOf(f Feed) Format {
+	switch f.(type) {
+	case *RssFeed:
+		return FormatRss
+	case *JSONFeed:
+		return FormatJSON
+	default:
+		return FormatAtom
+	}
+}
+
+// handlerFormat a format and the media types it can be served as.
+type handlerFormat struct {
+	format   Format
+	mime     string
+	fallback string
+	xml      bool
+}
+
+var handlerFormats = []handlerFormat{
+	{FormatAtom, AtomMime, AtomMimeFallback, true},
+	{FormatRss, RssMime, RssMimeFallback, true},
+	{FormatJSON, JSONMime, JSONMimeFallback, false},
+	{FormatRss10, RdfMime, RdfMimeFallback, true},
+}
+
+// mediaRange a media range of the Accept header with its weight.
+type mediaRange struct {
+	mime string
+	q    float64
+}
+
+func parseAccept(s string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(s, ",") {
+		params := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(params[0]))
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(k), "q") {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{mime: mime, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality the weight the ranges give mime, from the most specific range that matches, and how specific it is: 3 for the media type, 2 for type/*, 1 for */* and 0 for none.
+func acceptQuality(ranges []mediaRange, mime string) (float64, int) {
+	q, specificity := 0.0, 0
+	typ, _, _ := strings.Cut(mime, "/")
+	for _, r := range ranges {
+		s := 0
+		switch {
+		case r.mime == mime:
+			s = 3
+		case r.mime == typ+"/*":
+			s = 2
+		case r.mime == "*/*":
+			s = 1
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q, specificity
+}
+
+// negotiate picks the format and media type to serve, ok is false when the client accepts none.
+// Without an Accept header the feed is served in its own format. Browsers that only accept the feed media types through */* are sent the fallback type,
+// as they tend to download application/atom+xml or application/rss+xml instead of displaying it.
+func negotiate(accept string, own Format, only Format) (handlerFormat, string, bool) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		ranges = []mediaRange{{mime: "*/*", q: 1}}
+	}
+	browser := false
+	for _, r := range ranges {
+		if r.mime == "text/html" && r.q > 0 {
+			browser = true
+		}
+	}
+
+	var best handlerFormat
+	bestMime, bestQ, bestSpecificity, found := "", 0.0, 0, false
+	for _, hf := range handlerFormats {
+		if only != "" && hf.format != only {
+			continue
+		}
+		offers := []string{hf.mime, hf.fallback}
+		if hf.xml {
+			offers = append(offers, "text/xml")
+		}
+		for i, mime := range offers {
+			q, specificity := acceptQuality(ranges, mime)
+			if q <= 0 {
+				continue
+			}
+			if i == 0 && specificity < 3 && browser {
+				mime = hf.fallback
+			}
+			better := !found || q > bestQ || q == bestQ && specificity > bestSpecificity ||
+				q == bestQ && specificity == bestSpecificity && hf.format == own && best.format != own
+			if better {
+				best, bestMime, bestQ, bestSpecificity, found = hf, mime, q, specificity, true
+			}
+		}
+	}
+
+	// an explicit format wins over the Accept header
+	if !found && only != "" {
+		for _, hf := range handlerFormats {
+			if hf.format == only {
+				return hf, hf.fallback, true
+			}
+		}
+	}
+	return best, bestMime, found
+}
+
+// requestURL the absolute URL of the request, behind a proxy X-Forwarded-Proto tells the scheme.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// withSelf converts f to the format and adds a self link pointing at self when the feed has none.
+func withSelf(f Feed, format Format, self string) interface {
+	WriteOutWith(w io.Writer, opts WriteOptions) error
+} {
+	switch format {
+	case FormatRss:
+		ff := f.ToRss()
+		if ff.Channel == nil {
+			return ff
+		}
+		for _, link := range ff.Channel.AtomLinks() {
+			if link.Rel == "self" {
+				return ff
+			}
+		}
+		extensions := ff.Channel.ExtensionElement
+		ff.Channel.ExtensionElement = append(extensions[:len(extensions):len(extensions)], atomLinkElement(&AtomLink{
+			Href: AtomUri(self),
+			Rel:  "self",
+			Type: RssMime,
+		}))
+		return ff
+	case FormatJSON:
+		ff := f.ToJSON()
+		if ff.FeedURL == "" {
+			ff.FeedURL = self
+		}
+		return ff
+	case FormatRss10:
+		return f.ToRss10()
+	default:
+		ff := f.ToAtom()
+		for _, link := range ff.Links {
+			if link.Rel == "self" {
+				return ff
+			}
+		}
+		ff.Links = append(ff.Links[:len(ff.Links):len(ff.Links)], &AtomLink{
+			Href: AtomUri(self),
+			Rel:  "self",
+			Type: AtomMime,
+		})
+		return ff
+	}
+}
+
+// lastModified the latest date of the items of f, the zero time when none is dated.
+func lastModified(f Feed) time.Time {
+	var last time.Time
+	for _, item := range f.ToJSON().Items {
+		for _, s := range []string{item.DatePublished, item.DateModified} {
+			if s == "" {
+				continue
+			}
+			if t, err := ParseDate(s); err == nil && t.After(last) {
+				last = t
+			}
+		}
+	}
+	return last
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(k), "q") {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		return q > 0
+	}
+	return false
+}
+
+// Handler serves the feed returned by source in the format the client prefers, with the options of WriteOut.
+func Handler(source func(*http.Request) (Feed, error)) http.Handler {
+	return HandlerWith(source, WriteOptions{Indent: defaultIndent})
+}
+
+// HandlerWith serves the feed returned by source in the format the client prefers, written with opts.
+//
+// The format is the one named by the format query parameter (atom, rss, json or rss10), otherwise it is negotiated from the Accept header,
+// a client without preference gets the format of the feed. Atom and RSS get a self link to the request URL and JSON Feed a feed_url when they have none.
+// Last-Modified is the latest item date and the ETag is derived from the document, conditional requests are answered with 304 Not Modified.
+// The document is gzipped for clients that accept it.
+// A source that returns a nil Feed and no error answers 404 Not Found, an error answers 500 Internal Server Error.
+func HandlerWith(source func(*http.Request) (Feed, error), opts WriteOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		only := Format(strings.ToLower(r.URL.Query().Get("format")))
+		if only != "" {
+			known := false
+			for _, hf := range handlerFormats {
+				known = known || hf.format == only
+			}
+			if !known {
+				http.Error(w, "unknown format "+strconv.Quote(string(only)), http.StatusBadRequest)
+				return
+			}
+		}
+
+		f, err := source(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if f == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		hf, mime, ok := negotiate(r.Header.Get("Accept"), formatOf(f), only)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+			return
+		}
+
+		var body bytes.Buffer
+		err = withSelf(f, hf.format, requestURL(r)).WriteOutWith(&body, opts)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		contentType := mime
+		if hf.xml {
+			charset, _, err := opts.charsetEncoding()
+			if err == nil {
+				contentType += "; charset=" + charset
+			}
+		}
+		sum := sha256.Sum256(append([]byte(contentType+"\n"), body.Bytes()...))
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `W/"`+hex.EncodeToString(sum[:12])+`"`)
+
+		b := body.Bytes()
+		if acceptsGzip(r) {
+			var gz bytes.Buffer
+			zw := gzip.NewWriter(&gz)
+			_, _ = zw.Write(b)
+			_ = zw.Close()
+			b = gz.Bytes()
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+
+		// ServeContent answers If-None-Match and If-Modified-Since, HEAD and ranges
+		http.ServeContent(w, r, "", lastModified(f), bytes.NewReader(b))
+	})
+}
@@ -0,0 +1,196 @@
+package grss
+
+import (
+	"compress/gzip"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const handlerTestFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example Feed</title>
+  <link href="http://example.org/"/>
+  <updated>2003-12-13T18:30:02Z</updated>
+  <id>urn:uuid:60a76c80-d399-11d9-b93C-0003939e0af6</id>
+  <entry>
+    <title>Atom-Powered Robots Run Amok</title>
+    <link href="http://example.org/2003/12/13/atom03"/>
+    <id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+    <updated>2003-12-13T18:30:02Z</updated>
+    <summary>Some text.</summary>
+  </entry>
+  <entry>
+    <title>Older</title>
+    <id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6b</id>
+    <updated>2003-12-12T18:30:02Z</updated>
+  </entry>
+</feed>`
+
+func handlerTestServer(t *testing.T) *httptest.Server {
+	_, f, err := Parse(strings.NewReader(handlerTestFeed))
+	assert.Nil(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("/feed", Handler(func(r *http.Request) (Feed, error) {
+		return f, nil
+	}))
+	mux.Handle("/missing", Handler(func(r *http.Request) (Feed, error) {
+		return nil, nil
+	}))
+	mux.Handle("/broken", Handler(func(r *http.Request) (Feed, error) {
+		return nil, errors.New("broken")
+	}))
+	return httptest.NewServer(mux)
+}
+
+func handlerGet(t *testing.T, url string, header map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.Nil(t, err)
+	// gzip is handled by the tests, not by the transport
+	req.Header.Set("Accept-Encoding", "identity")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	var r io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		r, err = gzip.NewReader(resp.Body)
+		assert.Nil(t, err)
+	}
+	b, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	return resp, string(b)
+}
+
+func Test_Handler_Negotiate(t *testing.T) {
+	s := handlerTestServer(t)
+	defer s.Close()
+
+	// no preference, the feed's own format
+	resp, body := handlerGet(t, s.URL+"/feed", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/atom+xml; charset=UTF-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "Sat, 13 Dec 2003 18:30:02 GMT", resp.Header.Get("Last-Modified"))
+	assert.Equal(t, []string{"Accept", "Accept-Encoding"}, resp.Header.Values("Vary"))
+	assert.Contains(t, body, `<link href="`+s.URL+`/feed" rel="self" type="application/atom+xml"></link>`)
+
+	resp, body = handlerGet(t, s.URL+"/feed", map[string]string{"Accept": "application/feed+json, application/atom+xml;q=0.5"})
+	assert.Equal(t, "application/feed+json", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, `"feed_url": "`+s.URL+`/feed"`)
+
+	resp, body = handlerGet(t, s.URL+"/feed", map[string]string{"Accept": "application/rss+xml"})
+	assert.Equal(t, "application/rss+xml; charset=UTF-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, `<atom:link href="`+s.URL+`/feed" rel="self" type="application/rss+xml"></atom:link>`)
+
+	resp, _ = handlerGet(t, s.URL+"/feed", map[string]string{"Accept": "application/json"})
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	resp, _ = handlerGet(t, s.URL+"/feed", map[string]string{"Accept": "text/xml"})
+	assert.Equal(t, "text/xml; charset=UTF-8", resp.Header.Get("Content-Type"))
+
+	resp, _ = handlerGet(t, s.URL+"/feed", map[string]string{"Accept": "image/png"})
+	assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+
+	// browsers get the fallback type
+	browser := map[string]string{"Accept": "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"}
+	resp, _ = handlerGet(t, s.URL+"/feed", browser)
+	assert.Equal(t, "application/xml; charset=UTF-8", resp.Header.Get("Content-Type"))
+
+	// the format parameter wins
+	resp, body = handlerGet(t, s.URL+"/feed?format=rss", browser)
+	assert.Equal(t, "application/xml; charset=UTF-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, "<rss")
+	assert.Contains(t, body, `href="`+s.URL+`/feed?format=rss"`)
+
+	resp, body = handlerGet(t, s.URL+"/feed?format=json", map[string]string{"Accept": "application/rss+xml"})
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, `"version": "https://jsonfeed.org/version/1.1"`)
+
+	resp, body = handlerGet(t, s.URL+"/feed?format=rss10", nil)
+	assert.Equal(t, "application/rdf+xml; charset=UTF-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, "<rdf:RDF")
+
+	resp, _ = handlerGet(t, s.URL+"/feed?format=html", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = handlerGet(t, s.URL+"/missing", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = handlerGet(t, s.URL+"/broken", nil)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func Test_Handler_Conditional(t *testing.T) {
+	s := handlerTestServer(t)
+	defer s.Close()
+
+	resp, body := handlerGet(t, s.URL+"/feed", map[string]string{"Accept-Encoding": "gzip"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Contains(t, body, "Atom-Powered Robots Run Amok")
+	etag := resp.Header.Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `W/"`), etag)
+
+	resp, _ = handlerGet(t, s.URL+"/feed", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Equal(t, "", resp.Header.Get("Content-Encoding"))
+
+	// another format is another document
+	resp, _ = handlerGet(t, s.URL+"/feed?format=json", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+
+	resp, _ = handlerGet(t, s.URL+"/feed", map[string]string{"If-Modified-Since": "Sat, 13 Dec 2003 18:30:02 GMT"})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	resp, _ = handlerGet(t, s.URL+"/feed", map[string]string{"If-Modified-Since": "Sat, 13 Dec 2003 18:30:01 GMT"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = handlerGet(t, s.URL+"/feed", map[string]string{"Accept-Encoding": "gzip;q=0"})
+	assert.Equal(t, "", resp.Header.Get("Content-Encoding"))
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/feed", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func Test_Handler_Concurrent(t *testing.T) {
+	_, atom, err := Parse(strings.NewReader(handlerTestFeed))
+	assert.Nil(t, err)
+	// a parsed JSON Feed whose items are not uniform yet, the dates and ids the conversions fill must not be written to the feed
+	_, jsonFeed, err := Parse(strings.NewReader(`{"version":"https://jsonfeed.org/version/1.1","title":"JSON","items":[{"url":"https://example.org/1","date_published":"Sat, 13 Dec 2003 18:30:02 GMT","content_text":"One"}]}`))
+	assert.Nil(t, err)
+	jsonItem := *jsonFeed.(*JSONFeed).Items[0]
+
+	for _, f := range []Feed{atom, atom.ToRss(), jsonFeed} {
+		f := f
+		s := httptest.NewServer(Handler(func(r *http.Request) (Feed, error) {
+			return f, nil
+		}))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			for _, format := range []Format{FormatAtom, FormatRss, FormatJSON, FormatRss10} {
+				wg.Add(1)
+				go func(format Format) {
+					defer wg.Done()
+					resp, _ := handlerGet(t, s.URL+"/?format="+string(format), nil)
+					assert.Equal(t, http.StatusOK, resp.StatusCode)
+				}(format)
+			}
+		}
+		wg.Wait()
+		s.Close()
+	}
+
+	assert.Equal(t, jsonItem, *jsonFeed.(*JSONFeed).Items[0])
+}
@@ -0,0 +1,180 @@
+package grss
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"strings"
+)
+
+// Security considerations of RSS and Atom: feeds carry HTML from third parties, which has to be sanitized before it is displayed.
+// https://datatracker.ietf.org/doc/html/rfc4287#section-8.1
+// https://www.rssboard.org/rss-profile#data-types-characterdata
+
+// sanitizeElements the elements kept by SanitizeHTML, with the attributes they keep.
+var sanitizeElements = map[atom.Atom][]string{
+	atom.A: {"href"}, atom.Abbr: nil, atom.B: nil, atom.Blockquote: {"cite"}, atom.Br: nil, atom.Cite: nil, atom.Code: nil,
+	atom.Dd: nil, atom.Del: {"cite", "datetime"}, atom.Div: nil, atom.Dl: nil, atom.Dt: nil, atom.Em: nil,
+	atom.Figcaption: nil, atom.Figure: nil, atom.H1: nil, atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.Hr: nil, atom.I: nil, atom.Img: {"src", "alt", "width", "height"}, atom.Ins: {"cite", "datetime"}, atom.Kbd: nil,
+	atom.Li: nil, atom.Mark: nil, atom.Ol: {"start"}, atom.P: nil, atom.Pre: nil, atom.Q: {"cite"}, atom.S: nil,
+	atom.Small: nil, atom.Span: nil, atom.Strong: nil, atom.Sub: nil, atom.Sup: nil,
+	atom.Table: nil, atom.Tbody: nil, atom.Td: {"colspan", "rowspan"}, atom.Tfoot: nil, atom.Th: {"colspan", "rowspan", "scope"},
+	atom.Thead: nil, atom.Tr: nil, atom.U: nil, atom.Ul: nil, atom.Time: {"datetime"},
+	atom.Audio: {"src", "controls"}, atom.Video: {"src", "controls", "poster", "width", "height"}, atom.Source: {"src", "type"},
+}
+
+// sanitizeDropped the elements SanitizeHTML removes with their content, the others it does not know are replaced by their content.
+var sanitizeDropped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Frame: true, atom.Frameset: true, atom.Object: true, atom.Embed: true,
+	atom.Applet: true, atom.Form: true, atom.Input: true, atom.Button: true, atom.Select: true, atom.Textarea: true,
+	atom.Noscript: true, atom.Template: true, atom.Svg: true, atom.Math: true, atom.Head: true, atom.Title: true,
+	atom.Meta: true, atom.Link: true, atom.Base: true,
+}
+
+// urlAttributes the attributes holding a URL.
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "poster": true, "cite": true,
+}
+
+// safeURL reports whether a URL is relative or uses a scheme that runs nothing, http, https or mailto.
+func safeURL(s string) bool {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseHTMLFragment parses s as the content of a <div>, the way feed readers display it.
+func parseHTMLFragment(s string) ([]*html.Node, error) {
+	return html.ParseFragment(strings.NewReader(s), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+	})
+}
+
+func renderHTMLFragment(nodes []*html.Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		_ = html.Render(&b, n)
+	}
+	return b.String()
+}
+
+// SanitizeHTML returns s with the elements and attributes that could run scripts, load frames or style the page removed.
+// Known formatting elements are kept with a few attributes, URLs must be relative or http, https or mailto.
+// Unknown elements are replaced by their content, scripts, styles, frames, objects and forms are removed with it.
+func SanitizeHTML(s string) string {
+	if !strings.Contains(s, "<") && !strings.Contains(s, "&") {
+		return s
+	}
+	nodes, err := parseHTMLFragment(s)
+	if err != nil {
+		return html.EscapeString(s)
+	}
+
+	var clean func(n *html.Node) []*html.Node
+	clean = func(n *html.Node) []*html.Node {
+		switch n.Type {
+		case html.TextNode:
+			return []*html.Node{{Type: html.TextNode, Data: n.Data}}
+		case html.ElementNode:
+		default:
+			// comments, doctypes
+			return nil
+		}
+
+		if sanitizeDropped[n.DataAtom] {
+			return nil
+		}
+
+		var children []*html.Node
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			children = append(children, clean(c)...)
+		}
+
+		allowed, ok := sanitizeElements[n.DataAtom]
+		if !ok || n.Namespace != "" {
+			return children
+		}
+
+		e := &html.Node{Type: html.ElementNode, Data: n.Data, DataAtom: n.DataAtom}
+		for _, attr := range n.Attr {
+			if attr.Namespace != "" {
+				continue
+			}
+			keep := false
+			for _, name := range allowed {
+				keep = keep || attr.Key == name
+			}
+			if keep && urlAttributes[attr.Key] && !safeURL(attr.Val) {
+				keep = false
+			}
+			if keep {
+				e.Attr = append(e.Attr, html.Attribute{Key: attr.Key, Val: attr.Val})
+			}
+		}
+		for _, c := range children {
+			e.AppendChild(c)
+		}
+		return []*html.Node{e}
+	}
+
+	var out []*html.Node
+	for _, n := range nodes {
+		out = append(out, clean(n)...)
+	}
+	return renderHTMLFragment(out)
+}
+
+// ResolveHTML returns s with the relative URLs of its href, src, poster and cite attributes resolved against base,
+// so the HTML still works once it is read out of the feed, as xml:base does in Atom.
+func ResolveHTML(s, base string) string {
+	if base == "" || !strings.Contains(s, "<") {
+		return s
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return s
+	}
+	nodes, err := parseHTMLFragment(s)
+	if err != nil {
+		return s
+	}
+
+	changed := false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for i, attr := range n.Attr {
+				if !urlAttributes[attr.Key] || attr.Namespace != "" {
+					continue
+				}
+				r, err := url.Parse(strings.TrimSpace(attr.Val))
+				if err != nil || r.IsAbs() {
+					continue
+				}
+				n.Attr[i].Val = b.ResolveReference(r).String()
+				changed = true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+
+	if !changed {
+		return s
+	}
+	return renderHTMLFragment(nodes)
+}
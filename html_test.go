@@ -0,0 +1,35 @@
+package grss
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_SanitizeHTML(t *testing.T) {
+	for _, c := range [][2]string{
+		{`plain text`, `plain text`},
+		{`<p>Hello <b>world</b></p>`, `<p>Hello <b>world</b></p>`},
+		{`<p onclick="alert(1)" style="color:red">x</p>`, `<p>x</p>`},
+		{`<script>alert(1)</script><p>x</p>`, `<p>x</p>`},
+		{`<style>p{}</style><iframe src="https://example.com/"></iframe>y`, `y`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="/post" target="_blank">x</a>`, `<a href="/post">x</a>`},
+		{`<img src="data:image/png;base64,AAAA" alt="a"><img src="https://example.com/a.png" alt="b">`, `<img alt="a"/><img src="https://example.com/a.png" alt="b"/>`},
+		{`<font color="red"><blink>x</blink></font>`, `x`},
+		{`<!-- comment --><svg><script>alert(1)</script></svg>z`, `z`},
+		{`1 &lt; 2 &amp;&amp; <i>3</i>`, `1 &lt; 2 &amp;&amp; <i>3</i>`},
+		{`<p>unclosed`, `<p>unclosed</p>`},
+	} {
+		assert.Equal(t, c[1], SanitizeHTML(c[0]), c[0])
+	}
+}
+
+func Test_ResolveHTML(t *testing.T) {
+	assert.Equal(t,
+		`<p><a href="https://example.com/blog/post">x</a> <img src="https://example.com/i.png"/> <a href="https://other.org/">y</a></p>`,
+		ResolveHTML(`<p><a href="post">x</a> <img src="/i.png"> <a href="https://other.org/">y</a></p>`, "https://example.com/blog/"))
+
+	// nothing to resolve, the HTML is left as it was written
+	assert.Equal(t, `<p>x<br></p>`, ResolveHTML(`<p>x<br></p>`, "https://example.com/"))
+	assert.Equal(t, `<a href="x">`, ResolveHTML(`<a href="x">`, ""))
+}
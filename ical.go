@@ -0,0 +1,303 @@
+package grss
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// iCalendar https://datatracker.ietf.org/doc/html/rfc5545
+// New Properties for iCalendar https://datatracker.ietf.org/doc/html/rfc7986
+
+const (
+	// ICalendarMime https://datatracker.ietf.org/doc/html/rfc5545#section-8.1
+	ICalendarMime = "text/calendar"
+	// ICalendarProdID the PRODID of the calendars ToICalendar makes.
+	ICalendarProdID = "-//hellodword//grss//EN"
+)
+
+// ICalendar a VCALENDAR, the iCalendar object of the events of a feed.
+type ICalendar struct {
+	// ProdID PRODID, the identifier for the product that created the iCalendar object.
+	ProdID string
+	// Name NAME, and X-WR-CALNAME for the clients that predate RFC 7986, the name of the calendar.
+	Name string
+	// Description DESCRIPTION, and X-WR-CALDESC, the description of the calendar.
+	Description string
+	// URL URL, the location from which the calendar can be refreshed.
+	URL string
+
+	Events []*ICalEvent
+}
+
+// ICalEvent a VEVENT of a calendar.
+type ICalEvent struct {
+	// UID the persistent, globally unique identifier of the event.
+	UID string
+	// Stamp DTSTAMP, when the information of the event was last revised.
+	Stamp time.Time
+	// Start DTSTART, when the event begins.
+	Start time.Time
+	// End DTEND, when the event ends, exclusive, zero when it has none.
+	End time.Time
+	// AllDay the event lasts whole days, Start and End are dates.
+	AllDay bool
+	// Floating Start and End are floating local times, in UTC but written without time zone, the event happening at that time wherever it is seen.
+	Floating bool
+
+	// Summary SUMMARY, a short summary or subject of the event.
+	Summary string
+	// Description DESCRIPTION, a more complete description of the event.
+	Description string
+	// Location LOCATION, the intended venue of the event.
+	Location string
+	// Organizer ORGANIZER when it is an email address, or else CONTACT.
+	Organizer string
+	// URL URL, the location of a more dynamic rendition of the event.
+	URL string
+	// Categories CATEGORIES, the categories of the event.
+	Categories []string
+}
+
+// ToICalendar maps the items of f to the events of a calendar: the event of their ev: or xCal elements,
+// or else an event at the time they were published, and their guid or id as UID.
+// The items without event data or date are left out.
+func ToICalendar(f Feed) *ICalendar {
+	ff := f.ToJSON()
+
+	c := &ICalendar{
+		ProdID:      ICalendarProdID,
+		Name:        ff.Title,
+		Description: ff.Description,
+		URL:         ff.FeedURL,
+	}
+	for _, jitem := range ff.Items {
+		if e := newICalEvent(jitem); e != nil {
+			c.Events = append(c.Events, e)
+		}
+	}
+	return c
+}
+
+func newICalEvent(jitem *JSONItem) *ICalEvent {
+	event := jitem.Event()
+	if event == nil {
+		event = &JSONEvent{}
+	}
+
+	start, allDay, floating, ok := parseEventDate(event.StartDate)
+	if !ok {
+		// an item without event data is an event at the time it was published
+		for _, d := range []string{jitem.DatePublished, jitem.DateModified} {
+			if t, err := ParseDate(d); d != "" && err == nil {
+				start, ok = t, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil
+	}
+
+	e := &ICalEvent{
+		UID:        jitem.ID,
+		Start:      start,
+		AllDay:     allDay,
+		Floating:   floating,
+		Summary:    jitem.Title,
+		Location:   event.Location,
+		Organizer:  event.Organizer,
+		URL:        jitem.URL,
+		Categories: jitem.Tags,
+	}
+
+	if end, endAllDay, endFloating, ok := parseEventDate(event.EndDate); ok {
+		if endAllDay {
+			// an end date without time is the last day of the event, which ends the day after it
+			end = end.AddDate(0, 0, 1)
+		} else if allDay {
+			e.AllDay = false
+			e.Floating = endFloating
+		}
+		// a floating time and an instant cannot be compared, nor written as the same kind of time
+		if (endAllDay || e.Floating == endFloating) && end.After(e.Start) {
+			e.End = end
+		}
+	}
+
+	if event.Type != "" {
+		e.Categories = append(e.Categories[:len(e.Categories):len(e.Categories)], event.Type)
+	}
+
+	e.Stamp = e.Start
+	for _, d := range []string{jitem.DateModified, jitem.DatePublished} {
+		if t, err := ParseDate(d); d != "" && err == nil {
+			e.Stamp = t
+			break
+		}
+	}
+
+	e.Description = jitem.Summary
+	if e.Description == "" {
+		e.Description = jitem.ContentText
+	}
+	if e.Description == "" && jitem.ContentHTML != "" {
+		nodes, _ := parseHTMLFragment(jitem.ContentHTML)
+		root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+		for _, n := range nodes {
+			root.AppendChild(n)
+		}
+		e.Description = htmlText(root)
+	}
+
+	if e.UID == "" {
+		e.UID = e.URL
+	}
+	if e.UID == "" {
+		sum := sha1.Sum([]byte(e.Summary + "\n" + e.Start.Format(time.RFC3339)))
+		e.UID = hex.EncodeToString(sum[:])
+	}
+
+	return e
+}
+
+func (c *ICalendar) Mime(fallback bool) string {
+	return ICalendarMime
+}
+
+func (c *ICalendar) WriteOut(w io.Writer) error {
+	return c.WriteOutWith(w, WriteOptions{})
+}
+
+// WriteOutWith writes c as an iCalendar stream, which is always UTF-8 with CRLF line endings, the options of the XML and JSON writers do not apply to it.
+func (c *ICalendar) WriteOutWith(w io.Writer, opts WriteOptions) error {
+	b := &icalBuilder{}
+	b.line("BEGIN", "VCALENDAR")
+	b.line("VERSION", "2.0")
+	prodID := c.ProdID
+	if prodID == "" {
+		prodID = ICalendarProdID
+	}
+	b.line("PRODID", prodID)
+	b.line("CALSCALE", "GREGORIAN")
+	b.text("NAME", c.Name)
+	b.text("X-WR-CALNAME", c.Name)
+	b.text("DESCRIPTION", c.Description)
+	b.text("X-WR-CALDESC", c.Description)
+	b.uri("URL", c.URL)
+
+	for _, e := range c.Events {
+		b.line("BEGIN", "VEVENT")
+		b.text("UID", e.UID)
+		b.line("DTSTAMP", icalDateTime(e.Stamp))
+		if e.AllDay {
+			b.line("DTSTART;VALUE=DATE", e.Start.Format("20060102"))
+			if !e.End.IsZero() {
+				b.line("DTEND;VALUE=DATE", e.End.Format("20060102"))
+			}
+		} else {
+			b.line("DTSTART", icalEventTime(e.Start, e.Floating))
+			if !e.End.IsZero() {
+				b.line("DTEND", icalEventTime(e.End, e.Floating))
+			}
+		}
+		b.text("SUMMARY", e.Summary)
+		b.text("DESCRIPTION", e.Description)
+		b.text("LOCATION", e.Location)
+		if e.Organizer != "" {
+			// ORGANIZER is a calendar user address, an organizer known by name only is a CONTACT
+			if addr, err := mail.ParseAddress(e.Organizer); err == nil {
+				name := ""
+				if addr.Name != "" {
+					name = ";CN=" + icalParam(addr.Name)
+				}
+				b.line("ORGANIZER"+name, "mailto:"+addr.Address)
+			} else {
+				b.text("CONTACT", e.Organizer)
+			}
+		}
+		b.uri("URL", e.URL)
+		if len(e.Categories) > 0 {
+			categories := make([]string, len(e.Categories))
+			for i, category := range e.Categories {
+				categories[i] = icalEscaper.Replace(category)
+			}
+			b.line("CATEGORIES", strings.Join(categories, ","))
+		}
+		b.line("END", "VEVENT")
+	}
+
+	b.line("END", "VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func icalDateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icalEventTime the time of an event, in UTC, or without time zone when it is floating.
+func icalEventTime(t time.Time, floating bool) string {
+	if floating {
+		return t.UTC().Format("20060102T150405")
+	}
+	return icalDateTime(t)
+}
+
+// icalEscaper escapes the TEXT values https://datatracker.ietf.org/doc/html/rfc5545#section-3.3.11
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// icalParam a parameter value, quoted when it has the characters that end one, which cannot have double quotes.
+func icalParam(s string) string {
+	s = strings.Join(strings.Fields(strings.ReplaceAll(s, `"`, "")), " ")
+	if strings.ContainsAny(s, ":;,") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// icalBuilder writes the content lines of an iCalendar stream.
+type icalBuilder struct {
+	strings.Builder
+}
+
+// text writes a property of TEXT value, none when it is empty.
+func (b *icalBuilder) text(name, value string) {
+	b.line(name, icalEscaper.Replace(strings.TrimSpace(value)))
+}
+
+// uri writes a property of URI value, none when it is empty or has a line break, which no URI has.
+func (b *icalBuilder) uri(name, value string) {
+	value = strings.TrimSpace(value)
+	if strings.ContainsAny(value, "\r\n") {
+		return
+	}
+	b.line(name, value)
+}
+
+// line writes a content line, none when the value is empty, folded as lines SHOULD NOT be longer than 75 octets, excluding the line break.
+func (b *icalBuilder) line(name, value string) {
+	if value == "" {
+		return
+	}
+	s := name + ":" + value
+	max := 75
+	for len(s) > max {
+		// a multi-octet UTF-8 character is not split
+		i := max
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		b.WriteString(s[:i] + "\r\n ")
+		s = s[i:]
+		// the continuation lines begin with the white space
+		max = 74
+	}
+	b.WriteString(s + "\r\n")
+}
@@ -0,0 +1,144 @@
+package grss
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ToICalendar(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(eventTestRss))
+	assert.Nil(t, err)
+
+	c := ToICalendar(f)
+	assert.Equal(t, "Meetups", c.Name)
+	assert.Equal(t, 3, len(c.Events))
+
+	e := c.Events[0]
+	assert.Equal(t, "event-1", e.UID)
+	assert.Equal(t, time.Date(2023, 3, 1, 17, 0, 0, 0, time.UTC), e.Start.UTC())
+	assert.Equal(t, time.Date(2023, 3, 1, 20, 0, 0, 0, time.UTC), e.End.UTC())
+	assert.False(t, e.AllDay)
+	assert.Equal(t, []string{"meetup"}, e.Categories)
+
+	// an end date without time is the end of that day
+	e = c.Events[1]
+	assert.Equal(t, "https://events.example/release", e.UID)
+	assert.Equal(t, time.Date(2023, 5, 10, 17, 0, 0, 0, time.UTC), e.Start.UTC())
+	assert.Equal(t, time.Date(2023, 5, 11, 0, 0, 0, 0, time.UTC), e.End.UTC())
+
+	// an item without event data is an event at its publication date
+	e = c.Events[2]
+	assert.Equal(t, time.Date(2023, 1, 3, 10, 0, 0, 0, time.UTC), e.Start.UTC())
+	assert.True(t, e.End.IsZero())
+
+	var b bytes.Buffer
+	assert.Nil(t, c.WriteOut(&b))
+	s := b.String()
+	assert.True(t, strings.HasPrefix(s, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//hellodword//grss//EN\r\n"), s)
+	assert.True(t, strings.HasSuffix(s, "END:VEVENT\r\nEND:VCALENDAR\r\n"), s)
+	assert.Equal(t, 3, strings.Count(s, "BEGIN:VEVENT\r\n"))
+	assert.Contains(t, s, "BEGIN:VEVENT\r\nUID:event-1\r\nDTSTAMP:20230102T080000Z\r\nDTSTART:20230301T170000Z\r\nDTEND:20230301T200000Z\r\nSUMMARY:Go meetup\r\n")
+	assert.Contains(t, s, "\r\nLOCATION:Berlin\\, Factory\r\n")
+	assert.Contains(t, s, "\r\nORGANIZER;CN=Gophers:mailto:org@events.example\r\n")
+	assert.Contains(t, s, "\r\nCATEGORIES:meetup\r\n")
+	assert.Equal(t, "text/calendar", c.Mime(false))
+}
+
+func Test_ToICalendar_Floating(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(`<?xml version="1.0"?>
+<rss version="2.0" xmlns:ev="http://purl.org/rss/1.0/modules/event/" xmlns:xCal="urn:ietf:params:xml:ns:xcal">
+  <channel>
+    <title>Local events</title>
+    <link>https://events.example/</link>
+    <description>Local events</description>
+    <item>
+      <title>Market</title>
+      <guid isPermaLink="false">market</guid>
+      <xCal:dtstart>20230510T090000</xCal:dtstart>
+      <xCal:dtend>20230510T130000</xCal:dtend>
+    </item>
+    <item>
+      <title>Mixed</title>
+      <guid isPermaLink="false">mixed</guid>
+      <ev:startdate>2023-05-11T09:00</ev:startdate>
+      <ev:enddate>2023-05-11T13:00:00Z</ev:enddate>
+    </item>
+  </channel>
+</rss>`))
+	assert.Nil(t, err)
+
+	// a floating time stays a local time, without offset nor Z
+	rss := f.(*RssFeed)
+	assert.Equal(t, "2023-05-10T09:00:00", rss.Channel.Items[0].EventStartDate)
+	assert.Equal(t, "2023-05-11T09:00:00", f.ToJSON().Items[1].Event().StartDate)
+
+	c := ToICalendar(f)
+	assert.True(t, c.Events[0].Floating)
+	assert.Equal(t, time.Date(2023, 5, 10, 13, 0, 0, 0, time.UTC), c.Events[0].End)
+	// a floating start and an instant end cannot be compared
+	assert.True(t, c.Events[1].Floating)
+	assert.True(t, c.Events[1].End.IsZero())
+
+	var b bytes.Buffer
+	assert.Nil(t, c.WriteOut(&b))
+	s := b.String()
+	assert.Contains(t, s, "\r\nDTSTART:20230510T090000\r\nDTEND:20230510T130000\r\n")
+	assert.Contains(t, s, "\r\nDTSTART:20230511T090000\r\nSUMMARY:Mixed\r\n")
+}
+
+func Test_ICalendar_WriteOut(t *testing.T) {
+	c := &ICalendar{
+		Name: "Releases",
+		Events: []*ICalEvent{
+			{
+				UID:         "release-1",
+				Start:       time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC),
+				End:         time.Date(2023, 4, 3, 0, 0, 0, 0, time.UTC),
+				AllDay:      true,
+				Summary:     "Release; the big one",
+				Description: "Line one\nLine two, with " + strings.Repeat("très ", 20),
+				Organizer:   "The release team",
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	assert.Nil(t, c.WriteOut(&b))
+	s := b.String()
+	assert.Contains(t, s, "\r\nDTSTART;VALUE=DATE:20230401\r\nDTEND;VALUE=DATE:20230403\r\n")
+	assert.Contains(t, s, "\r\nSUMMARY:Release\\; the big one\r\n")
+	assert.Contains(t, s, "\r\nCONTACT:The release team\r\n")
+	assert.NotContains(t, s, "ORGANIZER")
+
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+
+	// unfolding gives the escaped description back
+	unfolded := strings.ReplaceAll(s, "\r\n ", "")
+	assert.Contains(t, unfolded, "\r\nDESCRIPTION:Line one\\nLine two\\, with très très ")
+}
+
+func Test_ICalendar_WriteOut_MultiLine(t *testing.T) {
+	c := &ICalendar{
+		Events: []*ICalEvent{
+			{
+				UID:     "\n abc;def\nDTSTART:19700101T000000Z\n",
+				URL:     "https://example.com/a\r\nDTSTART:19700101T000000Z",
+				Start:   time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC),
+				Summary: "Release",
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	assert.Nil(t, c.WriteOut(&b))
+	s := b.String()
+	assert.Contains(t, s, "\r\nUID:abc\\;def\\nDTSTART:19700101T000000Z\r\n")
+	assert.NotContains(t, s, "\r\nDTSTART:19700101T000000Z")
+	assert.NotContains(t, s, "\r\nURL:")
+	assert.Contains(t, s, "\r\nDTSTART:20230401T100000Z\r\n")
+}
@@ -0,0 +1,19 @@
+package textutil
+
+import (
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"unicode"
+)
+
+// Fold s without its diacritics, so that "café" and "cafe" are the same word, or s when it cannot be transformed.
+func Fold(s string) string {
+	// a transform.Chain keeps buffers, it is not shared between goroutines
+	fold := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(fold, s)
+	if err != nil {
+		return s
+	}
+	return folded
+}
@@ -0,0 +1,31 @@
+// Package textutil has the plain text helpers shared by the packages of grss.
+package textutil
+
+import (
+	"html"
+	"strings"
+)
+
+// HTML the HTML of the plain text s, its paragraphs separated by blank lines and its lines by <br>.
+func HTML(s string) string {
+	var b strings.Builder
+	for _, p := range strings.Split(strings.ReplaceAll(strings.TrimSpace(s), "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			b.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(p), "\n", "<br>") + "</p>")
+		}
+	}
+	return b.String()
+}
+
+// Truncate s at a word boundary to at most n runes, ending it with … when it is cut.
+func Truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	cut := string(r[:n])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
@@ -0,0 +1,23 @@
+package textutil
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_HTML(t *testing.T) {
+	assert.Equal(t, "<p>a &amp; b<br>c</p><p>d</p>", HTML(" a & b\r\nc\n\n\n d "))
+	assert.Equal(t, "", HTML(" \n\n "))
+}
+
+func Test_Truncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 10))
+	assert.Equal(t, "one two…", Truncate("one two, three", 10))
+	assert.Equal(t, "ééé…", Truncate("éééééé", 3))
+}
+
+func Test_Fold(t *testing.T) {
+	assert.Equal(t, "cafe creme", Fold("café crème"))
+	assert.Equal(t, "Angstrom", Fold("Ångström"))
+	assert.Equal(t, "日本語", Fold("日本語"))
+}
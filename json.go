@@ -1,6 +1,10 @@
 package grss
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
 
 // v1.0 https://www.jsonfeed.org/version/1/
 // v1.1 https://www.jsonfeed.org/version/1.1/
@@ -54,8 +58,8 @@ type JSONFeed struct {
 	// Items is an array, and is required. An item includes:
 	Items []*JSONItem `json:"items,omitempty"`
 
-	// Hub Subscribing to Real-time Notifications
-	Hub interface{} `json:"-"`
+	// Hubs hubs (very optional, array of objects) describes endpoints that can be used to subscribe to real-time notifications from the publisher of this feed. Each object has a type and url, both of which are required. See the section “Subscribing to Real-time Notifications” below for details.
+	Hubs []*JSONHub `json:"hubs,omitempty"`
 
 	// Extensions Publishers can use custom objects in JSON Feeds. Names must start with an _ character followed by a letter. Custom objects can appear anywhere in a feed.
 	Extensions map[string]interface{} `json:"-"`
@@ -157,6 +161,15 @@ type JSONAttachments struct {
 	DurationInSeconds uint64 `json:"duration_in_seconds,omitempty"`
 }
 
+// JSONHub Traditional feed readers usually poll a web site for changes at a regular interval. This is fine for many applications, but there’s a more efficient approach for applications that need to know the moment a feed changes. The top-level hubs array points to one or more services that can be used by feed aggregators to subscribe to changes to this feed. Those hubs can then send a notification to the application that subscribed.
+type JSONHub struct {
+	// Type The type field describes the protocol used to talk with the hub, such as “rssCloud” or “WebSub.”
+	Type string `json:"type,omitempty"`
+
+	// URL url (required, string) is the hub endpoint.
+	URL string `json:"url,omitempty"`
+}
+
 func (f *JSONFeed) UnmarshalJSON(b []byte) error {
 	var m = map[string]interface{}{}
 	err := json.Unmarshal(b, &m)
@@ -206,3 +219,70 @@ func (f *JSONFeed) UnmarshalJSON(b []byte) error {
 
 	return nil
 }
+
+// MarshalJSON writes the custom objects in Extensions after the standard members.
+func (f *JSONFeed) MarshalJSON() ([]byte, error) {
+	type inner JSONFeed
+	return marshalJSONExtensions((*inner)(f), f.Extensions)
+}
+
+// MarshalJSON writes the custom objects in Extensions after the standard members.
+func (item *JSONItem) MarshalJSON() ([]byte, error) {
+	type inner JSONItem
+	return marshalJSONExtensions((*inner)(item), item.Extensions)
+}
+
+// marshalJSONExtensions appends the extensions to the JSON object of v, keeping the member order of v.
+// HTML is left unescaped here, the encoder that called MarshalJSON escapes the result as it is configured to.
+func marshalJSONExtensions(v interface{}, extensions map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	err := e.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	b := bytes.TrimSpace(buf.Bytes())
+
+	var keys []string
+	for k := range extensions {
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return b, nil
+	}
+	sort.Strings(keys)
+
+	out := append([]byte{}, b[:len(b)-1]...)
+	for _, k := range keys {
+		buf.Reset()
+		err = e.Encode(extensions[k])
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+		key, _ := json.Marshal(k)
+		out = append(out, key...)
+		out = append(out, ':')
+		out = append(out, bytes.TrimSpace(buf.Bytes())...)
+	}
+	return append(out, '}'), nil
+}
+
+// decodeJSONExtension decodes an extension parsed as a generic object into out.
+func decodeJSONExtension(v interface{}, out interface{}) bool {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(b, out) == nil
+}
+
+func (item *JSONItem) setExtension(k string, v interface{}) {
+	if item.Extensions == nil {
+		item.Extensions = map[string]interface{}{}
+	}
+	item.Extensions[k] = v
+}
@@ -1,6 +1,8 @@
 package grss
 
 import (
+	"bytes"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"strings"
@@ -62,3 +64,54 @@ func Test_JSONFeed_001(t *testing.T) {
 	assert.Equal(t, false, a.Items[1].Extensions["_blue_shed"].(map[string]interface{})["explicit"], a)
 
 }
+
+func Test_JSONFeed_Hubs(t *testing.T) {
+	s := `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "hubs",
+  "feed_url": "https://example.org/feed.json",
+  "hubs": [
+    {"type": "WebSub", "url": "https://hub.example.org/"},
+    {"type": "rssCloud", "url": "https://cloud.example.org/"}
+  ],
+  "items": [{"id": "1", "content_text": "1"}]
+}`
+	j, err := jsonParse(strings.NewReader(s))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(j.Hubs))
+	assert.Equal(t, "WebSub", j.Hubs[0].Type)
+	assert.Equal(t, "https://hub.example.org/", j.Hubs[0].URL)
+
+	b, err := json.Marshal(j)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"hubs":[{"type":"WebSub","url":"https://hub.example.org/"}`)
+
+	rels := func(links []*AtomLink) map[string]string {
+		m := map[string]string{}
+		for _, link := range links {
+			m[link.Rel] = string(link.Href)
+		}
+		return m
+	}
+
+	a := j.ToAtom()
+	assert.Equal(t, "https://hub.example.org/", rels(a.Links)["hub"])
+
+	r := j.ToRss()
+	assert.Equal(t, "https://hub.example.org/", rels(r.Channel.AtomLinks())["hub"])
+	assert.Equal(t, "https://example.org/feed.json", rels(r.Channel.AtomLinks())["self"])
+
+	assert.Equal(t, &RssCloud{Domain: "cloud.example.org", Port: "443", Path: "/", Protocol: "http-post"}, r.Channel.Cloud)
+
+	var buf bytes.Buffer
+	err = r.WriteOut(&buf)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `<cloud domain="cloud.example.org" port="443" path="/" registerProcedure="" protocol="http-post"></cloud>`)
+
+	// back to JSON Feed, Atom has no cloud
+	assert.Equal(t, []*JSONHub{{Type: "WebSub", URL: "https://hub.example.org/"}}, a.ToJSON().Hubs)
+	assert.Equal(t, j.Hubs, r.ToJSON().Hubs)
+	for _, f := range []Feed{a, r} {
+		assert.Equal(t, "https://example.org/feed.json", f.ToJSON().FeedURL)
+	}
+}
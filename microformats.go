@@ -0,0 +1,562 @@
+package grss
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// microformats2 https://microformats.org/wiki/microformats2-parsing
+// h-feed https://microformats.org/wiki/h-feed
+// h-entry https://microformats.org/wiki/h-entry
+// h-card https://microformats.org/wiki/h-card
+
+var (
+	// ErrNoHEntry the HTML document has no h-entry, it is no feed.
+	ErrNoHEntry = errors.New("no h-feed or h-entry")
+)
+
+// mfElement an element of the document holding a microformat, the root classes (h-*) and its properties.
+type mfElement struct {
+	node  *html.Node
+	types []string
+	// props the elements of each property (p-name, u-url...), in document order
+	props map[string][]*html.Node
+	// children the microformats nested in it that are not properties
+	children []*mfElement
+}
+
+// mfClasses the h-* classes and the property classes of n.
+func mfClasses(n *html.Node) (types, props []string) {
+	if n.Type != html.ElementNode {
+		return nil, nil
+	}
+	for _, c := range strings.Fields(htmlAttr(n, "class")) {
+		switch {
+		case strings.HasPrefix(c, "h-") && len(c) > 2:
+			types = append(types, c)
+		case strings.HasPrefix(c, "p-"), strings.HasPrefix(c, "u-"), strings.HasPrefix(c, "dt-"), strings.HasPrefix(c, "e-"):
+			props = append(props, c)
+		}
+	}
+	return types, props
+}
+
+func (m *mfElement) is(typ string) bool {
+	for _, t := range m.types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMicroformat the microformat of n: the properties of its descendants, up to the nested microformats,
+// which are a property when they have a property class and a child otherwise.
+func parseMicroformat(n *html.Node) *mfElement {
+	m := &mfElement{node: n, props: map[string][]*html.Node{}}
+	m.types, _ = mfClasses(n)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			types, props := mfClasses(c)
+			for _, p := range props {
+				m.props[p] = append(m.props[p], c)
+			}
+			if len(types) > 0 {
+				if len(props) == 0 {
+					m.children = append(m.children, parseMicroformat(c))
+				}
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return m
+}
+
+// findMicroformats the outermost microformats under n.
+func findMicroformats(n *html.Node) []*mfElement {
+	var found []*mfElement
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if types, _ := mfClasses(c); len(types) > 0 {
+			found = append(found, parseMicroformat(c))
+			continue
+		}
+		found = append(found, findMicroformats(c)...)
+	}
+	return found
+}
+
+func htmlAttr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasHTMLAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlText the text of n, the images replaced by their alt, with the white space collapsed.
+func htmlText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type != html.ElementNode:
+		case n.DataAtom == atom.Script || n.DataAtom == atom.Style || n.DataAtom == atom.Template:
+			return
+		case n.DataAtom == atom.Img:
+			b.WriteString(htmlAttr(n, "alt"))
+		case n.DataAtom == atom.Br:
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// mfParser parses the properties of a document whose relative URLs resolve against base.
+type mfParser struct {
+	base *url.URL
+}
+
+func (p *mfParser) resolve(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || p.base == nil {
+		return s
+	}
+	u, err := p.base.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.String()
+}
+
+// text the value of a p-* property.
+func (p *mfParser) text(n *html.Node) string {
+	switch {
+	case (n.DataAtom == atom.Abbr || n.DataAtom == atom.Link) && hasHTMLAttr(n, "title"):
+		return htmlAttr(n, "title")
+	case (n.DataAtom == atom.Data || n.DataAtom == atom.Input) && hasHTMLAttr(n, "value"):
+		return htmlAttr(n, "value")
+	case (n.DataAtom == atom.Img || n.DataAtom == atom.Area) && hasHTMLAttr(n, "alt"):
+		return htmlAttr(n, "alt")
+	}
+	return htmlText(n)
+}
+
+// url the value of a u-* property.
+func (p *mfParser) url(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.A, atom.Area, atom.Link:
+		if hasHTMLAttr(n, "href") {
+			return p.resolve(htmlAttr(n, "href"))
+		}
+	case atom.Img, atom.Audio, atom.Video, atom.Source, atom.Iframe:
+		if hasHTMLAttr(n, "src") {
+			return p.resolve(htmlAttr(n, "src"))
+		}
+		if n.DataAtom == atom.Video && hasHTMLAttr(n, "poster") {
+			return p.resolve(htmlAttr(n, "poster"))
+		}
+	case atom.Object:
+		if hasHTMLAttr(n, "data") {
+			return p.resolve(htmlAttr(n, "data"))
+		}
+	}
+	return p.resolve(p.text(n))
+}
+
+// date the value of a dt-* property.
+func (p *mfParser) date(n *html.Node) string {
+	switch {
+	case (n.DataAtom == atom.Time || n.DataAtom == atom.Ins || n.DataAtom == atom.Del) && hasHTMLAttr(n, "datetime"):
+		return strings.TrimSpace(htmlAttr(n, "datetime"))
+	case n.DataAtom == atom.Abbr && hasHTMLAttr(n, "title"):
+		return strings.TrimSpace(htmlAttr(n, "title"))
+	case (n.DataAtom == atom.Data || n.DataAtom == atom.Input) && hasHTMLAttr(n, "value"):
+		return strings.TrimSpace(htmlAttr(n, "value"))
+	}
+	return htmlText(n)
+}
+
+// html the value of an e-* property, its inner HTML with the URLs resolved.
+func (p *mfParser) html(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		_ = html.Render(&b, c)
+	}
+	s := strings.TrimSpace(b.String())
+	if p.base != nil {
+		s = ResolveHTML(s, p.base.String())
+	}
+	return s
+}
+
+func (p *mfParser) first(m *mfElement, prop string, value func(n *html.Node) string) string {
+	for _, n := range m.props[prop] {
+		if v := value(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// author the JSON Feed author of a p-author, an h-card or a name.
+func (p *mfParser) author(n *html.Node) *JSONAuthor {
+	if types, _ := mfClasses(n); len(types) == 0 {
+		if name := p.text(n); name != "" {
+			return &JSONAuthor{Name: name}
+		}
+		return nil
+	}
+
+	card := parseMicroformat(n)
+	a := &JSONAuthor{
+		Name:   p.first(card, "p-name", p.text),
+		URL:    p.first(card, "u-url", p.url),
+		Avatar: p.first(card, "u-photo", p.url),
+	}
+	// the implied properties of an h-card without them
+	if a.Name == "" {
+		a.Name = p.text(n)
+	}
+	if a.URL == "" && n.DataAtom == atom.A {
+		a.URL = p.url(n)
+	}
+	if a.Avatar == "" {
+		if n.DataAtom == atom.Img {
+			a.Avatar = p.url(n)
+		} else if imgs := findElements(n, atom.Img); len(imgs) == 1 {
+			a.Avatar = p.url(imgs[0])
+		}
+	}
+	if a.Name == "" && a.URL == "" && a.Avatar == "" {
+		return nil
+	}
+	return a
+}
+
+func findElements(n *html.Node, a atom.Atom) []*html.Node {
+	var found []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == a {
+			found = append(found, c)
+		}
+		found = append(found, findElements(c, a)...)
+	}
+	return found
+}
+
+func (p *mfParser) authors(m *mfElement) []*JSONAuthor {
+	var authors []*JSONAuthor
+	for _, n := range m.props["p-author"] {
+		if a := p.author(n); a != nil {
+			authors = append(authors, a)
+		}
+	}
+	return authors
+}
+
+// item the JSON Feed item of an h-entry, mapped as https://indieweb.org/jf2 does.
+func (p *mfParser) item(m *mfElement) *JSONItem {
+	jitem := &JSONItem{}
+
+	// p-name maps to title, but a note has no name of its own, its implied name being its content.
+	jitem.Title = p.first(m, "p-name", p.text)
+
+	// u-url maps to url, and u-uid to id.
+	jitem.URL = p.first(m, "u-url", p.url)
+	jitem.ID = p.first(m, "u-uid", p.url)
+
+	// dt-published maps to date_published and dt-updated to date_modified.
+	jitem.DatePublished = p.first(m, "dt-published", p.date)
+	jitem.DateModified = p.first(m, "dt-updated", p.date)
+
+	// p-author, an h-card or a name, maps to authors.
+	jitem.Authors = p.authors(m)
+
+	// e-content maps to content_html, p-summary to summary.
+	jitem.Summary = p.first(m, "p-summary", p.text)
+	if contents := m.props["e-content"]; len(contents) > 0 {
+		jitem.ContentHTML = p.html(contents[0])
+		// a name that is the text of the content is the implied name of a note
+		if jitem.Title != "" && jitem.Title == htmlText(contents[0]) {
+			jitem.Title = ""
+		}
+	} else {
+		// content_html or content_text is required
+		jitem.ContentText = jitem.Summary
+		if jitem.ContentText == "" {
+			jitem.ContentText = jitem.Title
+		}
+	}
+
+	// p-category maps to tags, the category of a person tag is an h-card.
+	for _, n := range m.props["p-category"] {
+		if types, _ := mfClasses(n); len(types) > 0 {
+			if a := p.author(n); a != nil && a.Name != "" {
+				jitem.Tags = append(jitem.Tags, a.Name)
+			}
+			continue
+		}
+		if tag := p.text(n); tag != "" {
+			jitem.Tags = append(jitem.Tags, tag)
+		}
+	}
+
+	// u-photo maps to image, the other photos to attachments.
+	for i, n := range m.props["u-photo"] {
+		photo := p.url(n)
+		if photo == "" {
+			continue
+		}
+		if i == 0 {
+			jitem.Image = photo
+		} else {
+			jitem.Attachments = append(jitem.Attachments, &JSONAttachments{URL: photo, MimeType: mimeOfExtension(photo, "image/")})
+		}
+	}
+
+	return jitem
+}
+
+// mimeOfExtension the MIME type of the file extension of u, or prefix followed by the extension.
+func mimeOfExtension(u, prefix string) string {
+	if i := strings.LastIndexByte(u, '.'); i >= 0 && !strings.ContainsAny(u[i:], "/?#") {
+		ext := strings.ToLower(u[i+1:])
+		if ext == "jpg" {
+			ext = "jpeg"
+		}
+		return prefix + ext
+	}
+	return prefix + "*"
+}
+
+// ParseHTML parses the microformats2 h-feed of an HTML document as a JSON Feed, or its h-entry elements when it has no h-feed.
+// Relative URLs are resolved against base, the URL of the document, and its <base href>.
+// It returns ErrNoHEntry when the document has no h-entry.
+func ParseHTML(r io.Reader, base string) (*JSONFeed, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &mfParser{}
+	if base != "" {
+		p.base, err = url.Parse(base)
+		if err != nil {
+			return nil, fmt.Errorf("base: %w", err)
+		}
+	}
+	for _, n := range findElements(root, atom.Base) {
+		if href := htmlAttr(n, "href"); href != "" {
+			if p.base == nil {
+				p.base, _ = url.Parse(href)
+			} else if u, err := p.base.Parse(href); err == nil {
+				p.base = u
+			}
+			break
+		}
+	}
+
+	ff := &JSONFeed{}
+	if p.base != nil {
+		ff.HomePageURL = p.base.String()
+	}
+	if titles := findElements(root, atom.Title); len(titles) > 0 {
+		ff.Title = htmlText(titles[0])
+	}
+	for _, n := range findElements(root, atom.Html) {
+		ff.Language = htmlAttr(n, "lang")
+	}
+
+	var entries []*mfElement
+	all := findMicroformats(root)
+	for _, m := range all {
+		if !m.is("h-feed") {
+			continue
+		}
+		// the h-feed, its name, url, summary and author, and the h-entry in it
+		if name := p.first(m, "p-name", p.text); name != "" {
+			ff.Title = name
+		}
+		if u := p.first(m, "u-url", p.url); u != "" {
+			ff.HomePageURL = u
+		}
+		ff.Description = p.first(m, "p-summary", p.text)
+		ff.Icon = p.first(m, "u-photo", p.url)
+		ff.Authors = p.authors(m)
+		for _, child := range m.children {
+			if child.is("h-entry") {
+				entries = append(entries, child)
+			}
+		}
+		break
+	}
+	if entries == nil {
+		for _, m := range all {
+			if m.is("h-entry") {
+				entries = append(entries, m)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, ErrNoHEntry
+	}
+
+	for _, m := range entries {
+		ff.Items = append(ff.Items, p.item(m))
+	}
+
+	ff.Uniform()
+	return ff, nil
+}
+
+// looksLikeHTML reports whether the document starting with b is HTML rather than XML.
+func looksLikeHTML(b []byte) bool {
+	b = bytes.ToLower(bytes.TrimSpace(b))
+	return bytes.HasPrefix(b, []byte("<!doctype html")) || bytes.HasPrefix(b, []byte("<html"))
+}
+
+// parseHTMLFeed Parse of an HTML document, peeked from r.
+func parseHTMLFeed(r *bufio.Reader) (Feed, bool, error) {
+	b, _ := r.Peek(512)
+	if !looksLikeHTML(b) {
+		return nil, false, nil
+	}
+	f, err := ParseHTML(r, "")
+	if err != nil {
+		return nil, true, err
+	}
+	return f, true, nil
+}
+
+// hfeedURL the URL s if it is safe to link to from the h-feed, see safeURL, empty otherwise.
+func hfeedURL(s string) string {
+	if !safeURL(s) {
+		return ""
+	}
+	return s
+}
+
+// WriteHFeed writes f as an h-feed HTML fragment, to embed in a page.
+// The HTML content of the items is sanitized with SanitizeHTML, as it is embedded in another page,
+// and the URLs with a scheme that runs something, such as javascript:, are left out as the sanitizer does.
+func WriteHFeed(w io.Writer, f Feed) error {
+	j := f.ToJSON()
+	b := &bytes.Buffer{}
+	esc := html.EscapeString
+
+	b.WriteString(`<div class="h-feed">` + "\n")
+	if j.Title != "" {
+		if home := hfeedURL(j.HomePageURL); home != "" {
+			fmt.Fprintf(b, `  <h1 class="p-name"><a class="u-url" href="%s">%s</a></h1>`+"\n", esc(home), esc(j.Title))
+		} else {
+			fmt.Fprintf(b, `  <h1 class="p-name">%s</h1>`+"\n", esc(j.Title))
+		}
+	}
+	if j.Description != "" {
+		fmt.Fprintf(b, `  <p class="p-summary">%s</p>`+"\n", esc(j.Description))
+	}
+	for _, a := range j.Authors {
+		writeHCard(b, "  ", a)
+	}
+
+	for _, item := range j.Items {
+		itemURL := hfeedURL(item.URL)
+		b.WriteString(`  <article class="h-entry">` + "\n")
+		if item.Title != "" {
+			if itemURL != "" {
+				fmt.Fprintf(b, `    <h2 class="p-name"><a class="u-url" href="%s">%s</a></h2>`+"\n", esc(itemURL), esc(item.Title))
+			} else {
+				fmt.Fprintf(b, `    <h2 class="p-name">%s</h2>`+"\n", esc(item.Title))
+			}
+		}
+		if item.ID != "" && item.ID != item.URL {
+			fmt.Fprintf(b, `    <data class="u-uid" value="%s"></data>`+"\n", esc(item.ID))
+		}
+		for _, d := range []struct {
+			class, date string
+		}{
+			{"dt-published", item.DatePublished},
+			{"dt-updated", item.DateModified},
+		} {
+			if d.date == "" {
+				continue
+			}
+			t, err := ParseDate(d.date)
+			if err != nil {
+				fmt.Fprintf(b, `    <time class="%s">%s</time>`+"\n", d.class, esc(d.date))
+				continue
+			}
+			tag := fmt.Sprintf(`<time class="%s" datetime="%s">%s</time>`, d.class, t.Format(time.RFC3339), esc(t.Format("2006-01-02 15:04")))
+			if item.Title == "" && itemURL != "" && d.class == "dt-published" {
+				// a note links to itself from its date
+				tag = fmt.Sprintf(`<a class="u-url" href="%s">%s</a>`, esc(itemURL), tag)
+			}
+			b.WriteString("    " + tag + "\n")
+		}
+		for _, a := range item.Authors {
+			writeHCard(b, "    ", a)
+		}
+		if image := hfeedURL(item.Image); image != "" {
+			fmt.Fprintf(b, `    <img class="u-photo" src="%s" alt="">`+"\n", esc(image))
+		}
+		if item.Summary != "" {
+			fmt.Fprintf(b, `    <p class="p-summary">%s</p>`+"\n", esc(item.Summary))
+		}
+		switch {
+		case item.ContentHTML != "":
+			fmt.Fprintf(b, `    <div class="e-content">%s</div>`+"\n", SanitizeHTML(item.ContentHTML))
+		case item.ContentText != "":
+			fmt.Fprintf(b, `    <div class="e-content">%s</div>`+"\n", strings.ReplaceAll(esc(item.ContentText), "\n", "<br>"))
+		}
+		for _, tag := range item.Tags {
+			fmt.Fprintf(b, `    <span class="p-category">%s</span>`+"\n", esc(tag))
+		}
+		b.WriteString("  </article>\n")
+	}
+	b.WriteString("</div>\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func writeHCard(b *bytes.Buffer, indent string, a *JSONAuthor) {
+	esc := html.EscapeString
+	b.WriteString(indent + `<span class="p-author h-card">`)
+	if avatar := hfeedURL(a.Avatar); avatar != "" {
+		fmt.Fprintf(b, `<img class="u-photo" src="%s" alt=""> `, esc(avatar))
+	}
+	if u := hfeedURL(a.URL); u != "" {
+		fmt.Fprintf(b, `<a class="p-name u-url" href="%s">%s</a>`, esc(u), esc(a.Name))
+	} else {
+		fmt.Fprintf(b, `<span class="p-name">%s</span>`, esc(a.Name))
+	}
+	b.WriteString("</span>\n")
+}
@@ -0,0 +1,150 @@
+package grss
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testHFeed = `<!DOCTYPE html>
+<html lang="en">
+<head><title>Alice's site</title><base href="/blog/"></head>
+<body>
+  <div class="h-card"><a class="p-name u-url" href="/">Alice</a></div>
+  <main class="h-feed">
+    <h1 class="p-name">Alice's notes</h1>
+    <p class="p-summary">Notes and posts</p>
+    <a class="p-author h-card" href="/"><img src="/alice.png" alt="Alice"></a>
+    <article class="h-entry">
+      <h2 class="p-name"><a class="u-url" href="hello">Hello</a></h2>
+      <time class="dt-published" datetime="2023-01-02T10:00:00+01:00">2 Jan</time>
+      <time class="dt-updated" datetime="2023-01-04T10:00:00Z">4 Jan</time>
+      <span class="p-author h-card"><a class="p-name u-url" href="https://bob.example/">Bob</a></span>
+      <a class="p-category" href="/tags/intro">intro</a>
+      <a class="u-category h-card p-category" href="https://carol.example/">Carol</a>
+      <div class="e-content"><p>Hello <img class="u-photo" src="photo.jpg" alt="me"><img class="u-photo" src="more.png" alt=""></p></div>
+    </article>
+    <article class="h-entry">
+      <p class="p-name e-content">Just a note</p>
+      <a class="u-url" href="note"><time class="dt-published">2023-01-03 08:00</time></a>
+      <data class="u-uid" value="tag:alice.example,2023:note"></data>
+    </article>
+  </main>
+  <article class="h-entry"><p class="p-name">Outside of the feed</p></article>
+</body>
+</html>`
+
+func Test_ParseHTML(t *testing.T) {
+	f, err := ParseHTML(strings.NewReader(testHFeed), "https://alice.example/")
+	assert.Nil(t, err)
+	assert.Equal(t, "Alice's notes", f.Title)
+	assert.Equal(t, "Notes and posts", f.Description)
+	assert.Equal(t, "https://alice.example/blog/", f.HomePageURL)
+	assert.Equal(t, "en", f.Language)
+	assert.Equal(t, []*JSONAuthor{{Name: "Alice", URL: "https://alice.example/", Avatar: "https://alice.example/alice.png"}}, f.Authors)
+	assert.Equal(t, 2, len(f.Items))
+
+	hello := f.Items[0]
+	assert.Equal(t, "Hello", hello.Title)
+	assert.Equal(t, "https://alice.example/blog/hello", hello.URL)
+	assert.Equal(t, hello.URL, hello.ID)
+	assert.Equal(t, "2023-01-02T10:00:00+01:00", hello.DatePublished)
+	assert.Equal(t, "2023-01-04T10:00:00Z", hello.DateModified)
+	assert.Equal(t, []*JSONAuthor{{Name: "Bob", URL: "https://bob.example/"}}, hello.Authors)
+	assert.Equal(t, []string{"intro", "Carol"}, hello.Tags)
+	assert.Equal(t, "https://alice.example/blog/photo.jpg", hello.Image)
+	assert.Equal(t, []*JSONAttachments{{URL: "https://alice.example/blog/more.png", MimeType: "image/png"}}, hello.Attachments)
+	assert.Equal(t, `<p>Hello <img class="u-photo" src="https://alice.example/blog/photo.jpg" alt="me"/><img class="u-photo" src="https://alice.example/blog/more.png" alt=""/></p>`, hello.ContentHTML)
+
+	// a note has no title
+	note := f.Items[1]
+	assert.Equal(t, "", note.Title)
+	assert.Equal(t, "tag:alice.example,2023:note", note.ID)
+	assert.Equal(t, "https://alice.example/blog/note", note.URL)
+	assert.Equal(t, "2023-01-03T08:00:00Z", note.DatePublished)
+	assert.Equal(t, "Just a note", note.ContentHTML)
+
+	// without h-feed, the h-entry elements of the page
+	f, err = ParseHTML(strings.NewReader(`<html><body><div class="h-entry"><a class="u-url p-name" href="/a">A</a></div>`+
+		`<div class="h-entry"><span class="p-name">B</span></div></body></html>`), "https://example.com/")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(f.Items))
+	assert.Equal(t, "https://example.com/a", f.Items[0].URL)
+	assert.Equal(t, "B", f.Items[1].ContentText)
+
+	_, err = ParseHTML(strings.NewReader(`<html><body><p>nothing</p></body></html>`), "")
+	assert.Equal(t, ErrNoHEntry, err)
+
+	// through Parse
+	typ, feed, err := Parse(strings.NewReader(testHFeed))
+	assert.Nil(t, err)
+	assert.Equal(t, TypeHTML, typ)
+	assert.Equal(t, "Alice's notes", feed.ToJSON().Title)
+	assert.Equal(t, "/blog/hello", feed.ToJSON().Items[0].URL)
+
+	_, _, err = Parse(strings.NewReader(`<!doctype html><title>x</title>`))
+	assert.Equal(t, ErrNoHEntry, err)
+}
+
+func Test_WriteHFeed(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(`<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Example &amp; co</title>
+    <link>https://example.com/</link>
+    <description>An example</description>
+    <item>
+      <title>Hello</title>
+      <link>https://example.com/hello</link>
+      <guid>https://example.com/hello</guid>
+      <category>news</category>
+      <pubDate>Mon, 02 Jan 2023 10:00:00 GMT</pubDate>
+      <content:encoded><![CDATA[<p onclick="x()">Hello <b>world</b></p><script>alert(1)</script>]]></content:encoded>
+    </item>
+  </channel>
+</rss>`))
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, WriteHFeed(&b, f))
+	s := b.String()
+	assert.True(t, strings.HasPrefix(s, `<div class="h-feed">`))
+	assert.Contains(t, s, `<h1 class="p-name"><a class="u-url" href="https://example.com/">Example &amp; co</a></h1>`)
+	assert.Contains(t, s, `<time class="dt-published" datetime="2023-01-02T10:00:00Z">2023-01-02 10:00</time>`)
+	assert.Contains(t, s, `<div class="e-content"><p>Hello <b>world</b></p></div>`)
+	assert.NotContains(t, s, "script")
+
+	// read back
+	j, err := ParseHTML(&b, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "Example & co", j.Title)
+	assert.Equal(t, "An example", j.Description)
+	assert.Equal(t, 1, len(j.Items))
+	assert.Equal(t, "Hello", j.Items[0].Title)
+	assert.Equal(t, "https://example.com/hello", j.Items[0].URL)
+	assert.Equal(t, "2023-01-02T10:00:00Z", j.Items[0].DatePublished)
+	assert.Equal(t, []string{"news"}, j.Items[0].Tags)
+	assert.Equal(t, `<p>Hello <b>world</b></p>`, j.Items[0].ContentHTML)
+
+	// the links that would run a script are left out
+	b.Reset()
+	assert.Nil(t, WriteHFeed(&b, &JSONFeed{
+		Title:       "Unsafe",
+		HomePageURL: "javascript:alert(1)",
+		Items: []*JSONItem{
+			{Title: "Titled", URL: "JavaScript:alert(2)", Image: "data:text/html,<script>alert(3)</script>"},
+			{URL: " javascript:alert(4)", DatePublished: "2023-01-02T10:00:00Z", ContentText: "A note", Authors: []*JSONAuthor{
+				{Name: "Alice", URL: "vbscript:msgbox(5)", Avatar: "javascript:alert(6)"},
+				{Name: "Bob", URL: "https://bob.example/", Avatar: "/bob.png"},
+			}},
+		},
+	}))
+	s = b.String()
+	assert.NotContains(t, strings.ToLower(s), "script:")
+	assert.NotContains(t, s, "data:")
+	assert.Contains(t, s, `<h1 class="p-name">Unsafe</h1>`)
+	assert.Contains(t, s, `<h2 class="p-name">Titled</h2>`)
+	assert.Contains(t, s, `<span class="p-name">Alice</span>`)
+	assert.Contains(t, s, `<img class="u-photo" src="/bob.png" alt=""> <a class="p-name u-url" href="https://bob.example/">Bob</a>`)
+}
@@ -0,0 +1,56 @@
+package grss
+
+// MRSS 1.5.1 https://www.rssboard.org/media-rss
+
+const (
+	NamespaceMedia = "http://search.yahoo.com/mrss/"
+)
+
+// MediaGroup <media:group> is a sub-element of <item>. It allows grouping of <media:content> elements that are effectively the same content, yet different representations. For instance: the same song recorded in both the WAV and MP3 format. It's an optional element that must only be used for this purpose.
+type MediaGroup struct {
+	// Title The title of the particular media object.
+	Title string `xml:"http://search.yahoo.com/mrss/ media:title,omitempty"`
+
+	Contents []*MediaContent `xml:"http://search.yahoo.com/mrss/ media:content,omitempty"`
+}
+
+// MediaContent <media:content> is a sub-element of either <item> or <media:group>. Media objects that are not the same content should not be included in the same <media:group> element.
+type MediaContent struct {
+	// Url should specify the direct URL to the media object.
+	Url string `xml:"url,attr,omitempty"`
+	// FileSize is the number of bytes of the media object.
+	FileSize string `xml:"fileSize,attr,omitempty"`
+	// Type is the standard MIME type of the object.
+	Type string `xml:"type,attr,omitempty"`
+	// Medium is the type of object (image | audio | video | document | executable).
+	Medium string `xml:"medium,attr,omitempty"`
+	// IsDefault determines if this is the default object that should be used for the <media:group>. There should only be one default object per <media:group>.
+	IsDefault string `xml:"isDefault,attr,omitempty"`
+	// Expression determines if the object is a sample or the full version of the object, or even if it is a continuous stream (sample | full | nonstop). Default value is "full".
+	Expression string `xml:"expression,attr,omitempty"`
+	// Bitrate is the kilobits per second rate of media.
+	Bitrate string `xml:"bitrate,attr,omitempty"`
+	// Duration is the number of seconds the media object plays.
+	Duration string `xml:"duration,attr,omitempty"`
+	// Height is the height of the media object.
+	Height string `xml:"height,attr,omitempty"`
+	// Width is the width of the media object.
+	Width string `xml:"width,attr,omitempty"`
+	// Lang is the primary language encapsulated in the media object.
+	Lang string `xml:"lang,attr,omitempty"`
+
+	// Title The title of the particular media object.
+	Title string `xml:"http://search.yahoo.com/mrss/ media:title,omitempty"`
+}
+
+// MediaThumbnail <media:thumbnail> allows particular images to be used as representative images for the media object. If multiple thumbnails are included, and time coding is not at play, it is assumed that the images are in order of importance.
+type MediaThumbnail struct {
+	// Url specifies the url of the thumbnail.
+	Url string `xml:"url,attr"`
+	// Height specifies the height of the thumbnail.
+	Height string `xml:"height,attr,omitempty"`
+	// Width specifies the width of the thumbnail.
+	Width string `xml:"width,attr,omitempty"`
+	// Time specifies the time offset in relation to the media object.
+	Time string `xml:"time,attr,omitempty"`
+}
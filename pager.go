@@ -0,0 +1,448 @@
+package grss
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Feed Paging and Archiving https://datatracker.ietf.org/doc/html/rfc5005
+// https://www.jsonfeed.org/version/1.1/#top-level-a-name-top-level-a next_url
+
+const (
+	NamespaceFeedHistory = "http://purl.org/syndication/history/1.0"
+)
+
+var (
+	ErrPageLoop = errors.New("page loop")
+)
+
+// FeedPaging the paging and archiving links and markers of a feed document, with the links resolved against the document URL.
+type FeedPaging struct {
+	Self     string
+	Current  string
+	Next     string
+	Previous string
+	First    string
+	Last     string
+	// PrevArchive prev-archive, the immediately preceding archive document.
+	PrevArchive string
+	// NextArchive next-archive, the immediately following archive document.
+	NextArchive string
+	// Complete fh:complete, the document contains every entry of the feed.
+	Complete bool
+	// Archive fh:archive, the document is an archive document whose entries are not expected to change.
+	Archive bool
+}
+
+// Paging returns the paging and archiving links and markers of f, relative links are resolved against base.
+// Atom and RSS (atom:link) carry RFC 5005 link relations and fh:complete/fh:archive, JSON Feed carries next_url.
+func Paging(f Feed, base string) *FeedPaging {
+	p := &FeedPaging{}
+
+	var links []*AtomLink
+	var extensions []XmlGeneric
+	switch ff := f.(type) {
+	case *AtomFeed:
+		links = ff.Links
+		extensions = ff.ExtensionElement
+	case *RssFeed:
+		if ff.Channel != nil {
+			links = ff.Channel.AtomLinks()
+			extensions = ff.Channel.ExtensionElement
+		}
+	case *JSONFeed:
+		p.Self = resolveURL(base, ff.FeedURL)
+		p.Next = resolveURL(base, ff.NextURL)
+		return p
+	}
+
+	for _, link := range links {
+		href := resolveURL(base, string(link.Href))
+		var s *string
+		switch link.Rel {
+		case "self":
+			s = &p.Self
+		case "current":
+			s = &p.Current
+		case "next":
+			s = &p.Next
+		case "previous", "prev":
+			s = &p.Previous
+		case "first":
+			s = &p.First
+		case "last":
+			s = &p.Last
+		case "prev-archive":
+			s = &p.PrevArchive
+		case "next-archive":
+			s = &p.NextArchive
+		default:
+			continue
+		}
+		if *s == "" {
+			*s = href
+		}
+	}
+
+	for _, ext := range extensions {
+		if ext.XMLName.Space != NamespaceFeedHistory {
+			continue
+		}
+		switch ext.XMLName.Local {
+		case "complete":
+			p.Complete = true
+		case "archive":
+			p.Archive = true
+		}
+	}
+
+	return p
+}
+
+func resolveURL(base, ref string) string {
+	if ref == "" || base == "" {
+		return ref
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// pagingRels the link relations that describe a document's place in a paged or archived feed, they do not apply to a stitched feed.
+var pagingRels = map[string]bool{
+	"current": true, "next": true, "previous": true, "prev": true,
+	"first": true, "last": true, "prev-archive": true, "next-archive": true,
+}
+
+// FetchFunc retrieves and parses the feed document at url.
+type FetchFunc func(url string) (Feed, error)
+
+// Page a document fetched by a Pager.
+type Page struct {
+	URL    string
+	Feed   Feed
+	Paging *FeedPaging
+}
+
+// Pager walks the chain of a paged or archived feed, following next (Atom, RSS, next_url in JSON Feed) and then prev-archive.
+// The walk stops at the end of the chain, on a fh:complete document, or when a limit is reached.
+type Pager struct {
+	// Fetch retrieves the documents, required.
+	Fetch FetchFunc
+	// MaxPages limits the number of documents fetched, zero means no limit.
+	MaxPages int
+	// MaxItems stops the walk once that many distinct items were seen, zero means no limit.
+	MaxItems int
+	// Since stops the walk after the first document holding an item dated before it, the zero time means no limit.
+	Since time.Time
+}
+
+// Walk fetches the documents of the chain starting at url and calls fn for each, in order.
+// A URL met twice ends the walk with ErrPageLoop, JSON Feed forbids repeating next_url.
+func (p *Pager) Walk(url string, fn func(page *Page) error) error {
+	visited := map[string]bool{}
+	seen := map[string]bool{}
+
+	for n := 0; url != ""; n++ {
+		if p.MaxPages > 0 && n >= p.MaxPages {
+			return nil
+		}
+		if visited[url] {
+			return fmt.Errorf("%s: %w", url, ErrPageLoop)
+		}
+		visited[url] = true
+
+		f, err := p.Fetch(url)
+		if err != nil {
+			return err
+		}
+
+		page := &Page{
+			URL:    url,
+			Feed:   f,
+			Paging: Paging(f, url),
+		}
+		if page.Paging.Self != "" {
+			visited[page.Paging.Self] = true
+		}
+
+		err = fn(page)
+		if err != nil {
+			return err
+		}
+
+		// a complete feed holds every entry, any other document is stale
+		if page.Paging.Complete {
+			return nil
+		}
+
+		stop := false
+		for _, item := range f.ToJSON().Items {
+			if key := jsonItemKey(item); key != "" {
+				seen[key] = true
+			}
+			if !p.Since.IsZero() {
+				if t, ok := jsonItemDate(item); ok && t.Before(p.Since) {
+					stop = true
+				}
+			}
+		}
+		if stop || (p.MaxItems > 0 && len(seen) >= p.MaxItems) {
+			return nil
+		}
+
+		url = page.Paging.Next
+		if url == "" {
+			url = page.Paging.PrevArchive
+		}
+	}
+
+	return nil
+}
+
+// Collect returns the documents of the chain starting at url. When the walk fails the documents fetched so far are returned with the error.
+func (p *Pager) Collect(url string) ([]*Page, error) {
+	var pages []*Page
+	err := p.Walk(url, func(page *Page) error {
+		pages = append(pages, page)
+		return nil
+	})
+	return pages, err
+}
+
+// Feed walks the chain starting at url and stitches the documents into one feed, in the format of the first document.
+// Items dated before Since are dropped and at most MaxItems are kept. A loop in the chain is not an error here, the documents before it are stitched.
+func (p *Pager) Feed(url string) (Feed, error) {
+	pages, err := p.Collect(url)
+	if err != nil && !errors.Is(err, ErrPageLoop) {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, err
+	}
+
+	var feeds []Feed
+	for _, page := range pages {
+		feeds = append(feeds, page.Feed)
+	}
+
+	f := Stitch(feeds...)
+	keep := func(key string, date time.Time, dated bool) bool {
+		return p.Since.IsZero() || !dated || !date.Before(p.Since)
+	}
+	trimItems(f, keep, p.MaxItems)
+	return f, nil
+}
+
+// Stitch joins the documents of a paged or archived feed into one feed in the format of the first document, which also gives the feed metadata.
+// Items are deduplicated by id (Atom id, RSS guid or link, JSON Feed id or url), the first occurrence wins, so pass the newest document first as RFC 5005 asks.
+func Stitch(feeds ...Feed) Feed {
+	if len(feeds) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	dup := func(key string) bool {
+		if key == "" {
+			return false
+		}
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+		return false
+	}
+
+	switch first := feeds[0].(type) {
+	case *AtomFeed:
+		ff := *first
+		ff.Links = nil
+		for _, link := range first.Links {
+			if !pagingRels[link.Rel] {
+				ff.Links = append(ff.Links, link)
+			}
+		}
+		ff.ExtensionElement = withoutPagingExtensions(first.ExtensionElement)
+		ff.Entries = nil
+		for _, f := range feeds {
+			a, ok := f.(*AtomFeed)
+			if !ok {
+				a = f.ToAtom()
+			}
+			for _, entry := range a.Entries {
+				if !dup(atomEntryKey(entry)) {
+					ff.Entries = append(ff.Entries, entry)
+				}
+			}
+		}
+		return &ff
+	case *RssFeed:
+		ff := *first
+		ff.Items = nil
+		channel := RssChannel{}
+		if first.Channel != nil {
+			channel = *first.Channel
+		}
+		channel.ExtensionElement = withoutPagingExtensions(channel.ExtensionElement)
+		channel.Items = nil
+		for _, f := range feeds {
+			r, ok := f.(*RssFeed)
+			if !ok {
+				r = f.ToRss()
+			}
+			items := r.Items
+			if r.Channel != nil {
+				items = append(items[:len(items):len(items)], r.Channel.Items...)
+			}
+			for _, item := range items {
+				if !dup(rssItemKey(item)) {
+					channel.Items = append(channel.Items, item)
+				}
+			}
+		}
+		ff.Channel = &channel
+		return &ff
+	default:
+		ff := *feeds[0].ToJSON()
+		ff.NextURL = ""
+		ff.Items = nil
+		for _, f := range feeds {
+			for _, item := range f.ToJSON().Items {
+				if !dup(jsonItemKey(item)) {
+					ff.Items = append(ff.Items, item)
+				}
+			}
+		}
+		return &ff
+	}
+}
+
+// withoutPagingExtensions drops the atom:link paging relations and the fh: markers.
+func withoutPagingExtensions(extensions []XmlGeneric) []XmlGeneric {
+	var out []XmlGeneric
+	for _, ext := range extensions {
+		if ext.XMLName.Space == NamespaceFeedHistory {
+			continue
+		}
+		if ext.XMLName.Space == "http://www.w3.org/2005/Atom" && ext.XMLName.Local == "link" {
+			paging := false
+			for _, attr := range ext.Attributes {
+				if attr.Name.Local == "rel" && pagingRels[attr.Value] {
+					paging = true
+				}
+			}
+			if paging {
+				continue
+			}
+		}
+		out = append(out, ext)
+	}
+	return out
+}
+
+// trimItems keeps the items of f for which keep holds, at most max of them when max is positive.
+func trimItems(f Feed, keep func(key string, date time.Time, dated bool) bool, max int) {
+	full := func(n int) bool {
+		return max > 0 && n >= max
+	}
+
+	switch ff := f.(type) {
+	case *AtomFeed:
+		var entries []*AtomEntry
+		for _, entry := range ff.Entries {
+			if full(len(entries)) {
+				break
+			}
+			date, dated := atomEntryDate(entry)
+			if keep(atomEntryKey(entry), date, dated) {
+				entries = append(entries, entry)
+			}
+		}
+		ff.Entries = entries
+	case *RssFeed:
+		if ff.Channel == nil {
+			return
+		}
+		var items []*RssItem
+		for _, item := range ff.Channel.Items {
+			if full(len(items)) {
+				break
+			}
+			date, err := ParseDate(item.PubDate)
+			if keep(rssItemKey(item), date, err == nil) {
+				items = append(items, item)
+			}
+		}
+		ff.Channel.Items = items
+	case *JSONFeed:
+		var items []*JSONItem
+		for _, item := range ff.Items {
+			if full(len(items)) {
+				break
+			}
+			date, dated := jsonItemDate(item)
+			if keep(jsonItemKey(item), date, dated) {
+				items = append(items, item)
+			}
+		}
+		ff.Items = items
+	}
+}
+
+func atomEntryKey(entry *AtomEntry) string {
+	if entry.ID != nil && entry.ID.AtomUri != "" {
+		return string(entry.ID.AtomUri)
+	}
+	for _, link := range entry.Links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return string(link.Href)
+		}
+	}
+	return ""
+}
+
+func atomEntryDate(entry *AtomEntry) (time.Time, bool) {
+	for _, d := range []*AtomDateConstruct{entry.Updated, entry.Published} {
+		if d == nil {
+			continue
+		}
+		if t, err := ParseDate(d.DateTime); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func rssItemKey(item *RssItem) string {
+	if item.Guid != nil && item.Guid.Guid != "" {
+		return item.Guid.Guid
+	}
+	return item.Link
+}
+
+func jsonItemKey(item *JSONItem) string {
+	if item.ID != "" {
+		return item.ID
+	}
+	return item.URL
+}
+
+func jsonItemDate(item *JSONItem) (time.Time, bool) {
+	for _, s := range []string{item.DatePublished, item.DateModified} {
+		if s == "" {
+			continue
+		}
+		if t, err := ParseDate(s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,180 @@
+package grss
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func memoryFetch(pages map[string]string, fetched *[]string) FetchFunc {
+	return func(url string) (Feed, error) {
+		*fetched = append(*fetched, url)
+		s, ok := pages[url]
+		if !ok {
+			return nil, fmt.Errorf("%s: not found", url)
+		}
+		_, f, err := Parse(strings.NewReader(s))
+		return f, err
+	}
+}
+
+func atomArchivePage(self, prev string, archive bool, ids ...int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:fh="http://purl.org/syndication/history/1.0">
+  <title>Example Feed</title>
+  <id>urn:uuid:60a76c80-d399-11d9-b93C-0003939e0af6</id>
+  <link rel="self" href="` + self + `"/>
+  <link rel="current" href="http://example.org/index.atom"/>
+`)
+	if prev != "" {
+		b.WriteString(`  <link rel="prev-archive" href="` + prev + `"/>` + "\n")
+	}
+	if archive {
+		b.WriteString("  <fh:archive/>\n")
+	}
+	for _, id := range ids {
+		fmt.Fprintf(&b, `  <entry><id>urn:entry:%d</id><title>Entry %d</title><updated>2003-12-%02dT18:30:02Z</updated></entry>`+"\n", id, id, id)
+	}
+	b.WriteString("</feed>")
+	return b.String()
+}
+
+func Test_Pager_Archive(t *testing.T) {
+	pages := map[string]string{
+		"http://example.org/index.atom":     atomArchivePage("http://example.org/index.atom", "archive/2.atom", false, 7, 6, 5),
+		"http://example.org/archive/2.atom": atomArchivePage("http://example.org/archive/2.atom", "1.atom", true, 5, 4, 3),
+		"http://example.org/archive/1.atom": atomArchivePage("http://example.org/archive/1.atom", "", true, 2, 1),
+	}
+
+	var fetched []string
+	p := &Pager{Fetch: memoryFetch(pages, &fetched)}
+
+	collected, err := p.Collect("http://example.org/index.atom")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"http://example.org/index.atom", "http://example.org/archive/2.atom", "http://example.org/archive/1.atom"}, fetched)
+	assert.Equal(t, 3, len(collected))
+	assert.False(t, collected[0].Paging.Archive)
+	assert.True(t, collected[1].Paging.Archive)
+	assert.Equal(t, "http://example.org/archive/1.atom", collected[1].Paging.PrevArchive)
+	assert.Equal(t, "http://example.org/index.atom", collected[2].Paging.Current)
+
+	f, err := p.Feed("http://example.org/index.atom")
+	assert.Nil(t, err)
+	a := f.(*AtomFeed)
+	assert.Equal(t, 7, len(a.Entries))
+	assert.Equal(t, "Entry 7", a.Entries[0].Title.String())
+	assert.Equal(t, "Entry 1", a.Entries[6].Title.String())
+	for _, link := range a.Links {
+		assert.False(t, pagingRels[link.Rel], link.Rel)
+	}
+	assert.Equal(t, 0, len(a.ExtensionElement), a.ExtensionElement)
+
+	// item count limit
+	fetched = nil
+	p.MaxItems = 4
+	f, err = p.Feed("http://example.org/index.atom")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(f.(*AtomFeed).Entries))
+	assert.Equal(t, 2, len(fetched))
+
+	// date limit
+	fetched = nil
+	p.MaxItems = 0
+	p.Since = time.Date(2003, 12, 4, 0, 0, 0, 0, time.UTC)
+	f, err = p.Feed("http://example.org/index.atom")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(f.(*AtomFeed).Entries))
+	assert.Equal(t, "Entry 4", f.(*AtomFeed).Entries[3].Title.String())
+	assert.Equal(t, 2, len(fetched))
+
+	// page limit
+	fetched = nil
+	p.Since = time.Time{}
+	p.MaxPages = 1
+	collected, err = p.Collect("http://example.org/index.atom")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(collected))
+}
+
+func Test_Pager_Complete(t *testing.T) {
+	s := strings.Replace(atomArchivePage("http://example.org/index.atom", "archive/2.atom", false, 2, 1), "<title>", "<fh:complete/><title>", 1)
+	pages := map[string]string{
+		"http://example.org/index.atom": s,
+	}
+
+	var fetched []string
+	p := &Pager{Fetch: memoryFetch(pages, &fetched)}
+	collected, err := p.Collect("http://example.org/index.atom")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(collected))
+	assert.True(t, collected[0].Paging.Complete)
+}
+
+func Test_Pager_JSONLoop(t *testing.T) {
+	page := func(self, next string, ids ...string) string {
+		var items []string
+		for _, id := range ids {
+			items = append(items, `{"id": "`+id+`", "content_text": "`+id+`"}`)
+		}
+		return `{"version": "https://jsonfeed.org/version/1.1", "title": "paged", "feed_url": "` + self + `", "next_url": "` + next + `", "items": [` + strings.Join(items, ",") + `]}`
+	}
+	pages := map[string]string{
+		"https://example.org/feed.json":     page("https://example.org/feed.json", "/feed.json?p=2", "c", "b"),
+		"https://example.org/feed.json?p=2": page("https://example.org/feed.json?p=2", "https://example.org/feed.json", "b", "a"),
+	}
+
+	var fetched []string
+	p := &Pager{Fetch: memoryFetch(pages, &fetched)}
+	collected, err := p.Collect("https://example.org/feed.json")
+	assert.True(t, errors.Is(err, ErrPageLoop), err)
+	assert.Equal(t, 2, len(collected))
+
+	f, err := p.Feed("https://example.org/feed.json")
+	assert.Nil(t, err)
+	j := f.(*JSONFeed)
+	assert.Equal(t, "", j.NextURL)
+	var ids []string
+	for _, item := range j.Items {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []string{"c", "b", "a"}, ids)
+
+	// a failing fetch is returned with what was fetched before it
+	delete(pages, "https://example.org/feed.json?p=2")
+	collected, err = p.Collect("https://example.org/feed.json")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(collected))
+}
+
+func Test_Pager_Rss(t *testing.T) {
+	page := func(next string, guids ...string) string {
+		var b strings.Builder
+		b.WriteString(`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>paged</title><link>http://example.com/</link><description>d</description>`)
+		if next != "" {
+			b.WriteString(`<atom:link rel="next" href="` + next + `"/>`)
+		}
+		for _, guid := range guids {
+			b.WriteString(`<item><title>` + guid + `</title><guid isPermaLink="false">` + guid + `</guid></item>`)
+		}
+		b.WriteString(`</channel></rss>`)
+		return b.String()
+	}
+	pages := map[string]string{
+		"http://example.com/rss":        page("http://example.com/rss?page=2", "3", "2"),
+		"http://example.com/rss?page=2": page("", "2", "1"),
+	}
+
+	var fetched []string
+	p := &Pager{Fetch: memoryFetch(pages, &fetched)}
+	f, err := p.Feed("http://example.com/rss")
+	assert.Nil(t, err)
+	r := f.(*RssFeed)
+	assert.Equal(t, 3, len(r.Channel.Items))
+	assert.Equal(t, "1", r.Channel.Items[2].Title)
+	assert.Equal(t, 0, len(r.Channel.AtomLinks()))
+	assert.Equal(t, "http://example.com/", r.Channel.Link)
+}
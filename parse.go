@@ -1,6 +1,7 @@
 package grss
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -17,6 +18,12 @@ const (
 	TypeXML     Type = 2
 	TypeXMLAtom Type = 4
 	TypeXMLRss  Type = 8
+	// TypeHTML an HTML document with microformats2 h-feed or h-entry markup, parsed by ParseHTML
+	TypeHTML Type = 16
+	// TypeJSONActivityStreams an Activity Streams 2.0 collection, as an ActivityPub outbox, parsed by ParseActivityStreams
+	TypeJSONActivityStreams Type = 32
+	// TypeXMLSitemap a sitemap or a sitemap index, parsed by ParseSitemap
+	TypeXMLSitemap Type = 64
 )
 
 func DetectType(r io.Reader) Type {
@@ -53,28 +60,44 @@ func Parse(r io.Reader) (Type, Feed, error) {
 	default:
 		return t, nil, fmt.Errorf("unknown type")
 	case TypeJSON:
+		var b json.RawMessage
+		err := json.NewDecoder(mr).Decode(&b)
+		if err != nil {
+			return t, nil, err
+		}
+
+		// an Activity Streams document has a @context, a JSON Feed a version
+		if isActivityStreams(b) {
+			t |= TypeJSONActivityStreams
+			f, err := ParseActivityStreams(bytes.NewReader(b))
+			if err != nil {
+				return t, nil, err
+			}
+			return t, f, nil
+		}
+
 		var f = &JSONFeed{}
-		d := json.NewDecoder(mr)
-		err := d.Decode(f)
+		err = json.Unmarshal(b, f)
 		if err != nil {
 			return t, nil, err
 		}
 		return t, f, nil
 	case TypeXML:
-		var bufx bytes.Buffer
-		teex := io.TeeReader(mr, &bufx)
-		mrx := io.MultiReader(&bufx, mr)
-
-		var s struct {
-			XMLName xml.Name
+		br := bufio.NewReader(mr)
+		if f, ok, err := parseHTMLFeed(br); ok {
+			return TypeHTML, f, err
 		}
 
-		err := newXmlDecoder(teex).Decode(&s)
+		var bufx bytes.Buffer
+		teex := io.TeeReader(br, &bufx)
+		mrx := io.MultiReader(&bufx, br)
+
+		root, doctype, err := xmlRoot(teex)
 		if err != nil {
 			return t, nil, err
 		}
 
-		switch s.XMLName.Local {
+		switch root.Local {
 		case "feed":
 			t |= TypeXMLAtom
 			var f = &AtomFeed{}
@@ -90,9 +113,45 @@ func Parse(r io.Reader) (Type, Feed, error) {
 			if err != nil {
 				return t, nil, err
 			}
+			f.Doctype = doctype
+			f.ParsedVersion = detectRssVersion(f.XMLName, f.Attributes, f.Version, doctype)
 			return t, f, nil
+		case "urlset", "sitemapindex":
+			t |= TypeXMLSitemap
+			f, err := ParseSitemap(mrx)
+			if err != nil {
+				return t, nil, err
+			}
+			return t, f, nil
+		case "html":
+			// XHTML
+			f, err := ParseHTML(mrx, "")
+			if err != nil {
+				return TypeHTML, nil, err
+			}
+			return TypeHTML, f, nil
 		default:
 			return t, nil, fmt.Errorf("unknown xml")
 		}
 	}
 }
+
+// xmlRoot reads up to the root element, returning its name and the DOCTYPE directive if there is one.
+func xmlRoot(r io.Reader) (root xml.Name, doctype string, err error) {
+	d := newXmlDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return root, doctype, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.Directive:
+			if bytes.HasPrefix(bytes.TrimSpace(tok), []byte("DOCTYPE")) {
+				doctype = string(bytes.TrimSpace(tok))
+			}
+		case xml.StartElement:
+			return tok.Name, doctype, nil
+		}
+	}
+}
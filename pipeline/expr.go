@@ -0,0 +1,583 @@
+package pipeline
+
+import (
+	"fmt"
+	"github.com/hellodword/grss"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The expressions of Filter, as in
+//
+//	title =~ /sponsored/i || "ads" in categories
+//	date < "2023-01-01" and not (author == "bot")
+//
+// The fields are id, title, url, content, summary and date, which hold one value, and authors and categories, which hold a list,
+// author and tags being other names of authors and categories.
+//
+//	a == b, a != b     equal strings, or dates; a list is equal when one of its values is
+//	a =~ /re/flags     the regular expression matches, flags are i, m and s; a list matches when one of its values does
+//	a !~ /re/flags     the regular expression does not match
+//	a < b, <=, >, >=   compare strings, or dates, a string compared to the date is parsed as a date
+//	a in b             a is one of the values of the list b, or a substring of b
+//	!a, not a          negation, a field alone is true when it is not empty
+//	a && b, a and b
+//	a || b, a or b
+//
+// Strings are quoted with " or ', with the escapes of Go. A comparison with an item without date is false.
+
+type kind int
+
+const (
+	kindBool kind = iota
+	kindString
+	kindList
+	kindDate
+	kindRegexp
+)
+
+func (k kind) String() string {
+	return [...]string{"bool", "string", "list", "date", "regexp"}[k]
+}
+
+type value struct {
+	b    bool
+	s    string
+	list []string
+	t    time.Time
+	// dated a date value holds a date
+	dated bool
+}
+
+// node a compiled expression, its kind and how it evaluates.
+type node struct {
+	kind kind
+	eval func(item Item) value
+	re   *regexp.Regexp
+	// literal the node is a string literal, which can be compared to dates
+	literal bool
+	text    string
+}
+
+type field struct {
+	kind kind
+	get  func(item Item) value
+}
+
+var fields = map[string]field{
+	"id":      {kindString, func(item Item) value { return value{s: item.ID()} }},
+	"title":   {kindString, func(item Item) value { return value{s: item.Title()} }},
+	"url":     {kindString, func(item Item) value { return value{s: item.URL()} }},
+	"link":    {kindString, func(item Item) value { return value{s: item.URL()} }},
+	"content": {kindString, func(item Item) value { return value{s: item.Content()} }},
+	"summary": {kindString, func(item Item) value { return value{s: item.Summary()} }},
+	"date": {kindDate, func(item Item) value {
+		t, ok := item.Date()
+		return value{t: t, dated: ok}
+	}},
+	"authors":    {kindList, func(item Item) value { return value{list: item.Authors()} }},
+	"author":     {kindList, func(item Item) value { return value{list: item.Authors()} }},
+	"categories": {kindList, func(item Item) value { return value{list: item.Categories()} }},
+	"tags":       {kindList, func(item Item) value { return value{list: item.Categories()} }},
+}
+
+// Expr a compiled filter expression.
+type Expr struct {
+	text string
+	root *node
+}
+
+// Compile compiles an expression, which must be true or false, or a field whose emptiness is tested.
+func Compile(text string) (*Expr, error) {
+	p := &parser{lexer: lexer{text: text}}
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	root, err = truth(root)
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{text: text, root: root}, nil
+}
+
+// MustCompile is Compile for expressions known to be valid, it panics on error.
+func MustCompile(text string) *Expr {
+	e, err := Compile(text)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Match reports whether the expression holds for item.
+func (e *Expr) Match(item Item) bool {
+	return e.root.eval(item).b
+}
+
+func (e *Expr) String() string {
+	return e.text
+}
+
+// SyntaxError an expression that does not compile, Offset is the byte offset of the problem.
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("offset %d: %s", e.Offset, e.Msg)
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokRegexp
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+	// flags of a regular expression
+	flags string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	case tokRegexp:
+		return "/" + t.text + "/" + t.flags
+	default:
+		return strconv.Quote(t.text)
+	}
+}
+
+type lexer struct {
+	text string
+	pos  int
+}
+
+var operators = []string{"||", "&&", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!"}
+
+func (l *lexer) scan() (token, error) {
+	for l.pos < len(l.text) && unicode.IsSpace(rune(l.text[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.text) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	c := l.text[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return token{kind: tokLParen, text: "(", pos: start}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokRParen, text: ")", pos: start}, nil
+	case c == '"' || c == '\'':
+		end := l.pos + 1
+		for end < len(l.text) && l.text[end] != c {
+			if l.text[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(l.text) {
+			return token{}, &SyntaxError{start, "unterminated string"}
+		}
+		raw := l.text[l.pos : end+1]
+		if c == '\'' {
+			raw = `"` + strings.ReplaceAll(strings.ReplaceAll(raw[1:len(raw)-1], `\'`, `'`), `"`, `\"`) + `"`
+		}
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return token{}, &SyntaxError{start, "invalid string " + l.text[l.pos:end+1]}
+		}
+		l.pos = end + 1
+		return token{kind: tokString, text: s, pos: start}, nil
+	case c == '/':
+		var b strings.Builder
+		end := l.pos + 1
+		for ; end < len(l.text) && l.text[end] != '/'; end++ {
+			if l.text[end] == '\\' && end+1 < len(l.text) && l.text[end+1] == '/' {
+				end++
+			}
+			b.WriteByte(l.text[end])
+		}
+		if end >= len(l.text) {
+			return token{}, &SyntaxError{start, "unterminated regular expression"}
+		}
+		end++
+		flagsStart := end
+		for end < len(l.text) && unicode.IsLetter(rune(l.text[end])) {
+			end++
+		}
+		l.pos = end
+		return token{kind: tokRegexp, text: b.String(), flags: l.text[flagsStart:end], pos: start}, nil
+	case c == '_' || unicode.IsLetter(rune(c)):
+		end := l.pos
+		for end < len(l.text) && (l.text[end] == '_' || unicode.IsLetter(rune(l.text[end])) || unicode.IsDigit(rune(l.text[end]))) {
+			end++
+		}
+		l.pos = end
+		return token{kind: tokIdent, text: l.text[start:end], pos: start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.text[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+	return token{}, &SyntaxError{start, fmt.Sprintf("unexpected %q", c)}
+}
+
+type parser struct {
+	lexer
+	tok token
+}
+
+func (p *parser) next() error {
+	tok, err := p.scan()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{p.tok.pos, fmt.Sprintf(format, args...)}
+}
+
+// is reports whether the current token is the operator op, or the keyword of the same meaning.
+func (p *parser) is(op, keyword string) bool {
+	return (p.tok.kind == tokOp && p.tok.text == op) || (keyword != "" && p.tok.kind == tokIdent && p.tok.text == keyword)
+}
+
+// truth the node as a condition, strings, lists and dates are true when they are not empty.
+func truth(n *node) (*node, error) {
+	switch n.kind {
+	case kindBool:
+		return n, nil
+	case kindString:
+		if n.literal {
+			break
+		}
+		return &node{kind: kindBool, eval: func(item Item) value { return value{b: n.eval(item).s != ""} }}, nil
+	case kindList:
+		return &node{kind: kindBool, eval: func(item Item) value { return value{b: len(n.eval(item).list) > 0} }}, nil
+	case kindDate:
+		return &node{kind: kindBool, eval: func(item Item) value { return value{b: n.eval(item).dated} }}, nil
+	}
+	return nil, fmt.Errorf("%s is not a condition", n.text)
+}
+
+func (p *parser) parseOr() (*node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("||", "or") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l, err := truth(left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := truth(right)
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: kindBool, text: l.text + " || " + r.text, eval: func(item Item) value {
+			return value{b: l.eval(item).b || r.eval(item).b}
+		}}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("&&", "and") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l, err := truth(left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := truth(right)
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: kindBool, text: l.text + " && " + r.text, eval: func(item Item) value {
+			return value{b: l.eval(item).b && r.eval(item).b}
+		}}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (*node, error) {
+	if !p.is("!", "not") {
+		return p.parseComparison()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	n, err := truth(operand)
+	if err != nil {
+		return nil, err
+	}
+	return &node{kind: kindBool, text: "!" + n.text, eval: func(item Item) value {
+		return value{b: !n.eval(item).b}
+	}}, nil
+}
+
+func (p *parser) parseComparison() (*node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	switch {
+	case p.tok.kind == tokOp && p.tok.text != "!" && p.tok.text != "&&" && p.tok.text != "||":
+		op = p.tok.text
+	case p.tok.kind == tokIdent && p.tok.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	pos := p.tok.pos
+	if err = p.next(); err != nil {
+		return nil, err
+	}
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	n, err := compare(op, left, right)
+	if err != nil {
+		return nil, &SyntaxError{pos, err.Error()}
+	}
+	n.text = left.text + " " + op + " " + right.text
+	return n, nil
+}
+
+func (p *parser) parsePrimary() (*node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ), got %s", p.tok)
+		}
+		return n, p.next()
+	case tokString:
+		s := tok.text
+		return &node{kind: kindString, literal: true, text: tok.String(), eval: func(Item) value { return value{s: s} }}, p.next()
+	case tokRegexp:
+		flags := ""
+		for _, f := range tok.flags {
+			if !strings.ContainsRune("ims", f) {
+				return nil, p.errorf("unknown flag %q of regular expression", f)
+			}
+		}
+		if tok.flags != "" {
+			flags = "(?" + tok.flags + ")"
+		}
+		re, err := regexp.Compile(flags + tok.text)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		return &node{kind: kindRegexp, re: re, text: tok.String()}, p.next()
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			b := tok.text == "true"
+			return &node{kind: kindBool, text: tok.text, eval: func(Item) value { return value{b: b} }}, p.next()
+		}
+		f, ok := fields[tok.text]
+		if !ok {
+			return nil, p.errorf("unknown field %q", tok.text)
+		}
+		return &node{kind: f.kind, text: tok.text, eval: f.get}, p.next()
+	default:
+		return nil, p.errorf("unexpected %s", tok)
+	}
+}
+
+// compare compiles left op right, checking the kinds of the operands.
+func compare(op string, left, right *node) (*node, error) {
+	mismatch := fmt.Errorf("cannot compare %s %s %s", left.kind, op, right.kind)
+
+	// a string literal compared to a date is a date
+	if left.kind == kindDate && right.literal {
+		t, err := grss.ParseDate(right.eval(nil).s)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a date", right.text)
+		}
+		right = &node{kind: kindDate, text: right.text, eval: func(Item) value { return value{t: t, dated: true} }}
+	}
+	if right.kind == kindDate && left.literal {
+		left, right = right, left
+		op = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", "==": "==", "!=": "!="}[op]
+		if op == "" {
+			return nil, mismatch
+		}
+		return compare(op, left, right)
+	}
+
+	negate := func(n *node) *node {
+		return &node{kind: kindBool, eval: func(item Item) value { return value{b: !n.eval(item).b} }}
+	}
+
+	switch op {
+	case "=~", "!~":
+		if right.kind != kindRegexp || (left.kind != kindString && left.kind != kindList) {
+			return nil, mismatch
+		}
+		re := right.re
+		n := &node{kind: kindBool, eval: func(item Item) value {
+			return value{b: anyValue(left.eval(item), left.kind, re.MatchString)}
+		}}
+		if op == "!~" {
+			n = negate(n)
+		}
+		return n, nil
+
+	case "in":
+		if left.kind != kindString {
+			return nil, mismatch
+		}
+		switch right.kind {
+		case kindList:
+			return &node{kind: kindBool, eval: func(item Item) value {
+				s := left.eval(item).s
+				return value{b: anyValue(right.eval(item), kindList, func(v string) bool { return v == s })}
+			}}, nil
+		case kindString:
+			return &node{kind: kindBool, eval: func(item Item) value {
+				return value{b: strings.Contains(right.eval(item).s, left.eval(item).s)}
+			}}, nil
+		}
+		return nil, mismatch
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		if left.kind == kindList && right.kind == kindString && (op == "==" || op == "!=") {
+			n := &node{kind: kindBool, eval: func(item Item) value {
+				s := right.eval(item).s
+				return value{b: anyValue(left.eval(item), kindList, func(v string) bool { return v == s })}
+			}}
+			if op == "!=" {
+				n = negate(n)
+			}
+			return n, nil
+		}
+		if left.kind == kindString && right.kind == kindList {
+			return compare(op, right, left)
+		}
+		if left.kind != right.kind || (left.kind != kindString && left.kind != kindDate) {
+			return nil, mismatch
+		}
+
+		if left.kind == kindDate {
+			return &node{kind: kindBool, eval: func(item Item) value {
+				a, b := left.eval(item), right.eval(item)
+				if !a.dated || !b.dated {
+					return value{b: op == "!="}
+				}
+				return value{b: ordered(op, compareTimes(a.t, b.t))}
+			}}, nil
+		}
+		return &node{kind: kindBool, eval: func(item Item) value {
+			return value{b: ordered(op, strings.Compare(left.eval(item).s, right.eval(item).s))}
+		}}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+// anyValue reports whether fn holds for the string, or one of the values of the list.
+func anyValue(v value, k kind, fn func(string) bool) bool {
+	if k == kindString {
+		return fn(v.s)
+	}
+	for _, s := range v.list {
+		if fn(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func ordered(op string, c int) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
@@ -0,0 +1,446 @@
+package pipeline
+
+import (
+	"github.com/hellodword/grss"
+	"time"
+)
+
+// Item the fields of an RssItem, an AtomEntry or a JSONItem the stages read and rewrite.
+// The setters never change what the item points to, they replace it, so an item copied by Run can be rewritten without changing the feed it was copied from.
+type Item interface {
+	// ID the guid, id or, when there is none, the url of the item
+	ID() string
+	Title() string
+	SetTitle(s string)
+	// URL the link, the alternate link or the url of the item
+	URL() string
+	SetURL(s string)
+	// Content the HTML of the item, content:encoded or description, content, content_html or content_text
+	Content() string
+	SetContent(s string)
+	// Summary the description of an RSS item that has a content:encoded, the summary of an Atom entry or a JSON item
+	Summary() string
+	SetSummary(s string)
+	// Authors the names, or the email addresses, of the authors
+	Authors() []string
+	// Categories the categories, Atom terms or JSON tags
+	Categories() []string
+	// Date the date the item was last published or updated
+	Date() (time.Time, bool)
+	// RewriteLinks replaces the links of the item, the url, the comments and the enclosures, but not the ID, which readers use to know the items they have seen
+	RewriteLinks(rewrite func(string) string)
+	// Unwrap the *grss.RssItem, *grss.AtomEntry or *grss.JSONItem
+	Unwrap() interface{}
+}
+
+// Items the items of f, whose setters change f.
+func Items(f grss.Feed) []Item {
+	var items []Item
+	switch ff := f.(type) {
+	case *grss.RssFeed:
+		if ff.Channel != nil {
+			for _, item := range ff.Channel.Items {
+				items = append(items, &rssItem{item})
+			}
+		}
+		for _, item := range ff.Items {
+			items = append(items, &rssItem{item})
+		}
+	case *grss.AtomFeed:
+		for _, entry := range ff.Entries {
+			items = append(items, &atomEntry{entry})
+		}
+	case *grss.JSONFeed:
+		for _, item := range ff.Items {
+			items = append(items, &jsonItem{item})
+		}
+	}
+	return items
+}
+
+// copyItems copies the items of f, so that they can be rewritten without changing f.
+func copyItems(f grss.Feed) []Item {
+	items := Items(f)
+	for i, item := range items {
+		switch it := item.Unwrap().(type) {
+		case *grss.RssItem:
+			c := *it
+			items[i] = &rssItem{&c}
+		case *grss.AtomEntry:
+			c := *it
+			items[i] = &atomEntry{&c}
+		case *grss.JSONItem:
+			c := *it
+			items[i] = &jsonItem{&c}
+		}
+	}
+	return items
+}
+
+// withItems a copy of f holding items.
+func withItems(f grss.Feed, items []Item) grss.Feed {
+	switch ff := f.(type) {
+	case *grss.RssFeed:
+		var rss []*grss.RssItem
+		for _, item := range items {
+			rss = append(rss, item.Unwrap().(*grss.RssItem))
+		}
+		out := *ff
+		if ff.Channel == nil || (len(ff.Channel.Items) == 0 && len(ff.Items) > 0) {
+			// RSS 0.90 puts the items next to the channel
+			out.Items = rss
+			return &out
+		}
+		channel := *ff.Channel
+		channel.Items = rss
+		out.Channel = &channel
+		out.Items = nil
+		return &out
+	case *grss.AtomFeed:
+		out := *ff
+		out.Entries = nil
+		for _, item := range items {
+			out.Entries = append(out.Entries, item.Unwrap().(*grss.AtomEntry))
+		}
+		return &out
+	case *grss.JSONFeed:
+		out := *ff
+		out.Items = nil
+		for _, item := range items {
+			out.Items = append(out.Items, item.Unwrap().(*grss.JSONItem))
+		}
+		return &out
+	default:
+		return f
+	}
+}
+
+// setText the text s written the way t was, as text, CDATA or inner XML.
+func setText(t *grss.XmlText, s string) *grss.XmlText {
+	switch {
+	case t == nil || t.Text != "":
+		return &grss.XmlText{Text: s}
+	case t.Cdata != "":
+		return &grss.XmlText{Cdata: s}
+	case t.InnerXml != "":
+		return &grss.XmlText{InnerXml: s}
+	default:
+		return &grss.XmlText{Text: s}
+	}
+}
+
+func parseDates(dates ...string) (time.Time, bool) {
+	for _, s := range dates {
+		if s == "" {
+			continue
+		}
+		if t, err := grss.ParseDate(s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+type rssItem struct {
+	*grss.RssItem
+}
+
+func (r *rssItem) ID() string {
+	if r.Guid != nil && r.Guid.Guid != "" {
+		return r.Guid.Guid
+	}
+	return r.Link
+}
+
+func (r *rssItem) Title() string { return r.RssItem.Title }
+
+func (r *rssItem) SetTitle(s string) { r.RssItem.Title = s }
+
+func (r *rssItem) URL() string {
+	if r.Link == "" && r.Guid != nil && r.Guid.IsPermaLink != "false" {
+		return r.Guid.Guid
+	}
+	return r.Link
+}
+
+func (r *rssItem) SetURL(s string) { r.Link = s }
+
+func (r *rssItem) Content() string {
+	if r.ContentEncoded != nil {
+		return r.ContentEncoded.String()
+	}
+	return r.Description.String()
+}
+
+func (r *rssItem) SetContent(s string) {
+	if r.ContentEncoded != nil {
+		r.ContentEncoded = &grss.RssContent{XMLName: r.ContentEncoded.XMLName, XmlText: *setText(&r.ContentEncoded.XmlText, s)}
+		return
+	}
+	r.Description = setText(r.Description, s)
+}
+
+func (r *rssItem) Summary() string {
+	if r.ContentEncoded != nil {
+		return r.Description.String()
+	}
+	return ""
+}
+
+// SetSummary sets the description of an item that has a content:encoded, the description of the other items is their content.
+func (r *rssItem) SetSummary(s string) {
+	if r.ContentEncoded != nil {
+		r.Description = setText(r.Description, s)
+	}
+}
+
+func (r *rssItem) Authors() []string {
+	if r.Author != nil && r.Author.Email != "" {
+		return []string{r.Author.Email}
+	}
+	return nil
+}
+
+func (r *rssItem) Categories() []string {
+	var categories []string
+	for _, c := range r.RssItem.Categories {
+		categories = append(categories, c.Text)
+	}
+	return categories
+}
+
+func (r *rssItem) Date() (time.Time, bool) {
+	return parseDates(r.PubDate)
+}
+
+func (r *rssItem) RewriteLinks(rewrite func(string) string) {
+	if r.Link != "" {
+		r.Link = rewrite(r.Link)
+	}
+	if r.Comments != "" {
+		r.Comments = rewrite(r.Comments)
+	}
+	if len(r.Enclosures) > 0 {
+		enclosures := make([]*grss.RssEnclosure, len(r.Enclosures))
+		for i, e := range r.Enclosures {
+			c := *e
+			c.Url = rewrite(c.Url)
+			enclosures[i] = &c
+		}
+		r.Enclosures = enclosures
+	}
+}
+
+func (r *rssItem) Unwrap() interface{} { return r.RssItem }
+
+type atomEntry struct {
+	*grss.AtomEntry
+}
+
+func (a *atomEntry) ID() string {
+	if a.AtomEntry.ID != nil && a.AtomEntry.ID.AtomUri != "" {
+		return string(a.AtomEntry.ID.AtomUri)
+	}
+	return a.URL()
+}
+
+func (a *atomEntry) Title() string {
+	if a.AtomEntry.Title == nil {
+		return ""
+	}
+	return a.AtomEntry.Title.String()
+}
+
+// setTextConstruct the text construct t with the text s, xhtml becomes html.
+func setTextConstruct(t *grss.AtomTextConstruct, s string) *grss.AtomTextConstruct {
+	c := grss.AtomTextConstruct{}
+	if t != nil {
+		c = *t
+	}
+	if c.Type == "xhtml" {
+		c.Type = "html"
+	}
+	c.Div = nil
+	c.XmlText = *setText(&c.XmlText, s)
+	return &c
+}
+
+func (a *atomEntry) SetTitle(s string) { a.AtomEntry.Title = setTextConstruct(a.AtomEntry.Title, s) }
+
+func (a *atomEntry) alternate() int {
+	for i, link := range a.Links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return i
+		}
+	}
+	return -1
+}
+
+func (a *atomEntry) URL() string {
+	if i := a.alternate(); i >= 0 {
+		return string(a.Links[i].Href)
+	}
+	return ""
+}
+
+func (a *atomEntry) SetURL(s string) {
+	links := append([]*grss.AtomLink(nil), a.Links...)
+	if i := a.alternate(); i >= 0 {
+		link := *links[i]
+		link.Href = grss.AtomUri(s)
+		links[i] = &link
+	} else {
+		links = append(links, &grss.AtomLink{Href: grss.AtomUri(s), Rel: "alternate"})
+	}
+	a.Links = links
+}
+
+func (a *atomEntry) Content() string {
+	if a.AtomEntry.Content == nil {
+		return ""
+	}
+	return a.AtomEntry.Content.String()
+}
+
+func (a *atomEntry) SetContent(s string) {
+	c := grss.AtomContent{}
+	if a.AtomEntry.Content != nil {
+		c = *a.AtomEntry.Content
+	}
+	if c.Type == "xhtml" {
+		c.Type = "html"
+	}
+	c.Div = nil
+	c.XmlText = *setText(&c.XmlText, s)
+	a.AtomEntry.Content = &c
+}
+
+func (a *atomEntry) Summary() string {
+	if a.AtomEntry.Summary == nil {
+		return ""
+	}
+	return a.AtomEntry.Summary.String()
+}
+
+func (a *atomEntry) SetSummary(s string) {
+	a.AtomEntry.Summary = setTextConstruct(a.AtomEntry.Summary, s)
+}
+
+func (a *atomEntry) Authors() []string {
+	var authors []string
+	for _, author := range a.AtomEntry.Authors {
+		if author.Name != "" {
+			authors = append(authors, author.Name)
+		} else if author.Email != "" {
+			authors = append(authors, string(author.Email))
+		}
+	}
+	return authors
+}
+
+func (a *atomEntry) Categories() []string {
+	var categories []string
+	for _, c := range a.AtomEntry.Categories {
+		categories = append(categories, c.Term)
+	}
+	return categories
+}
+
+func (a *atomEntry) Date() (time.Time, bool) {
+	var dates []string
+	for _, d := range []*grss.AtomDateConstruct{a.Updated, a.Published} {
+		if d != nil {
+			dates = append(dates, d.DateTime)
+		}
+	}
+	return parseDates(dates...)
+}
+
+func (a *atomEntry) RewriteLinks(rewrite func(string) string) {
+	if len(a.Links) == 0 {
+		return
+	}
+	links := make([]*grss.AtomLink, len(a.Links))
+	for i, link := range a.Links {
+		c := *link
+		c.Href = grss.AtomUri(rewrite(string(c.Href)))
+		links[i] = &c
+	}
+	a.Links = links
+}
+
+func (a *atomEntry) Unwrap() interface{} { return a.AtomEntry }
+
+type jsonItem struct {
+	*grss.JSONItem
+}
+
+func (j *jsonItem) ID() string {
+	if j.JSONItem.ID != "" {
+		return j.JSONItem.ID
+	}
+	return j.JSONItem.URL
+}
+
+func (j *jsonItem) Title() string { return j.JSONItem.Title }
+
+func (j *jsonItem) SetTitle(s string) { j.JSONItem.Title = s }
+
+func (j *jsonItem) URL() string { return j.JSONItem.URL }
+
+func (j *jsonItem) SetURL(s string) { j.JSONItem.URL = s }
+
+func (j *jsonItem) Content() string {
+	if j.ContentHTML != "" {
+		return j.ContentHTML
+	}
+	return j.ContentText
+}
+
+func (j *jsonItem) SetContent(s string) {
+	if j.ContentHTML == "" && j.ContentText != "" {
+		j.ContentText = s
+		return
+	}
+	j.ContentHTML = s
+}
+
+func (j *jsonItem) Summary() string { return j.JSONItem.Summary }
+
+func (j *jsonItem) SetSummary(s string) { j.JSONItem.Summary = s }
+
+func (j *jsonItem) Authors() []string {
+	var authors []string
+	for _, author := range append(j.JSONItem.Authors[:len(j.JSONItem.Authors):len(j.JSONItem.Authors)], j.Author) {
+		if author != nil && author.Name != "" {
+			authors = append(authors, author.Name)
+		}
+	}
+	return authors
+}
+
+func (j *jsonItem) Categories() []string { return j.Tags }
+
+func (j *jsonItem) Date() (time.Time, bool) {
+	return parseDates(j.DateModified, j.DatePublished)
+}
+
+func (j *jsonItem) RewriteLinks(rewrite func(string) string) {
+	if j.JSONItem.URL != "" {
+		j.JSONItem.URL = rewrite(j.JSONItem.URL)
+	}
+	if j.ExternalURL != "" {
+		j.ExternalURL = rewrite(j.ExternalURL)
+	}
+	if len(j.Attachments) > 0 {
+		attachments := make([]*grss.JSONAttachments, len(j.Attachments))
+		for i, a := range j.Attachments {
+			c := *a
+			c.URL = rewrite(c.URL)
+			attachments[i] = &c
+		}
+		j.Attachments = attachments
+	}
+}
+
+func (j *jsonItem) Unwrap() interface{} { return j.JSONItem }
@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a pipeline written as text, a stage per line, blank lines and lines starting with # ignored:
+//
+//	# drop the ads
+//	filter !(title =~ /sponsored/i || "ads" in categories)
+//	exclude author == "bot"
+//	rewrite title /^\[[^]]*\]\s*/ ""
+//	clean-urls
+//	dedupe url
+//	sort date
+//	limit 20
+//
+// The stages are
+//
+//	filter <expression>                   keep the items for which the expression holds, see Compile
+//	exclude <expression>                  drop the items for which the expression holds
+//	rewrite <field> /re/flags "repl"      Rewrite title, url, content or summary
+//	limit <n>                             Limit
+//	sort <field> [asc|desc]               SortBy date, newest first by default, title, url or id, ascending by default
+//	dedupe [id|url|title]                 Dedupe, by id by default
+//	clean-urls [param...]                 CleanURLs, param* matches the parameters starting with param
+func Parse(text string) (Pipeline, error) {
+	var p Pipeline
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	line := 0
+	for scanner.Scan() {
+		line++
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		stage, err := parseStage(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		p = append(p, stage)
+	}
+	return p, scanner.Err()
+}
+
+func parseStage(s string) (Stage, error) {
+	args := strings.Fields(s)
+	name := args[0]
+	rest := strings.TrimSpace(s[len(name):])
+	args = args[1:]
+
+	switch name {
+	case "filter", "exclude":
+		if rest == "" {
+			return nil, fmt.Errorf("%s needs an expression", name)
+		}
+		e, err := Compile(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if name == "exclude" {
+			return StageFunc(func(items []Item) []Item {
+				var kept []Item
+				for _, item := range items {
+					if !e.Match(item) {
+						kept = append(kept, item)
+					}
+				}
+				return kept
+			}), nil
+		}
+		return Filter(e), nil
+
+	case "rewrite":
+		l := lexer{text: rest}
+		var toks []token
+		for {
+			tok, err := l.scan()
+			if err != nil {
+				return nil, fmt.Errorf("rewrite: %w", err)
+			}
+			if tok.kind == tokEOF {
+				break
+			}
+			toks = append(toks, tok)
+		}
+		if len(toks) != 3 || toks[0].kind != tokIdent || toks[1].kind != tokRegexp || toks[2].kind != tokString {
+			return nil, fmt.Errorf(`rewrite needs a field, a regular expression and a replacement, as in rewrite title /^ad: /i ""`)
+		}
+		flags := ""
+		if toks[1].flags != "" {
+			flags = "(?" + toks[1].flags + ")"
+		}
+		re, err := regexp.Compile(flags + toks[1].text)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite: %w", err)
+		}
+		return Rewrite(toks[0].text, re, toks[2].text)
+
+	case "limit":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("limit needs a number")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("limit needs a number, got %q", args[0])
+		}
+		return Limit(n), nil
+
+	case "sort":
+		if len(args) == 0 || len(args) > 2 {
+			return nil, fmt.Errorf("sort needs a field and an optional order, asc or desc")
+		}
+		desc := args[0] == "date"
+		if len(args) == 2 {
+			switch args[1] {
+			case "asc":
+				desc = false
+			case "desc":
+				desc = true
+			default:
+				return nil, fmt.Errorf("sort order %q, expected asc or desc", args[1])
+			}
+		}
+		return SortBy(args[0], desc)
+
+	case "dedupe":
+		if len(args) > 1 {
+			return nil, fmt.Errorf("dedupe takes one field")
+		}
+		field := "id"
+		if len(args) == 1 {
+			field = args[0]
+		}
+		return Dedupe(field)
+
+	case "clean-urls":
+		return CleanURLs(args...), nil
+
+	default:
+		return nil, fmt.Errorf("unknown stage %q", name)
+	}
+}
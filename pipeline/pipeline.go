@@ -0,0 +1,243 @@
+// Package pipeline filters, rewrites, sorts and trims the items of RSS, Atom and JSON feeds alike.
+//
+//	p := pipeline.Pipeline{
+//		pipeline.Filter(pipeline.MustCompile(`!(title =~ /sponsored/i || "ads" in categories)`)),
+//		pipeline.CleanURLs(),
+//		pipeline.MustDedupe("url"),
+//		pipeline.MustSortBy("date", true),
+//		pipeline.Limit(20),
+//	}
+//	f = p.Run(f)
+//
+// Pipelines can also be written as text, see Parse.
+package pipeline
+
+import (
+	"fmt"
+	"github.com/hellodword/grss"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Stage a step of a pipeline, it returns the items to pass to the next stage.
+type Stage interface {
+	Apply(items []Item) []Item
+}
+
+// StageFunc a function as a Stage.
+type StageFunc func(items []Item) []Item
+
+func (fn StageFunc) Apply(items []Item) []Item {
+	return fn(items)
+}
+
+// Pipeline stages run in order.
+type Pipeline []Stage
+
+// Run returns a copy of f whose items went through the stages, f is left as it was.
+// The feed keeps its format, the items of an RssFeed stay RssItems.
+func (p Pipeline) Run(f grss.Feed) grss.Feed {
+	items := copyItems(f)
+	for _, stage := range p {
+		items = stage.Apply(items)
+	}
+	return withItems(f, items)
+}
+
+// Filter keeps the items for which e holds.
+func Filter(e *Expr) Stage {
+	return StageFunc(func(items []Item) []Item {
+		var kept []Item
+		for _, item := range items {
+			if e.Match(item) {
+				kept = append(kept, item)
+			}
+		}
+		return kept
+	})
+}
+
+// Map calls fn on each item, to rewrite it with the setters of Item, or the fields of the item returned by Unwrap.
+func Map(fn func(item Item)) Stage {
+	return StageFunc(func(items []Item) []Item {
+		for _, item := range items {
+			fn(item)
+		}
+		return items
+	})
+}
+
+// textFields the fields Rewrite can change.
+var textFields = map[string]struct {
+	get func(item Item) string
+	set func(item Item, s string)
+}{
+	"title":   {Item.Title, Item.SetTitle},
+	"url":     {Item.URL, Item.SetURL},
+	"content": {Item.Content, Item.SetContent},
+	"summary": {Item.Summary, Item.SetSummary},
+}
+
+// Rewrite replaces the matches of re in a field, title, url, content or summary, with repl, which may refer to the submatches as $1, as regexp.ReplaceAllString.
+func Rewrite(field string, re *regexp.Regexp, repl string) (Stage, error) {
+	f, ok := textFields[field]
+	if !ok {
+		return nil, fmt.Errorf("cannot rewrite %q, only title, url, content and summary", field)
+	}
+	return Map(func(item Item) {
+		s := f.get(item)
+		if r := re.ReplaceAllString(s, repl); r != s {
+			f.set(item, r)
+		}
+	}), nil
+}
+
+// Limit keeps the first n items.
+func Limit(n int) Stage {
+	return StageFunc(func(items []Item) []Item {
+		if n >= 0 && len(items) > n {
+			return items[:n]
+		}
+		return items
+	})
+}
+
+// SortBy sorts the items by date, title, url or id, in descending order when desc is true.
+// Items without date are sorted last in both orders, items that compare equal keep their order.
+func SortBy(field string, desc bool) (Stage, error) {
+	if field == "date" {
+		return StageFunc(func(items []Item) []Item {
+			sort.SliceStable(items, func(i, j int) bool {
+				a, aok := items[i].Date()
+				b, bok := items[j].Date()
+				switch {
+				case !aok || !bok:
+					return aok && !bok
+				case desc:
+					return a.After(b)
+				default:
+					return a.Before(b)
+				}
+			})
+			return items
+		}), nil
+	}
+
+	get, ok := map[string]func(item Item) string{
+		"title": Item.Title,
+		"url":   Item.URL,
+		"id":    Item.ID,
+	}[field]
+	if !ok {
+		return nil, fmt.Errorf("cannot sort by %q, only date, title, url and id", field)
+	}
+	return StageFunc(func(items []Item) []Item {
+		sort.SliceStable(items, func(i, j int) bool {
+			if desc {
+				return get(items[i]) > get(items[j])
+			}
+			return get(items[i]) < get(items[j])
+		})
+		return items
+	}), nil
+}
+
+// MustSortBy is SortBy for fields known to be valid, it panics on error.
+func MustSortBy(field string, desc bool) Stage {
+	stage, err := SortBy(field, desc)
+	if err != nil {
+		panic(err)
+	}
+	return stage
+}
+
+// Dedupe keeps the first of the items with the same id, url or title, items without one are all kept.
+func Dedupe(field string) (Stage, error) {
+	get, ok := map[string]func(item Item) string{
+		"id":    Item.ID,
+		"url":   Item.URL,
+		"title": func(item Item) string { return strings.ToLower(strings.TrimSpace(item.Title())) },
+	}[field]
+	if !ok {
+		return nil, fmt.Errorf("cannot dedupe by %q, only id, url and title", field)
+	}
+	return StageFunc(func(items []Item) []Item {
+		seen := map[string]bool{}
+		var kept []Item
+		for _, item := range items {
+			key := get(item)
+			if key != "" && seen[key] {
+				continue
+			}
+			seen[key] = true
+			kept = append(kept, item)
+		}
+		return kept
+	}), nil
+}
+
+// MustDedupe is Dedupe for fields known to be valid, it panics on error.
+func MustDedupe(field string) Stage {
+	stage, err := Dedupe(field)
+	if err != nil {
+		panic(err)
+	}
+	return stage
+}
+
+// TrackingParams the query parameters CleanURLs removes by default, a trailing * matches any suffix, those grss.CanonicalURL removes.
+var TrackingParams = grss.TrackingParams
+
+// CleanURLs removes the tracking parameters from the links of the items, TrackingParams when none are given.
+func CleanURLs(params ...string) Stage {
+	if len(params) == 0 {
+		params = TrackingParams
+	}
+	return Map(func(item Item) {
+		item.RewriteLinks(func(s string) string {
+			return CleanURL(s, params...)
+		})
+	})
+}
+
+// CleanURL s without the query parameters named by params, TrackingParams when none are given.
+// The rest of the URL is left as written, s is returned as it is when it has none of the parameters.
+func CleanURL(s string, params ...string) string {
+	if len(params) == 0 {
+		params = TrackingParams
+	}
+	base, fragment := s, ""
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		base, fragment = s[:i], s[i:]
+	}
+	i := strings.IndexByte(base, '?')
+	if i < 0 {
+		return s
+	}
+	base, rawQuery := base[:i], base[i+1:]
+
+	// the pairs are filtered as written, url.Values would reorder and escape them again
+	pairs := strings.Split(rawQuery, "&")
+	var kept []string
+	for _, pair := range pairs {
+		name := pair
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			name = pair[:i]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !grss.MatchParam(name, params) {
+			kept = append(kept, pair)
+		}
+	}
+	if len(kept) == len(pairs) {
+		return s
+	}
+	if len(kept) > 0 {
+		base += "?" + strings.Join(kept, "&")
+	}
+	return base + fragment
+}
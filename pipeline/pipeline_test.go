@@ -0,0 +1,259 @@
+package pipeline
+
+import (
+	"bytes"
+	"github.com/hellodword/grss"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testRss = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Example</title>
+    <link>https://example.com/</link>
+    <description>An example</description>
+    <item>
+      <title>[News] Hello</title>
+      <link>https://example.com/hello?utm_source=rss&amp;id=1&amp;fbclid=x</link>
+      <guid>1</guid>
+      <author>alice@example.com (Alice)</author>
+      <category>news</category>
+      <pubDate>Mon, 02 Jan 2023 10:00:00 GMT</pubDate>
+      <description>Hello world</description>
+    </item>
+    <item>
+      <title>Sponsored: buy things</title>
+      <link>https://example.com/ad</link>
+      <guid>2</guid>
+      <pubDate>Wed, 04 Jan 2023 10:00:00 GMT</pubDate>
+    </item>
+    <item>
+      <title>Cheap</title>
+      <link>https://example.com/cheap</link>
+      <guid>3</guid>
+      <category>ads</category>
+      <pubDate>Tue, 03 Jan 2023 10:00:00 GMT</pubDate>
+    </item>
+    <item>
+      <title>[News] Later</title>
+      <link>https://example.com/later</link>
+      <guid>4</guid>
+      <pubDate>Thu, 05 Jan 2023 10:00:00 GMT</pubDate>
+      <description>Later</description>
+      <content:encoded><![CDATA[<p>Later</p>]]></content:encoded>
+    </item>
+    <item>
+      <title>Hello again</title>
+      <link>https://example.com/hello?id=1</link>
+      <guid>5</guid>
+    </item>
+  </channel>
+</rss>`
+
+const testAtom = `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example</title>
+  <id>https://example.com/</id>
+  <updated>2023-01-05T10:00:00Z</updated>
+  <entry>
+    <title>[News] Hello</title>
+    <link href="https://example.com/hello?utm_source=rss&amp;id=1&amp;fbclid=x"/>
+    <id>1</id>
+    <author><name>Alice</name></author>
+    <category term="news"/>
+    <updated>2023-01-02T10:00:00Z</updated>
+    <content>Hello world</content>
+  </entry>
+  <entry>
+    <title>Sponsored: buy things</title>
+    <link rel="alternate" href="https://example.com/ad"/>
+    <id>2</id>
+    <updated>2023-01-04T10:00:00Z</updated>
+  </entry>
+  <entry>
+    <title>Cheap</title>
+    <link href="https://example.com/cheap"/>
+    <id>3</id>
+    <category term="ads"/>
+    <updated>2023-01-03T10:00:00Z</updated>
+  </entry>
+  <entry>
+    <title type="html">[News] Later</title>
+    <link href="https://example.com/later"/>
+    <id>4</id>
+    <updated>2023-01-05T10:00:00Z</updated>
+    <summary>Later</summary>
+    <content type="html">&lt;p&gt;Later&lt;/p&gt;</content>
+  </entry>
+  <entry>
+    <title>Hello again</title>
+    <link href="https://example.com/hello?id=1"/>
+    <id>5</id>
+  </entry>
+</feed>`
+
+const testJSON = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Example",
+  "items": [
+    {"id": "1", "title": "[News] Hello", "url": "https://example.com/hello?utm_source=rss&id=1&fbclid=x", "authors": [{"name": "Alice"}], "tags": ["news"], "date_published": "2023-01-02T10:00:00Z", "content_text": "Hello world"},
+    {"id": "2", "title": "Sponsored: buy things", "url": "https://example.com/ad", "date_published": "2023-01-04T10:00:00Z"},
+    {"id": "3", "title": "Cheap", "url": "https://example.com/cheap", "tags": ["ads"], "date_published": "2023-01-03T10:00:00Z"},
+    {"id": "4", "title": "[News] Later", "url": "https://example.com/later", "date_published": "2023-01-05T10:00:00Z", "summary": "Later", "content_html": "<p>Later</p>"},
+    {"id": "5", "title": "Hello again", "url": "https://example.com/hello?id=1"}
+  ]
+}`
+
+const testPipeline = `
+# drop the ads
+filter !(title =~ /sponsored/i || "ads" in categories)
+rewrite title /^\[[^]]*\]\s*/ ""
+clean-urls
+dedupe url
+sort date
+limit 10
+`
+
+func parse(t *testing.T, s string) grss.Feed {
+	_, f, err := grss.Parse(strings.NewReader(s))
+	assert.Nil(t, err)
+	return f
+}
+
+func titles(f grss.Feed) []string {
+	var s []string
+	for _, item := range Items(f) {
+		s = append(s, item.Title()+" "+item.URL())
+	}
+	return s
+}
+
+func Test_Pipeline(t *testing.T) {
+	p, err := Parse(testPipeline)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(p))
+
+	expected := []string{
+		"Later https://example.com/later",
+		"Hello https://example.com/hello?id=1",
+	}
+
+	// the same items, whatever the format
+	for _, f := range []grss.Feed{parse(t, testRss), parse(t, testAtom), parse(t, testJSON)} {
+		out := p.Run(f)
+		assert.IsType(t, f, out)
+		assert.Equal(t, expected, titles(out))
+
+		// f is left as it was
+		assert.Equal(t, 5, len(Items(f)))
+		assert.Equal(t, "[News] Hello", Items(f)[0].Title())
+
+		var b bytes.Buffer
+		assert.Nil(t, out.WriteOut(&b))
+		assert.NotContains(t, b.String(), "utm_source")
+	}
+
+	// the example of the package
+	p = Pipeline{
+		Filter(MustCompile(`!(title =~ /sponsored/i || "ads" in categories)`)),
+		CleanURLs(),
+		MustDedupe("url"),
+		MustSortBy("date", true),
+		Limit(20),
+	}
+	assert.Equal(t, []string{"[News] Later https://example.com/later", "[News] Hello https://example.com/hello?id=1"}, titles(p.Run(parse(t, testRss))))
+	assert.Panics(t, func() { MustSortBy("author", true) })
+	assert.Panics(t, func() { MustDedupe("content") })
+
+	// Map and Unwrap
+	rss := parse(t, testRss)
+	out := Pipeline{Map(func(item Item) {
+		item.Unwrap().(*grss.RssItem).Comments = "https://example.com/comments"
+	}), Limit(1)}.Run(rss)
+	assert.Equal(t, "https://example.com/comments", out.(*grss.RssFeed).Channel.Items[0].Comments)
+	assert.Equal(t, "", rss.(*grss.RssFeed).Channel.Items[0].Comments)
+}
+
+func Test_Expr(t *testing.T) {
+	for expr, expected := range map[string][]bool{
+		`title =~ /sponsored/i || "ads" in categories`: {false, true, true, false, false},
+		`"news" in tags and not (content =~ /later/i)`: {true, false, false, false, false},
+		`date >= "2023-01-04"`:                         {false, true, false, true, false},
+		`"2023-01-04" > date`:                          {true, false, true, false, false},
+		`date != "2023-01-04T10:00:00Z"`:               {true, false, true, true, true},
+		`!date`:                                        {false, false, false, false, true},
+		`authors =~ /Alice/`:                           {true, false, false, false, false},
+		`authors =~ /alice/ && categories != "news"`:   {false, false, false, false, false},
+		`summary`: {false, false, false, true, false},
+		`"again" in title || url !~ /example\.com\/[a-h]/`: {false, false, false, true, true},
+		`id < '3' && true`: {true, true, false, false, false},
+	} {
+		e, err := Compile(expr)
+		if !assert.Nil(t, err, expr) {
+			continue
+		}
+		for _, f := range []grss.Feed{parse(t, testRss), parse(t, testAtom), parse(t, testJSON)} {
+			var got []bool
+			for _, item := range Items(f) {
+				got = append(got, e.Match(item))
+			}
+			assert.Equal(t, expected, got, expr)
+		}
+	}
+
+	for expr, msg := range map[string]string{
+		`title =~`:               "offset 8: unexpected end of expression",
+		`titel == "x"`:           `offset 0: unknown field "titel"`,
+		`title == /x/`:           "offset 6: cannot compare string == regexp",
+		`date < "someday"`:       `offset 5: "someday" is not a date`,
+		`"x"`:                    `"x" is not a condition`,
+		`(title`:                 "offset 6: expected ), got end of expression",
+		`title =~ /x/q`:          `offset 9: unknown flag 'q' of regular expression`,
+		`title == "unterminated`: "offset 9: unterminated string",
+		`categories in title`:    "offset 11: cannot compare list in string",
+	} {
+		_, err := Compile(expr)
+		if assert.NotNil(t, err, expr) {
+			assert.Equal(t, msg, err.Error(), expr)
+		}
+	}
+}
+
+func Test_Parse(t *testing.T) {
+	for text, msg := range map[string]string{
+		"limit ten":               `line 1: limit needs a number, got "ten"`,
+		"\nsort date sideways":    `line 2: sort order "sideways", expected asc or desc`,
+		"sort author":             `line 1: cannot sort by "author", only date, title, url and id`,
+		"dedupe content":          `line 1: cannot dedupe by "content", only id, url and title`,
+		"rewrite title sponsored": `line 1: rewrite needs a field, a regular expression and a replacement, as in rewrite title /^ad: /i ""`,
+		`rewrite date /x/ ""`:     `line 1: cannot rewrite "date", only title, url, content and summary`,
+		"filter":                  "line 1: filter needs an expression",
+		"filter title ==":         "line 1: filter: offset 8: unexpected end of expression",
+		"uppercase title":         `line 1: unknown stage "uppercase"`,
+	} {
+		_, err := Parse(text)
+		if assert.NotNil(t, err, text) {
+			assert.Equal(t, msg, err.Error(), text)
+		}
+	}
+
+	p, err := Parse("exclude date\nsort title desc\ndedupe title")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Hello again https://example.com/hello?id=1"}, titles(p.Run(parse(t, testRss))))
+}
+
+func Test_CleanURL(t *testing.T) {
+	for s, expected := range map[string]string{
+		"https://example.com/a?utm_source=x&utm_medium=y":       "https://example.com/a",
+		"https://example.com/a?b=1&fbclid=2&c=%20#frag?utm_x=1": "https://example.com/a?b=1&c=%20#frag?utm_x=1",
+		"https://example.com/a?UTM_Source=x&b":                  "https://example.com/a?b",
+		"https://example.com/a?b=1&c=2":                         "https://example.com/a?b=1&c=2",
+		"https://example.com/a":                                 "https://example.com/a",
+	} {
+		assert.Equal(t, expected, CleanURL(s), s)
+	}
+	assert.Equal(t, "https://example.com/a?utm_source=x", CleanURL("https://example.com/a?utm_source=x&ref=1", "ref"))
+	assert.Equal(t, "https://a.example/x", CleanURL("https://a.example/x?UTM_source=1&ref=2", "UTM_*", "Ref"))
+}
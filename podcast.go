@@ -0,0 +1,267 @@
+package grss
+
+import "github.com/nbio/xml"
+
+// Podcasting 2.0 https://podcastindex.org/namespace/1.0
+// https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md
+
+const (
+	NamespacePodcast = "https://podcastindex.org/namespace/1.0"
+)
+
+// PodcastChannel the Podcasting 2.0 elements of <channel>.
+type PodcastChannel struct {
+	// PodcastLocked tells podcast hosting platforms whether they are allowed to import this feed.
+	PodcastLocked *PodcastLocked `xml:"https://podcastindex.org/namespace/1.0 podcast:locked,omitempty"`
+	// PodcastFunding lists possible donation/funding links for the podcast.
+	PodcastFunding []*PodcastFunding `xml:"https://podcastindex.org/namespace/1.0 podcast:funding,omitempty"`
+	// PodcastPersons specifies a person of interest to the podcast, it applies to every episode.
+	PodcastPersons []*PodcastPerson `xml:"https://podcastindex.org/namespace/1.0 podcast:person,omitempty"`
+	// PodcastValue designates the cryptocurrency or payment layer used and how the value is split between recipients.
+	PodcastValue []*PodcastValue `xml:"https://podcastindex.org/namespace/1.0 podcast:value,omitempty"`
+	// PodcastGuid is the globally unique identifier for the podcast, a UUIDv5 of the feed url without its scheme.
+	PodcastGuid string `xml:"https://podcastindex.org/namespace/1.0 podcast:guid,omitempty"`
+	// PodcastLiveItems are episodes that are, were or will be live streamed.
+	PodcastLiveItems []*PodcastLiveItem `xml:"https://podcastindex.org/namespace/1.0 podcast:liveItem,omitempty"`
+}
+
+// PodcastItem the Podcasting 2.0 elements of <item>.
+type PodcastItem struct {
+	// PodcastTranscripts links to transcripts or closed captions files.
+	PodcastTranscripts []*PodcastTranscript `xml:"https://podcastindex.org/namespace/1.0 podcast:transcript,omitempty"`
+	// PodcastChapters links to an external file containing chapter data for the episode.
+	PodcastChapters *PodcastChapters `xml:"https://podcastindex.org/namespace/1.0 podcast:chapters,omitempty"`
+	// PodcastSoundbites points to soundbites within the episode, intended to be used as previews or trailers.
+	PodcastSoundbites []*PodcastSoundbite `xml:"https://podcastindex.org/namespace/1.0 podcast:soundbite,omitempty"`
+	// PodcastPersons specifies a person of interest to the episode.
+	PodcastPersons []*PodcastPerson `xml:"https://podcastindex.org/namespace/1.0 podcast:person,omitempty"`
+	// PodcastSeason identifies which season the episode belongs to.
+	PodcastSeason *PodcastSeason `xml:"https://podcastindex.org/namespace/1.0 podcast:season,omitempty"`
+	// PodcastEpisode exists largely for compatibility with the season tag, a number in the series.
+	PodcastEpisode *PodcastEpisode `xml:"https://podcastindex.org/namespace/1.0 podcast:episode,omitempty"`
+	// PodcastAlternateEnclosures provides different versions of, or companion media to, the main <enclosure>.
+	PodcastAlternateEnclosures []*PodcastAlternateEnclosure `xml:"https://podcastindex.org/namespace/1.0 podcast:alternateEnclosure,omitempty"`
+	// PodcastValue overrides the channel value block for this episode.
+	PodcastValue []*PodcastValue `xml:"https://podcastindex.org/namespace/1.0 podcast:value,omitempty"`
+}
+
+func (p *PodcastChannel) empty() bool {
+	return p.PodcastLocked == nil &&
+		len(p.PodcastFunding) == 0 &&
+		len(p.PodcastPersons) == 0 &&
+		len(p.PodcastValue) == 0 &&
+		p.PodcastGuid == "" &&
+		len(p.PodcastLiveItems) == 0
+}
+
+func (p *PodcastItem) empty() bool {
+	return len(p.PodcastTranscripts) == 0 &&
+		p.PodcastChapters == nil &&
+		len(p.PodcastSoundbites) == 0 &&
+		len(p.PodcastPersons) == 0 &&
+		p.PodcastSeason == nil &&
+		p.PodcastEpisode == nil &&
+		len(p.PodcastAlternateEnclosures) == 0 &&
+		len(p.PodcastValue) == 0
+}
+
+// PodcastLocked <podcast:locked owner="[email address]">[yes or no]</podcast:locked>
+type PodcastLocked struct {
+	// Owner The owner email address of the feed.
+	Owner string `xml:"owner,attr,omitempty"`
+	// Locked yes means a hosting platform must not import the feed without confirming with the owner.
+	Locked string `xml:",chardata"`
+}
+
+// PodcastFunding <podcast:funding url="[url for the show at the platform]">[user provided content to link]</podcast:funding>
+type PodcastFunding struct {
+	// Url The URL to where listeners can support the podcast.
+	Url string `xml:"url,attr"`
+	// Text A free form string supplied by the creator which they expect to be displayed in the app next to the link.
+	Text string `xml:",chardata"`
+}
+
+// PodcastPerson <podcast:person group="[role group]" role="[role]" img="[uri of content]" href="[uri to person's website]">[name of person]</podcast:person>
+type PodcastPerson struct {
+	// Role Used to identify what role the person serves on the show or episode, from the taxonomy. Default is "host".
+	Role string `xml:"role,attr,omitempty"`
+	// Group This should be a camel-cased value from the taxonomy. Default is "cast".
+	Group string `xml:"group,attr,omitempty"`
+	// Img This is the url of a picture or avatar of the person.
+	Img string `xml:"img,attr,omitempty"`
+	// Href The url to a relevant resource of information about the person, such as a homepage or third-party profile platform.
+	Href string `xml:"href,attr,omitempty"`
+	// Name This is the full name or alias of the person.
+	Name string `xml:",chardata"`
+}
+
+// PodcastValue <podcast:value type="[lightning]" method="[keysend]" suggested="[number of coins]">[one or more "podcast:valueRecipient" elements]</podcast:value>
+type PodcastValue struct {
+	// Type This is the service slug of the cryptocurrency or protocol layer.
+	Type string `xml:"type,attr"`
+	// Method This is the transport mechanism that will be used.
+	Method string `xml:"method,attr"`
+	// Suggested This is an optional suggestion on how much cryptocurrency to send with each payment.
+	Suggested string `xml:"suggested,attr,omitempty"`
+
+	Recipients []*PodcastValueRecipient `xml:"https://podcastindex.org/namespace/1.0 podcast:valueRecipient,omitempty"`
+}
+
+// PodcastValueRecipient designates various destinations for payments to be sent to during consumption of the enclosed media.
+type PodcastValueRecipient struct {
+	// Name A free-form string that designates who or what this recipient is.
+	Name string `xml:"name,attr,omitempty"`
+	// CustomKey The name of a custom record key to send along with the payment.
+	CustomKey string `xml:"customKey,attr,omitempty"`
+	// CustomValue A custom value to pass along with the payment. This is considered the value that belongs to the customKey.
+	CustomValue string `xml:"customValue,attr,omitempty"`
+	// Type A slug that represents the type of receiving address that will receive the payment.
+	Type string `xml:"type,attr"`
+	// Address This denotes the receiving address of the payee.
+	Address string `xml:"address,attr"`
+	// Split The number of shares of the payment this recipient will receive.
+	Split string `xml:"split,attr"`
+	// Fee If this attribute is not specified, it is assumed to be false.
+	Fee string `xml:"fee,attr,omitempty"`
+}
+
+// PodcastTranscript <podcast:transcript url="[url to a file or website]" type="[mime type]" language="[language code]" rel="captions" />
+type PodcastTranscript struct {
+	// Url URL of the podcast transcript.
+	Url string `xml:"url,attr" json:"url"`
+	// Type Mime type of the file such as text/plain, text/html, text/vtt, application/json, application/x-subrip
+	Type string `xml:"type,attr" json:"type"`
+	// Language The language of the linked transcript. If there is no language attribute given, the linked file is assumed to be the same language that is specified by the RSS <language> element.
+	Language string `xml:"language,attr,omitempty" json:"language,omitempty"`
+	// Rel If the rel="captions" attribute is present, the linked file is considered to be a closed captions file, regardless of what the mime type is.
+	Rel string `xml:"rel,attr,omitempty" json:"rel,omitempty"`
+}
+
+// PodcastChapters <podcast:chapters url="[url to chapter data file]" type="[mime type]" />
+type PodcastChapters struct {
+	// Url The URL where the chapters file is located.
+	Url string `xml:"url,attr" json:"url"`
+	// Type Mime type of file - JSON prefered, 'application/json+chapters'.
+	Type string `xml:"type,attr" json:"type"`
+}
+
+// PodcastSoundbite <podcast:soundbite startTime="[number]" duration="[number]">[Title of Soundbite]</podcast:soundbite>
+type PodcastSoundbite struct {
+	// StartTime The time where the soundbite begins, in seconds.
+	StartTime string `xml:"startTime,attr"`
+	// Duration How long is the soundbite, in seconds.
+	Duration string `xml:"duration,attr"`
+	// Title A free form string used to give a title to the soundbite.
+	Title string `xml:",chardata"`
+}
+
+// PodcastSeason <podcast:season name="[name of the season]">[season number]</podcast:season>
+type PodcastSeason struct {
+	// Name This is the "name" of the season.
+	Name string `xml:"name,attr,omitempty"`
+	// Number The node value is an integer, and represents the season "number".
+	Number string `xml:",chardata"`
+}
+
+// PodcastEpisode <podcast:episode display="[name of the episode]">[episode number]</podcast:episode>
+type PodcastEpisode struct {
+	// Display This is a free-form string meant to augment the number, such as "Ch.3".
+	Display string `xml:"display,attr,omitempty"`
+	// Number The node value is a decimal number.
+	Number string `xml:",chardata"`
+}
+
+// PodcastAlternateEnclosure <podcast:alternateEnclosure type="[mime type]" length="[(int)]" bitrate="[(float)]" ...>[one or more "podcast:source" elements]</podcast:alternateEnclosure>
+type PodcastAlternateEnclosure struct {
+	// Type Mime type of the media asset.
+	Type string `xml:"type,attr"`
+	// Length Length of the file in bytes.
+	Length string `xml:"length,attr,omitempty"`
+	// Bitrate Average encoding bitrate of the media asset, expressed in bits per second.
+	Bitrate string `xml:"bitrate,attr,omitempty"`
+	// Height Height of the media asset for video formats.
+	Height string `xml:"height,attr,omitempty"`
+	// Lang An IETF language tag (BCP 47) code identifying the language of this media.
+	Lang string `xml:"lang,attr,omitempty"`
+	// Title A human-readable string identifying the name of the media asset. Should be limited to 32 characters for UX.
+	Title string `xml:"title,attr,omitempty"`
+	// Rel Provides a method of offering and/or grouping together different media elements.
+	Rel string `xml:"rel,attr,omitempty"`
+	// Codecs An RFC 6381 string specifying the codecs available in this media.
+	Codecs string `xml:"codecs,attr,omitempty"`
+	// Default Boolean specifying whether or not the given media is the same as the file from the enclosure element.
+	Default string `xml:"default,attr,omitempty"`
+
+	Sources   []*PodcastSource  `xml:"https://podcastindex.org/namespace/1.0 podcast:source,omitempty"`
+	Integrity *PodcastIntegrity `xml:"https://podcastindex.org/namespace/1.0 podcast:integrity,omitempty"`
+}
+
+// PodcastSource <podcast:source uri="[uri of file]" contentType="[mime type]" />
+type PodcastSource struct {
+	// Uri This is the URI where the media file resides.
+	Uri string `xml:"uri,attr"`
+	// ContentType This is the mime type of the media asset.
+	ContentType string `xml:"contentType,attr,omitempty"`
+}
+
+// PodcastIntegrity <podcast:integrity type="[type]" value="[value]" />
+type PodcastIntegrity struct {
+	// Type Type of integrity, either "sri" or "pgp-signature".
+	Type string `xml:"type,attr"`
+	// Value Value of the sri or pgp signature.
+	Value string `xml:"value,attr"`
+}
+
+// PodcastLiveItem <podcast:liveItem status="[pending | live | ended]" start="[(date)]" end="[(date)]">[one or more <item> elements]</podcast:liveItem>
+// It has the same child elements as <item>.
+type PodcastLiveItem struct {
+	// Status A string that must be one of pending, live or ended.
+	Status string `xml:"status,attr"`
+	// Start The date and time the stream is scheduled to start, ISO8601.
+	Start string `xml:"start,attr"`
+	// End The date and time the stream is scheduled to end, ISO8601.
+	End string `xml:"end,attr,omitempty"`
+
+	RssItem
+}
+
+// UnmarshalXML reads the attributes of the live item, the RssItem unmarshaler would drop them.
+func (l *PodcastLiveItem) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "status":
+			l.Status = attr.Value
+		case "start":
+			l.Start = attr.Value
+		case "end":
+			l.End = attr.Value
+		}
+	}
+	return l.RssItem.UnmarshalXML(d, start)
+}
+
+// JSONPodcast the _podcast item extension, carrying the Podcasting 2.0 data JSON Feed has no field for.
+type JSONPodcast struct {
+	Transcripts []*PodcastTranscript `json:"transcripts,omitempty"`
+	Chapters    *PodcastChapters     `json:"chapters,omitempty"`
+}
+
+func (p *JSONPodcast) empty() bool {
+	return len(p.Transcripts) == 0 && p.Chapters == nil
+}
+
+// Podcast returns the _podcast extension of the item, nil if it has none.
+func (item *JSONItem) Podcast() *JSONPodcast {
+	switch v := item.Extensions["_podcast"].(type) {
+	case nil:
+		return nil
+	case *JSONPodcast:
+		return v
+	default:
+		p := &JSONPodcast{}
+		if !decodeJSONExtension(v, p) {
+			return nil
+		}
+		return p
+	}
+}
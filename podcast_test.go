@@ -0,0 +1,152 @@
+package grss
+
+import (
+	"bytes"
+	"github.com/nbio/xml"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const podcastTestRss = `
+<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:podcast="https://podcastindex.org/namespace/1.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Podcasting 2.0</title>
+    <link>https://podcastindex.org</link>
+    <description>The namespace</description>
+    <itunes:author>Podcastindex.org</itunes:author>
+    <podcast:locked owner="email@example.com">yes</podcast:locked>
+    <podcast:funding url="https://www.example.com/donations">Support the show!</podcast:funding>
+    <podcast:guid>917393e3-1b1e-5cef-ace4-edaa54e1f810</podcast:guid>
+    <podcast:person role="host" img="https://example.com/images/alice.jpg" href="https://www.podchaser.com/creators/alice">Alice</podcast:person>
+    <podcast:value type="lightning" method="keysend" suggested="0.00000005000">
+      <podcast:valueRecipient name="Alice (Podcaster)" type="node" address="02d5c1bf8b940dc9cadca86d1b0a3c37fbe39cee4c7e839e33bef9174531d27f52" split="40"/>
+      <podcast:valueRecipient name="Hosting Provider" type="node" address="03ae9f91a0cb8ff43840e3c322c4c61f019d8c1c3cea15a25cfc425ac605e61a4a" split="5" fee="true"/>
+    </podcast:value>
+    <podcast:liveItem status="live" start="2021-09-26T07:30:00.000-0600" end="2021-09-26T09:30:00.000-0600">
+      <title>Podcasting 2.0 Live Show</title>
+      <guid>e32b4890-983b-4ce5-8b46-f2d6bc1d8819</guid>
+      <enclosure url="https://example.com/pc20/livestream" type="audio/mpeg" length="312"/>
+    </podcast:liveItem>
+    <item>
+      <title>Episode 3</title>
+      <guid isPermaLink="false">ep3</guid>
+      <enclosure url="https://example.com/ep3.mp3" length="1000" type="audio/mpeg"/>
+      <podcast:transcript url="https://example.com/ep3/transcript.vtt" type="text/vtt" rel="captions"/>
+      <podcast:transcript url="https://example.com/ep3/transcript.json" type="application/json" language="es"/>
+      <podcast:chapters url="https://example.com/ep3/chapters.json" type="application/json+chapters"/>
+      <podcast:soundbite startTime="73.0" duration="60.0">Why the Podcast Namespace Matters</podcast:soundbite>
+      <podcast:person role="guest" href="https://example.com/bob">Bob</podcast:person>
+      <podcast:season name="Race for the Whitehouse 2020">3</podcast:season>
+      <podcast:episode display="Ch.3">204</podcast:episode>
+      <podcast:alternateEnclosure type="audio/opus" length="32400000" bitrate="96000" title="High quality">
+        <podcast:source uri="https://example.com/ep3.opus"/>
+        <podcast:source uri="ipfs://someRandomHighBitrateOpusFile"/>
+        <podcast:integrity type="sri" value="sha384-ExVqijgYHm15PqQqdXfW95x+Rs6C+d6E/ICxyQOeFevnxNLR/wtJNrNYTjIysUBo"/>
+      </podcast:alternateEnclosure>
+    </item>
+  </channel>
+</rss>
+`
+
+func Test_RssFeed_Podcast(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(podcastTestRss))
+	assert.Nil(t, err)
+
+	a := f.ToRss()
+	c := a.Channel
+	assert.Equal(t, "email@example.com", c.PodcastLocked.Owner)
+	assert.Equal(t, "yes", c.PodcastLocked.Locked)
+	assert.Equal(t, "Support the show!", c.PodcastFunding[0].Text)
+	assert.Equal(t, "917393e3-1b1e-5cef-ace4-edaa54e1f810", c.PodcastGuid)
+	assert.Equal(t, "Alice", c.PodcastPersons[0].Name)
+	assert.Equal(t, "keysend", c.PodcastValue[0].Method)
+	assert.Equal(t, 2, len(c.PodcastValue[0].Recipients))
+	assert.Equal(t, "true", c.PodcastValue[0].Recipients[1].Fee)
+	assert.Equal(t, "live", c.PodcastLiveItems[0].Status)
+	assert.Equal(t, "Podcasting 2.0 Live Show", c.PodcastLiveItems[0].Title)
+	assert.Equal(t, "https://example.com/pc20/livestream", c.PodcastLiveItems[0].Enclosures[0].Url)
+	assert.Equal(t, 1, len(c.Items))
+
+	item := c.Items[0]
+	assert.Equal(t, 2, len(item.PodcastTranscripts))
+	assert.Equal(t, "captions", item.PodcastTranscripts[0].Rel)
+	assert.Equal(t, "es", item.PodcastTranscripts[1].Language)
+	assert.Equal(t, "application/json+chapters", item.PodcastChapters.Type)
+	assert.Equal(t, "73.0", item.PodcastSoundbites[0].StartTime)
+	assert.Equal(t, "Bob", item.PodcastPersons[0].Name)
+	assert.Equal(t, "3", item.PodcastSeason.Number)
+	assert.Equal(t, "Ch.3", item.PodcastEpisode.Display)
+	assert.Equal(t, 2, len(item.PodcastAlternateEnclosures[0].Sources))
+	assert.Equal(t, "sri", item.PodcastAlternateEnclosures[0].Integrity.Type)
+
+	// the typed elements are not kept twice
+	for _, ext := range c.ExtensionElement {
+		assert.NotEqual(t, NamespacePodcast, ext.XMLName.Space, ext.XMLName)
+	}
+
+	var buf bytes.Buffer
+	err = a.WriteOut(&buf)
+	assert.Nil(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `<podcast:locked owner="email@example.com">yes</podcast:locked>`, out)
+	assert.Contains(t, out, `<podcast:valueRecipient name="Hosting Provider" type="node" address="03ae9f91a0cb8ff43840e3c322c4c61f019d8c1c3cea15a25cfc425ac605e61a4a" split="5" fee="true"></podcast:valueRecipient>`, out)
+	assert.Contains(t, out, `<podcast:liveItem status="live" start="2021-09-26T07:30:00.000-0600" end="2021-09-26T09:30:00.000-0600">`, out)
+	assert.Contains(t, out, `<podcast:transcript url="https://example.com/ep3/transcript.vtt" type="text/vtt" rel="captions"></podcast:transcript>`, out)
+	assert.Contains(t, out, `<podcast:season name="Race for the Whitehouse 2020">3</podcast:season>`, out)
+	assert.Contains(t, out, `<itunes:author>Podcastindex.org</itunes:author>`, out)
+	assert.Equal(t, 1, strings.Count(out, `xmlns:podcast=`), out)
+
+	// and back
+	_, f, err = Parse(&buf)
+	assert.Nil(t, err)
+	b := f.ToRss()
+	assert.Equal(t, c.PodcastValue, b.Channel.PodcastValue)
+	assert.Equal(t, c.PodcastLiveItems[0].Guid, b.Channel.PodcastLiveItems[0].Guid)
+	assert.Equal(t, item.PodcastItem, b.Channel.Items[0].PodcastItem)
+
+	// legacy versions have no namespaces
+	buf.Reset()
+	err = a.ToRssVersion(RssVersion092).WriteOut(&buf)
+	assert.Nil(t, err)
+	assert.NotContains(t, buf.String(), "podcast:", buf.String())
+}
+
+func Test_JSONFeed_Podcast(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(podcastTestRss))
+	assert.Nil(t, err)
+
+	a := f.ToJSON()
+	podcast := a.Items[0].Podcast()
+	assert.NotNil(t, podcast)
+	assert.Equal(t, 2, len(podcast.Transcripts))
+	assert.Equal(t, "https://example.com/ep3/chapters.json", podcast.Chapters.Url)
+
+	var buf bytes.Buffer
+	err = a.WriteOut(&buf)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `"_podcast": {`, buf.String())
+	assert.Contains(t, buf.String(), `"url": "https://example.com/ep3/transcript.vtt",`, buf.String())
+
+	xmlnsPodcast := xml.Attr{
+		Name:  xml.Name{Space: "http://www.w3.org/2000/xmlns/", Local: "podcast"},
+		Value: NamespacePodcast,
+	}
+
+	// and back, through the generic extension map
+	_, f, err = Parse(&buf)
+	assert.Nil(t, err)
+	b := f.ToRss()
+	assert.Equal(t, f.(*JSONFeed).Items[0].Podcast(), podcast)
+	assert.Equal(t, podcast.Transcripts, b.Channel.Items[0].PodcastTranscripts)
+	assert.Equal(t, podcast.Chapters, b.Channel.Items[0].PodcastChapters)
+	assert.Contains(t, b.Attributes, xmlnsPodcast)
+
+	// no podcast data, no namespace
+	_, f, err = Parse(strings.NewReader(writerTestRss))
+	assert.Nil(t, err)
+	assert.NotContains(t, f.ToRss().Attributes, xmlnsPodcast)
+	assert.Nil(t, f.ToJSON().Items[0].Podcast())
+}
@@ -0,0 +1,270 @@
+package grss
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/nbio/xml"
+	"io"
+)
+
+// 1.0 https://web.resource.org/rss/1.0/spec
+// https://web.resource.org/rss/1.0/modules/dc/
+// https://web.resource.org/rss/1.0/modules/content/
+// https://web.resource.org/rss/1.0/modules/syndication/
+
+const (
+	// RdfMime https://www.w3.org/TR/rdf-syntax-grammar/#section-MIME-Type
+	RdfMime         = "application/rdf+xml"
+	RdfMimeFallback = "application/xml"
+)
+
+const (
+	NamespaceRdf         = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	NamespaceRss10       = "http://purl.org/rss/1.0/"
+	NamespaceDublinCore  = "http://purl.org/dc/elements/1.1/"
+	NamespaceContent     = "http://purl.org/rss/1.0/modules/content/"
+	NamespaceSyndication = "http://purl.org/rss/1.0/modules/syndication/"
+)
+
+// RdfFeed is an RSS 1.0 document, the root rdf:RDF element.
+// The RSS 1.0 core elements are written unqualified under the default namespace declared on the root.
+type RdfFeed struct {
+	XMLName xml.Name `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# rdf:RDF"`
+
+	Attributes []xml.Attr `xml:",any,attr,omitempty"`
+
+	Channel *RdfChannel `xml:"channel"`
+
+	// Image An image to be associated with an HTML rendering of the channel.
+	Image *RdfImage `xml:"image,omitempty"`
+	// Items While commonly a news headline, with RSS 1.0's modular extensibility, this can be just about anything: discussion posting, job listing, software patch -- any object with a URI.
+	Items []*RdfItem `xml:"item,omitempty"`
+	// TextInput The textinput element affords a method for submitting form data to an arbitrary URL.
+	TextInput *RdfTextInput `xml:"textinput,omitempty"`
+}
+
+// RdfChannel The channel element contains metadata describing the channel itself, including a title, brief description, and URL link to the described resource (the channel provider's home page, for instance).
+type RdfChannel struct {
+	// About The {resource} URL is the channel's unique identifier, most commonly the URL of the RSS document itself.
+	About string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# rdf:about,attr"`
+
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description XmlText `xml:"description"`
+
+	// Image Establishes an RDF association between the optional image element and this particular RSS channel.
+	Image *RdfResource `xml:"image,omitempty"`
+	// Items An RDF table of contents, associating the document's items with this particular RSS channel.
+	Items RdfItems `xml:"items"`
+	// TextInput Establishes an RDF association between the optional textinput element and this particular RSS channel.
+	TextInput *RdfResource `xml:"textinput,omitempty"`
+
+	DublinCore
+	Syndication
+}
+
+// RdfItems <items><rdf:Seq><rdf:li resource="..."/></rdf:Seq></items>
+type RdfItems struct {
+	Seq RdfSeq `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# rdf:Seq"`
+}
+
+type RdfSeq struct {
+	Li []*RdfResource `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# rdf:li,omitempty"`
+}
+
+// RdfResource an empty element pointing at another resource by rdf:resource.
+type RdfResource struct {
+	Resource string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# rdf:resource,attr"`
+}
+
+type RdfImage struct {
+	// About The {image_uri} URL should be the same as the image's url element.
+	About string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# rdf:about,attr"`
+
+	Title string `xml:"title"`
+	Url   string `xml:"url"`
+	Link  string `xml:"link"`
+}
+
+type RdfItem struct {
+	// About The {item_uri} should be identical to the value of the link sub-element of the item element, if possible.
+	About string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# rdf:about,attr"`
+
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description *XmlText `xml:"description,omitempty"`
+
+	DublinCore
+
+	ContentEncoded *XmlText `xml:"http://purl.org/rss/1.0/modules/content/ content:encoded,omitempty"`
+
+	EventItem
+}
+
+type RdfTextInput struct {
+	// About The {textinput_uri} URL should be the same as the textinput's link element.
+	About string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# rdf:about,attr"`
+
+	Title       string `xml:"title"`
+	Description string `xml:"description"`
+	Name        string `xml:"name"`
+	Link        string `xml:"link"`
+}
+
+// DublinCore The Dublin Core Metadata Element Set, as used by the RSS 1.0 dc module. Dates are W3CDTF.
+type DublinCore struct {
+	Creator     []string `xml:"http://purl.org/dc/elements/1.1/ dc:creator,omitempty"`
+	Contributor []string `xml:"http://purl.org/dc/elements/1.1/ dc:contributor,omitempty"`
+	Subject     []string `xml:"http://purl.org/dc/elements/1.1/ dc:subject,omitempty"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ dc:date,omitempty"`
+	Publisher   string   `xml:"http://purl.org/dc/elements/1.1/ dc:publisher,omitempty"`
+	Rights      string   `xml:"http://purl.org/dc/elements/1.1/ dc:rights,omitempty"`
+	Language    string   `xml:"http://purl.org/dc/elements/1.1/ dc:language,omitempty"`
+	Identifier  string   `xml:"http://purl.org/dc/elements/1.1/ dc:identifier,omitempty"`
+	Source      string   `xml:"http://purl.org/dc/elements/1.1/ dc:source,omitempty"`
+	Format      string   `xml:"http://purl.org/dc/elements/1.1/ dc:format,omitempty"`
+	Type        string   `xml:"http://purl.org/dc/elements/1.1/ dc:type,omitempty"`
+}
+
+// Syndication Provides syndication hints to aggregators and others picking up this RDF Site Summary (RSS) feed regarding how often it is updated.
+type Syndication struct {
+	// UpdatePeriod Describes the period over which the channel format is updated. Acceptable values are: hourly, daily, weekly, monthly, yearly. If omitted, daily is assumed.
+	UpdatePeriod string `xml:"http://purl.org/rss/1.0/modules/syndication/ sy:updatePeriod,omitempty"`
+	// UpdateFrequency Used to describe the frequency of updates in relation to the update period. A positive integer indicates how many times in that period the channel is updated.
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ sy:updateFrequency,omitempty"`
+	// UpdateBase Defines a base date to be used in concert with updatePeriod and updateFrequency to calculate the publishing schedule. The date format takes the form: yyyy-mm-ddThh:mm
+	UpdateBase string `xml:"http://purl.org/rss/1.0/modules/syndication/ sy:updateBase,omitempty"`
+}
+
+// syndicationPeriods in minutes, shortest first
+var syndicationPeriods = []struct {
+	name    string
+	minutes int
+}{
+	{"hourly", 60},
+	{"daily", 60 * 24},
+	{"weekly", 60 * 24 * 7},
+	{"monthly", 60 * 24 * 30},
+	{"yearly", 60 * 24 * 365},
+}
+
+// syndicationFromTtl maps an RSS 2.0 ttl (minutes) to the closest sy:updatePeriod and sy:updateFrequency.
+func syndicationFromTtl(ttl int) (period string, frequency int) {
+	if ttl <= 0 {
+		return "", 0
+	}
+
+	for _, p := range syndicationPeriods {
+		if ttl <= p.minutes && p.minutes%ttl == 0 {
+			return p.name, p.minutes / ttl
+		}
+	}
+
+	for _, p := range syndicationPeriods {
+		if ttl <= p.minutes {
+			return p.name, p.minutes / ttl
+		}
+	}
+
+	return "yearly", 1
+}
+
+// namespaceURL the name space of the UUIDs named by URLs https://www.rfc-editor.org/rfc/rfc4122#appendix-C
+var namespaceURL = [16]byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// urnUUID the urn:uuid of the version 5 UUID of name in the URL name space, the same name always having the same UUID https://www.rfc-editor.org/rfc/rfc4122#section-4.3
+func urnUUID(name string) string {
+	h := sha1.New()
+	_, _ = h.Write(namespaceURL[:])
+	_, _ = h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	s := hex.EncodeToString(u)
+	return "urn:uuid:" + s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func (f *RdfFeed) Uniform() {
+	f.XMLName = xml.Name{
+		Space: NamespaceRdf,
+		Local: "rdf:RDF",
+	}
+
+	pre := [][3]string{
+		{"", "xmlns", NamespaceRss10},
+		{"http://www.w3.org/2000/xmlns/", "dc", NamespaceDublinCore},
+		{"http://www.w3.org/2000/xmlns/", "content", NamespaceContent},
+		{"http://www.w3.org/2000/xmlns/", "sy", NamespaceSyndication},
+	}
+	for _, item := range f.Items {
+		if !item.EventItem.empty() {
+			pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "ev", NamespaceEvent})
+			break
+		}
+	}
+
+	f.Attributes = append(f.Attributes, diffAttrs(pre, f.Attributes)...)
+
+	if f.Channel == nil {
+		f.Channel = &RdfChannel{}
+	}
+
+	f.Channel.Items.Seq.Li = nil
+	for _, item := range f.Items {
+		if item.About == "" {
+			item.About = item.Link
+		}
+		if item.Link == "" && absoluteURL(item.About) {
+			item.Link = item.About
+		}
+		if item.Title == "" {
+			item.Title = item.Link
+		}
+		f.Channel.Items.Seq.Li = append(f.Channel.Items.Seq.Li, &RdfResource{
+			Resource: item.About,
+		})
+	}
+
+	if f.Image != nil {
+		if f.Image.About == "" {
+			f.Image.About = f.Image.Url
+		}
+		f.Channel.Image = &RdfResource{
+			Resource: f.Image.About,
+		}
+	}
+
+	if f.TextInput != nil {
+		if f.TextInput.About == "" {
+			f.TextInput.About = f.TextInput.Link
+		}
+		f.Channel.TextInput = &RdfResource{
+			Resource: f.TextInput.About,
+		}
+	}
+
+	if f.Channel.About == "" {
+		f.Channel.About = f.Channel.Link
+	}
+}
+
+func (f *RdfFeed) Mime(fallback bool) string {
+	if fallback {
+		return RdfMimeFallback
+	} else {
+		return RdfMime
+	}
+}
+
+func (f *RdfFeed) WriteOut(w io.Writer) error {
+	return f.WriteOutWith(w, WriteOptions{Indent: defaultIndent})
+}
+
+func (f *RdfFeed) WriteOutWith(w io.Writer, opts WriteOptions) error {
+	return writeXml(w, f.withHTMLContent(opts.HTMLContent), opts, "")
+}
+
+// WriteOutRDF writes any Feed as an RSS 1.0 document.
+func WriteOutRDF(w io.Writer, f Feed) error {
+	return f.ToRss10().WriteOut(w)
+}
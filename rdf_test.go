@@ -0,0 +1,177 @@
+package grss
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_RdfFeed_001(t *testing.T) {
+	s := `
+<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>XML.com</title>
+    <link>http://xml.com/pub</link>
+    <description>XML.com features a rich mix of information and services for the XML community.</description>
+    <atom:link rel="self" href="http://xml.com/xml/news.rss" type="application/rss+xml" />
+    <language>en-us</language>
+    <copyright>Copyright 2000 O'Reilly &amp; Associates, Inc.</copyright>
+    <managingEditor>editor@xml.com</managingEditor>
+    <lastBuildDate>Tue, 10 Jun 2003 04:00:00 GMT</lastBuildDate>
+    <ttl>30</ttl>
+    <image>
+      <title>XML.com</title>
+      <url>http://xml.com/universal/images/xml_tiny.gif</url>
+      <link>http://www.xml.com</link>
+    </image>
+    <item>
+      <title>Processing Inclusions with XSLT</title>
+      <link>http://xml.com/pub/2000/08/09/xslt/xslt.html</link>
+      <description>Processing document inclusions with general XML tools can be problematic.</description>
+      <author>bob@example.com</author>
+      <category>xslt</category>
+      <pubDate>Wed, 09 Aug 2000 04:00:00 GMT</pubDate>
+      <content:encoded><![CDATA[<p>inclusions</p>]]></content:encoded>
+    </item>
+    <item>
+      <guid>http://xml.com/pub/2000/08/09/rdfdb/index.html</guid>
+      <title>Putting RDF to Work</title>
+    </item>
+  </channel>
+</rss>
+`
+
+	_, f, err := Parse(strings.NewReader(s))
+	assert.Nil(t, err)
+
+	a := f.ToRss10()
+	assert.Equal(t, "http://xml.com/xml/news.rss", a.Channel.About)
+	assert.Equal(t, "en-us", a.Channel.Language)
+	assert.Equal(t, []string{"editor@xml.com"}, a.Channel.Creator)
+	assert.Equal(t, "2003-06-10T04:00:00Z", a.Channel.Date)
+	assert.Equal(t, "hourly", a.Channel.UpdatePeriod)
+	assert.Equal(t, "2", a.Channel.UpdateFrequency)
+	assert.Equal(t, "http://xml.com/universal/images/xml_tiny.gif", a.Channel.Image.Resource)
+	assert.Equal(t, 2, len(a.Channel.Items.Seq.Li))
+	assert.Equal(t, "http://xml.com/pub/2000/08/09/rdfdb/index.html", a.Channel.Items.Seq.Li[1].Resource)
+	assert.Equal(t, "http://xml.com/pub/2000/08/09/rdfdb/index.html", a.Items[1].Link)
+	assert.Equal(t, []string{"bob@example.com"}, a.Items[0].Creator)
+	assert.Equal(t, []string{"xslt"}, a.Items[0].Subject)
+
+	var buf bytes.Buffer
+	err = a.WriteOut(&buf)
+	assert.Nil(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"`, out)
+	assert.Contains(t, out, `<channel rdf:about="http://xml.com/xml/news.rss">`, out)
+	assert.Contains(t, out, `<rdf:li rdf:resource="http://xml.com/pub/2000/08/09/xslt/xslt.html"></rdf:li>`, out)
+	assert.Contains(t, out, `<item rdf:about="http://xml.com/pub/2000/08/09/xslt/xslt.html">`, out)
+	assert.Contains(t, out, `<dc:date>2000-08-09T04:00:00Z</dc:date>`, out)
+	assert.Contains(t, out, `<sy:updatePeriod>hourly</sy:updatePeriod>`, out)
+	assert.Contains(t, out, `<content:encoded>`, out)
+	assert.NotContains(t, out, `xmlns=""`, out)
+
+	// and back
+	typ, f, err := Parse(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, TypeXML|TypeXMLRss, typ)
+	b := f.ToRss()
+	assert.Equal(t, "XML.com", b.Channel.Title.String())
+	assert.Equal(t, 2, len(b.Channel.Items))
+	assert.Equal(t, "Putting RDF to Work", b.Channel.Items[1].Title)
+	assert.Equal(t, "<p>inclusions</p>", b.Channel.Items[0].ContentEncoded.String())
+}
+
+func Test_RdfFeed_002(t *testing.T) {
+	s := `
+{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "JSON Feed",
+  "home_page_url": "https://www.jsonfeed.org/",
+  "authors": [{"name": "Brent"}],
+  "items": [
+    {
+      "id": "https://www.jsonfeed.org/2020/08/07/json-feed-version.html",
+      "title": "JSON Feed version 1.1",
+      "content_html": "<p>updated</p>",
+      "date_published": "2020-08-07T11:44:36-05:00",
+      "tags": ["spec"]
+    }
+  ]
+}
+`
+
+	_, f, err := Parse(strings.NewReader(s))
+	assert.Nil(t, err)
+
+	a := f.ToRss10()
+	assert.Equal(t, "https://www.jsonfeed.org/", a.Channel.About)
+	assert.Equal(t, "https://www.jsonfeed.org/2020/08/07/json-feed-version.html", a.Items[0].About)
+	assert.Equal(t, "2020-08-07T11:44:36-05:00", a.Items[0].Date)
+	assert.Equal(t, []string{"spec"}, a.Items[0].Subject)
+
+	var buf bytes.Buffer
+	err = WriteOutRDF(&buf, f)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `<rdf:RDF`)
+}
+
+func Test_RdfFeed_About(t *testing.T) {
+	s := `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Notes</title>
+    <link>https://example.com/</link>
+    <description>Notes</description>
+    <item><description>A note without link nor guid</description></item>
+    <item><description>Another note</description></item>
+    <item><title>Opaque</title><guid isPermaLink="false">note-3</guid></item>
+    <item><title>Linked</title><link>https://example.com/4</link></item>
+    <item><title>Linked again</title><link>https://example.com/4</link></item>
+  </channel>
+</rss>`
+	_, f, err := Parse(strings.NewReader(s))
+	assert.Nil(t, err)
+
+	// every item has its own rdf:about, the same on every conversion
+	a := f.ToRss10()
+	seen := map[string]bool{}
+	for _, item := range a.Items {
+		assert.NotEqual(t, "", item.About)
+		assert.False(t, seen[item.About], item.About)
+		seen[item.About] = true
+	}
+	assert.Equal(t, "https://example.com/4", a.Items[3].About)
+	assert.True(t, strings.HasPrefix(a.Items[0].About, "urn:uuid:"), a.Items[0].About)
+	assert.Equal(t, "", a.Items[0].Link)
+	assert.Equal(t, "https://example.com/4", a.Items[4].Link)
+	assert.Equal(t, a.Items[0].About, f.ToRss10().Items[0].About)
+	for i, li := range a.Channel.Items.Seq.Li {
+		assert.Equal(t, a.Items[i].About, li.Resource)
+	}
+
+	assert.Equal(t, "urn:uuid:dd2c1780-811a-5296-81c5-178a0ef488bc", urnUUID("https://example.com/"))
+}
+
+func Test_syndicationFromTtl(t *testing.T) {
+	for _, c := range []struct {
+		ttl       int
+		period    string
+		frequency int
+	}{
+		{0, "", 0},
+		{60, "hourly", 1},
+		{15, "hourly", 4},
+		{120, "daily", 12},
+		{1440, "daily", 1},
+		{7000, "weekly", 1},
+		{1000000, "yearly", 1},
+	} {
+		period, frequency := syndicationFromTtl(c.ttl)
+		assert.Equal(t, c.period, period, c.ttl)
+		assert.Equal(t, c.frequency, frequency, c.ttl)
+	}
+}
@@ -1,6 +1,11 @@
 package grss
 
-import "github.com/nbio/xml"
+import (
+	"github.com/nbio/xml"
+	"net"
+	"net/url"
+	"strings"
+)
 
 // 0.90 https://www.rssboard.org/rss-0-9-0
 // 0.91(netscape) https://www.rssboard.org/rss-0-9-1-netscape
@@ -29,6 +34,109 @@ const (
 	RssMimeFallback = "application/xml"
 )
 
+// RssVersion the RSS dialects listed at https://www.rssboard.org/rss-history
+type RssVersion int
+
+const (
+	RssVersionUnknown RssVersion = iota
+	// RssVersion090 https://www.rssboard.org/rss-0-9-0
+	RssVersion090
+	// RssVersion091Netscape https://www.rssboard.org/rss-0-9-1-netscape
+	RssVersion091Netscape
+	// RssVersion091 https://www.rssboard.org/rss-0-9-1
+	RssVersion091
+	// RssVersion092 https://www.rssboard.org/rss-0-9-2
+	RssVersion092
+	RssVersion093
+	RssVersion094
+	// RssVersion10 https://web.resource.org/rss/1.0/spec
+	RssVersion10
+	// RssVersion20 https://www.rssboard.org/rss-2-0
+	RssVersion20
+)
+
+const (
+	NamespaceRss090Netscape = "http://my.netscape.com/rdf/simple/0.9/"
+	// NamespaceRss090Channel is found in the wild, including the RSS Advisory Board's own 0.90 sample
+	NamespaceRss090Channel = "http://channel.netscape.com/rdf/simple/0.9/"
+
+	// Rss091NetscapeDoctype the DOCTYPE that tells RSS 0.91 (Netscape) from RSS 0.91 (UserLand)
+	Rss091NetscapeDoctype = `DOCTYPE rss PUBLIC "-//Netscape Communications//DTD RSS 0.91//EN" "http://my.netscape.com/publish/formats/rss-0.91.dtd"`
+)
+
+func (v RssVersion) String() string {
+	switch v {
+	case RssVersion090:
+		return "0.90"
+	case RssVersion091Netscape:
+		return "0.91 (Netscape)"
+	case RssVersion091:
+		return "0.91"
+	case RssVersion092:
+		return "0.92"
+	case RssVersion093:
+		return "0.93"
+	case RssVersion094:
+		return "0.94"
+	case RssVersion10:
+		return "1.0"
+	case RssVersion20:
+		return "2.0"
+	default:
+		return "unknown"
+	}
+}
+
+// Attr is the value of the version attribute of the <rss> root, empty for the RDF based versions.
+func (v RssVersion) Attr() string {
+	switch v {
+	case RssVersion091Netscape, RssVersion091:
+		return "0.91"
+	case RssVersion092, RssVersion093, RssVersion094:
+		return v.String()
+	case RssVersion20:
+		return "2.0"
+	default:
+		return ""
+	}
+}
+
+// detectRssVersion 0.90 and 1.0 are told apart by the default namespace of the rdf:RDF root, 0.91 (Netscape) by its DOCTYPE.
+func detectRssVersion(root xml.Name, attrs []xml.Attr, version, doctype string) RssVersion {
+	if root.Local == "RDF" {
+		for _, attr := range attrs {
+			if attr.Name.Space != "" || attr.Name.Local != "xmlns" {
+				continue
+			}
+			switch attr.Value {
+			case NamespaceRss090Netscape, NamespaceRss090Channel:
+				return RssVersion090
+			case NamespaceRss10:
+				return RssVersion10
+			}
+		}
+		return RssVersionUnknown
+	}
+
+	switch strings.TrimSpace(version) {
+	case "0.91":
+		if strings.Contains(doctype, "rss-0.91.dtd") {
+			return RssVersion091Netscape
+		}
+		return RssVersion091
+	case "0.92":
+		return RssVersion092
+	case "0.93":
+		return RssVersion093
+	case "0.94":
+		return RssVersion094
+	case "2.0", "2.0.1", "":
+		return RssVersion20
+	default:
+		return RssVersionUnknown
+	}
+}
+
 type RssFeed struct {
 	XMLName xml.Name
 
@@ -42,6 +150,11 @@ type RssFeed struct {
 	Image     *RssImage     `xml:"image,omitempty"`
 	Items     []*RssItem    `xml:"item,omitempty"`
 	TextInput *RssTextInput `xml:"textinput,omitempty"`
+
+	// ParsedVersion is the RSS version detected by Parse, RssVersionUnknown for feeds built in code.
+	ParsedVersion RssVersion `xml:"-"`
+	// Doctype is the <!DOCTYPE ...> directive written before the root element, as used by RSS 0.91 (Netscape).
+	Doctype string `xml:"-"`
 }
 
 type RssChannel struct {
@@ -78,7 +191,7 @@ type RssChannel struct {
 	// Docs A URL that points to the documentation for the format used in the RSS file. It's probably a pointer to this page. It's for people who might stumble across an RSS file on a Web server 25 years from now and wonder what it is.
 	Docs string `xml:"docs,omitempty"`
 	// Cloud Allows processes to register with a cloud to be notified of updates to the channel, implementing a lightweight publish-subscribe protocol for RSS feeds
-	Cloud *XmlGeneric `xml:"cloud,omitempty"`
+	Cloud *RssCloud `xml:"cloud,omitempty"`
 	// Ttl stands for time to live. It's a number of minutes that indicates how long a channel can be cached before refreshing from the source.
 	Ttl string `xml:"ttl,omitempty"`
 	// Image Specifies a GIF, JPEG or PNG image that can be displayed with the channel.
@@ -94,6 +207,8 @@ type RssChannel struct {
 
 	Items []*RssItem `xml:"item,omitempty"`
 
+	PodcastChannel
+
 	ExtensionElement []XmlGeneric `xml:",any"`
 }
 
@@ -103,15 +218,15 @@ type RssItem struct {
 	// Link The URL of the item.
 	Link string `xml:"link,omitempty"`
 	// Description	The item synopsis.
-	Description string `xml:"description,omitempty"`
+	Description *XmlText `xml:"description,omitempty"`
 	// Email address of the author of the item.
 	Author *RssAuthor `xml:"author,omitempty"`
 	// Categories Includes the item in one or more categories.
 	Categories []*RssCategory `xml:"category,omitempty"`
 	// Comments URL of a page for comments relating to the item.
 	Comments string `xml:"comments,omitempty"`
-	// Enclosure Describes a media object that is attached to the item.
-	Enclosure *RssEnclosure `xml:"enclosure,omitempty"`
+	// Enclosures Describes a media object that is attached to the item. RSS 2.0 allows one, real feeds often carry several.
+	Enclosures []*RssEnclosure `xml:"enclosure,omitempty"`
 	// Guid A string that uniquely identifies the item.
 	Guid *RssGuid `xml:"guid,omitempty"`
 	// PubDate Indicates when the item was published.
@@ -121,6 +236,24 @@ type RssItem struct {
 
 	//Content        *RssContent `xml:"content,omitempty"`
 	ContentEncoded *RssContent `xml:"http://purl.org/rss/1.0/modules/content/ encoded,omitempty"`
+
+	// MediaContents MRSS media objects of the item that are not alternates of each other.
+	MediaContents []*MediaContent `xml:"http://search.yahoo.com/mrss/ media:content,omitempty"`
+	// MediaGroups MRSS groups of alternate representations of the same media object.
+	MediaGroups []*MediaGroup `xml:"http://search.yahoo.com/mrss/ media:group,omitempty"`
+	// MediaThumbnails MRSS representative images of the item.
+	MediaThumbnails []*MediaThumbnail `xml:"http://search.yahoo.com/mrss/ media:thumbnail,omitempty"`
+
+	// CommentRss wfw:commentRss, the URL of the RSS feed of comments on the item.
+	CommentRss string `xml:"http://wellformedweb.org/CommentAPI/ wfw:commentRss,omitempty"`
+	// SlashComments slash:comments, the number of comments on the item.
+	SlashComments string `xml:"http://purl.org/rss/1.0/modules/slash/ slash:comments,omitempty"`
+	// InReplyTo thr:in-reply-to, the resources the item is a response to, as found in comment feeds.
+	InReplyTo []*AtomInReplyTo `xml:"http://purl.org/syndication/thread/1.0 thr:in-reply-to,omitempty"`
+
+	EventItem
+
+	PodcastItem
 }
 
 type RssContent struct {
@@ -145,6 +278,31 @@ type RssGuid struct {
 	Guid        string `xml:",chardata"`
 }
 
+// PermaLink the guid when it is a permalink of the item, its isPermaLink not being "false", empty otherwise.
+// The guids left without isPermaLink that are not http or https URLs, as many feeds have, are not permalinks either.
+func (g *RssGuid) PermaLink() string {
+	if g == nil || strings.EqualFold(strings.TrimSpace(g.IsPermaLink), "false") {
+		return ""
+	}
+	if guid := strings.TrimSpace(g.Guid); absoluteURL(guid) {
+		return guid
+	}
+	return ""
+}
+
+// newRssGuid the guid of the item of id whose permalink is link: a permalink when id is an http or https URL and the link, or there is no link,
+// as JSON Feed ids ideally are the URLs of the items, with isPermaLink="false" otherwise, as readers open the guids without it in a browser.
+// nil when id is empty.
+func newRssGuid(id, link string) *RssGuid {
+	if id == "" {
+		return nil
+	}
+	if absoluteURL(id) && (link == "" || link == id) {
+		return &RssGuid{Guid: id}
+	}
+	return &RssGuid{Guid: id, IsPermaLink: "false"}
+}
+
 // RssAuthor It's the email address of the author of the item. For newspapers and magazines syndicating via RSS, the author is the person who wrote the article that the <item> describes. For collaborative weblogs, the author of the item might be different from the managing editor or webmaster. For a weblog authored by a single individual it would make sense to omit the <author> element.
 type RssAuthor struct {
 	Email string `xml:",chardata"`
@@ -181,6 +339,71 @@ type RssImage struct {
 	Description string `xml:"description,omitempty"`
 }
 
+// RssCloud Specifies a web service that supports the rssCloud interface which can be implemented in HTTP-POST, XML-RPC or SOAP 1.1.
+// Its purpose is to allow processes to register with a cloud to be notified of updates to the channel, implementing a lightweight publish-subscribe protocol for RSS feeds.
+// <cloud domain="rpc.sys.com" port="80" path="/RPC2" registerProcedure="myCloud.rssPleaseNotify" protocol="xml-rpc" />
+// In this example, to request notification on the channel it appears in, you would send an XML-RPC message to rpc.sys.com on port 80, with a path of /RPC2. The procedure to call is myCloud.rssPleaseNotify.
+// https://www.rssboard.org/rsscloud-interface
+type RssCloud struct {
+	// Domain The host name or IP address of the cloud.
+	Domain string `xml:"domain,attr,omitempty"`
+	// Port The TCP port on which the cloud is running.
+	Port string `xml:"port,attr,omitempty"`
+	// Path The location of its responder.
+	Path string `xml:"path,attr,omitempty"`
+	// RegisterProcedure The name of the procedure to call to request notification, for XML-RPC and SOAP.
+	RegisterProcedure string `xml:"registerProcedure,attr"`
+	// Protocol xml-rpc, soap or http-post (case-sensitive), indicating which protocol is to be used.
+	Protocol string `xml:"protocol,attr,omitempty"`
+}
+
+// Endpoint returns the URL of the cloud's responder, http://domain:port/path, or https when the port is 443.
+func (c *RssCloud) Endpoint() string {
+	if c == nil || c.Domain == "" {
+		return ""
+	}
+	u := url.URL{
+		Scheme: "http",
+		Host:   c.Domain,
+		Path:   c.Path,
+	}
+	switch c.Port {
+	case "", "80":
+	case "443":
+		u.Scheme = "https"
+	default:
+		u.Host = net.JoinHostPort(c.Domain, c.Port)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
+
+// rssCloudFromURL the http-post cloud whose responder is at endpoint, the mapping of a JSON Feed hub of type rssCloud.
+func rssCloudFromURL(endpoint string) *RssCloud {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+	c := &RssCloud{
+		Domain:   u.Hostname(),
+		Port:     u.Port(),
+		Path:     u.EscapedPath(),
+		Protocol: "http-post",
+	}
+	if c.Port == "" {
+		c.Port = "80"
+		if u.Scheme == "https" {
+			c.Port = "443"
+		}
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	return c
+}
+
 // RssTextInput A channel may optionally contain a <textInput> sub-element, which contains four required sub-elements. The purpose of the <textInput> element is something of a mystery. You can use it to specify a search engine box. Or to allow a reader to provide feedback. Most aggregators ignore it.
 type RssTextInput struct {
 	// Title The label of the Submit button in the text input area.
@@ -202,3 +425,86 @@ type RssSkipHours struct {
 type RssSkipDays struct {
 	Days []string `xml:"day,omitempty"`
 }
+
+// UnmarshalXML keeps namespaced links such as <atom:link rel="self"/> out of Link, they are kept in ExtensionElement instead.
+func (c *RssChannel) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type inner RssChannel
+	var v struct {
+		inner
+		Links []XmlGeneric `xml:"link"`
+	}
+
+	err := d.DecodeElement(&v, &start)
+	if err != nil {
+		return err
+	}
+
+	*c = RssChannel(v.inner)
+	for _, link := range v.Links {
+		switch link.XMLName.Space {
+		case "http://www.w3.org/2005/Atom":
+			c.ExtensionElement = append(c.ExtensionElement, link)
+		default:
+			c.Link = link.String()
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalXML keeps namespaced comments such as <slash:comments> out of Comments, and reads the xCal event elements as ev: ones.
+func (item *RssItem) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type inner RssItem
+	var v struct {
+		inner
+		Comments []XmlGeneric `xml:"comments"`
+		xCal
+	}
+
+	err := d.DecodeElement(&v, &start)
+	if err != nil {
+		return err
+	}
+
+	*item = RssItem(v.inner)
+	v.xCal.applyEvent(&item.EventItem)
+	for _, comments := range v.Comments {
+		switch comments.XMLName.Space {
+		case NamespaceSlash:
+			item.SlashComments = comments.String()
+		case "":
+			item.Comments = comments.String()
+		}
+	}
+
+	return nil
+}
+
+// AtomLinks returns the atom:link elements carried as channel extensions, such as rel="self" and rel="hub".
+func (c *RssChannel) AtomLinks() []*AtomLink {
+	var links []*AtomLink
+	for _, ext := range c.ExtensionElement {
+		if ext.XMLName.Space != "http://www.w3.org/2005/Atom" || ext.XMLName.Local != "link" {
+			continue
+		}
+		link := &AtomLink{}
+		for _, attr := range ext.Attributes {
+			switch attr.Name.Local {
+			case "href":
+				link.Href = AtomUri(attr.Value)
+			case "rel":
+				link.Rel = attr.Value
+			case "type":
+				link.Type = AtomMediaType(attr.Value)
+			case "hreflang":
+				link.Hreflang = AtomLanguageTag(attr.Value)
+			case "title":
+				link.Title = attr.Value
+			case "length":
+				link.Length = attr.Value
+			}
+		}
+		links = append(links, link)
+	}
+	return links
+}
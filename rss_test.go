@@ -1,6 +1,7 @@
 package grss
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"strings"
@@ -409,13 +410,13 @@ The lyrics are &lt;a href=&quot;http://www.cs.cmu.edu/~mleone/gdead/dead-lyrics/
 	assert.Equal(t, "Dave Winer: Grateful Dead", a.Channel.Title.String(), a.Channel)
 	assert.Equal(t, "http://www.scripting.com/blog/categories/gratefulDead.html", a.Channel.Link, a.Channel)
 	assert.Equal(t, "A high-fidelity Grateful Dead song every day. This is where we're experimenting with enclosures on RSS news items that download when you're not using your computer. If it works (it will) it will be the end of the Click-And-Wait multimedia experience on the Internet. ", a.Channel.Description.String(), a.Channel)
-	assert.Equal(t, "data.ourfavoritesongs.com", a.Channel.Cloud.Attributes[0].Value, a.Channel.Cloud)
-	assert.Equal(t, "protocol", a.Channel.Cloud.Attributes[4].Name.Local, a.Channel.Cloud)
+	assert.Equal(t, "data.ourfavoritesongs.com", a.Channel.Cloud.Domain, a.Channel.Cloud)
+	assert.Equal(t, "xml-rpc", a.Channel.Cloud.Protocol, a.Channel.Cloud)
 	assert.Equal(t, 22, len(a.Channel.Items), a.Channel.Items)
-	assert.Equal(t, "audio/mpeg", a.Channel.Items[0].Enclosure.Type, a.Channel.Items[0].Enclosure)
+	assert.Equal(t, "audio/mpeg", a.Channel.Items[0].Enclosures[0].Type, a.Channel.Items[0].Enclosures)
 	assert.Equal(t, "http://scriptingnews.userland.com/xml/scriptingNews2.xml", a.Channel.Items[1].Source.Url, a.Channel.Items[1].Source)
 	assert.Equal(t, "Scripting News", a.Channel.Items[1].Source.Text, a.Channel.Items[1].Source)
-	assert.Equal(t, "5272510", a.Channel.Items[21].Enclosure.Length, a.Channel.Items[21].Enclosure)
+	assert.Equal(t, "5272510", a.Channel.Items[21].Enclosures[0].Length, a.Channel.Items[21].Enclosures)
 
 }
 
@@ -842,3 +843,352 @@ func Test_RssFeed_010(t *testing.T) {
 	// TODO itunes
 
 }
+
+func rssParseVersion(t *testing.T, s string) *RssFeed {
+	_, f, err := Parse(strings.NewReader(s))
+	assert.Nil(t, err)
+	return f.(*RssFeed)
+}
+
+func Test_RssVersion_Detect(t *testing.T) {
+	for _, c := range []struct {
+		s string
+		v RssVersion
+	}{
+		{`<?xml version="1.0"?><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://my.netscape.com/rdf/simple/0.9/"><channel><title>a</title></channel></rdf:RDF>`, RssVersion090},
+		{`<?xml version="1.0"?><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://channel.netscape.com/rdf/simple/0.9/"><channel><title>a</title></channel></rdf:RDF>`, RssVersion090},
+		{`<?xml version="1.0"?><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"><channel><title>a</title></channel></rdf:RDF>`, RssVersion10},
+		{"<?xml version=\"1.0\"?>\n<!DOCTYPE rss SYSTEM \"http://my.netscape.com/publish/formats/rss-0.91.dtd\">\n<rss version=\"0.91\"><channel><title>a</title></channel></rss>", RssVersion091Netscape},
+		{`<?xml version="1.0"?><rss version="0.91"><channel><title>a</title></channel></rss>`, RssVersion091},
+		{`<?xml version="1.0"?><rss version="0.92"><channel><title>a</title></channel></rss>`, RssVersion092},
+		{`<?xml version="1.0"?><rss version="2.0"><channel><title>a</title></channel></rss>`, RssVersion20},
+		{`<?xml version="1.0"?><rss><channel><title>a</title></channel></rss>`, RssVersion20},
+	} {
+		a := rssParseVersion(t, c.s)
+		assert.Equal(t, c.v, a.ParsedVersion, c.s)
+		assert.Equal(t, c.v, a.ToRss().ParsedVersion, c.s)
+	}
+}
+
+const rssVersionTestFeed = `
+<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>A channel title that is well over forty characters long, for 0.90</title>
+    <link>http://example.com/</link>
+    <description>desc</description>
+    <generator>grss</generator>
+    <ttl>60</ttl>
+    <category>news</category>
+    <cloud domain="rpc.sys.com" port="80" path="/RPC2" registerProcedure="pingMe" protocol="soap"/>
+    <rating>(PICS-1.1)</rating>
+    <image>
+      <title>img</title>
+      <url>http://example.com/i.gif</url>
+      <link>http://example.com/</link>
+      <width>200</width>
+    </image>
+    <item>
+      <title>one</title>
+      <link>http://example.com/1</link>
+      <content:encoded><![CDATA[<p>full</p>]]></content:encoded>
+      <guid>http://example.com/1</guid>
+      <author>a@example.com</author>
+      <pubDate>Wed, 09 Aug 2000 04:00:00 GMT</pubDate>
+      <enclosure url="http://example.com/1.mp3" length="1" type="audio/mpeg"/>
+      <source url="http://example.org/rss">Origin</source>
+      <category>x</category>
+    </item>
+  </channel>
+</rss>
+`
+
+func Test_RssVersion_091Netscape(t *testing.T) {
+	s := rssVersionTestFeed
+	s = strings.Replace(s, "</channel>", strings.Repeat("<item><title>more</title></item>", 20)+"</channel>", 1)
+
+	a := rssParseVersion(t, s).ToRssVersion(RssVersion091Netscape)
+	assert.Equal(t, "0.91", a.Version)
+	assert.Equal(t, "rss", a.XMLName.Local)
+	assert.Nil(t, a.Channel.Cloud)
+	assert.Nil(t, a.Channel.Categories)
+	assert.Equal(t, "", a.Channel.Generator)
+	assert.Equal(t, "", a.Channel.Ttl)
+	assert.Equal(t, "(PICS-1.1)", a.Channel.Rating.String())
+	assert.Equal(t, "144", a.Channel.Image.Width)
+	assert.Equal(t, 15, len(a.Channel.Items))
+	assert.Nil(t, a.Channel.Items[0].Guid)
+	assert.Nil(t, a.Channel.Items[0].Enclosures)
+	assert.Nil(t, a.Channel.Items[0].ContentEncoded)
+	assert.Equal(t, "<p>full</p>", a.Channel.Items[0].Description.String())
+	assert.Equal(t, "", a.Channel.Items[0].PubDate)
+
+	var buf bytes.Buffer
+	err := a.WriteOut(&buf)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "?>\n<!"+Rss091NetscapeDoctype+">\n<rss version=\"0.91\">", buf.String())
+	assert.NotContains(t, buf.String(), "xmlns", buf.String())
+
+	b := rssParseVersion(t, buf.String())
+	assert.Equal(t, RssVersion091Netscape, b.ParsedVersion)
+}
+
+func Test_RssVersion_092(t *testing.T) {
+	a := rssParseVersion(t, rssVersionTestFeed).ToRssVersion(RssVersion092)
+	assert.Equal(t, "0.92", a.Version)
+	assert.Equal(t, "", a.Doctype)
+	assert.NotNil(t, a.Channel.Cloud)
+	assert.Equal(t, "news", a.Channel.Categories[0].Text)
+	assert.Equal(t, "200", a.Channel.Image.Width)
+	assert.Nil(t, a.Channel.Items[0].Guid)
+	assert.Nil(t, a.Channel.Items[0].Author)
+	assert.Equal(t, "http://example.com/1.mp3", a.Channel.Items[0].Enclosures[0].Url)
+	assert.Equal(t, "Origin", a.Channel.Items[0].Source.Text)
+	assert.Equal(t, "x", a.Channel.Items[0].Categories[0].Text)
+}
+
+func Test_RssVersion_090(t *testing.T) {
+	a := rssParseVersion(t, rssVersionTestFeed).ToRssVersion(RssVersion090)
+	assert.Equal(t, "", a.Version)
+	assert.Equal(t, 40, len([]rune(a.Channel.Title.String())))
+	assert.Equal(t, "http://example.com/i.gif", a.Image.Url)
+	assert.Equal(t, 0, len(a.Channel.Items))
+	assert.Equal(t, "one", a.Items[0].Title)
+	assert.Nil(t, a.Items[0].Description)
+
+	var buf bytes.Buffer
+	err := a.WriteOut(&buf)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://my.netscape.com/rdf/simple/0.9/">`, buf.String())
+
+	b := rssParseVersion(t, buf.String())
+	assert.Equal(t, RssVersion090, b.ParsedVersion)
+	assert.Equal(t, "http://example.com/1", b.ToRss().Channel.Items[0].Link)
+}
+
+func Test_RssVersion_FromRDF(t *testing.T) {
+	// the 0.90 default namespace must not end up on the <rss> root
+	a := rssParseVersion(t, `<?xml version="1.0"?><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://my.netscape.com/rdf/simple/0.9/"><channel><title>a</title></channel><item><title>b</title><link>http://b</link></item></rdf:RDF>`)
+
+	var buf bytes.Buffer
+	err := a.ToRss().WriteOut(&buf)
+	assert.Nil(t, err)
+	assert.NotContains(t, buf.String(), "netscape", buf.String())
+	assert.NotContains(t, buf.String(), "xmlns:rdf", buf.String())
+}
+
+func Test_RssFeed_Enclosures(t *testing.T) {
+	s := `
+<?xml version="1.0"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/">
+  <channel>
+    <title>Podcast</title>
+    <link>http://example.com/</link>
+    <description>desc</description>
+    <item>
+      <title>Episode 1</title>
+      <link>http://example.com/1</link>
+      <enclosure url="http://example.com/1.mp3" length="100" type="audio/mpeg"/>
+      <enclosure url="http://example.com/1.pdf" length="5" type="application/pdf"/>
+      <media:group>
+        <media:content url="http://example.com/1.mp3" fileSize="100" type="audio/mpeg" isDefault="true"/>
+        <media:content url="http://example.com/1.ogg" fileSize="90" type="audio/ogg" duration="61"/>
+      </media:group>
+    </item>
+  </channel>
+</rss>
+`
+
+	a := rssParseVersion(t, s)
+	assert.Equal(t, 2, len(a.Channel.Items[0].Enclosures))
+	assert.Equal(t, 2, len(a.Channel.Items[0].MediaGroups[0].Contents))
+
+	j := a.ToJSON()
+	attachments := j.Items[0].Attachments
+	assert.Equal(t, 3, len(attachments))
+	assert.Equal(t, "Episode 1", attachments[0].Title)
+	assert.Equal(t, "", attachments[1].Title)
+	assert.Equal(t, "http://example.com/1.ogg", attachments[2].URL)
+	assert.Equal(t, "Episode 1", attachments[2].Title)
+	assert.Equal(t, uint64(61), attachments[2].DurationInSeconds)
+
+	atom := a.ToAtom()
+	var enclosures []*AtomLink
+	for _, link := range atom.Entries[0].Links {
+		if link.Rel == "enclosure" {
+			enclosures = append(enclosures, link)
+		}
+	}
+	assert.Equal(t, 3, len(enclosures))
+	assert.Equal(t, "5", enclosures[1].Length)
+
+	// alternates survive JSON and Atom round trips as a media:group
+	for _, b := range []*RssFeed{j.ToRss(), atom.ToRss()} {
+		item := b.Channel.Items[0]
+		assert.Equal(t, 2, len(item.Enclosures))
+		assert.Equal(t, "http://example.com/1.mp3", item.Enclosures[0].Url)
+		assert.Equal(t, "http://example.com/1.pdf", item.Enclosures[1].Url)
+		assert.Equal(t, 1, len(item.MediaGroups))
+		assert.Equal(t, "true", item.MediaGroups[0].Contents[0].IsDefault)
+		assert.Equal(t, "http://example.com/1.ogg", item.MediaGroups[0].Contents[1].Url)
+	}
+
+	var buf bytes.Buffer
+	err := a.ToRss().WriteOutWith(&buf, WriteOptions{SingleEnclosure: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(buf.String(), "<enclosure "), buf.String())
+	assert.Contains(t, buf.String(), `<media:content url="http://example.com/1.pdf" fileSize="5" type="application/pdf"></media:content>`, buf.String())
+	assert.Equal(t, 2, len(a.Channel.Items[0].Enclosures))
+}
+
+const rssGuidTestFeed = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Guids</title>
+    <link>https://example.com/</link>
+    <description>The guids</description>
+    <item>
+      <title>permalink, no link</title>
+      <guid>https://example.com/1</guid>
+    </item>
+    <item>
+      <title>explicit permalink, no link</title>
+      <guid isPermaLink="true">https://example.com/2</guid>
+    </item>
+    <item>
+      <title>permalink, link</title>
+      <link>https://example.com/3</link>
+      <guid>https://example.com/3</guid>
+    </item>
+    <item>
+      <title>not a permalink, no link</title>
+      <guid isPermaLink="false">https://example.com/4</guid>
+    </item>
+    <item>
+      <title>not a permalink, link</title>
+      <link>https://example.com/5</link>
+      <guid isPermaLink="false">5</guid>
+    </item>
+    <item>
+      <title>no isPermaLink, not a URL</title>
+      <guid>aae20190418</guid>
+    </item>
+  </channel>
+</rss>`
+
+func Test_RssGuid_PermaLink(t *testing.T) {
+	assert.Equal(t, "https://example.com/1", (&RssGuid{Guid: " https://example.com/1 "}).PermaLink())
+	assert.Equal(t, "https://example.com/1", (&RssGuid{Guid: "https://example.com/1", IsPermaLink: "true"}).PermaLink())
+	assert.Equal(t, "", (&RssGuid{Guid: "https://example.com/1", IsPermaLink: "false"}).PermaLink())
+	assert.Equal(t, "", (&RssGuid{Guid: "https://example.com/1", IsPermaLink: "False"}).PermaLink())
+	assert.Equal(t, "", (&RssGuid{Guid: "aae20190418"}).PermaLink())
+	assert.Equal(t, "", (&RssGuid{Guid: "tag:example.com,2023:1"}).PermaLink())
+	assert.Equal(t, "", (*RssGuid)(nil).PermaLink())
+}
+
+func Test_RssGuid_Conversions(t *testing.T) {
+	a := rssParseVersion(t, rssGuidTestFeed)
+
+	// a permalink guid is the url of an item without a link
+	j := a.ToJSON()
+	urls := []string{"https://example.com/1", "https://example.com/2", "https://example.com/3", "", "https://example.com/5", ""}
+	for i, item := range j.Items {
+		assert.Equal(t, urls[i], item.URL, item.Title)
+		assert.Equal(t, a.Channel.Items[i].Guid.Guid, item.ID, item.Title)
+	}
+	for i, entry := range a.ToAtom().Entries {
+		var alternate string
+		if len(entry.Links) > 0 {
+			alternate = string(entry.Links[0].Href)
+		}
+		assert.Equal(t, urls[i], alternate, entry.Title.String())
+		assert.Equal(t, a.Channel.Items[i].Guid.Guid, string(entry.ID.AtomUri))
+	}
+	r := a.ToRss10()
+	for i, item := range r.Items {
+		assert.Equal(t, urls[i], item.Link, item.Title)
+	}
+	// the other guids cannot be opened, nor are they URIs to identify the items, which get a urn:uuid
+	assert.True(t, strings.HasPrefix(r.Items[3].About, "urn:uuid:"), r.Items[3].About)
+	assert.True(t, strings.HasPrefix(r.Items[5].About, "urn:uuid:"), r.Items[5].About)
+	for i, activity := range a.ToActivityStreams().OrderedItems {
+		if object := activity.Object[0]; urls[i] == "" {
+			assert.Equal(t, 0, len(object.URL))
+		} else {
+			assert.Equal(t, urls[i], object.URL[0].link())
+		}
+	}
+
+	// the permalinks round trip through JSON Feed and Atom, the other guids are isPermaLink="false",
+	// but for an http guid without a link, which JSON Feed and Atom, having no isPermaLink, take for the URL of its item
+	permalinks := []string{"https://example.com/1", "https://example.com/2", "https://example.com/3", "https://example.com/4", "", ""}
+	for _, b := range []*RssFeed{j.ToRss(), a.ToAtom().ToRss()} {
+		for i, item := range b.Channel.Items {
+			assert.Equal(t, a.Channel.Items[i].Guid.Guid, item.Guid.Guid)
+			assert.Equal(t, permalinks[i], item.Guid.PermaLink(), item.Title)
+			assert.Equal(t, urls[i], item.Link, item.Title)
+		}
+		assert.Equal(t, "", b.Channel.Items[0].Guid.IsPermaLink)
+		assert.Equal(t, "false", b.Channel.Items[4].Guid.IsPermaLink)
+		assert.Equal(t, "false", b.Channel.Items[5].Guid.IsPermaLink)
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, j.ToRss().WriteOut(&buf))
+	assert.Contains(t, buf.String(), "<guid>https://example.com/1</guid>")
+	assert.Contains(t, buf.String(), `<guid isPermaLink="false">aae20190418</guid>`)
+}
+
+func Test_RssGuid_FromJSONAndAtom(t *testing.T) {
+	j := &JSONFeed{Title: "Notes", Items: []*JSONItem{
+		{ID: "https://example.com/1", URL: "https://example.com/1", ContentText: "the url"},
+		{ID: "note-2", URL: "https://example.com/2", ContentText: "not a URL"},
+		{ID: "https://example.com/3", ContentText: "a URL without url, the url"},
+		{ID: "https://example.com/4?v=1", URL: "https://example.com/4", ContentText: "another URL"},
+		{URL: "https://example.com/5", ContentText: "no id"},
+	}}
+	items := j.ToRss().Channel.Items
+	assert.Equal(t, &RssGuid{Guid: "https://example.com/1"}, items[0].Guid)
+	assert.Equal(t, &RssGuid{Guid: "note-2", IsPermaLink: "false"}, items[1].Guid)
+	assert.Equal(t, &RssGuid{Guid: "https://example.com/3"}, items[2].Guid)
+	assert.Equal(t, &RssGuid{Guid: "https://example.com/4?v=1", IsPermaLink: "false"}, items[3].Guid)
+	assert.Nil(t, items[4].Guid)
+
+	_, f, err := Parse(strings.NewReader(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>tag:example.com,2023:feed</id>
+  <title>Entries</title>
+  <updated>2023-01-02T00:00:00Z</updated>
+  <entry>
+    <id>tag:example.com,2023:1</id>
+    <title>tag URI</title>
+    <link href="https://example.com/1"/>
+    <updated>2023-01-02T00:00:00Z</updated>
+  </entry>
+  <entry>
+    <id>https://example.com/2</id>
+    <title>the alternate link</title>
+    <link rel="alternate" href="https://example.com/2"/>
+    <updated>2023-01-02T00:00:00Z</updated>
+  </entry>
+  <entry>
+    <id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+    <title>urn, no link</title>
+    <updated>2023-01-02T00:00:00Z</updated>
+  </entry>
+</feed>`))
+	assert.Nil(t, err)
+	items = f.ToRss().Channel.Items
+	assert.Equal(t, &RssGuid{Guid: "tag:example.com,2023:1", IsPermaLink: "false"}, items[0].Guid)
+	assert.Equal(t, &RssGuid{Guid: "https://example.com/2"}, items[1].Guid)
+	assert.Equal(t, &RssGuid{Guid: "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a", IsPermaLink: "false"}, items[2].Guid)
+	for i, item := range f.ToJSON().ToRss().Channel.Items {
+		assert.Equal(t, items[i].Guid, item.Guid)
+	}
+
+	// a tag: or urn: id identifies an RSS 1.0 item, but is not its link
+	r := f.ToRss10()
+	assert.Equal(t, "https://example.com/1", r.Items[0].About)
+	assert.Equal(t, "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a", r.Items[2].About)
+	assert.Equal(t, "", r.Items[2].Link)
+}
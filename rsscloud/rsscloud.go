@@ -0,0 +1,109 @@
+// Package rsscloud implements the rssCloud interface of RSS 2.0 <cloud> for grss feeds, the registration and notification of subscribers and the pings of publishers.
+package rsscloud
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellodword/grss"
+	"github.com/nbio/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// rssCloud https://www.rssboard.org/rsscloud-interface
+// RSS 2.0 <cloud> https://www.rssboard.org/rss-specification#ltcloudgtSubelementOfLtchannelgt
+// JSON Feed hubs https://www.jsonfeed.org/version/1.1/#subscribing-to-real-time-notifications-a-name-subscribing-to-real-time-notifications-a
+
+const (
+	ProtocolHttpPost = "http-post"
+	ProtocolXmlRpc   = "xml-rpc"
+	ProtocolSoap     = "soap"
+)
+
+// Lease registrations expire after 25 hours, subscribers re-register every day.
+const Lease = 25 * time.Hour
+
+var (
+	ErrNoCloud             = errors.New("no cloud")
+	ErrUnsupportedProtocol = errors.New("unsupported protocol")
+	ErrCloudRejected       = errors.New("cloud rejected the request")
+)
+
+// Discover returns the cloud advertised by f, the <cloud> of RSS or a hub of type rssCloud in JSON Feed, nil when there is none.
+func Discover(f grss.Feed) *grss.RssCloud {
+	r := f.ToRss()
+	if r == nil || r.Channel == nil {
+		return nil
+	}
+	return r.Channel.Cloud
+}
+
+// Result the answer of a cloud to a registration (<notifyResult>) or a ping (<result>) over http-post.
+type Result struct {
+	XMLName xml.Name
+	Success bool   `xml:"success,attr"`
+	Msg     string `xml:"msg,attr,omitempty"`
+}
+
+func writeResult(w http.ResponseWriter, name string, success bool, msg string) {
+	w.Header().Set("Content-Type", "text/xml")
+	if !success {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(&Result{
+		XMLName: xml.Name{Local: name},
+		Success: success,
+		Msg:     msg,
+	})
+}
+
+func httpClient(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
+
+// postForm sends a form and reads the <notifyResult> or <result> the cloud answers with.
+// Answers that are no result are accepted on any 2xx status, the clouds in the wild vary.
+func postForm(client *http.Client, endpoint string, form url.Values) error {
+	resp, err := httpClient(client).PostForm(endpoint, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	var result Result
+	if xml.Unmarshal(body, &result) == nil && result.XMLName.Local != "" {
+		if !result.Success {
+			return fmt.Errorf("%s: %w: %s", endpoint, ErrCloudRejected, result.Msg)
+		}
+		return nil
+	}
+
+	if resp.StatusCode/100 != 2 {
+		if len(body) > 512 {
+			body = body[:512]
+		}
+		return fmt.Errorf("%s: %w: %s %s", endpoint, ErrCloudRejected, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Ping tells the cloud that the feed at feedURL changed, the cloud then notifies its subscribers.
+// pingURL is the ping endpoint of the cloud, such as http://rpc.rsscloud.io:5337/ping.
+func Ping(client *http.Client, pingURL, feedURL string) error {
+	if pingURL == "" {
+		return ErrNoCloud
+	}
+	return postForm(client, pingURL, url.Values{"url": {feedURL}})
+}
@@ -0,0 +1,168 @@
+package rsscloud
+
+import (
+	"errors"
+	"github.com/hellodword/grss"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testRss = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Scripting News</title>
+    <link>http://scripting.com/</link>
+    <description>It's even worse than it appears.</description>
+    <atom:link rel="self" href="http://scripting.com/rss.xml"/>
+    <cloud domain="DOMAIN" port="PORT" path="/rsscloud/pleaseNotify" registerProcedure="" protocol="http-post"/>
+    <item><title>hello</title><guid isPermaLink="false">1</guid></item>
+  </channel>
+</rss>`
+
+// cloudFor the <cloud> of the server, its registration endpoint is the path of the test server.
+func cloudFor(t *testing.T, server *httptest.Server, path, protocol, procedure string) *grss.RssCloud {
+	u, err := url.Parse(server.URL)
+	assert.Nil(t, err)
+	return &grss.RssCloud{
+		Domain:            u.Hostname(),
+		Port:              u.Port(),
+		Path:              path,
+		RegisterProcedure: procedure,
+		Protocol:          protocol,
+	}
+}
+
+type notified struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (n *notified) add(url string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.urls = append(n.urls, url)
+}
+
+func (n *notified) get() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.urls...)
+}
+
+func Test_HttpPost(t *testing.T) {
+	cloud := &Server{}
+	mux := http.NewServeMux()
+	mux.Handle("/rsscloud/pleaseNotify", cloud)
+	mux.HandleFunc("/ping", cloud.ServePing)
+	cloudServer := httptest.NewServer(mux)
+	defer cloudServer.Close()
+
+	var n notified
+	s := &Subscriber{OnNotify: n.add}
+	subscriberServer := httptest.NewServer(s)
+	defer subscriberServer.Close()
+	s.Callback = subscriberServer.URL + "/notify"
+
+	c := cloudFor(t, cloudServer, "/rsscloud/pleaseNotify", ProtocolHttpPost, "")
+	content := strings.NewReplacer("DOMAIN", c.Domain, "PORT", c.Port).Replace(testRss)
+	_, f, err := grss.Parse(strings.NewReader(content))
+	assert.Nil(t, err)
+	assert.Equal(t, c, Discover(f))
+	assert.Equal(t, c, Discover(f.ToJSON()))
+
+	start := time.Now()
+	err = s.RegisterFeed(f, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cloud.Subscribers("http://scripting.com/rss.xml"))
+	regs := s.Registrations()
+	assert.Equal(t, 1, len(regs))
+	assert.Equal(t, "http://scripting.com/rss.xml", regs[0].URL)
+	assert.WithinDuration(t, start.Add(Lease), regs[0].Expires, time.Minute)
+	// the registration was checked with a challenge, not a notification
+	assert.Equal(t, 0, len(n.get()))
+
+	err = Ping(nil, cloudServer.URL+"/ping", "http://scripting.com/rss.xml")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"http://scripting.com/rss.xml"}, n.get())
+
+	// nobody registered for that one
+	err = cloud.Notify("http://example.com/rss.xml")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(n.get()))
+
+	// renewal
+	assert.Equal(t, 0, len(s.Expiring(time.Hour)))
+	s.now = func() time.Time { return start.Add(24 * time.Hour) }
+	assert.Equal(t, 1, len(s.Expiring(2*time.Hour)))
+	err = s.Renew(2 * time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(s.Expiring(2*time.Hour)))
+
+	// a subscriber that forgot the feed fails the notification and is dropped
+	s.Unregister("http://scripting.com/rss.xml")
+	err = cloud.Notify("http://scripting.com/rss.xml")
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, cloud.Subscribers("http://scripting.com/rss.xml"))
+	assert.Equal(t, 1, len(n.get()))
+
+	err = Ping(nil, cloudServer.URL+"/ping", "")
+	assert.True(t, errors.Is(err, ErrCloudRejected), err)
+}
+
+func Test_XmlRpc(t *testing.T) {
+	cloud := &Server{}
+	cloudServer := httptest.NewServer(cloud)
+	defer cloudServer.Close()
+
+	var n notified
+	s := &Subscriber{OnNotify: n.add, Protocol: ProtocolXmlRpc}
+	subscriberServer := httptest.NewServer(s)
+	defer subscriberServer.Close()
+	s.Callback = subscriberServer.URL + "/RPC2"
+
+	c := cloudFor(t, cloudServer, "/RPC2", ProtocolXmlRpc, "myCloud.rssPleaseNotify")
+	err := s.Register(c, "http://example.com/a.xml", "http://example.com/b.xml")
+	assert.Nil(t, err)
+	// an xml-rpc subscriber is checked with a first notification
+	assert.Equal(t, []string{"http://example.com/a.xml"}, n.get())
+	assert.Equal(t, 1, cloud.Subscribers("http://example.com/b.xml"))
+
+	err = cloud.Notify("http://example.com/b.xml")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"http://example.com/a.xml", "http://example.com/b.xml"}, n.get())
+
+	// expired registrations are not notified
+	cloud.now = func() time.Time { return time.Now().Add(Lease + time.Minute) }
+	err = cloud.Notify("http://example.com/b.xml")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(n.get()))
+	assert.Equal(t, 0, cloud.Subscribers("http://example.com/a.xml"))
+}
+
+func Test_Register_Rejected(t *testing.T) {
+	cloud := &Server{}
+	cloudServer := httptest.NewServer(cloud)
+	defer cloudServer.Close()
+
+	// the callback does not answer the challenge
+	s := &Subscriber{}
+	deaf := httptest.NewServer(http.NotFoundHandler())
+	defer deaf.Close()
+	s.Callback = deaf.URL
+
+	err := s.Register(cloudFor(t, cloudServer, "/", ProtocolHttpPost, ""), "http://example.com/rss.xml")
+	assert.True(t, errors.Is(err, ErrCloudRejected), err)
+	assert.Equal(t, 0, len(s.Registrations()))
+
+	err = s.Register(cloudFor(t, cloudServer, "/", ProtocolSoap, "pingMe"), "http://example.com/rss.xml")
+	assert.True(t, errors.Is(err, ErrUnsupportedProtocol), err)
+
+	err = s.RegisterFeed(&grss.JSONFeed{}, "http://example.com/feed.json")
+	assert.Equal(t, ErrNoCloud, err)
+}
@@ -0,0 +1,281 @@
+package rsscloud
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/hellodword/grss"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subscriber a registered subscriber of a feed.
+type subscriber struct {
+	endpoint  string
+	protocol  string
+	procedure string
+	expires   time.Time
+}
+
+// Server the cloud of a publisher. It is the http.Handler of the registerProcedure endpoint advertised by <cloud>,
+// and Notify tells the subscribers registered for a feed that it changed.
+type Server struct {
+	// Client sends the challenges and notifications to the subscribers, http.DefaultClient when nil.
+	Client *http.Client
+	// Lease how long a registration lasts, Lease when zero.
+	Lease time.Duration
+
+	mu   sync.Mutex
+	subs map[string]map[string]*subscriber
+	now  func() time.Time
+}
+
+func (s *Server) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func (s *Server) lease() time.Duration {
+	if s.Lease <= 0 {
+		return Lease
+	}
+	return s.Lease
+}
+
+// registration the parameters of a request for notification, as sent over http-post or xml-rpc.
+type registration struct {
+	notifyProcedure string
+	port            string
+	path            string
+	protocol        string
+	domain          string
+	urls            []string
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// a subscriber without a domain is notified at the address the request came from
+	remote, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+	if isXmlRpc(r) {
+		call, err := readCall(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// notifyProcedure, port, path, protocol, urlList [, domain]
+		if len(call.Params) < 5 {
+			writeResponse(w, &methodResponse{Fault: faultValue(4, "expected notifyProcedure, port, path, protocol and urlList")})
+			return
+		}
+		reg := &registration{
+			notifyProcedure: call.Params[0].str(),
+			port:            call.Params[1].str(),
+			path:            call.Params[2].str(),
+			protocol:        call.Params[3].str(),
+			urls:            call.Params[4].strings(),
+			domain:          remote,
+		}
+		if len(call.Params) > 5 && call.Params[5].str() != "" {
+			reg.domain = call.Params[5].str()
+		}
+		err = s.register(reg, len(call.Params) > 5)
+		if err != nil {
+			writeResponse(w, &methodResponse{Fault: faultValue(4, err.Error())})
+			return
+		}
+		writeResponse(w, &methodResponse{Params: []value{boolValue(true)}})
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		writeResult(w, "notifyResult", false, err.Error())
+		return
+	}
+	reg := &registration{
+		notifyProcedure: r.PostForm.Get("notifyProcedure"),
+		port:            r.PostForm.Get("port"),
+		path:            r.PostForm.Get("path"),
+		protocol:        r.PostForm.Get("protocol"),
+		domain:          r.PostForm.Get("domain"),
+	}
+	for i := 1; ; i++ {
+		u := r.PostForm.Get("url" + strconv.Itoa(i))
+		if u == "" {
+			break
+		}
+		reg.urls = append(reg.urls, u)
+	}
+	withDomain := reg.domain != ""
+	if !withDomain {
+		reg.domain = remote
+	}
+	err = s.register(reg, withDomain)
+	if err != nil {
+		writeResult(w, "notifyResult", false, err.Error())
+		return
+	}
+	writeResult(w, "notifyResult", true, "Thanks for the registration. It worked. When the feed updates we'll notify you. Don't forget to re-register after 24 hours, your subscription will expire in 25.")
+}
+
+// register checks that the subscriber answers before storing the registration.
+// A subscriber that gave a domain has to echo a challenge for http-post, any other is sent a first notification.
+func (s *Server) register(reg *registration, withDomain bool) error {
+	if reg.protocol != ProtocolHttpPost && reg.protocol != ProtocolXmlRpc {
+		return fmt.Errorf("%s: %w", reg.protocol, ErrUnsupportedProtocol)
+	}
+	if len(reg.urls) == 0 {
+		return fmt.Errorf("no url to register")
+	}
+	if _, err := strconv.Atoi(reg.port); err != nil {
+		return fmt.Errorf("port %q: %w", reg.port, err)
+	}
+
+	sub := &subscriber{
+		endpoint:  (&grss.RssCloud{Domain: reg.domain, Port: reg.port, Path: reg.path}).Endpoint(),
+		protocol:  reg.protocol,
+		procedure: reg.notifyProcedure,
+	}
+	if sub.protocol == ProtocolXmlRpc && sub.procedure == "" {
+		sub.procedure = DefaultNotifyProcedure
+	}
+
+	var err error
+	if withDomain && sub.protocol == ProtocolHttpPost {
+		err = s.challenge(sub, reg.urls[0])
+	} else {
+		err = s.notify(sub, reg.urls[0])
+	}
+	if err != nil {
+		return fmt.Errorf("the subscriber at %s did not answer: %w", sub.endpoint, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs == nil {
+		s.subs = map[string]map[string]*subscriber{}
+	}
+	sub.expires = s.clock().Add(s.lease())
+	for _, u := range reg.urls {
+		if s.subs[u] == nil {
+			s.subs[u] = map[string]*subscriber{}
+		}
+		s.subs[u][sub.endpoint] = sub
+	}
+	return nil
+}
+
+func (s *Server) challenge(sub *subscriber, feedURL string) error {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	challenge := hex.EncodeToString(b)
+
+	q := url.Values{"url": {feedURL}, "challenge": {challenge}}
+	resp, err := httpClient(s.Client).Get(sub.endpoint + "?" + q.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(len(challenge)+64)))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 || strings.TrimSpace(string(body)) != challenge {
+		return fmt.Errorf("challenge: %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *Server) notify(sub *subscriber, feedURL string) error {
+	if sub.protocol == ProtocolXmlRpc {
+		return callXmlRpc(s.Client, sub.endpoint, sub.procedure, stringValue(feedURL))
+	}
+
+	resp, err := httpClient(s.Client).PostForm(sub.endpoint, url.Values{"url": {feedURL}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("notify %s: %s", sub.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Subscribers returns the number of live registrations for the feed.
+func (s *Server) Subscribers(feedURL string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, sub := range s.subs[feedURL] {
+		if sub.expires.After(s.clock()) {
+			n++
+		}
+	}
+	return n
+}
+
+// Notify tells the subscribers registered for the feed that it changed.
+// Expired registrations and subscribers that fail to answer are dropped, their errors are returned together.
+func (s *Server) Notify(feedURL string) error {
+	s.mu.Lock()
+	var subs []*subscriber
+	for endpoint, sub := range s.subs[feedURL] {
+		if !sub.expires.After(s.clock()) {
+			delete(s.subs[feedURL], endpoint)
+			continue
+		}
+		subs = append(subs, sub)
+	}
+	s.mu.Unlock()
+
+	var errs []string
+	for _, sub := range subs {
+		err := s.notify(sub, feedURL)
+		if err != nil {
+			errs = append(errs, err.Error())
+			s.mu.Lock()
+			delete(s.subs[feedURL], sub.endpoint)
+			s.mu.Unlock()
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("notify %s: %s", feedURL, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ServePing is the handler of the ping endpoint, a publisher POSTs the url of the feed that changed and its subscribers are notified.
+func (s *Server) ServePing(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil || r.PostForm.Get("url") == "" {
+		writeResult(w, "result", false, "The url parameter is missing.")
+		return
+	}
+	// failing subscribers are the cloud's business, the publisher's ping succeeded
+	_ = s.Notify(r.PostForm.Get("url"))
+	writeResult(w, "result", true, "Thanks for the ping.")
+}
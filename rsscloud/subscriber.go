@@ -0,0 +1,308 @@
+package rsscloud
+
+import (
+	"fmt"
+	"github.com/hellodword/grss"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultNotifyProcedure the procedure a cloud calls to notify a Subscriber over xml-rpc.
+const DefaultNotifyProcedure = "rssCloud.notify"
+
+// Registration a feed registered with a cloud.
+type Registration struct {
+	// URL the feed the Subscriber is notified about.
+	URL   string
+	Cloud grss.RssCloud
+	// Expires zero until the cloud accepted the registration.
+	Expires time.Time
+}
+
+// Subscriber registers with clouds to be notified when feeds change.
+// It is the http.Handler of the callback URL, it answers the clouds' challenges and hands notifications to OnNotify.
+type Subscriber struct {
+	// Callback the public URL the Subscriber is served at, its host, port and path are sent to the clouds.
+	Callback string
+	// Protocol the clouds notify the Subscriber with, http-post (the default) or xml-rpc.
+	Protocol string
+	// NotifyProcedure the procedure the clouds call over xml-rpc, DefaultNotifyProcedure when empty.
+	NotifyProcedure string
+	// Client sends the requests to the clouds, http.DefaultClient when nil.
+	Client *http.Client
+
+	// OnNotify is called with the URL of a registered feed that changed, fetch it to read the news.
+	OnNotify func(url string)
+
+	mu   sync.Mutex
+	regs map[string]*Registration
+	now  func() time.Time
+}
+
+func (s *Subscriber) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func (s *Subscriber) protocol() string {
+	if s.Protocol == "" {
+		return ProtocolHttpPost
+	}
+	return s.Protocol
+}
+
+func (s *Subscriber) notifyProcedure() string {
+	if s.NotifyProcedure == "" {
+		return DefaultNotifyProcedure
+	}
+	return s.NotifyProcedure
+}
+
+// Register asks the cloud to notify the Subscriber when the feeds at urls change.
+// The cloud may call back to check the Subscriber before it answers, the registrations then last for Lease.
+func (s *Subscriber) Register(cloud *grss.RssCloud, urls ...string) error {
+	if cloud == nil || cloud.Endpoint() == "" {
+		return ErrNoCloud
+	}
+	if cloud.Protocol != ProtocolHttpPost && cloud.Protocol != ProtocolXmlRpc {
+		return fmt.Errorf("%s: %w", cloud.Protocol, ErrUnsupportedProtocol)
+	}
+	if p := s.protocol(); p != ProtocolHttpPost && p != ProtocolXmlRpc {
+		return fmt.Errorf("%s: %w", p, ErrUnsupportedProtocol)
+	}
+
+	callback, err := url.Parse(s.Callback)
+	if err != nil {
+		return err
+	}
+	if callback.Hostname() == "" {
+		return fmt.Errorf("callback %q: no host", s.Callback)
+	}
+	port := callback.Port()
+	if port == "" {
+		port = "80"
+		if callback.Scheme == "https" {
+			port = "443"
+		}
+	}
+	path := callback.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return err
+	}
+
+	// the cloud checks the registration before answering, the challenge must find it
+	s.mu.Lock()
+	if s.regs == nil {
+		s.regs = map[string]*Registration{}
+	}
+	var added []string
+	for _, u := range urls {
+		if _, ok := s.regs[u]; !ok {
+			s.regs[u] = &Registration{URL: u, Cloud: *cloud}
+			added = append(added, u)
+		}
+	}
+	s.mu.Unlock()
+
+	endpoint := cloud.Endpoint()
+	if cloud.Protocol == ProtocolXmlRpc {
+		var list []value
+		for _, u := range urls {
+			list = append(list, stringValue(u))
+		}
+		err = callXmlRpc(s.Client, endpoint, cloud.RegisterProcedure,
+			stringValue(s.notifyProcedure()),
+			intValue(portNumber),
+			stringValue(path),
+			stringValue(s.protocol()),
+			arrayOf(list...),
+			stringValue(callback.Hostname()),
+		)
+	} else {
+		form := url.Values{
+			"notifyProcedure": {""},
+			"port":            {port},
+			"path":            {path},
+			"protocol":        {s.protocol()},
+			"domain":          {callback.Hostname()},
+		}
+		if s.protocol() == ProtocolXmlRpc {
+			form.Set("notifyProcedure", s.notifyProcedure())
+		}
+		for i, u := range urls {
+			form.Set("url"+strconv.Itoa(i+1), u)
+		}
+		err = postForm(s.Client, endpoint, form)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		for _, u := range added {
+			delete(s.regs, u)
+		}
+		return err
+	}
+	expires := s.clock().Add(Lease)
+	for _, u := range urls {
+		s.regs[u].Cloud = *cloud
+		s.regs[u].Expires = expires
+	}
+	return nil
+}
+
+// RegisterFeed registers for the feed at the cloud it advertises, feedURL is where the feed is fetched from, its self link when empty.
+func (s *Subscriber) RegisterFeed(f grss.Feed, feedURL string) error {
+	cloud := Discover(f)
+	if cloud == nil {
+		return ErrNoCloud
+	}
+	if feedURL == "" {
+		feedURL = grss.Paging(f, "").Self
+	}
+	return s.Register(cloud, feedURL)
+}
+
+// Unregister forgets the feed, the Subscriber no longer renews it and refuses its notifications.
+// rssCloud has no unregistration, the cloud drops the registration when it expires or fails.
+func (s *Subscriber) Unregister(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.regs, url)
+}
+
+// Registrations returns a snapshot of the registrations.
+func (s *Subscriber) Registrations() []Registration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var regs []Registration
+	for _, reg := range s.regs {
+		regs = append(regs, *reg)
+	}
+	return regs
+}
+
+// Expiring returns the accepted registrations that expire within d.
+func (s *Subscriber) Expiring(d time.Duration) []Registration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadline := s.clock().Add(d)
+	var regs []Registration
+	for _, reg := range s.regs {
+		if !reg.Expires.IsZero() && reg.Expires.Before(deadline) {
+			regs = append(regs, *reg)
+		}
+	}
+	return regs
+}
+
+// Renew registers again the feeds that expire within d, one request per cloud, call it periodically.
+func (s *Subscriber) Renew(d time.Duration) error {
+	clouds := map[grss.RssCloud][]string{}
+	var order []grss.RssCloud
+	for _, reg := range s.Expiring(d) {
+		if _, ok := clouds[reg.Cloud]; !ok {
+			order = append(order, reg.Cloud)
+		}
+		clouds[reg.Cloud] = append(clouds[reg.Cloud], reg.URL)
+	}
+
+	var errs []string
+	for _, cloud := range order {
+		cloud := cloud
+		err := s.Register(&cloud, clouds[cloud]...)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("renew: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *Subscriber) registered(url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.regs[url]
+	return ok
+}
+
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.challenge(w, r)
+	case http.MethodPost:
+		if isXmlRpc(r) {
+			s.notifyXmlRpc(w, r)
+		} else {
+			s.notifyHttpPost(w, r)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// challenge answers the check of a cloud that registers a Subscriber with a domain, the challenge is echoed for the feeds being registered.
+func (s *Subscriber) challenge(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	challenge := q.Get("challenge")
+	if challenge == "" || !s.registered(q.Get("url")) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = io.WriteString(w, challenge)
+}
+
+func (s *Subscriber) notify(url string) bool {
+	if !s.registered(url) {
+		return false
+	}
+	if s.OnNotify != nil {
+		s.OnNotify(url)
+	}
+	return true
+}
+
+// notifyHttpPost a POST with the url of the feed that changed, a non-2xx status tells the cloud to drop the registration.
+func (s *Subscriber) notifyHttpPost(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.notify(r.PostForm.Get("url")) {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// notifyXmlRpc a call of the notify procedure with the url of the feed that changed.
+func (s *Subscriber) notifyXmlRpc(w http.ResponseWriter, r *http.Request) {
+	call, err := readCall(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(call.Params) == 0 || !s.notify(call.Params[0].str()) {
+		writeResponse(w, &methodResponse{Fault: faultValue(4, "not registered")})
+		return
+	}
+	writeResponse(w, &methodResponse{Params: []value{boolValue(true)}})
+}
@@ -0,0 +1,168 @@
+package rsscloud
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/nbio/xml"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// the subset of XML-RPC the rssCloud interface uses: strings, integers, booleans, arrays and faults.
+// http://xmlrpc.com/spec.md
+
+type methodCall struct {
+	XMLName    xml.Name `xml:"methodCall"`
+	MethodName string   `xml:"methodName"`
+	Params     []value  `xml:"params>param>value"`
+}
+
+type methodResponse struct {
+	XMLName xml.Name `xml:"methodResponse"`
+	Params  []value  `xml:"params>param>value,omitempty"`
+	Fault   *value   `xml:"fault>value,omitempty"`
+}
+
+type value struct {
+	String  *string      `xml:"string,omitempty"`
+	Int     *string      `xml:"int,omitempty"`
+	I4      *string      `xml:"i4,omitempty"`
+	Boolean *string      `xml:"boolean,omitempty"`
+	Array   *arrayValue  `xml:"array,omitempty"`
+	Struct  *structValue `xml:"struct,omitempty"`
+	// Text a value without a type is a string
+	Text string `xml:",chardata"`
+}
+
+type arrayValue struct {
+	Values []value `xml:"data>value"`
+}
+
+type structValue struct {
+	Members []member `xml:"member"`
+}
+
+type member struct {
+	Name  string `xml:"name"`
+	Value value  `xml:"value"`
+}
+
+func stringValue(s string) value {
+	return value{String: &s}
+}
+
+func intValue(i int) value {
+	s := strconv.Itoa(i)
+	return value{Int: &s}
+}
+
+func boolValue(b bool) value {
+	s := "0"
+	if b {
+		s = "1"
+	}
+	return value{Boolean: &s}
+}
+
+func arrayOf(values ...value) value {
+	return value{Array: &arrayValue{Values: values}}
+}
+
+func faultValue(code int, msg string) *value {
+	return &value{Struct: &structValue{Members: []member{
+		{Name: "faultCode", Value: intValue(code)},
+		{Name: "faultString", Value: stringValue(msg)},
+	}}}
+}
+
+func (v value) str() string {
+	switch {
+	case v.String != nil:
+		return *v.String
+	case v.Int != nil:
+		return strings.TrimSpace(*v.Int)
+	case v.I4 != nil:
+		return strings.TrimSpace(*v.I4)
+	case v.Boolean != nil:
+		return strings.TrimSpace(*v.Boolean)
+	default:
+		return strings.TrimSpace(v.Text)
+	}
+}
+
+func (v value) strings() []string {
+	if v.Array == nil {
+		if s := v.str(); s != "" {
+			return []string{s}
+		}
+		return nil
+	}
+	var ss []string
+	for _, item := range v.Array.Values {
+		ss = append(ss, item.str())
+	}
+	return ss
+}
+
+func (v value) member(name string) value {
+	if v.Struct != nil {
+		for _, m := range v.Struct.Members {
+			if m.Name == name {
+				return m.Value
+			}
+		}
+	}
+	return value{}
+}
+
+func isXmlRpc(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Content-Type"), "xml")
+}
+
+func readCall(r *http.Request) (*methodCall, error) {
+	var call methodCall
+	err := xml.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&call)
+	if err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+func writeResponse(w http.ResponseWriter, resp *methodResponse) {
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(resp)
+}
+
+// callXmlRpc calls method at endpoint, a fault or a false boolean is an error wrapping ErrCloudRejected.
+func callXmlRpc(client *http.Client, endpoint, method string, params ...value) error {
+	b, err := xml.Marshal(&methodCall{MethodName: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient(client).Post(endpoint, "text/xml", bytes.NewReader(append([]byte(xml.Header), b...)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %w: %s", method, endpoint, ErrCloudRejected, resp.Status)
+	}
+
+	var result methodResponse
+	err = xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, endpoint, err)
+	}
+	if result.Fault != nil {
+		return fmt.Errorf("%s %s: %w: %s", method, endpoint, ErrCloudRejected, result.Fault.member("faultString").str())
+	}
+	if len(result.Params) > 0 && result.Params[0].Boolean != nil && result.Params[0].str() != "1" {
+		return fmt.Errorf("%s %s: %w", method, endpoint, ErrCloudRejected)
+	}
+	return nil
+}
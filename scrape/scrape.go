@@ -0,0 +1,321 @@
+// Package scrape builds feeds out of HTML pages, for the sites that publish none.
+//
+// The items are selected with CSS selectors, or found in the microformats2 h-feed and h-entry markup of the page:
+//
+//	f, err := scrape.Scrape(resp.Body, "https://example.com/blog/", &scrape.Config{
+//		Item:    "article.post",
+//		Title:   "h2",
+//		Link:    "h2 a",
+//		Date:    "time",
+//		Summary: ".excerpt",
+//		Image:   "img.cover",
+//	})
+//	f.ToRss().WriteOut(w)
+package scrape
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/cascadia"
+	"github.com/hellodword/grss"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoItems the page has no item matching the configuration, or no h-entry.
+	ErrNoItems = errors.New("scrape: no items found")
+)
+
+// Config the CSS selectors of the items of a page.
+//
+// The selectors of the fields are relative to the item, they select the first matching element and may end with @attr to read an attribute instead of the default:
+// the text for Title and Summary, href for Link, src for Image, datetime, or else the text, for Date.
+// An empty selector of Link or Image reads the item element itself, which is then usually the link or the image, an empty Title or Summary is left out.
+type Config struct {
+	// Item selects the elements of the items
+	Item    string `json:"item" yaml:"item"`
+	Title   string `json:"title,omitempty" yaml:"title,omitempty"`
+	Link    string `json:"link,omitempty" yaml:"link,omitempty"`
+	Date    string `json:"date,omitempty" yaml:"date,omitempty"`
+	Summary string `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Image   string `json:"image,omitempty" yaml:"image,omitempty"`
+	// Content selects the HTML content of the item, written as content_html
+	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// field a compiled selector of Config, the attribute it reads and whether it reads the inner HTML.
+type field struct {
+	sel  cascadia.Selector
+	attr string
+	html bool
+}
+
+func compileField(name, s, attr string) (*field, error) {
+	s = strings.TrimSpace(s)
+	f := &field{attr: attr}
+	if i := strings.LastIndex(s, "@"); i >= 0 && !strings.ContainsAny(s[i:], "]) ") {
+		s, f.attr = strings.TrimSpace(s[:i]), s[i+1:]
+	}
+	if s == "" {
+		return f, nil
+	}
+	sel, err := cascadia.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("scrape: %s: %w", name, err)
+	}
+	f.sel = sel
+	return f, nil
+}
+
+// value the value of the field in the item n.
+func (f *field) value(n *html.Node) string {
+	if f == nil {
+		return ""
+	}
+	if f.sel != nil {
+		n = f.sel.MatchFirst(n)
+		if n == nil {
+			return ""
+		}
+	}
+	if f.attr != "" {
+		if v, ok := attr(n, f.attr); ok {
+			return strings.TrimSpace(v)
+		}
+		if f.attr != "datetime" {
+			return ""
+		}
+	}
+	if f.html {
+		return innerHTML(n)
+	}
+	return text(n)
+}
+
+func attr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// text the text of n with the white space collapsed.
+func text(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style):
+			return
+		case n.Type == html.ElementNode && n.DataAtom == atom.Img:
+			if alt, ok := attr(n, "alt"); ok {
+				b.WriteString(alt)
+			}
+		case n.Type == html.ElementNode && n.DataAtom == atom.Br:
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func innerHTML(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		_ = html.Render(&b, c)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// page the parsed document, its base URL and the metadata of its head.
+type page struct {
+	root *html.Node
+	base *url.URL
+}
+
+func parsePage(r io.Reader, base string) (*page, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("scrape: %w", err)
+	}
+	p := &page{root: root}
+	p.base, err = url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("scrape: base: %w", err)
+	}
+	// <base href> changes the base of the relative URLs of the document
+	if n := cascadia.MustCompile("head base[href]").MatchFirst(root); n != nil {
+		href, _ := attr(n, "href")
+		if u, err := p.base.Parse(strings.TrimSpace(href)); err == nil {
+			p.base = u
+		}
+	}
+	return p, nil
+}
+
+// resolve s against the base of the page.
+func (p *page) resolve(s string) string {
+	if s == "" {
+		return ""
+	}
+	u, err := p.base.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.String()
+}
+
+// feed the JSON Feed of the page, without items: the title, the description, the language and the icon of the document.
+func (p *page) feed() *grss.JSONFeed {
+	f := &grss.JSONFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		HomePageURL: p.base.String(),
+	}
+	if n := cascadia.MustCompile("html").MatchFirst(p.root); n != nil {
+		f.Language, _ = attr(n, "lang")
+	}
+	if n := cascadia.MustCompile("head title").MatchFirst(p.root); n != nil {
+		f.Title = text(n)
+	}
+	for _, n := range cascadia.MustCompile(`meta[name="description" i], meta[property="og:description"]`).MatchAll(p.root) {
+		if f.Description == "" {
+			f.Description, _ = attr(n, "content")
+		}
+	}
+	for _, n := range cascadia.MustCompile(`link[rel~="icon" i][href]`).MatchAll(p.root) {
+		if f.Favicon == "" {
+			href, _ := attr(n, "href")
+			f.Favicon = p.resolve(href)
+		}
+	}
+	if n := cascadia.MustCompile(`link[rel~="canonical" i][href]`).MatchFirst(p.root); n != nil {
+		href, _ := attr(n, "href")
+		f.HomePageURL = p.resolve(href)
+	}
+	return f
+}
+
+// itemID the id of an item, its URL or, without one, a hash of its title, summary and date.
+func itemID(item *grss.JSONItem) string {
+	if item.URL != "" {
+		return item.URL
+	}
+	sum := sha1.Sum([]byte(item.Title + "\n" + item.Summary + "\n" + item.DatePublished))
+	return "urn:sha1:" + hex.EncodeToString(sum[:])
+}
+
+// date the date s in RFC 3339, or "" when it does not parse.
+func date(s string) string {
+	if s == "" {
+		return ""
+	}
+	t, err := grss.ParseDate(s)
+	if err != nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Scrape builds a JSON Feed of the HTML page read from r, whose relative URLs are resolved against base, the URL of the page.
+// The items are selected by config, or when config is nil found in the h-feed or the h-entry elements of the page.
+// The feed is a JSONFeed, its ToRss and ToAtom convert it to the other formats.
+func Scrape(r io.Reader, base string, config *Config) (*grss.JSONFeed, error) {
+	if config == nil {
+		return microformats(r, base)
+	}
+	p, err := parsePage(r, base)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := cascadia.Compile(config.Item)
+	if err != nil {
+		return nil, fmt.Errorf("scrape: item: %w", err)
+	}
+	var title, link, published, summary, image, content *field
+	for _, c := range []struct {
+		name, selector, attr string
+		f                    **field
+	}{
+		{"title", config.Title, "", &title},
+		{"link", config.Link, "href", &link},
+		{"date", config.Date, "datetime", &published},
+		{"summary", config.Summary, "", &summary},
+		{"image", config.Image, "src", &image},
+		{"content", config.Content, "", &content},
+	} {
+		if c.selector == "" && c.name != "link" && c.name != "image" {
+			continue
+		}
+		f, err := compileField(c.name, c.selector, c.attr)
+		if err != nil {
+			return nil, err
+		}
+		*c.f = f
+	}
+	if content != nil {
+		content.html = content.attr == ""
+	}
+
+	f := p.feed()
+	for _, n := range item.MatchAll(p.root) {
+		it := &grss.JSONItem{
+			Title:         title.value(n),
+			URL:           p.resolve(link.value(n)),
+			DatePublished: date(published.value(n)),
+			Summary:       summary.value(n),
+			Image:         p.resolve(image.value(n)),
+		}
+		if content != nil {
+			it.ContentHTML = grss.ResolveHTML(content.value(n), p.base.String())
+		}
+		if it.ContentHTML == "" {
+			// content_html or content_text is required
+			it.ContentText = it.Summary
+			if it.ContentText == "" {
+				it.ContentText = it.Title
+			}
+		}
+		if it.Title == "" && it.URL == "" && it.Summary == "" && it.ContentHTML == "" {
+			continue
+		}
+		it.ID = itemID(it)
+		f.Items = append(f.Items, it)
+	}
+
+	if len(f.Items) == 0 {
+		return nil, ErrNoItems
+	}
+	return f, nil
+}
+
+// microformats the JSON Feed of the h-feed of the page, parsed by grss.ParseHTML, with an id for each item.
+func microformats(r io.Reader, base string) (*grss.JSONFeed, error) {
+	f, err := grss.ParseHTML(r, base)
+	if err == grss.ErrNoHEntry {
+		return nil, ErrNoItems
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scrape: %w", err)
+	}
+	for _, item := range f.Items {
+		if item.ID == "" {
+			item.ID = itemID(item)
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,96 @@
+package scrape
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func open(t *testing.T, name string) *os.File {
+	f, err := os.Open("testdata/" + name)
+	assert.Nil(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func Test_Scrape(t *testing.T) {
+	f, err := Scrape(open(t, "blog.html"), "https://example.com/blog/index.html", &Config{
+		Item:    "article.post",
+		Title:   "h2",
+		Link:    "h2 a",
+		Date:    "time, .date",
+		Summary: ".excerpt",
+		Image:   "img.cover",
+		Content: ".body",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "Example Blog", f.Title)
+	assert.Equal(t, "Notes about examples", f.Description)
+	assert.Equal(t, "https://example.com/blog/", f.HomePageURL)
+	assert.Equal(t, "https://example.com/favicon.ico", f.Favicon)
+	assert.Equal(t, "en", f.Language)
+	assert.Equal(t, 3, len(f.Items))
+
+	second := f.Items[0]
+	assert.Equal(t, "Second post", second.Title)
+	assert.Equal(t, "https://example.com/blog/second-post", second.URL)
+	assert.Equal(t, second.URL, second.ID)
+	assert.Equal(t, "2023-01-03T10:00:00Z", second.DatePublished)
+	assert.Equal(t, "https://example.com/blog/img/second.png", second.Image)
+	assert.Equal(t, "The second one.", second.Summary)
+	assert.Equal(t, `<p>More <a href="https://example.com/blog/third-post">here</a>.</p>`, second.ContentHTML)
+
+	first := f.Items[1]
+	assert.Equal(t, "First post", first.Title)
+	assert.Equal(t, "https://example.com/blog/first-post", first.URL)
+	assert.Equal(t, "2023-01-02T10:00:00Z", first.DatePublished)
+	assert.Equal(t, "The first one.", first.ContentText)
+
+	announcement := f.Items[2]
+	assert.Equal(t, "", announcement.URL)
+	assert.Equal(t, "", announcement.DatePublished)
+	assert.True(t, strings.HasPrefix(announcement.ID, "urn:sha1:"))
+
+	// through the existing writers
+	var b bytes.Buffer
+	assert.Nil(t, f.ToRss().WriteOut(&b))
+	assert.Contains(t, b.String(), "<link>https://example.com/blog/second-post</link>")
+	assert.Contains(t, b.String(), "<pubDate>Tue, 03 Jan 2023 10:00:00 +0000</pubDate>")
+
+	// an attribute
+	f, err = Scrape(open(t, "blog.html"), "https://example.com/blog/", &Config{Item: "article.post h2 a", Title: "@href"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/blog/second-post", f.Items[0].Title)
+	assert.Equal(t, "https://example.com/blog/second-post", f.Items[0].URL)
+
+	_, err = Scrape(open(t, "blog.html"), "https://example.com/", &Config{Item: "article.missing"})
+	assert.Equal(t, ErrNoItems, err)
+	_, err = Scrape(open(t, "blog.html"), "https://example.com/", &Config{Item: "article[", Title: "h2"})
+	assert.NotNil(t, err)
+}
+
+func Test_Scrape_Microformats(t *testing.T) {
+	f, err := Scrape(open(t, "hfeed.html"), "https://alice.example/", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Alice's notes", f.Title)
+	assert.Equal(t, 2, len(f.Items))
+
+	hello := f.Items[0]
+	assert.Equal(t, "Hello", hello.Title)
+	assert.Equal(t, "https://alice.example/2023/hello", hello.URL)
+	assert.Equal(t, "2023-01-02T10:00:00+01:00", hello.DatePublished)
+	assert.Equal(t, []string{"intro"}, hello.Tags)
+	assert.Equal(t, "https://alice.example/photo.jpg", hello.Image)
+	assert.Equal(t, `<p>Hello <img class="u-photo" src="https://alice.example/photo.jpg" alt="me"/></p>`, hello.ContentHTML)
+
+	note := f.Items[1]
+	assert.Equal(t, "", note.Title)
+	assert.Equal(t, "https://alice.example/2023/note", note.URL)
+	assert.Equal(t, "2023-01-03T08:00:00Z", note.DatePublished)
+	assert.Equal(t, "Just a note", note.ContentText)
+
+	_, err = Scrape(open(t, "blog.html"), "https://example.com/", nil)
+	assert.Equal(t, ErrNoItems, err)
+}
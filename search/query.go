@@ -0,0 +1,489 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	// ErrQuery the query is not valid, the errors of Search wrap it with what is wrong.
+	ErrQuery = errors.New("search: invalid query")
+)
+
+// tokenKind the kinds of the tokens of the queries.
+type tokenKind int
+
+const (
+	tokenTerm tokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+// token a token of a query, a term being a word or a "phrase", in a field when field is not empty.
+type token struct {
+	kind   tokenKind
+	field  string
+	text   string
+	phrase bool
+}
+
+// lex splits the query into its tokens.
+func lex(query string) ([]token, error) {
+	var tokens []token
+	r := []rune(query)
+
+	// quoted reads the phrase whose opening quote is at i, and returns it and the index after its closing quote.
+	quoted := func(i int) (string, int, error) {
+		for j := i + 1; j < len(r); j++ {
+			if r[j] == '"' {
+				return string(r[i+1 : j]), j + 1, nil
+			}
+		}
+		return "", 0, fmt.Errorf("%w: unterminated phrase %s", ErrQuery, string(r[i:]))
+	}
+
+	for i := 0; i < len(r); {
+		switch c := r[i]; {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose})
+			i++
+		case c == '-' && i+1 < len(r) && !unicode.IsSpace(r[i+1]) && r[i+1] != ')':
+			tokens = append(tokens, token{kind: tokenNot})
+			i++
+		case c == '"':
+			text, next, err := quoted(i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenTerm, text: text, phrase: true})
+			i = next
+		default:
+			j := i
+			for j < len(r) && !unicode.IsSpace(r[j]) && r[j] != '(' && r[j] != ')' && r[j] != '"' {
+				j++
+			}
+			word := string(r[i:j])
+
+			switch word {
+			case "AND":
+				tokens = append(tokens, token{kind: tokenAnd})
+				i = j
+				continue
+			case "OR":
+				tokens = append(tokens, token{kind: tokenOr})
+				i = j
+				continue
+			case "NOT":
+				tokens = append(tokens, token{kind: tokenNot})
+				i = j
+				continue
+			}
+
+			t := token{kind: tokenTerm, text: word}
+			if name, value, ok := strings.Cut(word, ":"); ok {
+				name = strings.ToLower(name)
+				if _, known := fieldNames[name]; known || name == "date" {
+					t.field, t.text = name, value
+					if value == "" && j < len(r) && r[j] == '"' {
+						text, next, err := quoted(j)
+						if err != nil {
+							return nil, err
+						}
+						t.text, t.phrase, j = text, true, next
+					}
+				}
+			}
+			tokens = append(tokens, t)
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// node a node of the tree of a query.
+type node interface {
+	// eval the documents matching the node, with their scores.
+	eval(ix *Index) map[int]float64
+}
+
+// parser a recursive descent parser of the queries:
+//
+//	or      = and { "OR" and }
+//	and     = unary { [ "AND" ] unary }
+//	unary   = ( "NOT" | "-" ) unary | primary
+//	primary = "(" or ")" | term
+//
+// A term without any word, punctuation only, is a nil node, which the other nodes ignore.
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// parseQuery the tree of the query, nil for a query without any term.
+func parseQuery(query string) (node, error) {
+	tokens, err := lex(query)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %s", ErrQuery, p.tokens[p.i])
+	}
+	return n, nil
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenNot:
+		return "NOT"
+	case tokenOpen:
+		return "("
+	case tokenClose:
+		return ")"
+	}
+	text := t.text
+	if t.phrase {
+		text = `"` + text + `"`
+	}
+	if t.field != "" {
+		text = t.field + ":" + text
+	}
+	return text
+}
+
+// peek the kind of the next token, or -1 at the end of the query.
+func (p *parser) peek() tokenKind {
+	if p.i < len(p.tokens) {
+		return p.tokens[p.i].kind
+	}
+	return -1
+}
+
+func (p *parser) or() (node, error) {
+	n, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []node{n}
+	for p.peek() == tokenOr {
+		p.i++
+		n, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return newOr(nodes), nil
+}
+
+func (p *parser) and() (node, error) {
+	var nodes []node
+	for {
+		switch p.peek() {
+		case -1, tokenOr, tokenClose:
+			if len(nodes) == 0 {
+				return nil, p.expected()
+			}
+			return newAnd(nodes), nil
+		case tokenAnd:
+			if len(nodes) == 0 {
+				return nil, p.expected()
+			}
+			p.i++
+		}
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if p.peek() != tokenNot {
+		return p.primary()
+	}
+	p.i++
+	n, err := p.unary()
+	if err != nil || n == nil {
+		return nil, err
+	}
+	return &notNode{n}, nil
+}
+
+func (p *parser) primary() (node, error) {
+	switch p.peek() {
+	case tokenOpen:
+		p.i++
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != tokenClose {
+			return nil, fmt.Errorf("%w: missing )", ErrQuery)
+		}
+		p.i++
+		return n, nil
+	case tokenTerm:
+		t := p.tokens[p.i]
+		p.i++
+		if t.field == "date" {
+			from, to, err := parseDateRange(t.text)
+			if err != nil {
+				return nil, err
+			}
+			return &dateNode{from: from, to: to}, nil
+		}
+		n := &termNode{field: -1, tokens: tokenize(t.text)}
+		if len(n.tokens) == 0 {
+			return nil, nil
+		}
+		if f, ok := fieldNames[t.field]; ok {
+			n.field = f
+		}
+		return n, nil
+	}
+	return nil, p.expected()
+}
+
+// expected the error of a missing term at the current token.
+func (p *parser) expected() error {
+	if p.i < len(p.tokens) {
+		return fmt.Errorf("%w: expected a term before %s", ErrQuery, p.tokens[p.i])
+	}
+	return fmt.Errorf("%w: expected a term at the end", ErrQuery)
+}
+
+// termNode the documents having the words of a term next to each other, in the field or in any field when it is -1.
+type termNode struct {
+	field  field
+	tokens []string
+}
+
+func (n *termNode) eval(ix *Index) map[int]float64 {
+	scores := map[int]float64{}
+	for f := field(0); f < numFields; f++ {
+		if n.field != -1 && n.field != f {
+			continue
+		}
+
+		if len(n.tokens) == 1 {
+			for _, term := range ix.terms(f, n.tokens[0]) {
+				postings := ix.postings[postingKey(f, term)]
+				for doc, positions := range postings {
+					scores[doc] += ix.bm25(f, doc, len(positions), len(postings))
+				}
+			}
+			continue
+		}
+
+		// a phrase, its frequency being the number of its occurrences
+		tfs := map[int]int{}
+		first := ix.postings[postingKey(f, n.tokens[0])]
+		for doc, positions := range first {
+			for _, p := range positions {
+				if n.at(ix, f, doc, p) {
+					tfs[doc]++
+				}
+			}
+		}
+		for doc, tf := range tfs {
+			scores[doc] += ix.bm25(f, doc, tf, len(tfs))
+		}
+	}
+	return scores
+}
+
+// at reports whether the phrase is in the field of the document at the position p.
+func (n *termNode) at(ix *Index, f field, doc, p int) bool {
+	for i, term := range n.tokens[1:] {
+		found := false
+		for _, q := range ix.postings[postingKey(f, term)][doc] {
+			if q == p+i+1 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// dateNode the documents dated from from, and before to, either being zero for an open range.
+type dateNode struct {
+	from, to time.Time
+}
+
+func (n *dateNode) eval(ix *Index) map[int]float64 {
+	scores := map[int]float64{}
+	for i, doc := range ix.docs {
+		if doc.Date.IsZero() || !n.from.IsZero() && doc.Date.Before(n.from) || !n.to.IsZero() && !doc.Date.Before(n.to) {
+			continue
+		}
+		scores[i] = 0
+	}
+	return scores
+}
+
+// dateLayouts the layouts of the dates of the queries, a year, a month or a day.
+var dateLayouts = []struct {
+	layout        string
+	years, months int
+	days          int
+}{
+	{"2006", 1, 0, 0},
+	{"2006-01", 0, 1, 0},
+	{"2006-01-02", 0, 0, 1},
+}
+
+// parseDateSpan the start and the exclusive end of the year, month or day s, in UTC.
+func parseDateSpan(s string) (start, end time.Time, err error) {
+	for _, l := range dateLayouts {
+		if len(s) != len(l.layout) {
+			continue
+		}
+		if start, err = time.Parse(l.layout, s); err == nil {
+			return start, start.AddDate(l.years, l.months, l.days), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%w: invalid date %q", ErrQuery, s)
+}
+
+// parseDateRange the range of the value of a date: filter, a date, a range a..b with an optional end, or a comparison.
+func parseDateRange(s string) (from, to time.Time, err error) {
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(s, op) {
+			continue
+		}
+		start, end, err := parseDateSpan(s[len(op):])
+		if err != nil {
+			return from, to, err
+		}
+		switch op {
+		case ">=":
+			return start, to, nil
+		case "<=":
+			return from, end, nil
+		case ">":
+			return end, to, nil
+		default:
+			return from, start, nil
+		}
+	}
+
+	a, z, isRange := strings.Cut(s, "..")
+	if !isRange {
+		return parseDateSpan(s)
+	}
+	if a == "" && z == "" {
+		return from, to, fmt.Errorf("%w: invalid date range %q", ErrQuery, s)
+	}
+	if a != "" {
+		if from, _, err = parseDateSpan(a); err != nil {
+			return from, to, err
+		}
+	}
+	if z != "" {
+		if _, to, err = parseDateSpan(z); err != nil {
+			return from, to, err
+		}
+	}
+	return from, to, nil
+}
+
+// andNode the documents matching all the nodes, their scores summed.
+type andNode []node
+
+func newAnd(nodes []node) node {
+	return combine(nodes, func(nodes []node) node { return andNode(nodes) })
+}
+
+func (n andNode) eval(ix *Index) map[int]float64 {
+	scores := n[0].eval(ix)
+	for _, other := range n[1:] {
+		if len(scores) == 0 {
+			break
+		}
+		otherScores := other.eval(ix)
+		for doc, score := range scores {
+			if s, ok := otherScores[doc]; ok {
+				scores[doc] = score + s
+			} else {
+				delete(scores, doc)
+			}
+		}
+	}
+	return scores
+}
+
+// orNode the documents matching any of the nodes, their scores summed.
+type orNode []node
+
+func newOr(nodes []node) node {
+	return combine(nodes, func(nodes []node) node { return orNode(nodes) })
+}
+
+func (n orNode) eval(ix *Index) map[int]float64 {
+	scores := map[int]float64{}
+	for _, other := range n {
+		for doc, score := range other.eval(ix) {
+			scores[doc] += score
+		}
+	}
+	return scores
+}
+
+// combine the node of the nodes that are not nil, nil when there are none.
+func combine(nodes []node, fn func([]node) node) node {
+	var n []node
+	for _, node := range nodes {
+		if node != nil {
+			n = append(n, node)
+		}
+	}
+	switch len(n) {
+	case 0:
+		return nil
+	case 1:
+		return n[0]
+	}
+	return fn(n)
+}
+
+// notNode the documents not matching the node.
+type notNode struct {
+	node
+}
+
+func (n *notNode) eval(ix *Index) map[int]float64 {
+	excluded := n.node.eval(ix)
+	scores := map[int]float64{}
+	for doc := range ix.docs {
+		if _, ok := excluded[doc]; !ok {
+			scores[doc] = 0
+		}
+	}
+	return scores
+}
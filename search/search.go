@@ -0,0 +1,555 @@
+// Package search is a full-text search index of the items of feeds, embedded and in pure Go.
+//
+// The titles, the text of the contents, the authors and the categories of the items, in any format, are indexed,
+// and the queries are ranked with BM25:
+//
+//	ix, err := search.Open("index")
+//	err = ix.AddFeed(feedURL, f)
+//	results, err := ix.Search(`"event loop" author:alice -tag:draft date:2023-01..2023-03`, 10)
+//	err = ix.Close()
+//
+// See Search for the syntax of the queries.
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hellodword/grss"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// field the indexed fields of the items.
+type field int
+
+const (
+	fieldTitle field = iota
+	fieldContent
+	fieldAuthor
+	fieldTag
+	numFields
+)
+
+// fieldNames the names of the fields in the queries, category and categories being tag.
+var fieldNames = map[string]field{
+	"title":      fieldTitle,
+	"content":    fieldContent,
+	"author":     fieldAuthor,
+	"tag":        fieldTag,
+	"category":   fieldTag,
+	"categories": fieldTag,
+}
+
+// fieldWeights the weights of the matches in the fields, a title says more than the content.
+var fieldWeights = [numFields]float64{
+	fieldTitle:   2,
+	fieldContent: 1,
+	fieldAuthor:  1,
+	fieldTag:     1.5,
+}
+
+// the parameters of BM25 https://en.wikipedia.org/wiki/Okapi_BM25
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// the files of the index in its directory: the index as of the last Save, and the journal of the changes since
+const (
+	indexFile   = "index.gob"
+	journalFile = "index.log"
+)
+
+var (
+	// ErrClosed the index was closed.
+	ErrClosed = errors.New("search: closed")
+)
+
+// Result an item matching a query.
+type Result struct {
+	// ID is the identity of the item, grss.StableID.
+	ID     string
+	FeedID string
+	Title  string
+	URL    string
+	// Date is the publication date of the item, or else its modification date, zero when it has none.
+	Date  time.Time
+	Score float64
+}
+
+// document an indexed item.
+type document struct {
+	ID     string
+	FeedID string
+	Title  string
+	URL    string
+	Date   time.Time
+	// Lengths the number of terms of the fields, for the length normalization of BM25.
+	Lengths [numFields]int
+	// Terms the keys of the postings of the document, to remove it.
+	Terms []string
+}
+
+// Index an inverted index of the terms of the items, with their positions for the phrases.
+type Index struct {
+	mu      sync.RWMutex
+	dir     string
+	journal *os.File
+
+	// docs the documents by number.
+	docs map[int]*document
+	// ids the numbers of the documents by item identity.
+	ids map[string]int
+	// postings the positions of the terms in the documents, by field and term, keyed as postingKey.
+	postings map[string]map[int][]int
+	// next the number of the next document.
+	next int
+
+	totalLengths [numFields]int
+	closed       bool
+}
+
+// snapshot the index as it is written to its file.
+type snapshot struct {
+	Docs     map[int]*document
+	IDs      map[string]int
+	Postings map[string]map[int][]int
+	Next     int
+}
+
+// change a line of the journal, the items added to a feed or the identities removed.
+type change struct {
+	Feed   string           `json:"feed,omitempty"`
+	Items  []*grss.JSONItem `json:"items,omitempty"`
+	Remove []string         `json:"remove,omitempty"`
+}
+
+// postingKey the key of the postings of the term in the field.
+func postingKey(f field, term string) string {
+	return string(rune('0'+f)) + term
+}
+
+// Open opens the index persisted in the directory dir, created when missing, or an index in memory only when dir is empty.
+//
+// The changes of Add and Remove are appended to a journal as they are made, and replayed by Open,
+// so that a crash loses none of them but a last change cut short. Save and Close fold the journal into the file of the index.
+func Open(dir string) (*Index, error) {
+	ix := &Index{
+		dir:      dir,
+		docs:     map[int]*document{},
+		ids:      map[string]int{},
+		postings: map[string]map[int][]int{},
+	}
+	if dir == "" {
+		return ix, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, indexFile))
+	if err == nil {
+		var snap snapshot
+		err = gob.NewDecoder(f).Decode(&snap)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		ix.docs, ix.ids, ix.postings, ix.next = snap.Docs, snap.IDs, snap.Postings, snap.Next
+		for _, doc := range ix.docs {
+			for i, n := range doc.Lengths {
+				ix.totalLengths[i] += n
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if err := ix.replay(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// replay applies the changes of the journal, which is then kept open to append the next ones.
+// The changes already in the file of the index, when a crash happened between the save and the truncation of the journal, are applied again to the same effect.
+func (ix *Index) replay() error {
+	path := filepath.Join(ix.dir, journalFile)
+	journal, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	r := bufio.NewReader(journal)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// the last write was interrupted
+				err = journal.Truncate(size)
+			} else {
+				err = nil
+			}
+			if err == nil {
+				_, err = journal.Seek(size, io.SeekStart)
+			}
+			if err != nil {
+				_ = journal.Close()
+				return err
+			}
+			ix.journal = journal
+			return nil
+		}
+		if err != nil {
+			_ = journal.Close()
+			return err
+		}
+
+		var c change
+		if err := json.Unmarshal(line, &c); err != nil {
+			_ = journal.Close()
+			return fmt.Errorf("search: %s: change at offset %d: %w", path, size, err)
+		}
+		ix.apply(&c)
+		size += int64(len(line))
+	}
+}
+
+// apply makes the change to the index.
+func (ix *Index) apply(c *change) {
+	for _, item := range c.Items {
+		if item != nil {
+			ix.add(c.Feed, item)
+		}
+	}
+	for _, id := range c.Remove {
+		if n, ok := ix.ids[id]; ok {
+			ix.remove(n)
+		}
+	}
+}
+
+// record appends the change to the journal, before it is applied.
+func (ix *Index) record(c *change) error {
+	if ix.journal == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(c); err != nil {
+		return err
+	}
+	_, err := ix.journal.Write(buf.Bytes())
+	return err
+}
+
+// Len the number of indexed items.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// AddFeed indexes the items of f as those of the feed feedID, as Add.
+func (ix *Index) AddFeed(feedID string, f grss.Feed) error {
+	return ix.Add(feedID, f.ToJSON().Items...)
+}
+
+// Add indexes the items of the feed feedID, replacing the items of the same identity already indexed.
+// The items are journaled before Add returns, see Open.
+func (ix *Index) Add(feedID string, items ...*grss.JSONItem) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return ErrClosed
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	c := &change{Feed: feedID, Items: items}
+	if err := ix.record(c); err != nil {
+		return err
+	}
+	ix.apply(c)
+	return nil
+}
+
+func (ix *Index) add(feedID string, item *grss.JSONItem) {
+	id := grss.StableID(item)
+	if n, ok := ix.ids[id]; ok {
+		ix.remove(n)
+	}
+
+	doc := &document{
+		ID:     id,
+		FeedID: feedID,
+		Title:  item.Title,
+		URL:    item.URL,
+	}
+	if doc.URL == "" {
+		doc.URL = item.ExternalURL
+	}
+	for _, d := range []string{item.DatePublished, item.DateModified} {
+		if t, err := grss.ParseDate(d); d != "" && err == nil {
+			doc.Date = t.UTC()
+			break
+		}
+	}
+
+	// the content_text of the RSS descriptions is often HTML too
+	var content []string
+	for _, s := range []string{item.Summary, item.ContentText, item.ContentHTML} {
+		if s != "" {
+			content = append(content, plainText(s))
+		}
+	}
+	var authors []string
+	if item.Author != nil {
+		authors = append(authors, item.Author.Name)
+	}
+	for _, a := range item.Authors {
+		authors = append(authors, a.Name)
+	}
+
+	n := ix.next
+	ix.next++
+	ix.docs[n] = doc
+	ix.ids[id] = n
+
+	for f, values := range [numFields][]string{
+		fieldTitle:   {item.Title},
+		fieldContent: content,
+		fieldAuthor:  authors,
+		fieldTag:     item.Tags,
+	} {
+		position := 0
+		for _, value := range values {
+			for _, term := range tokenize(value) {
+				key := postingKey(field(f), term)
+				postings := ix.postings[key]
+				if postings == nil {
+					postings = map[int][]int{}
+					ix.postings[key] = postings
+				}
+				if postings[n] == nil {
+					doc.Terms = append(doc.Terms, key)
+				}
+				postings[n] = append(postings[n], position)
+				position++
+			}
+			// the values are not phrases together
+			position++
+		}
+		doc.Lengths[f] = position - len(values)
+		ix.totalLengths[f] += doc.Lengths[f]
+	}
+}
+
+// Remove removes the items of identities ids from the index, the unknown ones are ignored.
+// The removal is journaled before Remove returns, see Open.
+func (ix *Index) Remove(ids ...string) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return ErrClosed
+	}
+
+	var known []string
+	for _, id := range ids {
+		if _, ok := ix.ids[id]; ok {
+			known = append(known, id)
+		}
+	}
+	if len(known) == 0 {
+		return nil
+	}
+
+	c := &change{Remove: known}
+	if err := ix.record(c); err != nil {
+		return err
+	}
+	ix.apply(c)
+	return nil
+}
+
+func (ix *Index) remove(n int) {
+	doc := ix.docs[n]
+	for _, key := range doc.Terms {
+		delete(ix.postings[key], n)
+		if len(ix.postings[key]) == 0 {
+			delete(ix.postings, key)
+		}
+	}
+	for f, length := range doc.Lengths {
+		ix.totalLengths[f] -= length
+	}
+	delete(ix.ids, doc.ID)
+	delete(ix.docs, n)
+}
+
+// Search returns the items matching the query, the best first, at most limit of them when limit is positive.
+//
+// The query is made of terms, all of which the items must match:
+//
+//	go channels            the items with both words, in any field
+//	"event loop"           the phrase, the words next to each other
+//	go OR rust             either
+//	go -rust, go NOT rust  the items without rust
+//	(go OR rust) AND wasm  grouping, AND being implicit
+//	author:alice           the words or "phrase" in a field: title, content, author or tag, category being tag
+//	date:2023-01-02        the items of the day, or of the month 2023-01, or of the year 2023
+//	date:2023-01..2023-03  the items from January to March 2023, either end may be left out
+//	date:>2023-01          the items after January 2023, and >=, < and <=
+//
+// The items matching only by their date are ordered by date, the most recent first.
+func (ix *Index) Search(query string, limit int) ([]*Result, error) {
+	n, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if ix.closed {
+		return nil, ErrClosed
+	}
+
+	var results []*Result
+	if n != nil {
+		for docN, score := range n.eval(ix) {
+			doc := ix.docs[docN]
+			results = append(results, &Result{
+				ID:     doc.ID,
+				FeedID: doc.FeedID,
+				Title:  doc.Title,
+				URL:    doc.URL,
+				Date:   doc.Date,
+				Score:  score,
+			})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if !results[i].Date.Equal(results[j].Date) {
+			return results[i].Date.After(results[j].Date)
+		}
+		return results[i].ID < results[j].ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// Save writes the index to its directory, the file of the index being replaced once the new one is completely written, and empties the journal.
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return ErrClosed
+	}
+	return ix.save()
+}
+
+func (ix *Index) save() error {
+	if ix.dir == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(ix.dir, ".index-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = gob.NewEncoder(tmp).Encode(&snapshot{Docs: ix.docs, IDs: ix.ids, Postings: ix.postings, Next: ix.next})
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(ix.dir, indexFile))
+	}
+	if err != nil || ix.journal == nil {
+		return err
+	}
+
+	// the journaled changes are in the index now
+	if err := ix.journal.Truncate(0); err != nil {
+		return err
+	}
+	_, err = ix.journal.Seek(0, io.SeekStart)
+	return err
+}
+
+// Close saves the index and closes it.
+func (ix *Index) Close() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return ErrClosed
+	}
+
+	ix.closed = true
+	err := ix.save()
+	if ix.journal != nil {
+		if closeErr := ix.journal.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// bm25 the score of the term occurring tf times in the field of the document n, which df documents have.
+func (ix *Index) bm25(f field, n, tf, df int) float64 {
+	if tf == 0 || df == 0 {
+		return 0
+	}
+	docs := float64(len(ix.docs))
+	idf := math.Log(1 + (docs-float64(df)+0.5)/(float64(df)+0.5))
+	avg := float64(ix.totalLengths[f]) / docs
+	length := float64(ix.docs[n].Lengths[f])
+	norm := 1.0
+	if avg > 0 {
+		norm = 1 - b + b*length/avg
+	}
+	return fieldWeights[f] * idf * float64(tf) * (k1 + 1) / (float64(tf) + k1*norm)
+}
+
+// terms the terms of the index matching the term of a query in the field: the term,
+// or for a lone CJK character, which is only indexed alone when it is not next to other ones, the bigrams having it too.
+func (ix *Index) terms(f field, term string) []string {
+	r := []rune(term)
+	if len(r) != 1 || !isCJK(r[0]) {
+		return []string{term}
+	}
+
+	prefix := postingKey(f, "")
+	terms := []string{term}
+	for key := range ix.postings {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if bigram := []rune(key[len(prefix):]); len(bigram) == 2 && (bigram[0] == r[0] || bigram[1] == r[0]) {
+			terms = append(terms, string(bigram))
+		}
+	}
+	return terms
+}
@@ -0,0 +1,213 @@
+package search
+
+import (
+	"errors"
+	"github.com/hellodword/grss"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRss = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <link>https://blog.example/</link>
+    <description>A blog</description>
+    <item>
+      <title>The event loop of Node</title>
+      <link>https://blog.example/event-loop</link>
+      <author>alice@blog.example (Alice Martin)</author>
+      <category>javascript</category>
+      <pubDate>Mon, 02 Jan 2023 08:00:00 GMT</pubDate>
+      <description>&lt;p&gt;How the &lt;b&gt;loop&lt;/b&gt; runs the events.&lt;/p&gt;&lt;script&gt;tracker()&lt;/script&gt;</description>
+    </item>
+    <item>
+      <title>Goroutines and channels</title>
+      <link>https://blog.example/go</link>
+      <author>bob@blog.example (Bob)</author>
+      <category>go</category>
+      <category>concurrency</category>
+      <pubDate>Wed, 15 Mar 2023 08:00:00 GMT</pubDate>
+      <description>Channels instead of an event loop, in Go.</description>
+    </item>
+    <item>
+      <title>Rust async</title>
+      <link>https://blog.example/rust</link>
+      <author>alice@blog.example (Alice Martin)</author>
+      <category>rust</category>
+      <category>concurrency</category>
+      <pubDate>Sat, 10 Jun 2023 08:00:00 GMT</pubDate>
+      <description>The loop of the events of async Rust, and the café.</description>
+    </item>
+    <item>
+      <title>東京都の天気</title>
+      <link>https://blog.example/tokyo</link>
+      <description>今日は晴れ</description>
+    </item>
+  </channel>
+</rss>`
+
+func open(t *testing.T, dir string) *Index {
+	ix, err := Open(dir)
+	assert.Nil(t, err)
+	return ix
+}
+
+func add(t *testing.T, ix *Index) {
+	_, f, err := grss.Parse(strings.NewReader(testRss))
+	assert.Nil(t, err)
+	assert.Nil(t, ix.AddFeed("blog", f))
+}
+
+func search(t *testing.T, ix *Index, query string) []string {
+	results, err := ix.Search(query, 0)
+	assert.Nil(t, err, query)
+	var urls []string
+	for _, r := range results {
+		urls = append(urls, strings.TrimPrefix(r.URL, "https://blog.example/"))
+	}
+	return urls
+}
+
+func Test_Tokenize(t *testing.T) {
+	assert.Equal(t, []string{"cafe", "creme", "brulee", "2023"}, tokenize("Café Crème-Brûlée, 2023!"))
+	assert.Equal(t, []string{"go", "東京", "京都", "の", "abc"}, tokenize("Go東京都 の ＡＢＣ"))
+	assert.Empty(t, tokenize("..."))
+	assert.Equal(t, []string{"カナ"}, tokenize("ｶﾅ"))
+	assert.Equal(t, []string{"How", "the", "loop", "runs.", "1", "<", "2"}, strings.Fields(plainText(`<p>How the <b>loop</b> runs.</p><script>x()</script><style>p{}</style>1 < 2`)))
+}
+
+func Test_Search(t *testing.T) {
+	ix := open(t, "")
+	add(t, ix)
+	assert.Equal(t, 4, ix.Len())
+
+	// the title weighs more than the content
+	assert.Equal(t, []string{"event-loop", "go", "rust"}, search(t, ix, "loop"))
+	assert.Equal(t, []string{"event-loop", "go"}, search(t, ix, `"event loop"`))
+	assert.ElementsMatch(t, []string{"event-loop", "rust"}, search(t, ix, `"loop of"`))
+	assert.Equal(t, []string{"rust"}, search(t, ix, "cafe"))
+	assert.Equal(t, []string{"rust"}, search(t, ix, "CAFÉ"))
+	// the scripts are not indexed
+	assert.Nil(t, search(t, ix, "tracker"))
+
+	assert.Equal(t, []string{"rust", "event-loop"}, search(t, ix, "author:alice"))
+	assert.Equal(t, []string{"rust", "event-loop"}, search(t, ix, `author:"alice martin"`))
+	assert.Nil(t, search(t, ix, `author:"martin alice"`))
+	// the same scores, the most recent first
+	assert.Equal(t, []string{"rust", "go"}, search(t, ix, "tag:concurrency"))
+	assert.Equal(t, []string{"rust", "go"}, search(t, ix, "category:concurrency"))
+	assert.Equal(t, []string{"go"}, search(t, ix, "title:channels"))
+	assert.Equal(t, []string{"event-loop"}, search(t, ix, "loop title:node"))
+
+	assert.Equal(t, []string{"rust"}, search(t, ix, "loop AND async"))
+	assert.Equal(t, []string{"rust"}, search(t, ix, "loop -tag:go -javascript"))
+	assert.Equal(t, []string{"rust"}, search(t, ix, "loop NOT (go OR node)"))
+	assert.ElementsMatch(t, []string{"go", "rust"}, search(t, ix, "goroutines OR async"))
+	assert.ElementsMatch(t, []string{"go", "rust", "tokyo"}, search(t, ix, "-javascript"))
+	assert.Nil(t, search(t, ix, ""))
+	assert.Nil(t, search(t, ix, "!!!"))
+	assert.Equal(t, []string{"go"}, search(t, ix, "goroutines !!!"))
+
+	// CJK words are found by their bigrams, and single characters by the bigrams having them
+	assert.Equal(t, []string{"tokyo"}, search(t, ix, "東京"))
+	assert.Equal(t, []string{"tokyo"}, search(t, ix, "東京都"))
+	assert.Equal(t, []string{"tokyo"}, search(t, ix, "天気"))
+	assert.Equal(t, []string{"tokyo"}, search(t, ix, "晴"))
+	assert.Nil(t, search(t, ix, "京東"))
+
+	// the items matching only by date are the most recent first
+	assert.Equal(t, []string{"rust", "go", "event-loop"}, search(t, ix, "date:2023"))
+	assert.Equal(t, []string{"event-loop"}, search(t, ix, "date:2023-01-02"))
+	assert.Equal(t, []string{"go", "event-loop"}, search(t, ix, "date:2023-01..2023-03"))
+	assert.Equal(t, []string{"rust", "go"}, search(t, ix, "date:2023-02.."))
+	assert.Equal(t, []string{"event-loop"}, search(t, ix, "date:..2023-01"))
+	assert.Equal(t, []string{"rust", "go"}, search(t, ix, "date:>2023-01"))
+	assert.Equal(t, []string{"rust", "go"}, search(t, ix, "date:>=2023-03-15"))
+	assert.Equal(t, []string{"go", "event-loop"}, search(t, ix, "date:<2023-06"))
+	assert.Equal(t, []string{"rust", "go", "event-loop"}, search(t, ix, "date:<=2023-06"))
+	assert.Equal(t, []string{"go"}, search(t, ix, "loop date:2023-03"))
+
+	results, err := ix.Search("loop", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, "https://blog.example/event-loop", results[0].ID)
+	assert.Equal(t, "blog", results[0].FeedID)
+	assert.Equal(t, "The event loop of Node", results[0].Title)
+	assert.Equal(t, 2023, results[0].Date.Year())
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	for _, query := range []string{`"event`, "(loop", "loop)", "loop OR", "AND loop", "NOT", "date:2023-13", "date:..", "date:yesterday"} {
+		_, err := ix.Search(query, 0)
+		assert.True(t, errors.Is(err, ErrQuery), query)
+	}
+}
+
+func Test_Index_Persistence(t *testing.T) {
+	dir := t.TempDir()
+	ix := open(t, dir)
+	add(t, ix)
+	assert.Nil(t, ix.Close())
+	assert.Equal(t, ErrClosed, ix.Add("blog"))
+	_, err := ix.Search("loop", 0)
+	assert.Equal(t, ErrClosed, err)
+
+	ix = open(t, dir)
+	assert.Equal(t, 4, ix.Len())
+	assert.Equal(t, []string{"event-loop", "go", "rust"}, search(t, ix, "loop"))
+
+	// an item added again replaces the indexed one
+	assert.Nil(t, ix.Add("blog", &grss.JSONItem{URL: "https://blog.example/go", Title: "Goroutines", ContentText: "Now without a loop"}))
+	assert.Equal(t, 4, ix.Len())
+	assert.Nil(t, search(t, ix, "channels"))
+	assert.Equal(t, []string{"go"}, search(t, ix, "without"))
+
+	assert.Nil(t, ix.Remove("https://blog.example/rust", "unknown"))
+	assert.Equal(t, 3, ix.Len())
+	assert.Nil(t, search(t, ix, "async"))
+	assert.Nil(t, ix.Save())
+
+	ix = open(t, dir)
+	assert.Equal(t, 3, ix.Len())
+	assert.Equal(t, []string{"go"}, search(t, ix, "without"))
+	assert.Equal(t, []string{"event-loop", "go"}, search(t, ix, "loop"))
+	assert.Nil(t, ix.Close())
+}
+
+func Test_Index_Journal(t *testing.T) {
+	dir := t.TempDir()
+	ix := open(t, dir)
+	add(t, ix)
+	assert.Nil(t, ix.Remove("https://blog.example/rust"))
+
+	// a crash before Save: the changes are replayed from the journal
+	crashed := open(t, dir)
+	assert.Equal(t, 3, crashed.Len())
+	assert.Nil(t, search(t, crashed, "async"))
+	assert.Equal(t, []string{"event-loop", "go"}, search(t, crashed, "loop"))
+
+	// a last change cut short is dropped
+	assert.Nil(t, ix.Add("blog", &grss.JSONItem{URL: "https://blog.example/wasm", Title: "Wasm"}))
+	journal, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_WRONLY|os.O_APPEND, 0600)
+	assert.Nil(t, err)
+	_, err = journal.WriteString(`{"feed":"blog","items":[{"url":"https://blog.example/cut`)
+	assert.Nil(t, err)
+	assert.Nil(t, journal.Close())
+	crashed = open(t, dir)
+	assert.Equal(t, 4, crashed.Len())
+	assert.Equal(t, []string{"wasm"}, search(t, crashed, "wasm"))
+	assert.Nil(t, crashed.Add("blog", &grss.JSONItem{URL: "https://blog.example/zig", Title: "Zig"}))
+	assert.Nil(t, crashed.Close())
+
+	// Close folds the journal into the index
+	info, err := os.Stat(filepath.Join(dir, journalFile))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), info.Size())
+	ix = open(t, dir)
+	assert.Equal(t, 5, ix.Len())
+	assert.Equal(t, []string{"zig"}, search(t, ix, "zig"))
+	assert.Nil(t, ix.Close())
+}
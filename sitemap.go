@@ -0,0 +1,399 @@
+package grss
+
+import (
+	"errors"
+	"github.com/nbio/xml"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sitemaps 0.9 https://www.sitemaps.org/protocol.html
+// Image sitemaps https://developers.google.com/search/docs/crawling-indexing/sitemaps/image-sitemaps
+// News sitemaps https://developers.google.com/search/docs/crawling-indexing/sitemaps/news-sitemap
+
+const (
+	// SitemapMime https://www.sitemaps.org/protocol.html#escaping
+	SitemapMime         = "application/xml"
+	SitemapMimeFallback = "text/xml"
+)
+
+const (
+	NamespaceSitemap      = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	NamespaceSitemapImage = "http://www.google.com/schemas/sitemap-image/1.1"
+	NamespaceSitemapNews  = "http://www.google.com/schemas/sitemap-news/0.9"
+)
+
+const (
+	// SitemapMaxURLs Each Sitemap file that you provide must have no more than 50,000 URLs and must be no larger than 50MB (52,428,800 bytes).
+	SitemapMaxURLs = 50000
+	// SitemapMaxImages You can include up to 1,000 image:image tags for each page.
+	SitemapMaxImages = 1000
+)
+
+var (
+	ErrNotSitemap = errors.New("not a sitemap")
+)
+
+// Sitemap the <urlset> of a sitemap, it encapsulates the file and references the current protocol standard.
+type Sitemap struct {
+	XMLName xml.Name `xml:"urlset"`
+
+	Attributes []xml.Attr `xml:",any,attr,omitempty"`
+
+	URLs []*SitemapURL `xml:"url"`
+}
+
+// SitemapURL Parent tag for each URL entry. The remaining tags are children of this tag.
+type SitemapURL struct {
+	// Loc URL of the page. This URL must begin with the protocol (such as http) and end with a trailing slash, if your web server requires it. This value must be less than 2,048 characters.
+	Loc string `xml:"loc"`
+	// Lastmod The date of last modification of the page. This date should be in W3C Datetime format. This format allows you to omit the time portion, if desired, and use YYYY-MM-DD.
+	Lastmod string `xml:"lastmod,omitempty"`
+	// Changefreq How frequently the page is likely to change: always, hourly, daily, weekly, monthly, yearly or never.
+	Changefreq string `xml:"changefreq,omitempty"`
+	// Priority The priority of this URL relative to other URLs on your site. Valid values range from 0.0 to 1.0.
+	Priority string `xml:"priority,omitempty"`
+
+	// Images Encloses all information about a single image. Each <url> tag can contain up to 1,000 <image:image> tags.
+	Images []*SitemapImage `xml:"http://www.google.com/schemas/sitemap-image/1.1 image:image,omitempty"`
+	// News The parent tag for a Google News article.
+	News *SitemapNews `xml:"http://www.google.com/schemas/sitemap-news/0.9 news:news,omitempty"`
+}
+
+type SitemapImage struct {
+	// Loc The URL of the image.
+	Loc string `xml:"http://www.google.com/schemas/sitemap-image/1.1 image:loc"`
+}
+
+type SitemapNews struct {
+	// Publication The publication where the article appears.
+	Publication *SitemapNewsPublication `xml:"http://www.google.com/schemas/sitemap-news/0.9 news:publication"`
+	// PublicationDate The article publication date in W3C format.
+	PublicationDate string `xml:"http://www.google.com/schemas/sitemap-news/0.9 news:publication_date"`
+	// Title The title of the news article.
+	Title string `xml:"http://www.google.com/schemas/sitemap-news/0.9 news:title"`
+}
+
+type SitemapNewsPublication struct {
+	// Name The name of the news publication. It must exactly match the name as it appears on your articles on news.google.com, omitting anything in parentheses.
+	Name string `xml:"http://www.google.com/schemas/sitemap-news/0.9 news:name"`
+	// Language The language of your publication. Use an ISO 639 language code (two or three letters), zh-cn for Simplified Chinese and zh-tw for Traditional Chinese.
+	Language string `xml:"http://www.google.com/schemas/sitemap-news/0.9 news:language"`
+}
+
+// SitemapIndex the <sitemapindex> listing the sitemaps of a site, which a sitemap of more than 50,000 URLs is split across.
+type SitemapIndex struct {
+	XMLName xml.Name `xml:"sitemapindex"`
+
+	Attributes []xml.Attr `xml:",any,attr,omitempty"`
+
+	Sitemaps []*SitemapIndexEntry `xml:"sitemap"`
+}
+
+// SitemapIndexEntry Encapsulates information about an individual Sitemap.
+type SitemapIndexEntry struct {
+	// Loc Identifies the location of the Sitemap.
+	Loc string `xml:"loc"`
+	// Lastmod Identifies the time that the corresponding Sitemap file was modified.
+	Lastmod string `xml:"lastmod,omitempty"`
+}
+
+// SitemapOptions controls how ToSitemap maps the items of a feed.
+type SitemapOptions struct {
+	// News adds a Google News <news:news> entry to the URLs of the dated items with a title.
+	News bool
+	// PublicationName is the name of the publication of the news entries, the title of the feed when empty.
+	PublicationName string
+	// PublicationLanguage is the language of the publication of the news entries, the language of the feed when empty.
+	PublicationLanguage string
+}
+
+// sitemapDate d in W3C Datetime, or "" when it does not parse.
+func sitemapDate(d string) string {
+	if d == "" {
+		return ""
+	}
+	t, err := ParseDate(d)
+	if err != nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// newsLanguage the ISO 639 code of the language tag lang, Google News tells the Chinese scripts apart by zh-cn and zh-tw.
+func newsLanguage(lang string) string {
+	lang = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+	switch lang {
+	case "zh-cn", "zh-tw":
+		return lang
+	case "zh-hans", "zh-sg":
+		return "zh-cn"
+	case "zh-hant", "zh-hk":
+		return "zh-tw"
+	}
+	primary, _, _ := strings.Cut(lang, "-")
+	return primary
+}
+
+// absoluteURL reports whether s is an http or https URL, as the locations of a sitemap are.
+func absoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// ToSitemap maps the items of f to the URLs of a sitemap: the url of the item, or its id when it is an http URL,
+// lastmod from the date it was updated or else published, and the images of the item, its image, banner image and image attachments, which media:thumbnail and image enclosures are.
+// The items without such a URL are left out, as are the items repeating the URL of a previous one.
+func ToSitemap(f Feed, opts SitemapOptions) *Sitemap {
+	ff := f.ToJSON()
+
+	var publication *SitemapNewsPublication
+	if opts.News {
+		publication = &SitemapNewsPublication{
+			Name:     opts.PublicationName,
+			Language: newsLanguage(opts.PublicationLanguage),
+		}
+		if publication.Name == "" {
+			publication.Name = ff.Title
+		}
+		if publication.Language == "" {
+			publication.Language = newsLanguage(ff.Language)
+		}
+	}
+
+	s := &Sitemap{}
+	seen := map[string]bool{}
+	for _, jitem := range ff.Items {
+		loc := jitem.URL
+		if loc == "" && absoluteURL(jitem.ID) {
+			loc = jitem.ID
+		}
+		if loc == "" || seen[loc] {
+			continue
+		}
+		seen[loc] = true
+
+		u := &SitemapURL{
+			Loc:     loc,
+			Lastmod: sitemapDate(jitem.DateModified),
+		}
+		if u.Lastmod == "" {
+			u.Lastmod = sitemapDate(jitem.DatePublished)
+		}
+
+		images := map[string]bool{}
+		addImage := func(image string) {
+			if image != "" && !images[image] && len(u.Images) < SitemapMaxImages {
+				images[image] = true
+				u.Images = append(u.Images, &SitemapImage{Loc: image})
+			}
+		}
+		addImage(jitem.Image)
+		addImage(jitem.BannerImage)
+		for _, attachment := range jitem.Attachments {
+			if strings.HasPrefix(attachment.MimeType, "image/") {
+				addImage(attachment.URL)
+			}
+		}
+
+		// Google News needs the publication date and the title of the article.
+		if published := sitemapDate(jitem.DatePublished); publication != nil && published != "" && jitem.Title != "" {
+			u.News = &SitemapNews{
+				Publication:     publication,
+				PublicationDate: published,
+				Title:           jitem.Title,
+			}
+		}
+
+		s.URLs = append(s.URLs, u)
+	}
+
+	s.Uniform()
+	return s
+}
+
+func (s *Sitemap) Uniform() {
+	s.XMLName = xml.Name{Local: "urlset"}
+
+	pre := [][3]string{
+		{"", "xmlns", NamespaceSitemap},
+	}
+	var images, news bool
+	for _, u := range s.URLs {
+		images = images || len(u.Images) > 0
+		news = news || u.News != nil
+	}
+	if images {
+		pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "image", NamespaceSitemapImage})
+	}
+	if news {
+		pre = append(pre, [3]string{"http://www.w3.org/2000/xmlns/", "news", NamespaceSitemapNews})
+	}
+
+	s.Attributes = append(s.Attributes, diffAttrs(pre, s.Attributes)...)
+}
+
+func (s *Sitemap) Mime(fallback bool) string {
+	if fallback {
+		return SitemapMimeFallback
+	} else {
+		return SitemapMime
+	}
+}
+
+func (s *Sitemap) WriteOut(w io.Writer) error {
+	return s.WriteOutWith(w, WriteOptions{Indent: defaultIndent})
+}
+
+func (s *Sitemap) WriteOutWith(w io.Writer, opts WriteOptions) error {
+	return writeXml(w, s, opts, "")
+}
+
+// Lastmod the most recent lastmod of the URLs of s, "" when none has one.
+func (s *Sitemap) Lastmod() string {
+	var newest time.Time
+	var lastmod string
+	for _, u := range s.URLs {
+		t, err := ParseDate(u.Lastmod)
+		if err == nil && t.After(newest) {
+			newest, lastmod = t, u.Lastmod
+		}
+	}
+	return lastmod
+}
+
+// Split splits s into sitemaps of at most max URLs, SitemapMaxURLs when max is 0 or less, to be listed in a sitemap index.
+func (s *Sitemap) Split(max int) []*Sitemap {
+	if max <= 0 {
+		max = SitemapMaxURLs
+	}
+	var sitemaps []*Sitemap
+	for i := 0; i < len(s.URLs) || i == 0; i += max {
+		end := i + max
+		if end > len(s.URLs) {
+			end = len(s.URLs)
+		}
+		part := &Sitemap{URLs: s.URLs[i:end]}
+		part.Uniform()
+		sitemaps = append(sitemaps, part)
+	}
+	return sitemaps
+}
+
+// NewSitemapIndex lists sitemaps, the sitemap i being published at loc(i), with the most recent lastmod of its URLs.
+func NewSitemapIndex(sitemaps []*Sitemap, loc func(i int) string) *SitemapIndex {
+	index := &SitemapIndex{}
+	for i, s := range sitemaps {
+		index.Sitemaps = append(index.Sitemaps, &SitemapIndexEntry{
+			Loc:     loc(i),
+			Lastmod: s.Lastmod(),
+		})
+	}
+	index.Uniform()
+	return index
+}
+
+func (index *SitemapIndex) Uniform() {
+	index.XMLName = xml.Name{Local: "sitemapindex"}
+
+	pre := [][3]string{
+		{"", "xmlns", NamespaceSitemap},
+	}
+	index.Attributes = append(index.Attributes, diffAttrs(pre, index.Attributes)...)
+}
+
+func (index *SitemapIndex) Mime(fallback bool) string {
+	if fallback {
+		return SitemapMimeFallback
+	} else {
+		return SitemapMime
+	}
+}
+
+func (index *SitemapIndex) WriteOut(w io.Writer) error {
+	return index.WriteOutWith(w, WriteOptions{Indent: defaultIndent})
+}
+
+func (index *SitemapIndex) WriteOutWith(w io.Writer, opts WriteOptions) error {
+	return writeXml(w, index, opts, "")
+}
+
+// ParseSitemap parses a sitemap or a sitemap index as a minimal JSON Feed, to watch a site for changes.
+// The items are the URLs, dated by lastmod, with the title and the publication date of their news entry, and their first image.
+// Of a sitemap index, the items are the sitemaps it lists.
+func ParseSitemap(r io.Reader) (*JSONFeed, error) {
+	var doc struct {
+		XMLName  xml.Name
+		URLs     []*SitemapURL        `xml:"url"`
+		Sitemaps []*SitemapIndexEntry `xml:"sitemap"`
+	}
+	err := newXmlDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset":
+		return (&Sitemap{URLs: doc.URLs}).ToJSON(), nil
+	case "sitemapindex":
+		return (&SitemapIndex{Sitemaps: doc.Sitemaps}).ToJSON(), nil
+	default:
+		return nil, ErrNotSitemap
+	}
+}
+
+// ToJSON the JSON Feed of the URLs of the sitemap.
+func (s *Sitemap) ToJSON() *JSONFeed {
+	ff := &JSONFeed{}
+	for _, u := range s.URLs {
+		jitem := &JSONItem{
+			ID:           u.Loc,
+			URL:          u.Loc,
+			DateModified: u.Lastmod,
+		}
+		if u.News != nil {
+			jitem.Title = u.News.Title
+			jitem.DatePublished = u.News.PublicationDate
+			if u.News.Publication != nil {
+				if ff.Title == "" {
+					ff.Title = u.News.Publication.Name
+				}
+				if ff.Language == "" {
+					ff.Language = u.News.Publication.Language
+				}
+			}
+		}
+		if len(u.Images) > 0 {
+			jitem.Image = u.Images[0].Loc
+		}
+
+		// content_html or content_text is required
+		jitem.ContentText = jitem.Title
+		if jitem.ContentText == "" {
+			jitem.ContentText = jitem.URL
+		}
+
+		ff.Items = append(ff.Items, jitem)
+	}
+
+	ff.Uniform()
+	return ff
+}
+
+// ToJSON the JSON Feed of the sitemaps of the index.
+func (index *SitemapIndex) ToJSON() *JSONFeed {
+	ff := &JSONFeed{}
+	for _, s := range index.Sitemaps {
+		ff.Items = append(ff.Items, &JSONItem{
+			ID:           s.Loc,
+			URL:          s.Loc,
+			DateModified: s.Lastmod,
+			ContentText:  s.Loc,
+		})
+	}
+
+	ff.Uniform()
+	return ff
+}
@@ -0,0 +1,145 @@
+package grss
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testSitemapRss = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/">
+  <channel>
+    <title>Example News</title>
+    <link>https://example.com/</link>
+    <description>News</description>
+    <language>en-US</language>
+    <item>
+      <title>Elections &amp; results</title>
+      <link>https://example.com/news/elections?a=1&amp;b=2</link>
+      <pubDate>Mon, 02 Jan 2023 10:00:00 GMT</pubDate>
+      <media:thumbnail url="https://example.com/img/thumb.jpg" width="75" height="50"/>
+      <enclosure url="https://example.com/img/photo.png" length="1024" type="image/png"/>
+      <enclosure url="https://example.com/audio.mp3" length="1024" type="audio/mpeg"/>
+    </item>
+    <item>
+      <title>No date</title>
+      <link>https://example.com/news/undated</link>
+    </item>
+    <item>
+      <title>Repeated</title>
+      <link>https://example.com/news/undated</link>
+    </item>
+    <item>
+      <title>No link</title>
+      <guid isPermaLink="false">123</guid>
+    </item>
+  </channel>
+</rss>`
+
+func Test_ToSitemap(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(testSitemapRss))
+	assert.Nil(t, err)
+
+	s := ToSitemap(f, SitemapOptions{News: true})
+	assert.Equal(t, 2, len(s.URLs))
+
+	elections := s.URLs[0]
+	assert.Equal(t, "https://example.com/news/elections?a=1&b=2", elections.Loc)
+	assert.Equal(t, "2023-01-02T10:00:00Z", elections.Lastmod)
+	assert.Equal(t, []*SitemapImage{{Loc: "https://example.com/img/thumb.jpg"}, {Loc: "https://example.com/img/photo.png"}}, elections.Images)
+	assert.Equal(t, &SitemapNews{
+		Publication:     &SitemapNewsPublication{Name: "Example News", Language: "en"},
+		PublicationDate: "2023-01-02T10:00:00Z",
+		Title:           "Elections & results",
+	}, elections.News)
+
+	// Google News needs a publication date
+	assert.Nil(t, s.URLs[1].News)
+	assert.Equal(t, "", s.URLs[1].Lastmod)
+
+	var b bytes.Buffer
+	assert.Nil(t, s.WriteOut(&b))
+	assert.Contains(t, b.String(), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1" xmlns:news="http://www.google.com/schemas/sitemap-news/0.9">`)
+	assert.Contains(t, b.String(), `<loc>https://example.com/news/elections?a=1&amp;b=2</loc>`)
+	assert.Contains(t, b.String(), `<news:title>Elections &amp; results</news:title>`)
+
+	// without news nor images, only the sitemap namespace
+	s = ToSitemap(&JSONFeed{Items: []*JSONItem{
+		{ID: "https://example.com/1", DateModified: "2023-01-03T10:00:00+01:00", DatePublished: "2023-01-01T10:00:00Z"},
+	}}, SitemapOptions{})
+	b.Reset()
+	assert.Nil(t, s.WriteOut(&b))
+	assert.Contains(t, b.String(), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	assert.Contains(t, b.String(), `<loc>https://example.com/1</loc>`)
+	assert.Contains(t, b.String(), `<lastmod>2023-01-03T10:00:00+01:00</lastmod>`)
+
+	for lang, expected := range map[string]string{"en-US": "en", "zh-CN": "zh-cn", "zh-Hant": "zh-tw", "fr": "fr", "pt_BR": "pt"} {
+		assert.Equal(t, expected, newsLanguage(lang), lang)
+	}
+}
+
+func Test_SitemapIndex(t *testing.T) {
+	ff := &JSONFeed{}
+	for i := 0; i < 5; i++ {
+		ff.Items = append(ff.Items, &JSONItem{
+			URL:           fmt.Sprintf("https://example.com/%d", i),
+			DatePublished: fmt.Sprintf("2023-01-0%dT10:00:00Z", i+1),
+		})
+	}
+
+	sitemaps := ToSitemap(ff, SitemapOptions{}).Split(2)
+	assert.Equal(t, 3, len(sitemaps))
+	assert.Equal(t, 2, len(sitemaps[0].URLs))
+	assert.Equal(t, 1, len(sitemaps[2].URLs))
+	assert.Equal(t, 1, len(ToSitemap(ff, SitemapOptions{}).Split(0)))
+	assert.Equal(t, 1, len((&Sitemap{}).Split(0)))
+
+	index := NewSitemapIndex(sitemaps, func(i int) string { return fmt.Sprintf("https://example.com/sitemap-%d.xml", i) })
+	assert.Equal(t, &SitemapIndexEntry{Loc: "https://example.com/sitemap-1.xml", Lastmod: "2023-01-04T10:00:00Z"}, index.Sitemaps[1])
+
+	var b bytes.Buffer
+	assert.Nil(t, index.WriteOut(&b))
+	assert.Contains(t, b.String(), `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+
+	typ, f, err := Parse(&b)
+	assert.Nil(t, err)
+	assert.Equal(t, TypeXML|TypeXMLSitemap, typ)
+	j := f.ToJSON()
+	assert.Equal(t, 3, len(j.Items))
+	assert.Equal(t, "https://example.com/sitemap-2.xml", j.Items[2].URL)
+	assert.Equal(t, "2023-01-05T10:00:00Z", j.Items[2].DateModified)
+}
+
+func Test_ParseSitemap(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(testSitemapRss))
+	assert.Nil(t, err)
+	var b bytes.Buffer
+	assert.Nil(t, ToSitemap(f, SitemapOptions{News: true, PublicationName: "The Example"}).WriteOut(&b))
+
+	typ, feed, err := Parse(&b)
+	assert.Nil(t, err)
+	assert.Equal(t, TypeXML|TypeXMLSitemap, typ)
+	j := feed.(*JSONFeed)
+	assert.Equal(t, "The Example", j.Title)
+	assert.Equal(t, "en", j.Language)
+	assert.Equal(t, 2, len(j.Items))
+	assert.Equal(t, "https://example.com/news/elections?a=1&b=2", j.Items[0].ID)
+	assert.Equal(t, "Elections & results", j.Items[0].Title)
+	assert.Equal(t, "2023-01-02T10:00:00Z", j.Items[0].DatePublished)
+	assert.Equal(t, "https://example.com/img/thumb.jpg", j.Items[0].Image)
+	assert.Equal(t, "https://example.com/news/undated", j.Items[1].ContentText)
+
+	// a plain sitemap, as the search engines read them
+	j, err = ParseSitemap(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc><lastmod>2023-01-01</lastmod><changefreq>daily</changefreq><priority>0.8</priority></url>
+</urlset>`))
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/", j.Items[0].URL)
+	assert.Equal(t, "2023-01-01T00:00:00Z", j.Items[0].DateModified)
+
+	_, err = ParseSitemap(strings.NewReader(`<feed xmlns="http://www.w3.org/2005/Atom"></feed>`))
+	assert.Equal(t, ErrNotSitemap, err)
+}
@@ -0,0 +1,438 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hellodword/grss"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrClosed the store was closed.
+	ErrClosed = errors.New("store: closed")
+)
+
+// the operations of the records of the log
+const (
+	opPut        = "put"
+	opRead       = "read"
+	opUnread     = "unread"
+	opValidators = "validators"
+)
+
+// record a line of the log.
+type record struct {
+	Op string `json:"op"`
+
+	// put
+	*Item
+
+	// read and unread
+	IDs []string `json:"ids,omitempty"`
+
+	// validators
+	Feed       string      `json:"feed,omitempty"`
+	Validators *Validators `json:"validators,omitempty"`
+}
+
+// entry the index entry of an item, its metadata and where its last put record is in the log.
+type entry struct {
+	Item
+	offset int64
+	size   int
+}
+
+// Log a Store in an append-only log file of JSON records, one per line, needing no database.
+//
+// The log is read once by Open to build the index of the items, which keeps their metadata and the offset of their record,
+// their content is read from the log when they are returned. Compact rewrites the log without the records that were superseded.
+type Log struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	size       int64
+	index      map[string]*entry
+	validators map[string]Validators
+
+	now func() time.Time
+}
+
+var _ Store = (*Log)(nil)
+
+// Open opens the log at path, created when missing.
+// A last record cut short by a crash is dropped.
+func Open(path string) (*Log, error) {
+	s := &Log{path: path, now: time.Now}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Log) open() error {
+	file, err := os.OpenFile(s.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	s.file = file
+	s.size = 0
+	s.index = map[string]*entry{}
+	s.validators = map[string]Validators{}
+
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// the last write was interrupted
+				err = file.Truncate(s.size)
+			} else {
+				err = nil
+			}
+			if err == nil {
+				_, err = file.Seek(s.size, io.SeekStart)
+			}
+			if err != nil {
+				_ = file.Close()
+				return err
+			}
+			return nil
+		}
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("store: %s: record at offset %d: %w", s.path, s.size, err)
+		}
+		s.apply(&rec, s.size, len(line))
+		s.size += int64(len(line))
+	}
+}
+
+// apply updates the index with the record at offset.
+func (s *Log) apply(rec *record, offset int64, size int) {
+	switch rec.Op {
+	case opPut:
+		if rec.Item == nil {
+			return
+		}
+		e := &entry{Item: *rec.Item, offset: offset, size: size}
+		e.Item.Item = nil
+		s.index[e.ID] = e
+	case opRead, opUnread:
+		for _, id := range rec.IDs {
+			if e, ok := s.index[id]; ok {
+				e.Read = rec.Op == opRead
+			}
+		}
+	case opValidators:
+		if rec.Validators == nil {
+			delete(s.validators, rec.Feed)
+		} else {
+			s.validators[rec.Feed] = *rec.Validators
+		}
+	}
+}
+
+// append writes the record at the end of the log and updates the index.
+func (s *Log) append(rec *record) error {
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(rec); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+	if _, err := s.file.WriteAt(b, s.size); err != nil {
+		return err
+	}
+	s.apply(rec, s.size, len(b))
+	s.size += int64(len(b))
+	return nil
+}
+
+// load the item of the entry, with its content read from the log.
+func (s *Log) load(e *entry) (*Item, error) {
+	b := make([]byte, e.size)
+	if _, err := s.file.ReadAt(b, e.offset); err != nil {
+		return nil, err
+	}
+	var rec struct {
+		Item json.RawMessage `json:"item"`
+	}
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, err
+	}
+
+	// the extensions of the items are only read by the unmarshaler of the feed
+	var f grss.JSONFeed
+	if err := json.Unmarshal(bytes.Join([][]byte{[]byte(`{"items":[`), rec.Item, []byte(`]}`)}, nil), &f); err != nil {
+		return nil, err
+	}
+
+	item := e.Item
+	if len(f.Items) == 1 {
+		item.Item = f.Items[0]
+	}
+	return &item, nil
+}
+
+// items the items of the entries selected by fn, the least recently updated first.
+func (s *Log) items(fn func(e *entry) bool) ([]*Item, error) {
+	if s.file == nil {
+		return nil, ErrClosed
+	}
+
+	var entries []*entry
+	for _, e := range s.index {
+		if fn(e) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Updated.Equal(entries[j].Updated) {
+			return entries[i].Updated.Before(entries[j].Updated)
+		}
+		return entries[i].offset < entries[j].offset
+	})
+
+	items := make([]*Item, 0, len(entries))
+	for _, e := range entries {
+		item, err := s.load(e)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *Log) Put(feedID string, items []*grss.JSONItem) (added, updated []*Item, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil, nil, ErrClosed
+	}
+
+	now := s.now().UTC()
+	for _, jitem := range items {
+		item := &Item{
+			ID:      ItemID(jitem),
+			FeedID:  feedID,
+			Hash:    Hash(jitem),
+			Added:   now,
+			Updated: now,
+			Item:    jitem,
+		}
+
+		e, ok := s.index[item.ID]
+		if ok {
+			if e.Hash == item.Hash {
+				continue
+			}
+			// an updated item keeps its feed and whether it was read
+			item.FeedID = e.FeedID
+			item.Added = e.Added
+			item.Read = e.Read
+		}
+
+		if err := s.append(&record{Op: opPut, Item: item}); err != nil {
+			return added, updated, err
+		}
+		if ok {
+			updated = append(updated, item)
+		} else {
+			added = append(added, item)
+		}
+	}
+	return added, updated, nil
+}
+
+func (s *Log) Seen(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return false, ErrClosed
+	}
+
+	_, ok := s.index[id]
+	return ok, nil
+}
+
+func (s *Log) Since(t time.Time) ([]*Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.items(func(e *entry) bool {
+		return !e.Updated.Before(t)
+	})
+}
+
+func (s *Log) Unread(feedID string) ([]*Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.items(func(e *entry) bool {
+		return !e.Read && (feedID == "" || e.FeedID == feedID)
+	})
+}
+
+func (s *Log) MarkRead(ids ...string) error {
+	return s.mark(true, ids)
+}
+
+func (s *Log) MarkUnread(ids ...string) error {
+	return s.mark(false, ids)
+}
+
+// mark records the ids whose read state changes.
+func (s *Log) mark(read bool, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+
+	rec := &record{Op: opUnread}
+	if read {
+		rec.Op = opRead
+	}
+	for _, id := range ids {
+		if e, ok := s.index[id]; ok && e.Read != read {
+			rec.IDs = append(rec.IDs, id)
+		}
+	}
+	if len(rec.IDs) == 0 {
+		return nil
+	}
+	return s.append(rec)
+}
+
+func (s *Log) Validators(feedID string) (Validators, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return Validators{}, ErrClosed
+	}
+
+	return s.validators[feedID], nil
+}
+
+func (s *Log) SetValidators(feedID string, v Validators) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+
+	if s.validators[feedID] == v {
+		return nil
+	}
+	rec := &record{Op: opValidators, Feed: feedID}
+	if v != (Validators{}) {
+		rec.Validators = &v
+	}
+	return s.append(rec)
+}
+
+// Compact rewrites the log with one record per item, holding its read state, and one per feed with validators,
+// the log is replaced once the new one is completely written.
+func (s *Log) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+
+	entries := make([]*entry, 0, len(s.index))
+	for _, e := range s.index {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].offset < entries[j].offset
+	})
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".compact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	err = func() error {
+		for _, entry := range entries {
+			item, err := s.load(entry)
+			if err != nil {
+				return err
+			}
+			if err := e.Encode(&record{Op: opPut, Item: item}); err != nil {
+				return err
+			}
+		}
+		feeds := make([]string, 0, len(s.validators))
+		for feed := range s.validators {
+			feeds = append(feeds, feed)
+		}
+		sort.Strings(feeds)
+		for _, feed := range feeds {
+			v := s.validators[feed]
+			if err := e.Encode(&record{Op: opValidators, Feed: feed, Validators: &v}); err != nil {
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return tmp.Sync()
+	}()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	_ = s.file.Close()
+	s.file = nil
+	return s.open()
+}
+
+// Sync commits the log to stable storage.
+func (s *Log) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+	return s.file.Sync()
+}
+
+func (s *Log) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,88 @@
+// Package store keeps the items of feeds across restarts, with what was read of them, for the readers built on grss.
+//
+// The items, in any format, are stored as the JSON Feed items of ToJSON, identified by their guid or id, or else their link,
+// with a hash of their content to tell the updated items from the unchanged ones:
+//
+//	s, err := store.Open("feeds.log")
+//	added, updated, err := store.PutFeed(s, feedURL, f)
+//	unread, err := s.Unread("")
+//	err = s.MarkRead(unread[0].ID)
+//
+// The validators of the fetched feeds are stored too, for the conditional requests of the next fetch.
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/hellodword/grss"
+	"time"
+)
+
+// Store the items of feeds, and whether they were read.
+type Store interface {
+	// Put stores the items of the feed feedID, and returns those it did not have and those whose content changed.
+	Put(feedID string, items []*grss.JSONItem) (added, updated []*Item, err error)
+	// Seen reports whether the item of identity id was stored, from any feed.
+	Seen(id string) (bool, error)
+	// Since returns the items added or updated at or after t, the least recently updated first.
+	Since(t time.Time) ([]*Item, error)
+	// Unread returns the unread items of the feed feedID, of all the feeds when feedID is empty, the least recently updated first.
+	Unread(feedID string) ([]*Item, error)
+	// MarkRead marks the items of identities ids read, the unknown ones are ignored.
+	MarkRead(ids ...string) error
+	// MarkUnread marks the items of identities ids unread, the unknown ones are ignored.
+	MarkUnread(ids ...string) error
+
+	// Validators returns the validators of the last fetch of the feed feedID, empty when there are none.
+	Validators(feedID string) (Validators, error)
+	// SetValidators stores the validators of the last fetch of the feed feedID.
+	SetValidators(feedID string, v Validators) error
+
+	Close() error
+}
+
+// Item a stored item.
+type Item struct {
+	// ID is the identity of the item, see ItemID.
+	ID string `json:"id"`
+	// FeedID is the feed the item was first stored from.
+	FeedID string `json:"feed_id"`
+	// Hash is the content hash of the item, see Hash.
+	Hash string `json:"hash"`
+	// Added is when the item was first stored.
+	Added time.Time `json:"added"`
+	// Updated is when the content of the item was last stored, Added for the items never updated.
+	Updated time.Time `json:"updated"`
+	Read    bool      `json:"read,omitempty"`
+
+	Item *grss.JSONItem `json:"item,omitempty"`
+}
+
+// Validators the validators of a fetched feed, sent back as If-None-Match and If-Modified-Since.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ItemID the identity of an item, its grss.StableID: its id, which is the RSS guid, or else its url or external_url, which is the RSS link,
+// canonicalized so that an item whose links gain or lose tracking parameters, https or www. between the polls keeps its read state,
+// or else the hash of its title and text.
+func ItemID(item *grss.JSONItem) string {
+	return grss.StableID(item)
+}
+
+// Hash the SHA-256 hash of the JSON Feed item, which changes when any of its members does.
+func Hash(item *grss.JSONItem) string {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// PutFeed stores the items of f as those of the feed feedID, usually the URL of f.
+func PutFeed(s Store, feedID string, f grss.Feed) (added, updated []*Item, err error) {
+	return s.Put(feedID, f.ToJSON().Items)
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"github.com/hellodword/grss"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRss = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <link>https://blog.example/</link>
+    <description>A blog</description>
+    <item>
+      <title>First</title>
+      <guid isPermaLink="false">post-1</guid>
+      <link>https://blog.example/first</link>
+      <description>The first post</description>
+    </item>
+    <item>
+      <title>Second</title>
+      <link>https://blog.example/second</link>
+      <description>The second post</description>
+    </item>
+  </channel>
+</rss>`
+
+const testJSON = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Notes",
+  "items": [
+    {"id": "note-1", "content_text": "A note", "_reading": {"minutes": 3}}
+  ]
+}`
+
+func parse(t *testing.T, s string) grss.Feed {
+	_, f, err := grss.Parse(strings.NewReader(s))
+	assert.Nil(t, err)
+	return f
+}
+
+func ids(items []*Item) []string {
+	var s []string
+	for _, item := range items {
+		s = append(s, item.ID)
+	}
+	return s
+}
+
+// clock the times of the log, advanced by a minute on each call.
+func clock(s *Log) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+}
+
+func Test_ItemID(t *testing.T) {
+	items := parse(t, testRss).ToJSON().Items
+	assert.Equal(t, "post-1", ItemID(items[0]))
+	assert.Equal(t, "https://blog.example/second", ItemID(items[1]))
+
+	// the same item whatever the variations of its link
+	assert.Equal(t, "https://blog.example/second", ItemID(&grss.JSONItem{URL: "http://www.blog.example/second/?utm_source=rss"}))
+
+	note := &grss.JSONItem{ContentText: "A note"}
+	assert.Equal(t, grss.StableID(note), ItemID(note))
+	assert.True(t, strings.HasPrefix(ItemID(note), "sha256:"))
+	assert.Equal(t, 64, len(Hash(note)))
+	assert.NotEqual(t, Hash(note), Hash(&grss.JSONItem{ContentText: "A note, edited"}))
+}
+
+func Test_Log(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feeds.log")
+	s, err := Open(path)
+	assert.Nil(t, err)
+	clock(s)
+
+	added, updated, err := PutFeed(s, "https://blog.example/feed", parse(t, testRss))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"post-1", "https://blog.example/second"}, ids(added))
+	assert.Equal(t, 0, len(updated))
+	assert.Equal(t, "https://blog.example/feed", added[0].FeedID)
+
+	seen, err := s.Seen("post-1")
+	assert.Nil(t, err)
+	assert.True(t, seen)
+	seen, err = s.Seen("post-2")
+	assert.Nil(t, err)
+	assert.False(t, seen)
+
+	// the unchanged items are not stored again, the changed ones are updated
+	added, updated, err = PutFeed(s, "https://blog.example/feed", parse(t, testRss))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(added)+len(updated))
+
+	assert.Nil(t, s.MarkRead("post-1", "unknown"))
+	added, updated, err = PutFeed(s, "https://blog.example/feed", parse(t, strings.Replace(testRss, "The first post", "The first post, edited", 1)))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(added))
+	assert.Equal(t, []string{"post-1"}, ids(updated))
+	assert.True(t, updated[0].Read)
+	assert.True(t, updated[0].Updated.After(updated[0].Added))
+
+	added, _, err = PutFeed(s, "notes", parse(t, testJSON))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"note-1"}, ids(added))
+
+	unread, err := s.Unread("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://blog.example/second", "note-1"}, ids(unread))
+	unread, err = s.Unread("notes")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"note-1"}, ids(unread))
+
+	since, err := s.Since(updated[0].Updated)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"post-1", "note-1"}, ids(since))
+	assert.Equal(t, "The first post, edited", since[0].Item.ContentText)
+
+	v, err := s.Validators("https://blog.example/feed")
+	assert.Nil(t, err)
+	assert.Equal(t, Validators{}, v)
+	assert.Nil(t, s.SetValidators("https://blog.example/feed", Validators{ETag: `"abc"`, LastModified: "Mon, 02 Jan 2023 08:00:00 GMT"}))
+
+	// the state is kept across restarts
+	assert.Nil(t, s.Close())
+	assert.Equal(t, ErrClosed, s.MarkRead("note-1"))
+	s, err = Open(path)
+	assert.Nil(t, err)
+	clock(s)
+
+	unread, err = s.Unread("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://blog.example/second", "note-1"}, ids(unread))
+	assert.Equal(t, map[string]interface{}{"minutes": float64(3)}, unread[1].Item.Extensions["_reading"])
+	v, err = s.Validators("https://blog.example/feed")
+	assert.Nil(t, err)
+	assert.Equal(t, `"abc"`, v.ETag)
+
+	assert.Nil(t, s.MarkUnread("post-1"))
+	unread, err = s.Unread("https://blog.example/feed")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://blog.example/second", "post-1"}, ids(unread))
+	assert.Nil(t, s.Close())
+}
+
+func Test_Log_Recovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feeds.log")
+	s, err := Open(path)
+	assert.Nil(t, err)
+	_, _, err = PutFeed(s, "blog", parse(t, testRss))
+	assert.Nil(t, err)
+	assert.Nil(t, s.Close())
+
+	// a record cut short by a crash is dropped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.Nil(t, err)
+	_, err = f.WriteString(`{"op":"read","ids":["post-`)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	s, err = Open(path)
+	assert.Nil(t, err)
+	assert.Nil(t, s.MarkRead("post-1"))
+	assert.Nil(t, s.Close())
+
+	s, err = Open(path)
+	assert.Nil(t, err)
+	unread, err := s.Unread("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://blog.example/second"}, ids(unread))
+	assert.Nil(t, s.Close())
+
+	assert.Nil(t, os.WriteFile(path, []byte("not json\n"), 0600))
+	_, err = Open(path)
+	assert.NotNil(t, err)
+}
+
+func Test_Log_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feeds.log")
+	s, err := Open(path)
+	assert.Nil(t, err)
+	clock(s)
+
+	for i := 0; i < 5; i++ {
+		_, _, err = PutFeed(s, "blog", parse(t, strings.Replace(testRss, "The first post", "The first post, version "+string(rune('a'+i)), 1)))
+		assert.Nil(t, err)
+		assert.Nil(t, s.MarkRead("post-1"))
+		assert.Nil(t, s.MarkUnread("post-1"))
+	}
+	assert.Nil(t, s.MarkRead("https://blog.example/second"))
+	assert.Nil(t, s.SetValidators("blog", Validators{ETag: `"v1"`}))
+	assert.Nil(t, s.SetValidators("blog", Validators{ETag: `"v2"`}))
+
+	before, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Compact())
+	after, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Less(t, after.Size(), before.Size())
+
+	check := func(s *Log) {
+		unread, err := s.Unread("")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"post-1"}, ids(unread))
+		assert.Equal(t, "The first post, version e", unread[0].Item.ContentText)
+		v, err := s.Validators("blog")
+		assert.Nil(t, err)
+		assert.Equal(t, `"v2"`, v.ETag)
+	}
+	check(s)
+	assert.Nil(t, s.Close())
+	s, err = Open(path)
+	assert.Nil(t, err)
+	check(s)
+	assert.Nil(t, s.Close())
+}
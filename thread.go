@@ -0,0 +1,240 @@
+package grss
+
+import "strconv"
+
+// Atom Threading Extensions https://datatracker.ietf.org/doc/html/rfc4685
+// Well-Formed Web Comment API https://web.archive.org/web/20200807114711/http://wellformedweb.org/news/wfw_namespace_elements/
+// RSS 1.0 Slash module https://web.resource.org/rss/1.0/modules/slash/
+
+const (
+	NamespaceThread = "http://purl.org/syndication/thread/1.0"
+	NamespaceWfw    = "http://wellformedweb.org/CommentAPI/"
+	NamespaceSlash  = "http://purl.org/rss/1.0/modules/slash/"
+)
+
+// AtomInReplyTo The "in-reply-to" element is used to indicate that an entry is a response to another resource.
+//
+//	in-reply-to =
+//	  element thr:in-reply-to {
+//	    atomCommonAttributes,
+//	    ref,
+//	    href?,
+//	    source?,
+//	    type?,
+//	    ( undefinedContent )
+//	  }
+type AtomInReplyTo struct {
+	// Ref The "ref" attribute specifies the persistent, universally unique identifier of the resource being responded to.
+	Ref AtomUri `xml:"ref,attr" json:"ref"`
+	// Href If present, the "href" attribute specifies the location from which a representation of the resource being responded to can be retrieved.
+	Href AtomUri `xml:"href,attr,omitempty" json:"href,omitempty"`
+	// Type If present, the "type" attribute provides a hint to the client about the media type of the resource identified by the "href" attribute.
+	Type AtomMediaType `xml:"type,attr,omitempty" json:"type,omitempty"`
+	// Source If present, the "source" attribute provides the IRI of an Atom Feed or Entry Document containing the resource being responded to.
+	Source AtomUri `xml:"source,attr,omitempty" json:"source,omitempty"`
+}
+
+// JSONThread the _thread item extension, carrying the threading data JSON Feed has no field for.
+type JSONThread struct {
+	// InReplyTo the resources the item responds to, thr:in-reply-to.
+	InReplyTo []*AtomInReplyTo `json:"in_reply_to,omitempty"`
+	// RepliesURL the feed of responses to the item, link rel="replies" or wfw:commentRss.
+	RepliesURL string `json:"replies_url,omitempty"`
+	// CommentsURL the HTML page of responses to the item, RSS comments or link rel="replies" type="text/html".
+	CommentsURL string `json:"comments_url,omitempty"`
+	// Total the number of responses to the item, thr:total or slash:comments.
+	Total *uint64 `json:"total,omitempty"`
+}
+
+func (t *JSONThread) empty() bool {
+	return len(t.InReplyTo) == 0 && t.RepliesURL == "" && t.CommentsURL == "" && t.Total == nil
+}
+
+func (t *JSONThread) total() string {
+	if t.Total == nil {
+		return ""
+	}
+	return strconv.FormatUint(*t.Total, 10)
+}
+
+func parseTotal(s string) *uint64 {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// Thread returns the _thread extension of the item, nil if it has none.
+func (item *JSONItem) Thread() *JSONThread {
+	switch v := item.Extensions["_thread"].(type) {
+	case nil:
+		return nil
+	case *JSONThread:
+		return v
+	default:
+		t := &JSONThread{}
+		if !decodeJSONExtension(v, t) {
+			return nil
+		}
+		return t
+	}
+}
+
+// thread collects the threading data of the entry, nil if it has none.
+func (entry *AtomEntry) thread() *JSONThread {
+	t := &JSONThread{
+		InReplyTo: entry.InReplyTo,
+		Total:     parseTotal(entry.Total),
+	}
+
+	for _, link := range entry.Links {
+		if link.Rel != "replies" {
+			continue
+		}
+		if link.Type == "text/html" {
+			if t.CommentsURL == "" {
+				t.CommentsURL = string(link.Href)
+			}
+			continue
+		}
+		if t.RepliesURL == "" {
+			t.RepliesURL = string(link.Href)
+			if t.Total == nil {
+				t.Total = parseTotal(link.ThrCount)
+			}
+		}
+	}
+
+	if t.empty() {
+		return nil
+	}
+	return t
+}
+
+// thread collects the threading data of the item, nil if it has none.
+func (item *RssItem) thread() *JSONThread {
+	t := &JSONThread{
+		InReplyTo:   item.InReplyTo,
+		RepliesURL:  item.CommentRss,
+		CommentsURL: item.Comments,
+		Total:       parseTotal(item.SlashComments),
+	}
+
+	if t.empty() {
+		return nil
+	}
+	return t
+}
+
+// applyAtom writes the threading data to the entry as thr:in-reply-to, thr:total and rel="replies" links.
+func (t *JSONThread) applyAtom(entry *AtomEntry) {
+	if t == nil {
+		return
+	}
+
+	entry.InReplyTo = t.InReplyTo
+	entry.Total = t.total()
+
+	if t.RepliesURL != "" {
+		entry.Links = append(entry.Links, &AtomLink{
+			Href:     AtomUri(t.RepliesURL),
+			Rel:      "replies",
+			ThrCount: t.total(),
+		})
+	}
+	if t.CommentsURL != "" {
+		entry.Links = append(entry.Links, &AtomLink{
+			Href: AtomUri(t.CommentsURL),
+			Rel:  "replies",
+			Type: "text/html",
+		})
+	}
+}
+
+// applyRss writes the threading data to the item as comments, wfw:commentRss, slash:comments and thr:in-reply-to.
+func (t *JSONThread) applyRss(item *RssItem) {
+	if t == nil {
+		return
+	}
+
+	item.InReplyTo = t.InReplyTo
+	item.CommentRss = t.RepliesURL
+	item.Comments = t.CommentsURL
+	item.SlashComments = t.total()
+}
+
+// ReplyNode an item of a comment feed with the items that respond to it.
+type ReplyNode struct {
+	Item    *JSONItem
+	Replies []*ReplyNode
+}
+
+// Walk calls fn for n and every reply below it, depth first in feed order, with the depth below n.
+func (n *ReplyNode) Walk(fn func(node *ReplyNode, depth int)) {
+	n.walk(fn, 0)
+}
+
+func (n *ReplyNode) walk(fn func(node *ReplyNode, depth int), depth int) {
+	fn(n, depth)
+	for _, reply := range n.Replies {
+		reply.walk(fn, depth+1)
+	}
+}
+
+// ReplyTree reconstructs the reply trees of a comment feed from the thr:in-reply-to references of its items.
+// An item is a root when it replies to nothing in the feed, such as a comment on the post itself.
+// Items are matched by id, then by url, and keep their feed order among siblings.
+func ReplyTree(f Feed) []*ReplyNode {
+	items := f.ToJSON().Items
+
+	nodes := make([]*ReplyNode, len(items))
+	byRef := map[string]*ReplyNode{}
+	for i, item := range items {
+		nodes[i] = &ReplyNode{Item: item}
+		for _, ref := range []string{item.ID, item.URL} {
+			if _, ok := byRef[ref]; ref != "" && !ok {
+				byRef[ref] = nodes[i]
+			}
+		}
+	}
+
+	parents := map[*ReplyNode]*ReplyNode{}
+	for _, node := range nodes {
+		t := node.Item.Thread()
+		if t == nil {
+			continue
+		}
+		for _, irt := range t.InReplyTo {
+			parent := byRef[string(irt.Ref)]
+			if parent == nil {
+				parent = byRef[string(irt.Href)]
+			}
+			if parent == nil || parent == node || isAncestor(parents, node, parent) {
+				continue
+			}
+			parents[node] = parent
+			break
+		}
+	}
+
+	var roots []*ReplyNode
+	for _, node := range nodes {
+		if parent, ok := parents[node]; ok {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// isAncestor reports whether node is above n, that is whether making node a child of n would close a cycle.
+func isAncestor(parents map[*ReplyNode]*ReplyNode, node, n *ReplyNode) bool {
+	for p, ok := n, true; ok; p, ok = parents[p] {
+		if p == node {
+			return true
+		}
+	}
+	return false
+}
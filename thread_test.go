@@ -0,0 +1,191 @@
+package grss
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const threadTestAtom = `
+<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:thr="http://purl.org/syndication/thread/1.0">
+  <id>http://www.example.org/myfeed</id>
+  <title>My Example Feed</title>
+  <updated>2005-07-28T12:00:00Z</updated>
+  <link href="http://www.example.org/myfeed" />
+  <author><name>James</name></author>
+  <entry>
+    <id>tag:example.org,1999:entry-1</id>
+    <title>One</title>
+    <link type="application/xhtml+xml" href="http://www.example.org/entries/1" />
+    <link rel="replies" type="application/atom+xml" href="http://www.example.org/mycommentsfeed.xml" thr:count="10" thr:updated="2005-07-28T12:10:00Z" />
+    <link rel="replies" type="text/html" href="http://www.example.org/entries/1#comments" />
+    <updated>2005-07-28T12:00:00Z</updated>
+  </entry>
+  <entry>
+    <id>tag:example.org,1999:comment-2</id>
+    <title>Re: One</title>
+    <updated>2005-07-28T12:05:00Z</updated>
+    <thr:in-reply-to ref="tag:example.org,1999:entry-1" type="application/xhtml+xml" href="http://www.example.org/entries/1"/>
+    <thr:total>1</thr:total>
+    <content>A response to the original</content>
+  </entry>
+  <entry>
+    <id>tag:example.org,1999:comment-3</id>
+    <title>Re: Re: One</title>
+    <updated>2005-07-28T12:06:00Z</updated>
+    <thr:in-reply-to ref="tag:example.org,1999:comment-2"/>
+    <content>A response to the response</content>
+  </entry>
+  <entry>
+    <id>tag:example.org,1999:comment-4</id>
+    <title>Re: Other</title>
+    <updated>2005-07-28T12:07:00Z</updated>
+    <thr:in-reply-to ref="tag:example.org,1999:entry-2"/>
+    <content>A response to something not in the feed</content>
+  </entry>
+</feed>
+`
+
+const threadTestRss = `
+<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:wfw="http://wellformedweb.org/CommentAPI/" xmlns:slash="http://purl.org/rss/1.0/modules/slash/">
+  <channel>
+    <title>WordPress</title>
+    <link>https://example.com</link>
+    <description>Just another WordPress site</description>
+    <item>
+      <title>Hello world!</title>
+      <link>https://example.com/hello-world/</link>
+      <comments>https://example.com/hello-world/#comments</comments>
+      <guid isPermaLink="false">https://example.com/?p=1</guid>
+      <wfw:commentRss>https://example.com/hello-world/feed/</wfw:commentRss>
+      <slash:comments>3</slash:comments>
+    </item>
+  </channel>
+</rss>
+`
+
+func Test_AtomEntry_Thread(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(threadTestAtom))
+	assert.Nil(t, err)
+
+	a := f.ToAtom()
+	assert.Equal(t, "10", a.Entries[0].Links[1].ThrCount)
+	assert.Equal(t, "2005-07-28T12:10:00Z", a.Entries[0].Links[1].ThrUpdated)
+	assert.Equal(t, AtomUri("tag:example.org,1999:entry-1"), a.Entries[1].InReplyTo[0].Ref)
+	assert.Equal(t, AtomUri("http://www.example.org/entries/1"), a.Entries[1].InReplyTo[0].Href)
+	assert.Equal(t, "1", a.Entries[1].Total)
+	assert.Equal(t, 0, len(a.Entries[1].ExtensionElement), a.Entries[1].ExtensionElement)
+
+	var buf bytes.Buffer
+	err = a.WriteOut(&buf)
+	assert.Nil(t, err)
+	out := buf.String()
+	assert.Contains(t, out, `<link href="http://www.example.org/mycommentsfeed.xml" rel="replies" type="application/atom+xml" thr:count="10" thr:updated="2005-07-28T12:10:00Z"></link>`, out)
+	assert.Contains(t, out, `<thr:in-reply-to ref="tag:example.org,1999:entry-1" href="http://www.example.org/entries/1" type="application/xhtml+xml"></thr:in-reply-to>`, out)
+	assert.Contains(t, out, `<thr:total>1</thr:total>`, out)
+	assert.Equal(t, 1, strings.Count(out, `xmlns:thr=`), out)
+
+	// RSS
+	b := f.ToRss()
+	assert.Equal(t, "http://www.example.org/mycommentsfeed.xml", b.Channel.Items[0].CommentRss)
+	assert.Equal(t, "http://www.example.org/entries/1#comments", b.Channel.Items[0].Comments)
+	assert.Equal(t, "10", b.Channel.Items[0].SlashComments)
+	assert.Equal(t, "1", b.Channel.Items[1].SlashComments)
+	assert.Equal(t, AtomUri("tag:example.org,1999:comment-2"), b.Channel.Items[2].InReplyTo[0].Ref)
+
+	buf.Reset()
+	err = b.WriteOut(&buf)
+	assert.Nil(t, err)
+	out = buf.String()
+	assert.Contains(t, out, `<wfw:commentRss>http://www.example.org/mycommentsfeed.xml</wfw:commentRss>`, out)
+	assert.Contains(t, out, `<slash:comments>10</slash:comments>`, out)
+	assert.Contains(t, out, `<thr:in-reply-to ref="tag:example.org,1999:comment-2"></thr:in-reply-to>`, out)
+
+	// JSON
+	c := f.ToJSON()
+	thread := c.Items[0].Thread()
+	assert.Equal(t, "http://www.example.org/mycommentsfeed.xml", thread.RepliesURL)
+	assert.Equal(t, "http://www.example.org/entries/1#comments", thread.CommentsURL)
+	assert.Equal(t, uint64(10), *thread.Total)
+	assert.Nil(t, c.Items[3].Thread().Total)
+
+	buf.Reset()
+	err = c.WriteOut(&buf)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `"_thread": {`, buf.String())
+
+	_, f, err = Parse(&buf)
+	assert.Nil(t, err)
+	d := f.ToAtom()
+	assert.Equal(t, a.Entries[2].InReplyTo, d.Entries[2].InReplyTo)
+	assert.Equal(t, "replies", d.Entries[0].Links[1].Rel)
+	assert.Equal(t, "10", d.Entries[0].Links[1].ThrCount)
+}
+
+func Test_RssItem_Thread(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(threadTestRss))
+	assert.Nil(t, err)
+
+	a := f.ToRss()
+	assert.Equal(t, "https://example.com/hello-world/feed/", a.Channel.Items[0].CommentRss)
+	assert.Equal(t, "3", a.Channel.Items[0].SlashComments)
+
+	b := f.ToAtom()
+	var replies []*AtomLink
+	for _, link := range b.Entries[0].Links {
+		if link.Rel == "replies" {
+			replies = append(replies, link)
+		}
+	}
+	assert.Equal(t, 2, len(replies))
+	assert.Equal(t, AtomUri("https://example.com/hello-world/feed/"), replies[0].Href)
+	assert.Equal(t, "3", replies[0].ThrCount)
+	assert.Equal(t, AtomUri("https://example.com/hello-world/#comments"), replies[1].Href)
+	assert.Equal(t, AtomMediaType("text/html"), replies[1].Type)
+	assert.Equal(t, "3", b.Entries[0].Total)
+
+	// and back
+	var buf bytes.Buffer
+	err = b.WriteOut(&buf)
+	assert.Nil(t, err)
+	_, f, err = Parse(&buf)
+	assert.Nil(t, err)
+	c := f.ToRss()
+	assert.Equal(t, a.Channel.Items[0].CommentRss, c.Channel.Items[0].CommentRss)
+	assert.Equal(t, a.Channel.Items[0].Comments, c.Channel.Items[0].Comments)
+	assert.Equal(t, a.Channel.Items[0].SlashComments, c.Channel.Items[0].SlashComments)
+}
+
+func Test_ReplyTree(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(threadTestAtom))
+	assert.Nil(t, err)
+
+	roots := ReplyTree(f)
+	assert.Equal(t, 2, len(roots))
+	assert.Equal(t, "One", roots[0].Item.Title)
+	assert.Equal(t, "Re: Other", roots[1].Item.Title)
+
+	var titles []string
+	var depths []int
+	roots[0].Walk(func(node *ReplyNode, depth int) {
+		titles = append(titles, node.Item.Title)
+		depths = append(depths, depth)
+	})
+	assert.Equal(t, []string{"One", "Re: One", "Re: Re: One"}, titles)
+	assert.Equal(t, []int{0, 1, 2}, depths)
+
+	// a reply loop does not lose items
+	s := strings.Replace(threadTestAtom, `<thr:in-reply-to ref="tag:example.org,1999:entry-1" type`, `<thr:in-reply-to ref="tag:example.org,1999:comment-3" type`, 1)
+	_, f, err = Parse(strings.NewReader(s))
+	assert.Nil(t, err)
+
+	roots = ReplyTree(f)
+	var n int
+	for _, root := range roots {
+		root.Walk(func(node *ReplyNode, depth int) { n++ })
+	}
+	assert.Equal(t, 4, n)
+}
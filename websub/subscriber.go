@@ -0,0 +1,419 @@
+package websub
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hellodword/grss"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoCallback       = errors.New("no callback")
+	ErrNoSubscription   = errors.New("no such subscription")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// maxContentLength limits the body of a content distribution request.
+const maxContentLength = 16 << 20
+
+// State of a Subscription.
+type State int
+
+const (
+	// StatePending the request was sent, the hub has not verified it yet.
+	StatePending State = iota
+	// StateActive the hub verified the subscription, content is delivered until Expires.
+	StateActive
+	// StateDenied the hub or the publisher refused the subscription.
+	StateDenied
+	// StateUnsubscribed the hub verified the unsubscription.
+	StateUnsubscribed
+)
+
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateActive:
+		return "active"
+	case StateDenied:
+		return "denied"
+	case StateUnsubscribed:
+		return "unsubscribed"
+	default:
+		return "unknown"
+	}
+}
+
+// Subscription a topic subscribed to at a hub.
+type Subscription struct {
+	// ID identifies the subscription in its callback URL.
+	ID       string
+	Hub      string
+	Topic    string
+	Callback string
+	// Secret signs the content the hub delivers, empty when the subscription is not signed.
+	Secret string
+
+	State State
+	// Expires is the end of the lease granted by the hub.
+	Expires time.Time
+	// Reason the hub gave when it denied the subscription.
+	Reason string
+
+	// mode the hub.mode waiting for verification
+	mode string
+}
+
+// Subscriber subscribes to topics at WebSub hubs and receives their content.
+// It is the http.Handler of the callback URL, it answers the hubs' verification requests and hands delivered content to OnFeed.
+type Subscriber struct {
+	// Callback the public URL the Subscriber is served at. Each subscription gets its own callback below it.
+	Callback string
+	// Client sends the requests to the hubs, http.DefaultClient when nil.
+	Client *http.Client
+	// LeaseSeconds the lease asked for, the hub decides, zero leaves it to the hub.
+	LeaseSeconds int
+	// Sign asks the hubs to sign the content with a random secret per subscription (hub.secret).
+	// The secret is sent in the clear, only use it with https hubs.
+	Sign bool
+
+	// OnFeed is called with the content delivered for an active subscription.
+	OnFeed func(sub *Subscription, f grss.Feed)
+	// OnError is called with content that could not be accepted, such as a bad signature or a body that is no feed, optional.
+	OnError func(sub *Subscription, err error)
+
+	mu   sync.Mutex
+	subs map[string]*Subscription
+	now  func() time.Time
+}
+
+func (s *Subscriber) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *Subscriber) callbackURL(id string) (string, error) {
+	if s.Callback == "" {
+		return "", ErrNoCallback
+	}
+	u, err := url.Parse(s.Callback)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("sub", id)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Subscribe asks the hub for a subscription to topic. The subscription is pending until the hub verifies it through the callback.
+func (s *Subscriber) Subscribe(hub, topic string) (*Subscription, error) {
+	if hub == "" {
+		return nil, ErrNoHub
+	}
+
+	id := randomHex(16)
+	callback, err := s.callbackURL(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &Subscription{
+		ID:       id,
+		Hub:      hub,
+		Topic:    topic,
+		Callback: callback,
+	}
+	if s.Sign {
+		sub.Secret = randomHex(32)
+	}
+
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = map[string]*Subscription{}
+	}
+	s.subs[id] = sub
+	s.mu.Unlock()
+
+	err = s.request(sub, "subscribe")
+	if err != nil {
+		s.mu.Lock()
+		delete(s.subs, id)
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// SubscribeFeed subscribes to the feed at its first advertised hub, the topic is its self URL, or base when it has none.
+func (s *Subscriber) SubscribeFeed(f grss.Feed, base string) (*Subscription, error) {
+	hubs, self := Discover(f, base)
+	if len(hubs) == 0 {
+		return nil, ErrNoHub
+	}
+	if self == "" {
+		self = base
+	}
+	return s.Subscribe(hubs[0], self)
+}
+
+// Unsubscribe asks the hub to end the subscription, it stays active until the hub verifies the request.
+func (s *Subscriber) Unsubscribe(sub *Subscription) error {
+	s.mu.Lock()
+	_, ok := s.subs[sub.ID]
+	s.mu.Unlock()
+	if !ok {
+		return ErrNoSubscription
+	}
+	return s.request(sub, "unsubscribe")
+}
+
+func (s *Subscriber) request(sub *Subscription, mode string) error {
+	s.mu.Lock()
+	sub.mode = mode
+	form := url.Values{
+		"hub.mode":     {mode},
+		"hub.topic":    {sub.Topic},
+		"hub.callback": {sub.Callback},
+	}
+	if mode == "subscribe" {
+		if s.LeaseSeconds > 0 {
+			form.Set("hub.lease_seconds", strconv.Itoa(s.LeaseSeconds))
+		}
+		if sub.Secret != "" {
+			form.Set("hub.secret", sub.Secret)
+		}
+	}
+	s.mu.Unlock()
+
+	return postForm(s.Client, sub.Hub, form)
+}
+
+// Subscriptions returns a snapshot of the subscriptions.
+func (s *Subscriber) Subscriptions() []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var subs []Subscription
+	for _, sub := range s.subs {
+		subs = append(subs, *sub)
+	}
+	return subs
+}
+
+// Expiring returns the active subscriptions whose lease ends within d.
+func (s *Subscriber) Expiring(d time.Duration) []*Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadline := s.clock().Add(d)
+	var subs []*Subscription
+	for _, sub := range s.subs {
+		if sub.State == StateActive && !sub.Expires.IsZero() && sub.Expires.Before(deadline) {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// Renew sends a new subscription request for each active subscription whose lease ends within d, call it periodically.
+// The subscription stays active with its old lease until the hub verifies the renewal.
+func (s *Subscriber) Renew(d time.Duration) error {
+	var errs []string
+	for _, sub := range s.Expiring(d) {
+		err := s.request(sub, "subscribe")
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("renew: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *Subscriber) subscription(id string) *Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subs[id]
+}
+
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sub := s.subscription(r.URL.Query().Get("sub"))
+
+	switch r.Method {
+	case http.MethodGet:
+		s.verify(w, r, sub)
+	case http.MethodPost:
+		s.deliver(w, r, sub)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// verify answers the hub's verification of intent, and records denials.
+// https://www.w3.org/TR/websub/#hub-verifies-intent
+func (s *Subscriber) verify(w http.ResponseWriter, r *http.Request, sub *Subscription) {
+	q := r.URL.Query()
+	mode := q.Get("hub.mode")
+
+	if sub == nil || q.Get("hub.topic") != sub.Topic {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case mode == "denied":
+		// https://www.w3.org/TR/websub/#subscription-validation
+		sub.State = StateDenied
+		sub.Reason = q.Get("hub.reason")
+		sub.mode = ""
+		w.WriteHeader(http.StatusOK)
+		return
+	case mode == "" || mode != sub.mode:
+		http.NotFound(w, r)
+		return
+	}
+
+	challenge := q.Get("hub.challenge")
+	if challenge == "" {
+		http.Error(w, "missing hub.challenge", http.StatusBadRequest)
+		return
+	}
+
+	switch mode {
+	case "subscribe":
+		sub.State = StateActive
+		sub.Expires = time.Time{}
+		if lease, err := strconv.Atoi(q.Get("hub.lease_seconds")); err == nil && lease > 0 {
+			sub.Expires = s.clock().Add(time.Duration(lease) * time.Second)
+		}
+	case "unsubscribe":
+		sub.State = StateUnsubscribed
+		delete(s.subs, sub.ID)
+	}
+	sub.mode = ""
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, challenge)
+}
+
+// deliver accepts the content distributed by the hub.
+// A bad signature is still acknowledged with a 2xx, the content is ignored.
+// https://www.w3.org/TR/websub/#content-distribution
+func (s *Subscriber) deliver(w http.ResponseWriter, r *http.Request, sub *Subscription) {
+	if sub == nil {
+		// 410 Gone tells the hub to drop the subscription
+		http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxContentLength))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	secret, state := sub.Secret, sub.State
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusAccepted)
+
+	if state != StateActive {
+		return
+	}
+
+	if secret != "" {
+		err = verifySignature(secret, r.Header.Get("X-Hub-Signature"), body)
+		if err != nil {
+			s.error(sub, err)
+			return
+		}
+	}
+
+	_, f, err := grss.Parse(bytes.NewReader(body))
+	if err != nil {
+		s.error(sub, err)
+		return
+	}
+
+	if s.OnFeed != nil {
+		s.OnFeed(sub, f)
+	}
+}
+
+func (s *Subscriber) error(sub *Subscription, err error) {
+	if s.OnError != nil {
+		s.OnError(sub, err)
+	}
+}
+
+var signatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// Sign returns the X-Hub-Signature value of body, method is one of sha1, sha256, sha384 and sha512.
+func Sign(method, secret string, body []byte) (string, error) {
+	h, ok := signatureHashes[method]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", method, ErrInvalidSignature)
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return method + "=" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func verifySignature(secret, header string, body []byte) error {
+	method, signature, ok := strings.Cut(header, "=")
+	if !ok {
+		return fmt.Errorf("X-Hub-Signature %q: %w", header, ErrInvalidSignature)
+	}
+	h, ok := signatureHashes[strings.ToLower(method)]
+	if !ok {
+		return fmt.Errorf("X-Hub-Signature %q: %w", header, ErrInvalidSignature)
+	}
+	want, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("X-Hub-Signature %q: %w", header, ErrInvalidSignature)
+	}
+
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), want) {
+		return fmt.Errorf("X-Hub-Signature: %w", ErrInvalidSignature)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Package websub implements the subscriber and publisher sides of WebSub (formerly PubSubHubbub) for grss feeds.
+package websub
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellodword/grss"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// WebSub https://www.w3.org/TR/websub/
+// PubSubHubbub 0.4 https://pubsubhubbub.github.io/PubSubHubbub/pubsubhubbub-core-0.4.html
+// JSON Feed hubs https://www.jsonfeed.org/version/1.1/#subscribing-to-real-time-notifications-a-name-subscribing-to-real-time-notifications-a
+
+var (
+	ErrNoHub       = errors.New("no hub")
+	ErrHubRejected = errors.New("hub rejected the request")
+)
+
+// Discover returns the WebSub hubs and the self URL (the topic) advertised by f.
+// Atom uses <link rel="hub">, RSS <atom:link rel="hub"> and JSON Feed hubs with the type WebSub, relative URLs are resolved against base.
+func Discover(f grss.Feed, base string) (hubs []string, self string) {
+	var links []*grss.AtomLink
+	switch ff := f.(type) {
+	case *grss.AtomFeed:
+		links = ff.Links
+	case *grss.RssFeed:
+		if ff.Channel != nil {
+			links = ff.Channel.AtomLinks()
+		}
+	case *grss.JSONFeed:
+		for _, hub := range ff.Hubs {
+			if strings.EqualFold(hub.Type, "WebSub") || strings.EqualFold(hub.Type, "PubSubHubbub") {
+				hubs = append(hubs, resolve(base, hub.URL))
+			}
+		}
+		return hubs, resolve(base, ff.FeedURL)
+	}
+
+	for _, link := range links {
+		switch link.Rel {
+		case "hub":
+			hubs = append(hubs, resolve(base, string(link.Href)))
+		case "self":
+			if self == "" {
+				self = resolve(base, string(link.Href))
+			}
+		}
+	}
+	return hubs, self
+}
+
+var linkHeaderRegexp = regexp.MustCompile(`<([^>]*)>\s*((?:;\s*[^;,]*)*)`)
+var linkRelRegexp = regexp.MustCompile(`(?i);\s*rel\s*=\s*"?([^";,]*)"?`)
+
+// DiscoverHeader returns the hubs and the self URL of the Link headers of a response, the discovery method that works for any content type.
+func DiscoverHeader(h http.Header, base string) (hubs []string, self string) {
+	for _, v := range h.Values("Link") {
+		for _, m := range linkHeaderRegexp.FindAllStringSubmatch(v, -1) {
+			rel := linkRelRegexp.FindStringSubmatch(m[2])
+			if rel == nil {
+				continue
+			}
+			for _, r := range strings.Fields(rel[1]) {
+				switch strings.ToLower(r) {
+				case "hub":
+					hubs = append(hubs, resolve(base, m[1]))
+				case "self":
+					if self == "" {
+						self = resolve(base, m[1])
+					}
+				}
+			}
+		}
+	}
+	return hubs, self
+}
+
+func resolve(base, ref string) string {
+	if ref == "" || base == "" {
+		return ref
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// postForm sends a form to the hub, any 2xx status is a success, WebSub hubs answer 202 Accepted.
+func postForm(client *http.Client, hub string, form url.Values) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.PostForm(hub, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %w: %s %s", form.Get("hub.mode"), hub, ErrHubRejected, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Publish notifies the hub that the topics have new content, the hub then fetches them and distributes them to the subscribers.
+// WebSub leaves the publish request to the hub, this is the hub.mode=publish request of PubSubHubbub 0.4 that common hubs accept.
+func Publish(client *http.Client, hub string, topics ...string) error {
+	if hub == "" {
+		return ErrNoHub
+	}
+
+	form := url.Values{
+		"hub.mode": {"publish"},
+		"hub.url":  topics,
+	}
+	return postForm(client, hub, form)
+}
@@ -0,0 +1,245 @@
+package websub
+
+import (
+	"bytes"
+	"errors"
+	"github.com/hellodword/grss"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testAtom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example Feed</title>
+  <id>urn:uuid:60a76c80-d399-11d9-b93C-0003939e0af6</id>
+  <link rel="self" href="/feed.atom"/>
+  <link rel="hub" href="HUB"/>
+  <updated>2003-12-13T18:30:02Z</updated>
+  <entry>
+    <title>Atom-Powered Robots Run Amok</title>
+    <id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+    <updated>2003-12-13T18:30:02Z</updated>
+  </entry>
+</feed>`
+
+// stubHub a minimal in-process hub, it verifies intent synchronously and distributes the topic content on publish.
+type stubHub struct {
+	t      *testing.T
+	lease  string
+	deny   bool
+	mu     sync.Mutex
+	subs   map[string]url.Values
+	topics map[string]string
+	method string
+}
+
+func (h *stubHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	assert.Nil(h.t, err)
+
+	switch r.PostForm.Get("hub.mode") {
+	case "subscribe", "unsubscribe":
+		callback := r.PostForm.Get("hub.callback")
+		q := url.Values{
+			"hub.mode":      {r.PostForm.Get("hub.mode")},
+			"hub.topic":     {r.PostForm.Get("hub.topic")},
+			"hub.challenge": {"c-" + r.PostForm.Get("hub.topic")},
+		}
+		if h.deny {
+			q = url.Values{
+				"hub.mode":   {"denied"},
+				"hub.topic":  {r.PostForm.Get("hub.topic")},
+				"hub.reason": {"not allowed"},
+			}
+		} else if r.PostForm.Get("hub.mode") == "subscribe" {
+			q.Set("hub.lease_seconds", h.lease)
+		}
+
+		resp, err := http.Get(callback + "&" + q.Encode())
+		assert.Nil(h.t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		h.mu.Lock()
+		if !h.deny && string(body) == q.Get("hub.challenge") {
+			if r.PostForm.Get("hub.mode") == "subscribe" {
+				h.subs[callback] = r.PostForm
+			} else {
+				delete(h.subs, callback)
+			}
+		}
+		h.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	case "publish":
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for _, topic := range r.PostForm["hub.url"] {
+			for callback, sub := range h.subs {
+				if sub.Get("hub.topic") != topic {
+					continue
+				}
+				body := []byte(h.topics[topic])
+				req, _ := http.NewRequest(http.MethodPost, callback, bytes.NewReader(body))
+				req.Header.Set("Content-Type", "application/atom+xml")
+				req.Header.Add("Link", `<`+topic+`>; rel="self"`)
+				if secret := sub.Get("hub.secret"); secret != "" {
+					signature, err := Sign(h.method, secret, body)
+					assert.Nil(h.t, err)
+					req.Header.Set("X-Hub-Signature", signature)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				assert.Nil(h.t, err)
+				assert.Equal(h.t, http.StatusAccepted, resp.StatusCode)
+				resp.Body.Close()
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "bad hub.mode", http.StatusBadRequest)
+	}
+}
+
+func Test_Subscriber(t *testing.T) {
+	hub := &stubHub{t: t, lease: "3600", method: "sha256", subs: map[string]url.Values{}, topics: map[string]string{}}
+	hubServer := httptest.NewServer(hub)
+	defer hubServer.Close()
+
+	var feeds []grss.Feed
+	var errs []error
+	s := &Subscriber{
+		Sign: true,
+		OnFeed: func(sub *Subscription, f grss.Feed) {
+			feeds = append(feeds, f)
+		},
+		OnError: func(sub *Subscription, err error) {
+			errs = append(errs, err)
+		},
+	}
+	subscriberServer := httptest.NewServer(s)
+	defer subscriberServer.Close()
+	s.Callback = subscriberServer.URL + "/websub"
+
+	content := strings.Replace(testAtom, "HUB", hubServer.URL, 1)
+	topic := "http://example.org/feed.atom"
+	hub.topics[topic] = content
+
+	_, f, err := grss.Parse(strings.NewReader(content))
+	assert.Nil(t, err)
+
+	hubs, self := Discover(f, "http://example.org/")
+	assert.Equal(t, []string{hubServer.URL}, hubs)
+	assert.Equal(t, topic, self)
+
+	// the hub advertised in any format
+	hubs, self = Discover(f.ToRss(), "")
+	assert.Equal(t, []string{hubServer.URL}, hubs)
+	assert.Equal(t, "/feed.atom", self)
+	hubs, _ = Discover(f.ToJSON(), "")
+	assert.Equal(t, []string{hubServer.URL}, hubs)
+	hubs, _ = Discover(f.ToJSON().ToRss(), "")
+	assert.Equal(t, []string{hubServer.URL}, hubs)
+
+	start := time.Now()
+	sub, err := s.SubscribeFeed(f, "http://example.org/")
+	assert.Nil(t, err)
+	assert.Equal(t, StateActive, sub.State)
+	assert.Equal(t, topic, sub.Topic)
+	assert.NotEqual(t, "", sub.Secret)
+	assert.WithinDuration(t, start.Add(time.Hour), sub.Expires, time.Minute)
+
+	err = Publish(nil, hubServer.URL, topic)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(errs), errs)
+	assert.Equal(t, 1, len(feeds))
+	assert.Equal(t, "Atom-Powered Robots Run Amok", feeds[0].ToJSON().Items[0].Title)
+
+	// a forged delivery is acknowledged and dropped
+	resp, err := http.Post(sub.Callback, "application/atom+xml", strings.NewReader(content))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, 1, len(feeds))
+	assert.Equal(t, 1, len(errs))
+	assert.True(t, errors.Is(errs[0], ErrInvalidSignature), errs)
+
+	// unknown callbacks are gone
+	resp, err = http.Post(s.Callback+"?sub=nope", "application/atom+xml", strings.NewReader(content))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+
+	// lease renewal
+	assert.Equal(t, 0, len(s.Expiring(time.Minute)))
+	s.now = func() time.Time { return start.Add(59 * time.Minute) }
+	assert.Equal(t, 1, len(s.Expiring(5*time.Minute)))
+	hub.lease = "7200"
+	err = s.Renew(5 * time.Minute)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, start.Add(59*time.Minute+2*time.Hour), sub.Expires, time.Minute)
+	assert.Equal(t, 0, len(s.Expiring(5*time.Minute)))
+
+	err = s.Unsubscribe(sub)
+	assert.Nil(t, err)
+	assert.Equal(t, StateUnsubscribed, sub.State)
+	assert.Equal(t, 0, len(s.Subscriptions()))
+	assert.Equal(t, 0, len(hub.subs))
+}
+
+func Test_Subscriber_Denied(t *testing.T) {
+	hub := &stubHub{t: t, deny: true, subs: map[string]url.Values{}}
+	hubServer := httptest.NewServer(hub)
+	defer hubServer.Close()
+
+	s := &Subscriber{}
+	subscriberServer := httptest.NewServer(s)
+	defer subscriberServer.Close()
+	s.Callback = subscriberServer.URL
+
+	sub, err := s.Subscribe(hubServer.URL, "http://example.org/feed.atom")
+	assert.Nil(t, err)
+	assert.Equal(t, StateDenied, sub.State)
+	assert.Equal(t, "not allowed", sub.Reason)
+
+	// a verification nobody asked for
+	resp, err := http.Get(sub.Callback + "&" + url.Values{
+		"hub.mode":      {"unsubscribe"},
+		"hub.topic":     {"http://example.org/feed.atom"},
+		"hub.challenge": {"x"},
+	}.Encode())
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	rejecting := httptest.NewServer(http.NotFoundHandler())
+	defer rejecting.Close()
+	_, err = s.Subscribe(rejecting.URL, "http://example.org/feed.atom")
+	assert.True(t, errors.Is(err, ErrHubRejected), err)
+
+	_, err = s.SubscribeFeed(&grss.JSONFeed{}, "")
+	assert.Equal(t, ErrNoHub, err)
+}
+
+func Test_DiscoverHeader(t *testing.T) {
+	h := http.Header{}
+	h.Add("Link", `<https://hub.example.com/>; rel="hub", </feed>; rel="self"`)
+	h.Add("Link", `<https://hub2.example.com/>; rel=hub`)
+
+	hubs, self := DiscoverHeader(h, "https://example.com/blog/")
+	assert.Equal(t, []string{"https://hub.example.com/", "https://hub2.example.com/"}, hubs)
+	assert.Equal(t, "https://example.com/feed", self)
+}
+
+func Test_Sign(t *testing.T) {
+	signature, err := Sign("sha1", "secret", []byte("body"))
+	assert.Nil(t, err)
+	assert.Nil(t, verifySignature("secret", signature, []byte("body")))
+	assert.NotNil(t, verifySignature("secret", signature, []byte("other")))
+	assert.NotNil(t, verifySignature("secret", "md5=00", []byte("body")))
+
+	_, err = Sign("md5", "secret", nil)
+	assert.NotNil(t, err)
+}
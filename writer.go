@@ -0,0 +1,296 @@
+package grss
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nbio/xml"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"io"
+	"strings"
+)
+
+// https://www.w3.org/TR/xml-stylesheet/
+// https://www.w3.org/TR/REC-xml/#charencoding
+
+var (
+	ErrUnsupportedCharset = errors.New("unsupported charset")
+)
+
+// defaultIndent is the indentation used by WriteOut.
+const defaultIndent = "    "
+
+// HTMLContentMode selects how HTML content is written in the XML formats.
+type HTMLContentMode int
+
+const (
+	// HTMLContentKeep writes HTML content the way it was parsed or constructed.
+	HTMLContentKeep HTMLContentMode = iota
+	// HTMLContentCDATA wraps HTML content in <![CDATA[ ... ]]> sections.
+	HTMLContentCDATA
+	// HTMLContentEscaped writes HTML content as escaped character data.
+	HTMLContentEscaped
+)
+
+// XmlStylesheet is an <?xml-stylesheet?> processing instruction, it lets browsers render a feed with XSLT or CSS instead of showing the raw XML.
+type XmlStylesheet struct {
+	// Href is the URL of the stylesheet.
+	Href string
+	// Type is the media type of the stylesheet, such as text/xsl or text/css.
+	Type string
+	// Title names the stylesheet, optional.
+	Title string
+	// Media is the media query the stylesheet applies to, optional.
+	Media string
+	// Alternate marks the stylesheet as an alternative one.
+	Alternate bool
+}
+
+func (s *XmlStylesheet) String() string {
+	var b strings.Builder
+	b.WriteString("<?xml-stylesheet")
+	for _, attr := range [][2]string{
+		{"href", s.Href},
+		{"type", s.Type},
+		{"title", s.Title},
+		{"media", s.Media},
+	} {
+		if attr[1] == "" {
+			continue
+		}
+		b.WriteString(" " + attr[0] + `="`)
+		_ = xml.EscapeText(&b, []byte(attr[1]))
+		b.WriteString(`"`)
+	}
+	if s.Alternate {
+		b.WriteString(` alternate="yes"`)
+	}
+	b.WriteString("?>")
+	return b.String()
+}
+
+// WriteOptions controls how a Feed is written by WriteOutWith.
+//
+// The zero value writes compact UTF-8 output with an XML declaration and HTML escaping in JSON.
+// Charset, Stylesheets and HTMLContent apply to the XML formats only, JSON is always UTF-8 (RFC 8259).
+type WriteOptions struct {
+	// Indent is the string used for each nesting level, empty for compact output.
+	Indent string
+	// OmitDeclaration skips the <?xml version="1.0" encoding="..."?> declaration.
+	OmitDeclaration bool
+	// Charset is the IANA name of the output encoding, such as ISO-8859-1 or GB18030. Empty means UTF-8.
+	// Runes the charset cannot represent are written as numeric character references, which are not interpreted inside CDATA sections, so prefer HTMLContentEscaped with legacy charsets.
+	Charset string
+	// Stylesheets are written as <?xml-stylesheet?> processing instructions before the root element.
+	Stylesheets []*XmlStylesheet
+	// HTMLContent selects between CDATA and escaped text for HTML content.
+	HTMLContent HTMLContentMode
+	// DisableHTMLEscape stops the JSON encoder from escaping <, > and & inside strings.
+	DisableHTMLEscape bool
+	// SingleEnclosure writes at most one <enclosure> per RSS item as strict RSS 2.0 consumers expect, the others are written as media:content.
+	SingleEnclosure bool
+}
+
+func (o *WriteOptions) charsetEncoding() (string, encoding.Encoding, error) {
+	charset := strings.TrimSpace(o.Charset)
+	if charset == "" {
+		return "UTF-8", unicode.UTF8, nil
+	}
+
+	enc, err := ianaindex.IANA.Encoding(charset)
+	if err != nil {
+		return "", nil, fmt.Errorf("charset %s: %w", charset, err)
+	}
+	if enc == nil {
+		return "", nil, fmt.Errorf("charset %s: %w", charset, ErrUnsupportedCharset)
+	}
+
+	return charset, enc, nil
+}
+
+// as returns a copy of a that holds its text in the form mode asks for.
+// Markup-only content (InnerXml without character data) is left untouched.
+func (a XmlText) as(mode HTMLContentMode) XmlText {
+	s := a.Text
+	if s == "" {
+		s = a.Cdata
+	}
+	if s == "" {
+		return a
+	}
+
+	switch mode {
+	case HTMLContentCDATA:
+		return XmlText{Cdata: s}
+	case HTMLContentEscaped:
+		return XmlText{Text: s}
+	default:
+		return a
+	}
+}
+
+func writeXml(w io.Writer, v interface{}, opts WriteOptions, doctype string) error {
+	charset, enc, err := opts.charsetEncoding()
+	if err != nil {
+		return err
+	}
+
+	var tw io.WriteCloser
+	if enc != unicode.UTF8 {
+		tw = transform.NewWriter(w, encoding.HTMLEscapeUnsupported(enc.NewEncoder()))
+		w = tw
+	}
+
+	var head strings.Builder
+	if !opts.OmitDeclaration {
+		head.WriteString(`<?xml version="1.0" encoding="` + charset + `"?>` + "\n")
+	}
+	for _, s := range opts.Stylesheets {
+		head.WriteString(s.String() + "\n")
+	}
+	if doctype != "" {
+		head.WriteString("<!" + doctype + ">\n")
+	}
+
+	_, err = io.WriteString(w, head.String())
+	if err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", opts.Indent)
+	err = e.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	if tw != nil {
+		return tw.Close()
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}, opts WriteOptions) error {
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(!opts.DisableHTMLEscape)
+	e.SetIndent("", opts.Indent)
+	return e.Encode(v)
+}
+
+// withOptions returns a shallow copy of f shaped by the HTMLContent and SingleEnclosure options, f itself is not modified.
+func (f *RssFeed) withOptions(opts WriteOptions) *RssFeed {
+	if opts.HTMLContent == HTMLContentKeep && !opts.SingleEnclosure {
+		return f
+	}
+
+	ff := *f
+	if f.Channel != nil {
+		channel := *f.Channel
+		channel.Description = channel.Description.as(opts.HTMLContent)
+		channel.Items = rssItemsWithOptions(f.Channel.Items, opts)
+		ff.Channel = &channel
+	}
+	ff.Items = rssItemsWithOptions(f.Items, opts)
+
+	return &ff
+}
+
+func rssItemsWithOptions(items []*RssItem, opts WriteOptions) []*RssItem {
+	var out []*RssItem
+	for _, item := range items {
+		c := *item
+		if item.Description != nil {
+			description := item.Description.as(opts.HTMLContent)
+			c.Description = &description
+		}
+		if item.ContentEncoded != nil {
+			c.ContentEncoded = &RssContent{
+				XMLName: item.ContentEncoded.XMLName,
+				XmlText: item.ContentEncoded.XmlText.as(opts.HTMLContent),
+			}
+		}
+		if opts.SingleEnclosure && len(item.Enclosures) > 1 {
+			c.Enclosures = item.Enclosures[:1]
+			c.MediaContents = append([]*MediaContent{}, item.MediaContents...)
+			for _, enclosure := range item.Enclosures[1:] {
+				c.MediaContents = append(c.MediaContents, &MediaContent{
+					Url:      enclosure.Url,
+					FileSize: enclosure.Length,
+					Type:     enclosure.Type,
+				})
+			}
+		}
+		out = append(out, &c)
+	}
+	return out
+}
+
+// withHTMLContent returns a shallow copy of f whose type="html" text and content is held as mode asks, f itself is not modified.
+func (f *AtomFeed) withHTMLContent(mode HTMLContentMode) *AtomFeed {
+	if mode == HTMLContentKeep {
+		return f
+	}
+
+	ff := *f
+	ff.Title = f.Title.withHTMLContent(mode)
+	ff.Subtitle = f.Subtitle.withHTMLContent(mode)
+	ff.Rights = f.Rights.withHTMLContent(mode)
+
+	ff.Entries = nil
+	for _, entry := range f.Entries {
+		c := *entry
+		c.Title = entry.Title.withHTMLContent(mode)
+		c.Summary = entry.Summary.withHTMLContent(mode)
+		c.Rights = entry.Rights.withHTMLContent(mode)
+		if entry.Content != nil && entry.Content.Type == "html" {
+			content := *entry.Content
+			content.XmlText = content.XmlText.as(mode)
+			c.Content = &content
+		}
+		ff.Entries = append(ff.Entries, &c)
+	}
+
+	return &ff
+}
+
+func (a *AtomTextConstruct) withHTMLContent(mode HTMLContentMode) *AtomTextConstruct {
+	if a == nil || a.Type != "html" {
+		return a
+	}
+	c := *a
+	c.XmlText = c.XmlText.as(mode)
+	return &c
+}
+
+// withHTMLContent returns a shallow copy of f whose HTML-bearing text is held as mode asks, f itself is not modified.
+func (f *RdfFeed) withHTMLContent(mode HTMLContentMode) *RdfFeed {
+	if mode == HTMLContentKeep {
+		return f
+	}
+
+	ff := *f
+	if f.Channel != nil {
+		channel := *f.Channel
+		channel.Description = channel.Description.as(mode)
+		ff.Channel = &channel
+	}
+
+	ff.Items = nil
+	for _, item := range f.Items {
+		c := *item
+		if item.Description != nil {
+			description := item.Description.as(mode)
+			c.Description = &description
+		}
+		if item.ContentEncoded != nil {
+			content := item.ContentEncoded.as(mode)
+			c.ContentEncoded = &content
+		}
+		ff.Items = append(ff.Items, &c)
+	}
+
+	return &ff
+}
@@ -0,0 +1,125 @@
+package grss
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const writerTestRss = `
+<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Café 中文</title>
+    <link>http://example.com/</link>
+    <description>desc</description>
+    <item>
+      <title>Item</title>
+      <link>http://example.com/1</link>
+      <description>&lt;p&gt;escaped&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>cdata & more</p>]]></content:encoded>
+    </item>
+  </channel>
+</rss>
+`
+
+func Test_WriteOptions_Compact(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(writerTestRss))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = f.ToRss().WriteOutWith(&buf, WriteOptions{OmitDeclaration: true})
+	assert.Nil(t, err)
+
+	assert.True(t, strings.HasPrefix(buf.String(), "<rss"), buf.String())
+	assert.Equal(t, 0, strings.Count(buf.String(), "\n"), buf.String())
+
+	buf.Reset()
+	err = f.ToRss().WriteOut(&buf)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(buf.String(), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"), buf.String())
+	assert.Contains(t, buf.String(), "\n    <channel>", buf.String())
+}
+
+func Test_WriteOptions_Charset(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(writerTestRss))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = f.ToRss().WriteOutWith(&buf, WriteOptions{Charset: "ISO-8859-1"})
+	assert.Nil(t, err)
+
+	assert.True(t, strings.HasPrefix(buf.String(), `<?xml version="1.0" encoding="ISO-8859-1"?>`), buf.String())
+	assert.Contains(t, buf.String(), "<title>Caf\xe9 &#20013;&#25991;</title>", buf.String())
+
+	// round trip through the charset reader
+	_, f, err = Parse(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "Café 中文", f.ToJSON().Title)
+
+	buf.Reset()
+	err = f.ToRss().WriteOutWith(&buf, WriteOptions{Charset: "GB18030"})
+	assert.Nil(t, err)
+	_, f, err = Parse(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "Café 中文", f.ToJSON().Title)
+
+	err = f.ToRss().WriteOutWith(&buf, WriteOptions{Charset: "no-such-charset"})
+	assert.NotNil(t, err)
+}
+
+func Test_WriteOptions_Stylesheet(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(writerTestRss))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = f.ToAtom().WriteOutWith(&buf, WriteOptions{
+		Stylesheets: []*XmlStylesheet{
+			{Href: "/feed.xsl?a=1&b=2", Type: "text/xsl"},
+		},
+	})
+	assert.Nil(t, err)
+
+	assert.Contains(t, buf.String(), "?>\n<?xml-stylesheet href=\"/feed.xsl?a=1&amp;b=2\" type=\"text/xsl\"?>\n<feed", buf.String())
+}
+
+func Test_WriteOptions_HTMLContent(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(writerTestRss))
+	assert.Nil(t, err)
+	rss := f.ToRss()
+
+	var buf bytes.Buffer
+	err = rss.WriteOutWith(&buf, WriteOptions{HTMLContent: HTMLContentCDATA})
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "<description><![CDATA[<p>escaped</p>]]></description>", buf.String())
+	assert.Contains(t, buf.String(), "<content:encoded><![CDATA[<p>cdata & more</p>]]></content:encoded>", buf.String())
+
+	buf.Reset()
+	err = rss.WriteOutWith(&buf, WriteOptions{HTMLContent: HTMLContentEscaped})
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "<content:encoded>&lt;p&gt;cdata &amp; more&lt;/p&gt;</content:encoded>", buf.String())
+
+	// the feed itself is left as it was
+	assert.Equal(t, "", rss.Channel.Items[0].ContentEncoded.Cdata)
+
+	buf.Reset()
+	err = rss.ToAtom().WriteOutWith(&buf, WriteOptions{HTMLContent: HTMLContentCDATA})
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "<![CDATA[<p>cdata & more</p>]]>", buf.String())
+}
+
+func Test_WriteOptions_JSON(t *testing.T) {
+	_, f, err := Parse(strings.NewReader(writerTestRss))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = f.ToJSON().WriteOutWith(&buf, WriteOptions{DisableHTMLEscape: true})
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `"content_html":"<p>cdata & more</p>"`, buf.String())
+
+	buf.Reset()
+	err = f.ToJSON().WriteOutWith(&buf, WriteOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `\u003cp\u003e`, buf.String())
+}
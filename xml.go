@@ -5,6 +5,7 @@ import (
 	"github.com/nbio/xml"
 	"golang.org/x/text/encoding/ianaindex"
 	"io"
+	"strings"
 )
 
 type XmlGeneric struct {
@@ -13,6 +14,15 @@ type XmlGeneric struct {
 	XmlText
 }
 
+// MarshalXML writes the element under its own name and attributes, which the XmlText marshaler alone would drop.
+func (a *XmlGeneric) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if a.XMLName.Local != "" {
+		start.Name = a.XMLName
+	}
+	start.Attr = append(start.Attr, a.Attributes...)
+	return a.XmlText.MarshalXML(e, start)
+}
+
 type XmlText struct {
 	Text     string `xml:",chardata"`
 	Cdata    string `xml:",cdata"`
@@ -20,7 +30,9 @@ type XmlText struct {
 }
 
 func (a *XmlText) String() string {
-	if a.Text != "" {
+	if a == nil {
+		return ""
+	} else if a.Text != "" {
 		return a.Text
 	} else if a.Cdata != "" {
 		return a.Cdata
@@ -35,7 +47,10 @@ func (a *XmlText) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 		Cdata    string `xml:",cdata"`
 		InnerXml string `xml:",innerxml"`
 	}
-	if a.Text != "" {
+	if strings.TrimSpace(a.Text) == "" && a.Cdata == "" && a.InnerXml != "" {
+		// structured content, the character data is only the whitespace between child elements
+		inner.InnerXml = a.InnerXml
+	} else if a.Text != "" {
 		inner.Text = a.Text
 	} else if a.Cdata != "" {
 		inner.Cdata = a.Cdata